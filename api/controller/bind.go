@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// report invalid fields by the name clients send them with
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// fieldName is the JSON name of a request field, or its form name for
+// multipart requests.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// invalidRequest turns an error binding a request into a validation error,
+// listing the fields that broke a rule.
+func invalidRequest(err error) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return &domain.Error{Kind: domain.KindValidation, Code: "invalid_request", Message: "invalid request body: " + err.Error(), Err: err}
+	}
+
+	fields := make(map[string]string, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields[fe.Field()] = usecase.DescribeFieldError(fe)
+	}
+	return domain.InvalidFields("invalid_request", "invalid request fields", fields)
+}
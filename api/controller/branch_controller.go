@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type BranchController struct {
+	BranchUsecase domain.BranchUsecase
+}
+
+func NewBranchController(branchUsecase domain.BranchUsecase) *BranchController {
+	return &BranchController{
+		BranchUsecase: branchUsecase,
+	}
+}
+
+func (bc *BranchController) CreateBranch(ctx *gin.Context) {
+	var request domain.CreateBranchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	branch, err := bc.BranchUsecase.Create(ctx, request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, branch)
+}
+
+func (bc *BranchController) GetBranches(ctx *gin.Context) {
+	branches, err := bc.BranchUsecase.List(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, branches)
+}
+
+// UpdateBranch renames, closes or reopens a branch. Officers stay attached
+// to a closed branch, but no officer can be attached to it anymore.
+func (bc *BranchController) UpdateBranch(ctx *gin.Context) {
+	var request domain.UpdateBranchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	branch, err := bc.BranchUsecase.Update(ctx, ctx.Param("id"), request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, branch)
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// DocsController serves the OpenAPI document of the API and a page to read
+// it with.
+type DocsController struct {
+	spec []byte
+}
+
+func NewDocsController(doc *openapi.Document) (*DocsController, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsController{spec: spec}, nil
+}
+
+func (dc *DocsController) GetSpec(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json", dc.spec)
+}
+
+func (dc *DocsController) GetDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage)
+}
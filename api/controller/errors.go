@@ -0,0 +1,33 @@
+package controller
+
+import "github.com/dagota12/Loan-Tracker/domain"
+
+// Errors the controllers answer with themselves, for requests that are
+// rejected before they reach a usecase.
+var (
+	errMissingUserID       = domain.Validation("missing_user_id", "missing user ID")
+	errNotSignedIn         = domain.Unauthorized("unauthenticated", "missing user ID")
+	errInvalidCredentials  = domain.Unauthorized("invalid_credentials", "invalid email or password")
+	errAccountNotActive    = domain.Unauthorized("account_not_active", "user is not active")
+	errAccountSuspended    = domain.Forbidden("account_suspended", "user is suspended")
+	errInvalidRefreshToken = domain.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	errRefreshTokenRevoked = domain.Unauthorized("refresh_token_revoked", "refresh token has been revoked")
+	errLockedOut           = domain.TooManyRequests("locked_out", "too many failed attempts, try again later")
+	errOTPExpired          = domain.Unauthorized("otp_expired", "OTP expired")
+	errOTPExhausted        = domain.Unauthorized("otp_exhausted", "Too many invalid attempts, request a new OTP")
+	errInvalidOTP          = domain.Unauthorized("invalid_otp", "Invalid OTP")
+	errInvalidVerification = domain.Unauthorized("invalid_verification_link", "unauthorized")
+	errAlreadyVerified     = domain.Conflict("already_verified", "user already verified!")
+	errStaleVerification   = domain.Unauthorized("stale_verification_link", "verification link is no longer valid")
+	errVerificationCooling = domain.TooManyRequests("verification_cooldown", "a verification email was sent recently, please wait before requesting another")
+	errUserExists          = domain.Conflict("user_exists", "user already exists")
+	errMissingFile         = domain.Validation("missing_file", "missing file")
+	errInvalidKYCStatus    = domain.Validation("invalid_status", "invalid status")
+	errInvalidExportFormat = domain.Validation("invalid_format", "format must be json or zip")
+
+	errPatchContentType error = &domain.Error{
+		Kind:    domain.KindUnsupportedMediaType,
+		Code:    "unsupported_media_type",
+		Message: "content type must be application/merge-patch+json",
+	}
+)
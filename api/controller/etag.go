@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// errPreconditionFailed is returned for an If-Match header that can never
+// match a version, such as a weak or malformed entity tag.
+var errPreconditionFailed error = &domain.Error{
+	Kind:    domain.KindPreconditionFailed,
+	Code:    "invalid_if_match",
+	Message: "precondition failed: If-Match must be a strong entity tag from a previous response",
+}
+
+// setETag tags the response with the version of the record it carries.
+func setETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatch reads the version the client expects from the If-Match header.
+// A missing header or "*" gives version 0, which matches any version.
+func ifMatch(ctx *gin.Context) (int64, error) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return 0, errPreconditionFailed
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 1 {
+		return 0, errPreconditionFailed
+	}
+	return version, nil
+}
+
+// versionConflict prepares the answer to a write that lost to a concurrent
+// change: the response carries the current ETag, and the conflict becomes a
+// failed precondition when the client sent If-Match. Other errors are
+// returned as they are.
+func versionConflict(ctx *gin.Context, err error) error {
+	var conflict *repository.ConflictError
+	if !errors.As(err, &conflict) {
+		return err
+	}
+	setETag(ctx, conflict.Current)
+	if ctx.GetHeader("If-Match") == "" {
+		return err
+	}
+	return &domain.Error{Kind: domain.KindPreconditionFailed, Code: "version_conflict", Message: err.Error(), Err: err}
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type KYCController struct {
+	KYCUsecase domain.KYCUsecase
+}
+
+func NewKYCController(kycUsecase domain.KYCUsecase) *KYCController {
+	return &KYCController{
+		KYCUsecase: kycUsecase,
+	}
+}
+
+// GetStatus returns the verification record of the authenticated user.
+func (kc *KYCController) GetStatus(ctx *gin.Context) {
+	record, err := kc.KYCUsecase.GetStatus(ctx, ctx.GetString("x-user-id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, record)
+}
+
+// UploadDocument stores a document sent as the "file" part of a multipart
+// form, along with its "type" and optional "expires_at" fields.
+func (kc *KYCController) UploadDocument(ctx *gin.Context) {
+	var upload domain.KYCDocumentUpload
+	if err := ctx.ShouldBind(&upload); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.Error(errMissingFile)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+	defer file.Close()
+
+	document, err := kc.KYCUsecase.UploadDocument(ctx, ctx.GetString("x-user-id"), upload, fileHeader.Filename, file)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, document)
+}
+
+// Submit sends the documents of the authenticated user for review.
+func (kc *KYCController) Submit(ctx *gin.Context) {
+	record, err := kc.KYCUsecase.Submit(ctx, ctx.GetString("x-user-id"), ctx.GetString("x-actor-id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, record)
+}
+
+// ListByStatus lists the verifications in the status given by the "status"
+// query parameter, the ones waiting for review by default.
+func (kc *KYCController) ListByStatus(ctx *gin.Context) {
+	status := domain.KYCStatus(ctx.DefaultQuery("status", string(domain.KYCStatusSubmitted)))
+	switch status {
+	case domain.KYCStatusNotStarted, domain.KYCStatusSubmitted, domain.KYCStatusApproved, domain.KYCStatusRejected, domain.KYCStatusExpired:
+	default:
+		ctx.Error(errInvalidKYCStatus)
+		return
+	}
+
+	records, err := kc.KYCUsecase.ListByStatus(ctx, status)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, records)
+}
+
+// GetUserStatus returns the verification record of the user in the path,
+// which is not found until the user starts a verification.
+func (kc *KYCController) GetUserStatus(ctx *gin.Context) {
+	record, err := kc.KYCUsecase.Get(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, record)
+}
+
+// GetDocument streams an uploaded document to a reviewer.
+func (kc *KYCController) GetDocument(ctx *gin.Context) {
+	document, content, err := kc.KYCUsecase.OpenDocument(ctx, ctx.Param("id"), ctx.Param("document"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer content.Close()
+
+	ctx.DataFromReader(http.StatusOK, document.Size, document.ContentType, content, map[string]string{
+		"Content-Disposition":    "attachment; filename=" + strconv.Quote(document.FileName),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "no-store",
+	})
+}
+
+// Review approves or rejects the submitted verification of a user.
+func (kc *KYCController) Review(ctx *gin.Context) {
+	var request domain.KYCReviewRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	record, err := kc.KYCUsecase.Review(ctx, ctx.Param("id"), ctx.GetString("x-actor-id"), request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, record)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/query"
+	"github.com/gin-gonic/gin"
+)
+
+type LoanController struct {
+	LoanUsecase domain.LoanUsecase
+}
+
+func NewLoanController(loanUsecase domain.LoanUsecase) *LoanController {
+	return &LoanController{
+		LoanUsecase: loanUsecase,
+	}
+}
+
+// CreateLoan opens a loan for a borrower, pending approval.
+func (lc *LoanController) CreateLoan(ctx *gin.Context) {
+	var request domain.CreateLoanRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	loan, err := lc.LoanUsecase.Create(ctx, ctx.GetString("x-actor-id"), request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, loan)
+}
+
+// GetLoan responds with the loan, tagged with its version.
+func (lc *LoanController) GetLoan(ctx *gin.Context) {
+	loan, err := lc.LoanUsecase.Get(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	setETag(ctx, loan.Version)
+	ctx.JSON(http.StatusOK, loan)
+}
+
+// GetLoans lists one page of loans. See package query for the filter, sort
+// and paging parameters.
+func (lc *LoanController) GetLoans(ctx *gin.Context) {
+	spec, err := query.Parse(ctx.Request.URL.Query(), domain.LoanQuerySchema)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	page, err := lc.LoanUsecase.List(ctx, spec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writePage(ctx, page)
+}
+
+// GetUserLoans lists the loans of the borrower in the path, newest first.
+func (lc *LoanController) GetUserLoans(ctx *gin.Context) {
+	loans, err := lc.LoanUsecase.ListByBorrower(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, loans)
+}
+
+// ApproveLoan approves a pending loan. Like DisburseLoan, it is
+// conditional on the version from an earlier ETag when If-Match is sent.
+func (lc *LoanController) ApproveLoan(ctx *gin.Context) {
+	version, err := ifMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	loan, err := lc.LoanUsecase.Approve(ctx, ctx.Param("id"), ctx.GetString("x-actor-id"), version)
+	if err != nil {
+		ctx.Error(versionConflict(ctx, err))
+		return
+	}
+
+	setETag(ctx, loan.Version)
+	ctx.JSON(http.StatusOK, loan)
+}
+
+// DisburseLoan pays out an approved loan once the identity of the borrower
+// is verified.
+func (lc *LoanController) DisburseLoan(ctx *gin.Context) {
+	version, err := ifMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	loan, err := lc.LoanUsecase.Disburse(ctx, ctx.Param("id"), ctx.GetString("x-actor-id"), version)
+	if err != nil {
+		ctx.Error(versionConflict(ctx, err))
+		return
+	}
+
+	setETag(ctx, loan.Version)
+	ctx.JSON(http.StatusOK, loan)
+}
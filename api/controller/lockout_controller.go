@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type LockoutController struct {
+	LockoutUsecase domain.LockoutUsecase
+}
+
+func NewLockoutController(lockoutUsecase domain.LockoutUsecase) *LockoutController {
+	return &LockoutController{
+		LockoutUsecase: lockoutUsecase,
+	}
+}
+
+// UnlockWithToken unlocks the account referenced by the link sent in the
+// lockout email.
+func (lc *LockoutController) UnlockWithToken(ctx *gin.Context) {
+	err := lc.LockoutUsecase.UnlockWithToken(ctx, ctx.Param("token"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "account unlocked successfully"})
+}
+
+// UnlockUser lets an admin lift the lockout of any account.
+func (lc *LockoutController) UnlockUser(ctx *gin.Context) {
+	userID := ctx.Param("id")
+	if userID == "" {
+		ctx.Error(errMissingUserID)
+		return
+	}
+
+	err := lc.LockoutUsecase.Unlock(ctx, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "account unlocked successfully"})
+}
+
+// abortLockedOut rejects a request made while the account or client is
+// locked out, telling the client when it may retry.
+func abortLockedOut(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	ctx.Error(errLockedOut)
+	ctx.Abort()
+}
@@ -0,0 +1,16 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// writePage responds with a page of a list. The total number of matching
+// items is also sent in the X-Total-Count header.
+func writePage[T any](ctx *gin.Context, page domain.Page[T]) {
+	ctx.Header("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	ctx.JSON(http.StatusOK, page)
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -8,32 +9,47 @@ import (
 	"github.com/dagota12/Loan-Tracker/domain"
 	"github.com/dagota12/Loan-Tracker/internal/emailutil"
 	"github.com/dagota12/Loan-Tracker/internal/otputil"
+	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type ResetPasswordController struct {
 	ResetPasswordUsecase domain.ResetPasswordUsecase
+	LockoutUsecase       domain.LockoutUsecase
 	Env                  *bootstrap.Env
+	Mailer               emailutil.Mailer
 }
 
-func NewResetPasswordController(env *bootstrap.Env, resetPasswordUsecase domain.ResetPasswordUsecase) *ResetPasswordController {
+func NewResetPasswordController(env *bootstrap.Env, mailer emailutil.Mailer, resetPasswordUsecase domain.ResetPasswordUsecase, lockoutUsecase domain.LockoutUsecase) *ResetPasswordController {
 	return &ResetPasswordController{
 		ResetPasswordUsecase: resetPasswordUsecase,
+		LockoutUsecase:       lockoutUsecase,
 		Env:                  env,
+		Mailer:               mailer,
 	}
 }
 
+// forgotPasswordMessage answers every request for a reset code, so that
+// the answer does not tell whether the email belongs to an account.
+var forgotPasswordMessage = gin.H{"message": "if the email belongs to an account, a code was sent to it"}
+
+// ForgotPassword emails a reset code to the owner of the email, when
+// there is one.
 func (rc *ResetPasswordController) ForgotPassword(ctx *gin.Context) {
 	var req domain.ForgotPasswordRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 	_, err := rc.ResetPasswordUsecase.GetUserByEmail(ctx, req.Email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		ctx.JSON(http.StatusOK, forgotPasswordMessage)
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -46,17 +62,17 @@ func (rc *ResetPasswordController) ForgotPassword(ctx *gin.Context) {
 	}
 	otp, err := otputil.GenerateOTP()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
-	err = emailutil.SendOtpVerificationEmail(req.Email, otp, rc.Env)
+	err = emailutil.SendOtpVerificationEmail(rc.Mailer, req.Email, otp, rc.Env)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	hashedcode, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -67,49 +83,73 @@ func (rc *ResetPasswordController) ForgotPassword(ctx *gin.Context) {
 	}
 	err = rc.ResetPasswordUsecase.SaveOtp(ctx, &newOtp)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully"})
+	ctx.JSON(http.StatusOK, forgotPasswordMessage)
 }
 
 func (rc *ResetPasswordController) ResetPassword(ctx *gin.Context) {
 	var req domain.ResetPasswordRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	wait, err := rc.LockoutUsecase.Check(ctx, domain.LockoutOTP, req.Email, ctx.ClientIP())
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
+	if wait > 0 {
+		abortLockedOut(ctx, wait)
+		return
+	}
+
 	user, err := rc.ResetPasswordUsecase.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	originalOtp, err := rc.ResetPasswordUsecase.GetOTPByEmail(ctx, req.Email)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	if time.Now().After(originalOtp.ExpiresAt) {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "OTP expired"})
+		ctx.Error(errOTPExpired)
 		return
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(originalOtp.Code), []byte(req.Code))
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid OTP"})
+		if err := rc.LockoutUsecase.RegisterFailure(ctx, domain.LockoutOTP, req.Email, ctx.ClientIP()); err != nil {
+			ctx.Error(err)
+			return
+		}
+		exhausted, err := rc.ResetPasswordUsecase.RegisterInvalidOtp(ctx, req.Email)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		if exhausted {
+			ctx.Error(errOTPExhausted)
+			return
+		}
+		ctx.Error(errInvalidOTP)
 		return
 	}
 
 	err = rc.ResetPasswordUsecase.ResetPassword(ctx, user.ID.Hex(), &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
-	err = rc.ResetPasswordUsecase.DeleteOtp(ctx, req.Email)
+	err = rc.LockoutUsecase.RegisterSuccess(ctx, domain.LockoutOTP, req.Email)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/query"
+	"github.com/gin-gonic/gin"
+)
+
+type PortfolioController struct {
+	PortfolioUsecase domain.PortfolioUsecase
+}
+
+func NewPortfolioController(portfolioUsecase domain.PortfolioUsecase) *PortfolioController {
+	return &PortfolioController{
+		PortfolioUsecase: portfolioUsecase,
+	}
+}
+
+// AssignOfficer hands a borrower to a loan officer.
+func (pc *PortfolioController) AssignOfficer(ctx *gin.Context) {
+	var request domain.AssignOfficerRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	user, err := pc.PortfolioUsecase.AssignOfficer(ctx, ctx.GetString("x-actor-id"), ctx.Param("id"), request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// AssignBranch attaches a loan officer to a branch.
+func (pc *PortfolioController) AssignBranch(ctx *gin.Context) {
+	var request domain.AssignBranchRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	user, err := pc.PortfolioUsecase.AssignBranch(ctx, ctx.GetString("x-actor-id"), ctx.Param("id"), request.BranchID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// GetAssignmentHistory lists the officers a borrower was assigned to,
+// latest first.
+func (pc *PortfolioController) GetAssignmentHistory(ctx *gin.Context) {
+	history, err := pc.PortfolioUsecase.History(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, history)
+}
+
+// GetMyPortfolio lists one page of the loans of the borrowers assigned to
+// the authenticated officer, with what is owed on each. It takes the same
+// parameters as the loan listing.
+func (pc *PortfolioController) GetMyPortfolio(ctx *gin.Context) {
+	spec, err := query.Parse(ctx.Request.URL.Query(), domain.LoanQuerySchema)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	page, err := pc.PortfolioUsecase.Portfolio(ctx, ctx.GetString("x-user-id"), spec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writePage(ctx, page)
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyController struct {
+	PrivacyUsecase domain.PrivacyUsecase
+}
+
+func NewPrivacyController(privacyUsecase domain.PrivacyUsecase) *PrivacyController {
+	return &PrivacyController{
+		PrivacyUsecase: privacyUsecase,
+	}
+}
+
+// ExportProfile hands the authenticated user everything held about them, as
+// JSON or, with ?format=zip, as a ZIP archive that includes their documents.
+func (pc *PrivacyController) ExportProfile(ctx *gin.Context) {
+	userID := ctx.GetString("x-user-id")
+	filename := "export-" + time.Now().UTC().Format("20060102")
+	ctx.Header("Cache-Control", "no-store")
+
+	switch ctx.DefaultQuery("format", "json") {
+	case "json":
+		export, err := pc.PrivacyUsecase.Export(ctx, userID)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.Header("Content-Disposition", "attachment; filename="+filename+".json")
+		ctx.JSON(http.StatusOK, export)
+	case "zip":
+		// make sure the user exists before the archive starts streaming
+		if _, err := pc.PrivacyUsecase.Export(ctx, userID); err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.Header("Content-Disposition", "attachment; filename="+filename+".zip")
+		ctx.Header("Content-Type", "application/zip")
+		ctx.Status(http.StatusOK)
+		err := pc.PrivacyUsecase.WriteArchive(ctx, userID, ctx.Writer)
+		if err != nil {
+			// the status is already sent, so all that is left is to cut the
+			// archive short
+			ctx.Error(err)
+			ctx.Abort()
+		}
+	default:
+		ctx.Error(errInvalidExportFormat)
+	}
+}
+
+// EraseUser pseudonymizes the personal data of a user.
+func (pc *PrivacyController) EraseUser(ctx *gin.Context) {
+	err := pc.PrivacyUsecase.Erase(ctx, ctx.GetString("x-actor-id"), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "user data erased"})
+}
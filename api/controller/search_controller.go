@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	SearchUsecase domain.SearchUsecase
+}
+
+func NewSearchController(searchUsecase domain.SearchUsecase) *SearchController {
+	return &SearchController{
+		SearchUsecase: searchUsecase,
+	}
+}
+
+// Search finds records matching the "q" query parameter, best match first.
+func (sc *SearchController) Search(ctx *gin.Context) {
+	query := ctx.Query("q")
+	limit, _ := strconv.Atoi(ctx.Query("limit"))
+
+	results, err := sc.SearchUsecase.Search(ctx, query, limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domain.SearchResponse{Query: query, Results: results})
+}
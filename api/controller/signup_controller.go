@@ -1,16 +1,18 @@
 package controller
 
 import (
-	"context"
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	b64 "encoding/base64"
 
 	"github.com/dagota12/Loan-Tracker/bootstrap"
 	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/security"
 	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
@@ -26,52 +28,114 @@ func (sc *SignupController) VerifyEmail(c *gin.Context) {
 	decodedToken, _ := b64.URLEncoding.DecodeString(Verificationtoken)
 
 	valid, err := tokenutil.IsAuthorized(string(decodedToken), sc.Env.VerificationTokenSecret)
-
-	fmt.Println(string(decodedToken))
-
 	if !valid || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		c.Error(errInvalidVerification)
 		return
 	}
 
 	claims, err := tokenutil.ExtractUserClaimsFromToken(string(decodedToken), sc.Env.VerificationTokenSecret)
-	userID := claims["id"].(string)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err != nil || claims["purpose"] != domain.TokenPurposeVerifyEmail {
+		c.Error(errInvalidVerification)
 		return
 	}
-	user, err := sc.SignupUsecase.GetUserById(context.TODO(), userID)
+	userID, _ := claims["id"].(string)
+	c.Request = c.Request.WithContext(tokenutil.WithTokenTenant(c.Request.Context(), claims))
+
+	user, err := sc.SignupUsecase.GetUserById(c, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	if user.Active {
-		c.JSON(http.StatusConflict, gin.H{"error": "user already verified!"})
+		c.Error(errAlreadyVerified)
+		return
+	}
+
+	// only the most recently issued link is valid, and only once
+	if user.VerifyToken != security.HashToken(string(decodedToken)) {
+		c.Error(errStaleVerification)
 		return
 	}
 
-	err = sc.SignupUsecase.ActivateUser(context.TODO(), userID)
+	err = sc.SignupUsecase.ActivateUser(c, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
 
 }
-func (sc *SignupController) Signup(c *gin.Context) {
-	var request domain.SignupRequest
+
+// ResendVerificationEmail issues a new verification link, invalidating the
+// previous one. The response does not reveal whether the account exists.
+func (sc *SignupController) ResendVerificationEmail(c *gin.Context) {
+	var request domain.ResendVerificationRequest
 
 	err := c.ShouldBindJSON(&request)
 	if err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	response := gin.H{"message": "if the account exists and is not verified yet, a new verification email was sent"}
+
+	user, err := sc.SignupUsecase.GetUserByEmail(c, request.Email)
+	if errors.Is(err, repository.ErrUserNotFound) || (err == nil && user.Active) {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	cooldown := time.Duration(sc.Env.VerificationResendCooldown) * time.Second
+	if wait := cooldown - time.Since(user.VerifySentAt); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		c.Error(errVerificationCooling)
+		return
+	}
 
+	VerificationToken, err := sc.SignupUsecase.CreateVerificationToken(&user, sc.Env.VerificationTokenSecret, sc.Env.VerificationTokenExpiryMin)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	user, err := sc.SignupUsecase.GetUserByEmail(c, request.Email)
+	err = sc.SignupUsecase.UpdateVerifyToken(c, user.ID.Hex(), security.HashToken(VerificationToken))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	encodedToken := b64.URLEncoding.EncodeToString([]byte(VerificationToken))
+	err = sc.SignupUsecase.SendVerificationEmail(user.Email, encodedToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (sc *SignupController) Signup(c *gin.Context) {
+	var request domain.SignupRequest
+
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	_, err = sc.SignupUsecase.GetUserByEmail(c, request.Email)
 	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
+		c.Error(errUserExists)
+		return
+	}
+
+	err = sc.SignupUsecase.ValidatePassword(&request)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -80,26 +144,21 @@ func (sc *SignupController) Signup(c *gin.Context) {
 		bcrypt.DefaultCost,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	request.Password = string(encryptedPassword)
 
-	IsOwner, err := sc.SignupUsecase.IsOwner(c, user.ID.Hex())
+	role, IsOwner, err := sc.SignupUsecase.InitialRole(c, request.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	var role string
-	if IsOwner {
-		role = "admin"
-	} else {
-		role = "user"
-	}
 
 	NewUser := domain.User{
 		ID:        primitive.NewObjectID(),
+		TenantID:  c.GetString("x-tenant-id"),
 		FirstName: request.FirstName,
 		LastName:  request.LastName,
 		Email:     request.Email,
@@ -113,23 +172,29 @@ func (sc *SignupController) Signup(c *gin.Context) {
 
 	VerificationToken, err := sc.SignupUsecase.CreateVerificationToken(&NewUser, sc.Env.VerificationTokenSecret, sc.Env.VerificationTokenExpiryMin)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	user.VerifyToken = VerificationToken
-
-	_, err = sc.SignupUsecase.Create(c, &user)
+	NewUser.VerifyToken = security.HashToken(VerificationToken)
+	NewUser.VerifySentAt = time.Now()
 
+	_, err = sc.SignupUsecase.Create(c, &NewUser)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		// another signup took the address since it was checked
+		c.Error(errUserExists)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	//send email
 	encodedToken := b64.URLEncoding.EncodeToString([]byte(VerificationToken))
-	err = sc.SignupUsecase.SendVerificationEmail(user.Email, encodedToken, sc.Env)
+	err = sc.SignupUsecase.SendVerificationEmail(NewUser.Email, encodedToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "email sent successfully, please verify your email"})
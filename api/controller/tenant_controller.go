@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+type TenantController struct {
+	TenantUsecase domain.TenantUsecase
+}
+
+func NewTenantController(tenantUsecase domain.TenantUsecase) *TenantController {
+	return &TenantController{
+		TenantUsecase: tenantUsecase,
+	}
+}
+
+func (tc *TenantController) CreateTenant(ctx *gin.Context) {
+	var request domain.CreateTenantRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	tenant, err := tc.TenantUsecase.Create(ctx, request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, tenant)
+}
+
+func (tc *TenantController) GetTenants(ctx *gin.Context) {
+	tenants, err := tc.TenantUsecase.List(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tenants)
+}
+
+// UpdateTenant renames, deactivates or reactivates a tenant. The users of
+// an inactive tenant can no longer sign in or refresh their tokens.
+func (tc *TenantController) UpdateTenant(ctx *gin.Context) {
+	var request domain.UpdateTenantRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	tenant, err := tc.TenantUsecase.Update(ctx, ctx.Param("id"), request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tenant)
+}
@@ -1,24 +1,33 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/dagota12/Loan-Tracker/bootstrap"
 	"github.com/dagota12/Loan-Tracker/domain"
 	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/usecase"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthController struct {
-	AuthUsecase domain.AuthUsecase
-	Env         *bootstrap.Env
+	AuthUsecase      domain.AuthUsecase
+	LockoutUsecase   domain.LockoutUsecase
+	MagicLinkUsecase domain.MagicLinkUsecase
+	TenantUsecase    domain.TenantUsecase
+	Env              *bootstrap.Env
 }
 
-func NewAuthController(usecase domain.AuthUsecase, env *bootstrap.Env) *AuthController {
+func NewAuthController(usecase domain.AuthUsecase, lockoutUsecase domain.LockoutUsecase, magicLinkUsecase domain.MagicLinkUsecase, tenantUsecase domain.TenantUsecase, env *bootstrap.Env) *AuthController {
 	return &AuthController{
-		AuthUsecase: usecase,
-		Env:         env,
+		AuthUsecase:      usecase,
+		LockoutUsecase:   lockoutUsecase,
+		MagicLinkUsecase: magicLinkUsecase,
+		TenantUsecase:    tenantUsecase,
+		Env:              env,
 	}
 }
 
@@ -27,96 +36,203 @@ func (ac *AuthController) Login(ctx *gin.Context) {
 
 	err := ctx.ShouldBindJSON(&request)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Request body cannot be empty"})
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	wait, err := ac.LockoutUsecase.Check(ctx, domain.LockoutLogin, request.Email, ctx.ClientIP())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if wait > 0 {
+		abortLockedOut(ctx, wait)
 		return
 	}
 
 	user, err := ac.AuthUsecase.GetUserByEmail(ctx, request.Email)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if lockErr := ac.LockoutUsecase.RegisterFailure(ctx, domain.LockoutLogin, request.Email, ctx.ClientIP()); lockErr != nil {
+			ctx.Error(lockErr)
+			return
+		}
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			ctx.Error(err)
+			return
+		}
+		// an unknown email is answered like a wrong password
+		ctx.Error(errInvalidCredentials)
 		return
 	}
 
 	if !user.Active {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user is not active"})
+		ctx.Error(errAccountNotActive)
 		return
 	}
 
 	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)) != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		if lockErr := ac.LockoutUsecase.RegisterFailure(ctx, domain.LockoutLogin, request.Email, ctx.ClientIP()); lockErr != nil {
+			ctx.Error(lockErr)
+			return
+		}
+		ctx.Error(errInvalidCredentials)
 		return
 	}
 
-	accessToken, err := ac.AuthUsecase.CreateAccessToken(user, ac.Env.AccessTokenSecret, ac.Env.AccessTokenExpiryHour)
+	if user.Suspended {
+		ctx.Error(errAccountSuspended)
+		return
+	}
+
+	if err := ac.LockoutUsecase.RegisterSuccess(ctx, domain.LockoutLogin, request.Email); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	loginResponse, err := ac.issueTokens(ctx, user)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	refreshToken, err := ac.AuthUsecase.CreateRefreshToken(user, ac.Env.RefreshTokenSecret, ac.Env.RefreshTokenExpiryHour)
+	ctx.JSON(http.StatusOK, loginResponse)
+}
+
+// RequestMagicLink emails a passwordless sign in link and binds it to the
+// requesting browser with a nonce cookie.
+func (ac *AuthController) RequestMagicLink(ctx *gin.Context) {
+	var request domain.MagicLinkRequest
+
+	err := ctx.ShouldBindJSON(&request)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	err = ac.AuthUsecase.UpdateRefreshToken(ctx.Request.Context(), user.ID.Hex(), refreshToken)
+	nonce, err := ac.MagicLinkUsecase.RequestLink(ctx, request.Email)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// return response
-	loginResponse := domain.LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(domain.MagicLinkNonceCookie, nonce, ac.Env.MagicLinkExpiryMin*60, "/users/login/magic-link", "", ac.Env.AppEnv != "development", true)
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a sign in link was sent to the email address"})
+}
+
+// MagicLinkCallback exchanges a sign in link for the same token pair Login
+// returns. It only succeeds in the browser that requested the link.
+func (ac *AuthController) MagicLinkCallback(ctx *gin.Context) {
+	nonce, _ := ctx.Cookie(domain.MagicLinkNonceCookie)
+
+	user, err := ac.MagicLinkUsecase.Redeem(ctx, ctx.Param("token"), nonce)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	loginResponse, err := ac.issueTokens(ctx, user)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
+	ctx.SetCookie(domain.MagicLinkNonceCookie, "", -1, "/users/login/magic-link", "", ac.Env.AppEnv != "development", true)
 	ctx.JSON(http.StatusOK, loginResponse)
 }
 
+// issueTokens creates the access and refresh token pair of a signed in user
+// and records the refresh token.
+func (ac *AuthController) issueTokens(ctx *gin.Context, user domain.User) (domain.LoginResponse, error) {
+	accessToken, err := ac.AuthUsecase.CreateAccessToken(user, ac.Env.AccessTokenSecret, ac.Env.AccessTokenExpiryHour)
+	if err != nil {
+		return domain.LoginResponse{}, err
+	}
+
+	refreshToken, err := ac.AuthUsecase.CreateRefreshToken(user, ac.Env.RefreshTokenSecret, ac.Env.RefreshTokenExpiryHour)
+	if err != nil {
+		return domain.LoginResponse{}, err
+	}
+
+	err = ac.AuthUsecase.UpdateRefreshToken(ctx.Request.Context(), user.ID.Hex(), refreshToken)
+	if err != nil {
+		return domain.LoginResponse{}, err
+	}
+
+	return domain.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
 func (ac *AuthController) RefreshToken(c *gin.Context) {
 	var request domain.RefreshTokenRequest
 
 	err := c.ShouldBind(&request)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
 	if valid, err := tokenutil.IsAuthorized(string(request.RefreshToken), ac.Env.RefreshTokenSecret); !valid || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Error(errInvalidRefreshToken)
 		return
 	}
 
 	claims, err := tokenutil.ExtractUserClaimsFromToken(request.RefreshToken, ac.Env.RefreshTokenSecret)
 
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Error(errInvalidRefreshToken)
+		return
+	}
+
+	// the token, not the request, says which tenant the user belongs to
+	c.Request = c.Request.WithContext(tokenutil.WithTokenTenant(c.Request.Context(), claims))
+	tenantID, _ := claims["tid"].(string)
+	_, err = ac.TenantUsecase.GetActive(c, tenantID)
+	if errors.Is(err, repository.ErrTenantNotFound) || errors.Is(err, usecase.ErrTenantInactive) {
+		c.Error(errInvalidRefreshToken)
+		return
+	}
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	userID := claims["user_id"].(string)
+	userID, _ := claims["id"].(string)
 	user, err := ac.AuthUsecase.GetUserByID(c, userID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Error(errInvalidRefreshToken)
+		return
+	}
+	if user.Suspended {
+		c.Error(errAccountSuspended)
 		return
 	}
 
-	accessToken, err := ac.AuthUsecase.CreateAccessToken(user, ac.Env.AccessTokenSecret, ac.Env.AccessTokenExpiryHour)
+	// rotate the refresh token so that each one can only be used once. The
+	// revocation fails for a token revoked already, e.g. by an earlier
+	// refresh or an email change, which also keeps two concurrent refreshes
+	// from both succeeding.
+	err = ac.AuthUsecase.RevokeRefreshToken(c, userID, request.RefreshToken)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.Error(errRefreshTokenRevoked)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	refreshToken, err := ac.AuthUsecase.CreateRefreshToken(user, ac.Env.RefreshTokenSecret, ac.Env.RefreshTokenExpiryHour)
+	tokens, err := ac.issueTokens(c, user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	refreshTokenResponse := domain.RefreshTokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}
 
 	c.JSON(http.StatusOK, refreshTokenResponse)
@@ -1,10 +1,12 @@
 package controller
 
 import (
-	"context"
+	"io"
 	"net/http"
+	"sort"
 
 	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/query"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,7 +23,7 @@ func NewUserController(usercase domain.UserUsecase) *UserController {
 func (uc *UserController) Register(ctx *gin.Context) {
 	userdata := domain.UserForm{}
 	if err := ctx.ShouldBindJSON(&userdata); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
@@ -31,9 +33,9 @@ func (uc *UserController) Register(ctx *gin.Context) {
 		Email:     userdata.Email,
 		Password:  userdata.Password,
 	}
-	user, err := uc.userUsecase.Create(context.Background(), user)
+	user, err := uc.userUsecase.Create(ctx, user)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusCreated, user)
@@ -42,84 +44,178 @@ func (uc *UserController) Register(ctx *gin.Context) {
 func (uc *UserController) GetUser(ctx *gin.Context) {
 	userID := ctx.Param("id")
 	if userID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing user ID"})
+		ctx.Error(errMissingUserID)
 		return
 	}
 
-	user, err := uc.userUsecase.GetByID(context.Background(), userID)
+	user, err := uc.userUsecase.GetByID(ctx, userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
+	setETag(ctx, user.Version)
 	ctx.JSON(http.StatusOK, user)
 }
 
+// GetAllUsers lists one page of users. See package query for the filter,
+// sort and paging parameters.
 func (uc *UserController) GetAllUsers(ctx *gin.Context) {
-	users, err := uc.userUsecase.GetAll(context.Background())
+	spec, err := query.Parse(ctx.Request.URL.Query(), domain.UserQuerySchema)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, users)
+	page, err := uc.userUsecase.List(ctx, spec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	writePage(ctx, page)
 }
 
+// UpdateUser renames a user; the names left out of the request keep their
+// value. An If-Match header makes the update conditional on the version
+// from an earlier ETag.
 func (uc *UserController) UpdateUser(ctx *gin.Context) {
 	userID := ctx.Param("id")
 	if userID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing user ID"})
+		ctx.Error(errMissingUserID)
+		return
+	}
+	version, err := ifMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	var userUpdate domain.UserUpdate
-	if err := ctx.ShouldBindJSON(&userUpdate); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var request domain.UpdateUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	user, err := uc.userUsecase.Update(context.Background(), userID, userUpdate)
+	user, err := uc.userUsecase.Update(ctx, ctx.GetString("x-actor-id"), userID, version, request)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(versionConflict(ctx, err))
 		return
 	}
 
+	setETag(ctx, user.Version)
 	ctx.JSON(http.StatusOK, user)
 }
 
 func (uc *UserController) DeleteUser(ctx *gin.Context) {
 	userID := ctx.Param("id")
 	if userID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing user ID"})
+		ctx.Error(errMissingUserID)
 		return
 	}
 
-	err := uc.userUsecase.Delete(context.Background(), userID)
+	err := uc.userUsecase.Delete(ctx, ctx.GetString("x-actor-id"), userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
 }
 
+// GetRoles lists every assignable role with the permissions it grants.
+func (uc *UserController) GetRoles(ctx *gin.Context) {
+	roles := make([]domain.RoleResponse, 0, len(domain.RolePermissions))
+	for role := range domain.RolePermissions {
+		if role == domain.RoleSuperAdmin {
+			continue
+		}
+		roles = append(roles, domain.RoleResponse{
+			Role:        role,
+			Permissions: domain.PermissionsForRole(role),
+		})
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Role < roles[j].Role })
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+func (uc *UserController) AssignRole(ctx *gin.Context) {
+	userID := ctx.Param("id")
+	if userID == "" {
+		ctx.Error(errMissingUserID)
+		return
+	}
+
+	var request domain.AssignRoleRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	user, err := uc.userUsecase.AssignRole(ctx, ctx.GetString("x-actor-id"), userID, request.Role)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// SuspendUser blocks a user from signing in until they are reactivated.
+func (uc *UserController) SuspendUser(ctx *gin.Context) {
+	var request domain.SuspendUserRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	user, err := uc.userUsecase.Suspend(ctx, ctx.GetString("x-actor-id"), ctx.Param("id"), request.Reason)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+func (uc *UserController) ReactivateUser(ctx *gin.Context) {
+	user, err := uc.userUsecase.Reactivate(ctx, ctx.GetString("x-actor-id"), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
+
+// ImpersonateUser issues a short lived access token to act as a user.
+func (uc *UserController) ImpersonateUser(ctx *gin.Context) {
+	response, err := uc.userUsecase.Impersonate(ctx, ctx.GetString("x-actor-id"), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
 func (uc *UserController) UpdatePassword(ctx *gin.Context) {
 	userID := ctx.MustGet("x-user-id").(string)
 	if userID == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing user ID"})
+		ctx.Error(errMissingUserID)
 		return
 	}
 
 	var passwordUpdate domain.UpdatePassword
 	if err := ctx.ShouldBindJSON(&passwordUpdate); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	err := uc.userUsecase.UpdateUserPassword(context.Background(), userID, passwordUpdate)
+	err := uc.userUsecase.UpdateUserPassword(ctx, userID, passwordUpdate)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -130,15 +226,100 @@ func (uc *UserController) UpdatePassword(ctx *gin.Context) {
 func (uc *UserController) GetUserProfile(ctx *gin.Context) {
 	userID := ctx.MustGet("x-user-id").(string)
 	if userID == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "missing user ID"})
+		ctx.Error(errNotSignedIn)
+		return
+	}
+
+	user, err := uc.userUsecase.GetByID(ctx, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	setETag(ctx, user.Version)
+	ctx.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile applies a JSON Merge Patch (RFC 7396) to the profile of the
+// authenticated user. An If-Match header makes the patch conditional on the
+// version from an earlier ETag.
+func (uc *UserController) UpdateProfile(ctx *gin.Context) {
+	userID := ctx.GetString("x-user-id")
+	if userID == "" {
+		ctx.Error(errNotSignedIn)
+		return
+	}
+	version, err := ifMatch(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	switch ctx.ContentType() {
+	case "application/merge-patch+json", "application/json":
+	default:
+		ctx.Error(errPatchContentType)
+		return
+	}
+
+	patch, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.Error(invalidRequest(err))
 		return
 	}
 
-	user, err := uc.userUsecase.GetByID(context.Background(), userID)
+	user, err := uc.userUsecase.UpdateProfile(ctx, userID, ctx.GetString("x-actor-id"), version, patch)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(versionConflict(ctx, err))
 		return
 	}
 
+	setETag(ctx, user.Version)
 	ctx.JSON(http.StatusOK, user)
 }
+
+// GetAuditTrail lists the recorded changes to a user, newest first.
+func (uc *UserController) GetAuditTrail(ctx *gin.Context) {
+	entries, err := uc.userUsecase.GetAuditTrail(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// RequestEmailChange starts changing the email of the authenticated user.
+func (uc *UserController) RequestEmailChange(ctx *gin.Context) {
+	userID := ctx.GetString("x-user-id")
+	if userID == "" {
+		ctx.Error(errNotSignedIn)
+		return
+	}
+
+	var request domain.ChangeEmailRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.Error(invalidRequest(err))
+		return
+	}
+
+	err := uc.userUsecase.RequestEmailChange(ctx, userID, request)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "confirmation email sent to the new address"})
+}
+
+// ConfirmEmailChange completes an email change from the link sent to the
+// new address.
+func (uc *UserController) ConfirmEmailChange(ctx *gin.Context) {
+	err := uc.userUsecase.ConfirmEmailChange(ctx, ctx.Param("token"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "email changed successfully, please sign in again"})
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// codeInternal is the code of every error that is not a domain.Error.
+const codeInternal = "internal_error"
+
+var statusOfKind = map[domain.ErrorKind]int{
+	domain.KindNotFound:             http.StatusNotFound,
+	domain.KindConflict:             http.StatusConflict,
+	domain.KindValidation:           http.StatusBadRequest,
+	domain.KindUnprocessable:        http.StatusUnprocessableEntity,
+	domain.KindUnauthorized:         http.StatusUnauthorized,
+	domain.KindForbidden:            http.StatusForbidden,
+	domain.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	domain.KindTooLarge:             http.StatusRequestEntityTooLarge,
+	domain.KindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	domain.KindTooManyRequests:      http.StatusTooManyRequests,
+}
+
+// ErrorMiddleware answers the requests whose handler recorded an error with
+// ctx.Error and wrote no response with that error as problem details (RFC
+// 7807). The status follows from the kind of the domain.Error in the
+// chain; any other error is logged and answered with a 500 that does not
+// reveal it. It has to run before every other middleware.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+		writePendingError(ctx)
+	}
+}
+
+// writePendingError answers the request with the last error recorded with
+// ctx.Error, unless a response was already written.
+func writePendingError(ctx *gin.Context) {
+	if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+		return
+	}
+	writeProblem(ctx, ctx.Errors.Last().Err)
+}
+
+// abortWithError stops the request and has ErrorMiddleware answer it with
+// err.
+func abortWithError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+	ctx.Abort()
+}
+
+func writeProblem(ctx *gin.Context, err error) {
+	problem := problemOf(err)
+	if problem.Status == http.StatusInternalServerError {
+		log.Printf("[error] %s %s: %v", ctx.Request.Method, ctx.Request.URL.Path, err)
+	}
+	// ctx.JSON keeps a content type that is already set
+	ctx.Header("Content-Type", domain.ProblemContentType)
+	ctx.JSON(problem.Status, problem)
+}
+
+// problemOf describes err the way ErrorMiddleware answers it.
+func problemOf(err error) domain.Problem {
+	domainErr := domain.ErrorOf(err)
+	status, ok := http.StatusInternalServerError, false
+	if domainErr != nil {
+		status, ok = statusOfKind[domainErr.Kind]
+	}
+	if !ok {
+		return domain.Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: "the request could not be completed",
+			Code:   codeInternal,
+		}
+	}
+	return domain.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+		Code:   domainErr.Code,
+		Fields: domainErr.Fields,
+	}
+}
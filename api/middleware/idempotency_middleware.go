@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength is long enough for a UUID or any other random key.
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers, besides Content-Type, a retry
+// is answered with.
+var replayedHeaders = []string{"ETag", "Location", "Content-Location", "Last-Modified"}
+
+var errIdempotencyKeyTooLong = domain.Validation("idempotency_key_too_long", "idempotency key is too long")
+
+// IdempotencyMiddleware makes the unsafe requests that carry an
+// Idempotency-Key header safe to retry: the first request with a key is
+// handled and its response stored, and retries get that response back with
+// an Idempotent-Replayed header. Reusing a key for a different request is
+// rejected with a 422, and a retry that arrives while the first request is
+// still running with a 409. Error responses are not stored and a panic
+// releases the key too, so the request can be retried. It must run after
+// JwtAuthMiddleware, as keys belong to the authenticated user.
+func IdempotencyMiddleware(idempotencyUsecase domain.IdempotencyUsecase) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(domain.IdempotencyHeader)
+		if key == "" || isSafeMethod(ctx.Request.Method) {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(ctx, errIdempotencyKeyTooLong)
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			abortWithError(ctx, &domain.Error{Kind: domain.KindValidation, Code: "invalid_request", Message: "invalid request body: " + err.Error(), Err: err})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = KeyByUser(ctx) + ":" + key
+		record, err := idempotencyUsecase.Begin(ctx, key, fingerprint(ctx.Request, body))
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		if record.Status != 0 {
+			for name, value := range record.Header {
+				ctx.Header(name, value)
+			}
+			ctx.Header("Idempotent-Replayed", "true")
+			ctx.Data(record.Status, record.ContentType, record.Body)
+			ctx.Abort()
+			return
+		}
+
+		finished := false
+		defer func() {
+			// runs while a panic unwinds as well, so the key is never left
+			// claimed by a request that will not finish
+			if finished {
+				return
+			}
+			if err := idempotencyUsecase.Abandon(ctx, key); err != nil {
+				log.Println("[middleware] idempotency", err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+		// an error is answered here, so that its status is known
+		writePendingError(ctx)
+
+		status := ctx.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+		finished = true
+		header := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := ctx.Writer.Header().Get(name); value != "" {
+				header[name] = value
+			}
+		}
+		err = idempotencyUsecase.Finish(ctx, key, status, ctx.Writer.Header().Get("Content-Type"), header, recorder.body.Bytes())
+		if err != nil {
+			// the response is already on its way; a retry will be told the
+			// request is still being processed until the key expires
+			log.Println("[middleware] idempotency", err)
+		}
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the body written to the response.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -1,47 +1,163 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
+	"github.com/dagota12/Loan-Tracker/domain"
 	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v4"
 )
 
-func JwtAuthMiddleware(secret string) gin.HandlerFunc {
+var (
+	errUnauthorized  = domain.Unauthorized("unauthenticated", "unauthorized")
+	errNoTokenTenant = domain.Unauthorized("unauthenticated", "token is not bound to a tenant")
+	errImpersonating = domain.Forbidden("impersonation_not_allowed", "not allowed while impersonating")
+)
+
+// JwtAuthMiddleware authenticates the request by its bearer token. Tokens
+// issued before every session of their user was revoked are rejected, and
+// so are the tokens of a suspended user and of a tenant that is no longer
+// active.
+func JwtAuthMiddleware(secret string, authUsecase domain.AuthUsecase, tenantUsecase domain.TenantUsecase) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.Request.Header.Get("Authorization")
 		t := strings.Split(authHeader, " ")
 		if len(t) != 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{})
+			abortWithError(c, errUnauthorized)
 			return
 		}
 		authToken := t[1]
 		authorized, err := tokenutil.IsAuthorized(authToken, secret)
 
 		if err != nil || !authorized {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			abortWithError(c, errUnauthorized)
 			return
 		}
 
 		claims, err := tokenutil.ExtractUserClaimsFromToken(authToken, secret)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			abortWithError(c, errUnauthorized)
+			return
+		}
+		tenantID, _ := claims["tid"].(string)
+		if tenantID == "" {
+			abortWithError(c, errNoTokenTenant)
+			return
+		}
+		if _, err := tenantUsecase.GetActive(c, tenantID); err != nil {
+			if errors.Is(err, repository.ErrTenantNotFound) {
+				err = errUnauthorized
+			}
+			abortWithError(c, err)
 			return
 		}
+		setTenant(c, tenantID)
+
+		userID, _ := claims["id"].(string)
+		tokenVersion, _ := claims["ver"].(float64)
+		if err := authUsecase.CheckSession(c, userID, int64(tokenVersion)); err != nil {
+			abortWithError(c, err)
+			return
+		}
+
 		c.Set("x-user-id", claims["id"])
 		c.Set("x-user-role", claims["role"])
 		c.Set("x-user-owner", claims["is_owner"])
+		c.Set("x-user-permissions", permissionsFromClaims(claims))
+		c.Set("x-actor-id", actorFromClaims(claims))
 		c.Next()
 	}
 }
 
+// permissionsFromClaims reads the permission set embedded in the token,
+// resolving it from the role for tokens issued without one.
+func permissionsFromClaims(claims jwt.MapClaims) []string {
+	raw, ok := claims["permissions"].([]interface{})
+	if !ok {
+		role, _ := claims["role"].(string)
+		return domain.PermissionsForRole(role)
+	}
+
+	permissions := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if name, ok := p.(string); ok {
+			permissions = append(permissions, name)
+		}
+	}
+	return permissions
+}
+
+// actorFromClaims returns who is acting: the admin named by the act claim of
+// an impersonation token, otherwise the user the token was issued to.
+func actorFromClaims(claims jwt.MapClaims) string {
+	if act, ok := claims["act"].(map[string]interface{}); ok {
+		if sub, ok := act["sub"].(string); ok && sub != "" {
+			return sub
+		}
+	}
+	id, _ := claims["id"].(string)
+	return id
+}
+
+// RejectImpersonation keeps impersonation tokens away from credential and
+// account changes. It must run after JwtAuthMiddleware.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("x-actor-id") != ctx.GetString("x-user-id") {
+			abortWithError(ctx, errImpersonating)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequirePermission only lets the request through when the authenticated
+// user holds every one of the given permissions.
+// It must run after JwtAuthMiddleware.
+func RequirePermission(permissions ...domain.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		granted := ctx.GetStringSlice("x-user-permissions")
+		for _, required := range permissions {
+			if !hasPermission(granted, required) {
+				abortWithError(ctx, domain.Forbidden("missing_permission", "missing permission "+string(required)))
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// RestrictToPortfolio limits the users the request can look up to the
+// borrowers assigned to the authenticated user, unless they hold
+// portfolio:all. It must run after JwtAuthMiddleware.
+func RestrictToPortfolio() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !hasPermission(ctx.GetStringSlice("x-user-permissions"), domain.PermissionPortfolioAll) {
+			scope := domain.WithPortfolio(ctx.Request.Context(), ctx.GetString("x-user-id"))
+			ctx.Request = ctx.Request.WithContext(scope)
+		}
+		ctx.Next()
+	}
+}
+
+func hasPermission(granted []string, permission domain.Permission) bool {
+	for _, p := range granted {
+		if p == string(permission) {
+			return true
+		}
+	}
+	return false
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		role := ctx.MustGet("x-user-role")
-		if role != "admin" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			ctx.Abort()
+		if role != domain.RoleAdmin && role != domain.RoleSuperAdmin {
+			abortWithError(ctx, errUnauthorized)
+			return
 		}
 		ctx.Next()
 	}
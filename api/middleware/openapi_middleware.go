@@ -0,0 +1,277 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// bodyField names the body itself among the invalid fields of a request,
+// whose JSON properties are named by their path.
+const bodyField = "body"
+
+// OpenAPIMiddleware checks requests against the operation doc documents
+// for their route before they are handled: the parameters against their
+// schemas, the content type against the documented ones and JSON bodies
+// against their schema. A request that does not match is rejected with a
+// 400 listing the invalid fields, or with a 415 for its content type.
+// Routes doc does not document are left alone.
+//
+// With checkResponses, the responses are checked too, which tests use to
+// catch the document drifting from the handlers: a response whose status,
+// content type or JSON body is not documented, or that has properties its
+// schema does not describe, is replaced by a 500 listing what is wrong.
+// The response is held back until it is checked, so it must not be used in
+// production.
+func OpenAPIMiddleware(doc *openapi.Document, checkResponses bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		op := doc.Operation(ctx.Request.Method, ctx.FullPath())
+		if op == nil {
+			ctx.Next()
+			return
+		}
+		if err := checkRequest(ctx, doc, op); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+		if !checkResponses {
+			ctx.Next()
+			return
+		}
+
+		buffer := &bufferedWriter{ResponseWriter: ctx.Writer, status: http.StatusOK}
+		ctx.Writer = buffer
+		ctx.Next()
+		// an error is answered here, so that the answer is checked too
+		writePendingError(ctx)
+		ctx.Writer = buffer.ResponseWriter
+
+		violations := checkResponse(doc, op, buffer.status, buffer.Header(), buffer.body.Bytes())
+		if len(violations) == 0 {
+			buffer.flush()
+			return
+		}
+		log.Printf("[middleware] %s %s answered %d, which the OpenAPI document does not describe: %v", ctx.Request.Method, ctx.FullPath(), buffer.status, violations)
+		ctx.Header("Content-Type", domain.ProblemContentType)
+		ctx.JSON(http.StatusInternalServerError, domain.Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: fmt.Sprintf("the %d response does not match the API specification", buffer.status),
+			Code:   "invalid_response",
+			Fields: violations,
+		})
+	}
+}
+
+func checkRequest(ctx *gin.Context, doc *openapi.Document, op *openapi.Operation) error {
+	fields := map[string]string{}
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case openapi.InPath:
+			values = []string{ctx.Param(p.Name)}
+		case openapi.InQuery:
+			values = ctx.QueryArray(p.Name)
+		case openapi.InHeader:
+			values = ctx.Request.Header.Values(p.Name)
+		}
+		if len(values) == 0 {
+			if p.Required {
+				fields[p.Name] = "is required"
+			}
+			continue
+		}
+		for _, value := range values {
+			if message, ok := doc.Validate(p.Schema, doc.ParameterValue(p.Schema, value))[""]; ok {
+				fields[p.Name] = message
+			}
+		}
+	}
+
+	if op.RequestBody != nil {
+		if err := checkRequestBody(ctx, doc, op.RequestBody, fields); err != nil {
+			return err
+		}
+	}
+	if len(fields) > 0 {
+		return domain.InvalidFields("invalid_request", "the request does not match the API specification", fields)
+	}
+	return nil
+}
+
+// checkRequestBody checks the content type of the body and adds the
+// violations of a JSON body to fields. Other bodies are left to the
+// handler, so that uploads are not read twice.
+func checkRequestBody(ctx *gin.Context, doc *openapi.Document, body *openapi.RequestBody, fields map[string]string) error {
+	mediaType := ctx.ContentType()
+	if mediaType == "" && ctx.Request.ContentLength == 0 {
+		if body.Required {
+			fields[bodyField] = "is required"
+		}
+		return nil
+	}
+	content, ok := body.Content[mediaType]
+	if !ok {
+		types := make([]string, 0, len(body.Content))
+		for t := range body.Content {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return &domain.Error{
+			Kind:    domain.KindUnsupportedMediaType,
+			Code:    "unsupported_media_type",
+			Message: "content type must be " + strings.Join(types, " or "),
+		}
+	}
+	if !isJSON(mediaType) || content.Schema == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return &domain.Error{Kind: domain.KindValidation, Code: "invalid_request", Message: "invalid request body: " + err.Error(), Err: err}
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(data))
+
+	value, err := decodeJSON(data)
+	if err != nil {
+		fields[bodyField] = "must be JSON"
+		return nil
+	}
+	for path, message := range doc.Validate(content.Schema, value) {
+		fields[fieldOf(path)] = message
+	}
+	return nil
+}
+
+// checkResponse returns what the document does not describe about a
+// response of op.
+func checkResponse(doc *openapi.Document, op *openapi.Operation, status int, header http.Header, body []byte) map[string]string {
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		response, ok = op.Responses["default"]
+	}
+	if !ok {
+		return map[string]string{"status": "is not documented"}
+	}
+	response = doc.Response(response)
+	if len(body) == 0 {
+		if len(response.Content) > 0 {
+			return map[string]string{bodyField: "is missing"}
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	content, ok := response.Content[mediaType]
+	if !ok {
+		return map[string]string{"Content-Type": fmt.Sprintf("%q is not documented", mediaType)}
+	}
+	if !isJSON(mediaType) || content.Schema == nil {
+		return nil
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return map[string]string{bodyField: "is not JSON"}
+	}
+	violations := map[string]string{}
+	for path, message := range doc.ValidateStrict(content.Schema, value) {
+		violations[fieldOf(path)] = message
+	}
+	return violations
+}
+
+// isJSON reports whether a media type is JSON, like application/json or
+// application/merge-patch+json.
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeJSON decodes data keeping numbers exact, as the validator expects.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("data after the JSON value")
+	}
+	return value, nil
+}
+
+func fieldOf(path string) string {
+	if path == "" {
+		return bodyField
+	}
+	return path
+}
+
+// bufferedWriter holds back the response written through it until flush.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush keeps the response held back; flush writes it.
+func (w *bufferedWriter) Flush() {}
+
+// flush writes the response held back to the writer it wraps.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.ResponseWriter.Write(w.body.Bytes())
+}
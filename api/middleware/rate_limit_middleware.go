@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+var errRateLimited = domain.TooManyRequests("rate_limited", "rate limit exceeded")
+
+// RateLimitKeyFunc identifies who a request is counted against.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP counts requests per client address.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser counts requests per authenticated user, falling back to the
+// client address. It must run after JwtAuthMiddleware.
+func KeyByUser(c *gin.Context) string {
+	if userID := c.GetString("x-user-id"); userID != "" {
+		return "user:" + userID
+	}
+	return KeyByIP(c)
+}
+
+// RateLimitMiddleware applies policy to every route of the group it is
+// attached to; each route gets its own quota. Rejected requests get a 429
+// with Retry-After, and every response carries the RateLimit-* headers.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, policy ratelimit.Policy, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds()))
+
+	return func(c *gin.Context) {
+		key := keyFunc(c) + ":" + c.Request.Method + " " + c.FullPath()
+		result, err := limiter.Allow(c, key, policy)
+		if err != nil {
+			// an unavailable store must not take the API down with it
+			log.Println("[middleware] rate limit", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			abortWithError(c, errRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// TenantMiddleware scopes an unauthenticated request to the active tenant
+// named by the X-Tenant header, or to defaultSlug without one.
+func TenantMiddleware(tenantUsecase domain.TenantUsecase, defaultSlug string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		slug := ctx.GetHeader(domain.TenantHeader)
+		if slug == "" {
+			slug = defaultSlug
+		}
+
+		tenant, err := tenantUsecase.Resolve(ctx, slug)
+		if err != nil {
+			if errors.Is(err, repository.ErrTenantNotFound) || errors.Is(err, usecase.ErrTenantInactive) {
+				err = domain.NotFound("unknown_tenant", "unknown tenant "+slug)
+			}
+			abortWithError(ctx, err)
+			return
+		}
+
+		setTenant(ctx, tenant.ID.Hex())
+		ctx.Next()
+	}
+}
+
+// setTenant scopes every repository call made with ctx for the rest of the
+// request to tenantID.
+func setTenant(ctx *gin.Context, tenantID string) {
+	ctx.Set("x-tenant-id", tenantID)
+	ctx.Request = ctx.Request.WithContext(domain.WithTenant(ctx.Request.Context(), tenantID))
+}
@@ -5,18 +5,25 @@ import (
 
 	"github.com/dagota12/Loan-Tracker/api/controller"
 	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
 	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/dagota12/Loan-Tracker/usecase"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewAuthRouter(env *bootstrap.Env, timeout time.Duration, db *mongo.Database, group *gin.RouterGroup) {
-	userRepo := repository.NewUserRepository(db)
-	authUsecase := usecase.NewAuthUsease(userRepo)
-	authController := controller.NewAuthController(authUsecase, env)
+// NewAuthRouter registers the sign in routes; throttle guards the ones that
+// check credentials.
+func NewAuthRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, mailer emailutil.Mailer, throttle gin.HandlerFunc, group *gin.RouterGroup) {
+	authUsecase := usecase.NewAuthUsease(repos.Users)
+	lockoutUsecase := usecase.NewLockoutUsecase(repos.LoginAttempts, repos.Users, env, mailer)
+	magicLinkUsecase := usecase.NewMagicLinkUsecase(repos.MagicLinks, repos.Users, env, mailer)
+	tenantUsecase := usecase.NewTenantUsecase(repos.Tenants, timeout)
+	authController := controller.NewAuthController(authUsecase, lockoutUsecase, magicLinkUsecase, tenantUsecase, env)
+	lockoutController := controller.NewLockoutController(lockoutUsecase)
 
-	group.POST("/users/login", authController.Login)
+	group.POST("/users/login", throttle, authController.Login)
 	group.POST("/users/token/refresh", authController.RefreshToken)
-	// group.POST("/users/verify-email", authController.AuthUsecase)
+	group.POST("/users/login/magic-link", throttle, authController.RequestMagicLink)
+	group.GET("/users/login/magic-link/:token", authController.MagicLinkCallback)
+	group.GET("/users/unlock/:token", lockoutController.UnlockWithToken)
 }
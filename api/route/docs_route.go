@@ -0,0 +1,21 @@
+package route
+
+import (
+	"log"
+
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// NewDocsRouter serves doc at /openapi.json and the API reference rendered
+// from it at /docs.
+func NewDocsRouter(doc *openapi.Document, group *gin.RouterGroup) {
+	docsController, err := controller.NewDocsController(doc)
+	if err != nil {
+		log.Fatal("OpenAPI document can't be encoded: ", err)
+	}
+
+	group.GET("/openapi.json", docsController.GetSpec)
+	group.GET("/docs", docsController.GetDocs)
+}
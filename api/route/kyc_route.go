@@ -0,0 +1,22 @@
+package route
+
+import (
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func NewKYCRouter(kycUsecase domain.KYCUsecase, group *gin.RouterGroup) {
+	kycController := controller.NewKYCController(kycUsecase)
+
+	group.GET("/users/kyc", kycController.GetStatus)
+	group.POST("/users/kyc/documents", kycController.UploadDocument)
+	group.POST("/users/kyc/submit", kycController.Submit)
+
+	admin := group.Group("/admin")
+	admin.GET("/kyc", middleware.RequirePermission(domain.PermissionKYCRead), middleware.RestrictToPortfolio(), kycController.ListByStatus)
+	admin.GET("/users/:id/kyc", middleware.RequirePermission(domain.PermissionKYCRead), middleware.RestrictToPortfolio(), kycController.GetUserStatus)
+	admin.GET("/users/:id/kyc/documents/:document", middleware.RequirePermission(domain.PermissionKYCRead), middleware.RestrictToPortfolio(), kycController.GetDocument)
+	admin.POST("/users/:id/kyc/review", middleware.RequirePermission(domain.PermissionKYCReview), middleware.RestrictToPortfolio(), kycController.Review)
+}
@@ -0,0 +1,25 @@
+package route
+
+import (
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+func NewLoanRouter(timeout time.Duration, repos repository.Set, kycUsecase domain.KYCUsecase, group *gin.RouterGroup) {
+	loanUsecase := usecase.NewLoanUsecase(repos.Loans, repos.Users, repos.Audit, repos.Tx, kycUsecase, timeout)
+	loanController := controller.NewLoanController(loanUsecase)
+
+	admin := group.Group("/admin")
+	admin.POST("/loans", middleware.RequirePermission(domain.PermissionLoansCreate), middleware.RestrictToPortfolio(), loanController.CreateLoan)
+	admin.GET("/loans", middleware.RequirePermission(domain.PermissionLoansRead), middleware.RestrictToPortfolio(), loanController.GetLoans)
+	admin.GET("/loans/:id", middleware.RequirePermission(domain.PermissionLoansRead), middleware.RestrictToPortfolio(), loanController.GetLoan)
+	admin.POST("/loans/:id/approve", middleware.RequirePermission(domain.PermissionLoansApprove), middleware.RestrictToPortfolio(), loanController.ApproveLoan)
+	admin.POST("/loans/:id/disburse", middleware.RequirePermission(domain.PermissionLoansDisburse), middleware.RestrictToPortfolio(), loanController.DisburseLoan)
+	admin.GET("/users/:id/loans", middleware.RequirePermission(domain.PermissionLoansRead), middleware.RestrictToPortfolio(), loanController.GetUserLoans)
+}
@@ -0,0 +1,719 @@
+package route
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/openapi"
+)
+
+// tags group the operations in the API reference
+const (
+	tagAuth      = "authentication"
+	tagProfile   = "profile"
+	tagUsers     = "users"
+	tagKYC       = "kyc"
+	tagLoans     = "loans"
+	tagPortfolio = "portfolio"
+	tagTenants   = "tenants"
+	tagDocs      = "documentation"
+)
+
+const bearerAuth = "bearerAuth"
+
+// OpenAPI describes every route Setup registers. Request and response
+// bodies are described by the domain types the handlers bind and return.
+func OpenAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:   "Loan Tracker API",
+		Version: "1.0.0",
+		Description: "Errors are answered as problem details (RFC 7807) whose code never changes. " +
+			"Unauthenticated requests belong to the tenant named by the X-Tenant header, " +
+			"authenticated ones to the tenant of their token.",
+	})
+	doc.Components.SecuritySchemes[bearerAuth] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "The access token returned by sign in.",
+	}
+	doc.Components.Schemas["Message"] = openapi.Object(map[string]*openapi.Schema{"message": openapi.String()})
+	problem := doc.SchemaOf(domain.Problem{})
+	for _, status := range []int{
+		http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusConflict, http.StatusPreconditionFailed, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+	} {
+		response := &openapi.Response{
+			Description: http.StatusText(status),
+			Content:     map[string]openapi.MediaType{domain.ProblemContentType: {Schema: problem}},
+		}
+		if status == http.StatusTooManyRequests {
+			response.Headers = map[string]*openapi.Header{
+				"Retry-After": {Description: "Seconds to wait before retrying.", Schema: openapi.Integer()},
+			}
+		}
+		doc.Components.Responses[problemName(status)] = response
+	}
+
+	s := spec{doc}
+	s.auth()
+	s.profile()
+	s.users()
+	s.kyc()
+	s.loans()
+	s.portfolio()
+	s.tenants()
+	s.docs()
+	return doc
+}
+
+// spec adds the operations of the API to a document.
+type spec struct {
+	doc *openapi.Document
+}
+
+func (s spec) auth() {
+	s.public(http.MethodPost, "/users/register", &openapi.Operation{
+		OperationID: "signup",
+		Summary:     "Sign up and receive a verification email",
+		Tags:        []string{tagAuth},
+		RequestBody: s.body(domain.SignupRequest{}),
+		Responses:   openapi.Responses{"201": s.message("The verification email was sent.")},
+	}, http.StatusBadRequest, http.StatusConflict)
+	s.public(http.MethodGet, "/users/verify-email/:token", &openapi.Operation{
+		OperationID: "verifyEmail",
+		Summary:     "Verify an email address from the emailed link",
+		Tags:        []string{tagAuth},
+		Responses:   openapi.Responses{"200": s.message("The account is active.")},
+	}, http.StatusUnauthorized, http.StatusConflict)
+	s.public(http.MethodPost, "/users/verify-email/resend", &openapi.Operation{
+		OperationID: "resendVerificationEmail",
+		Summary:     "Send a new verification link",
+		Description: "The response does not reveal whether the account exists.",
+		Tags:        []string{tagAuth},
+		RequestBody: s.body(domain.ResendVerificationRequest{}),
+		Responses:   openapi.Responses{"200": s.message("A new link was sent if the account is waiting for verification.")},
+	}, http.StatusBadRequest)
+	s.public(http.MethodPost, "/users/login", &openapi.Operation{
+		OperationID: "login",
+		Summary:     "Sign in with email and password",
+		Description: "Repeated failures lock the account and the client out for a while.",
+		Tags:        []string{tagAuth},
+		RequestBody: s.body(domain.LoginRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The access and refresh tokens.", domain.LoginResponse{})},
+	}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+	s.public(http.MethodPost, "/users/token/refresh", &openapi.Operation{
+		OperationID: "refreshToken",
+		Summary:     "Exchange a refresh token for a new token pair",
+		Description: "Every refresh token can be used once.",
+		Tags:        []string{tagAuth},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"application/json":                  {Schema: s.doc.SchemaOf(domain.RefreshTokenRequest{})},
+				"application/x-www-form-urlencoded": {Schema: s.doc.SchemaOf(domain.RefreshTokenRequest{})},
+			},
+		},
+		Responses: openapi.Responses{"200": s.json("The new access and refresh tokens.", domain.RefreshTokenResponse{})},
+	}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)
+	s.public(http.MethodPost, "/users/login/magic-link", &openapi.Operation{
+		OperationID: "requestMagicLink",
+		Summary:     "Email a passwordless sign in link",
+		Description: "The link only works in the browser that requested it, which receives a nonce cookie. " +
+			"The response does not reveal whether the account exists.",
+		Tags:        []string{tagAuth},
+		RequestBody: s.body(domain.MagicLinkRequest{}),
+		Responses:   openapi.Responses{"202": s.message("A link was sent if the account exists.")},
+	}, http.StatusBadRequest)
+	s.public(http.MethodGet, "/users/login/magic-link/:token", &openapi.Operation{
+		OperationID: "redeemMagicLink",
+		Summary:     "Sign in with an emailed link",
+		Tags:        []string{tagAuth},
+		Responses:   openapi.Responses{"200": s.json("The access and refresh tokens.", domain.LoginResponse{})},
+	}, http.StatusUnauthorized)
+	s.public(http.MethodGet, "/users/unlock/:token", &openapi.Operation{
+		OperationID: "unlockWithToken",
+		Summary:     "Unlock a locked out account from the emailed link",
+		Tags:        []string{tagAuth},
+		Responses:   openapi.Responses{"200": s.message("The account is unlocked.")},
+	}, http.StatusUnauthorized)
+	s.public(http.MethodPost, "/users/forgot-password", &openapi.Operation{
+		OperationID: "forgotPassword",
+		Summary:     "Email a one time code to reset the password",
+		Description: "The answer is the same whether or not the email belongs to an account.",
+		Tags:        []string{tagAuth},
+		RequestBody: s.body(domain.ForgotPasswordRequest{}),
+		Responses:   openapi.Responses{"200": s.message("The code was sent if the email belongs to an account.")},
+	}, http.StatusBadRequest)
+	s.public(http.MethodPost, "/users/reset-password", &openapi.Operation{
+		OperationID: "resetPassword",
+		Summary:     "Set a new password with an emailed code",
+		Tags:        []string{tagAuth},
+		RequestBody: s.body(domain.ResetPasswordRequest{}),
+		Responses:   openapi.Responses{"200": s.message("The password was changed.")},
+	}, http.StatusBadRequest, http.StatusUnauthorized)
+	s.public(http.MethodGet, "/users/profile/email/confirm/:token", &openapi.Operation{
+		OperationID: "confirmEmailChange",
+		Summary:     "Confirm a new email address from the emailed link",
+		Description: "Signs the user out everywhere.",
+		Tags:        []string{tagAuth},
+		Responses:   openapi.Responses{"200": s.message("The email address was changed.")},
+	}, http.StatusUnauthorized, http.StatusConflict)
+}
+
+func (s spec) profile() {
+	s.protected(http.MethodGet, "/users/profile", &openapi.Operation{
+		OperationID: "getProfile",
+		Summary:     "Get the signed in user",
+		Tags:        []string{tagProfile},
+		Responses:   openapi.Responses{"200": s.tagged(s.json("The user.", domain.User{}))},
+	}, nil)
+	s.protected(http.MethodPatch, "/users/profile", &openapi.Operation{
+		OperationID: "updateProfile",
+		Summary:     "Edit the profile of the signed in user",
+		Description: "The body is a JSON Merge Patch (RFC 7396): null removes a field.",
+		Tags:        []string{tagProfile},
+		Parameters:  []openapi.Parameter{ifMatch()},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content: map[string]openapi.MediaType{
+				"application/merge-patch+json": {Schema: s.doc.PatchOf(domain.EditableProfile{})},
+				"application/json":             {Schema: s.doc.PatchOf(domain.EditableProfile{})},
+			},
+		},
+		Responses: openapi.Responses{"200": s.tagged(s.json("The updated user.", domain.User{}))},
+	}, nil, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusUnsupportedMediaType)
+	s.protected(http.MethodPost, "/users/profile/email", &openapi.Operation{
+		OperationID: "requestEmailChange",
+		Summary:     "Change the email address of the signed in user",
+		Description: "The change applies once it is confirmed from the link sent to the new address.",
+		Tags:        []string{tagProfile},
+		RequestBody: s.body(domain.ChangeEmailRequest{}),
+		Responses:   openapi.Responses{"202": s.message("The confirmation email was sent.")},
+	}, nil, http.StatusBadRequest, http.StatusForbidden)
+	s.protected(http.MethodPost, "/users/update-password", &openapi.Operation{
+		OperationID: "updatePassword",
+		Summary:     "Change the password of the signed in user",
+		Tags:        []string{tagProfile},
+		RequestBody: s.body(domain.UpdatePassword{}),
+		Responses:   openapi.Responses{"200": s.message("The password was changed.")},
+	}, nil, http.StatusBadRequest, http.StatusForbidden)
+	s.protected(http.MethodGet, "/users/profile/export", &openapi.Operation{
+		OperationID: "exportProfile",
+		Summary:     "Download everything held about the signed in user",
+		Tags:        []string{tagProfile},
+		Parameters: []openapi.Parameter{{
+			Name:        "format",
+			In:          openapi.InQuery,
+			Description: "json, or zip for an archive that includes the KYC documents.",
+			Schema:      &openapi.Schema{Type: openapi.Types{"string"}, Enum: []string{"json", "zip"}},
+		}},
+		Responses: openapi.Responses{"200": &openapi.Response{
+			Description: "The export, as an attachment.",
+			Content: map[string]openapi.MediaType{
+				"application/json": {Schema: s.doc.SchemaOf(domain.UserExport{})},
+				"application/zip":  {Schema: openapi.Binary()},
+			},
+		}},
+	}, nil, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound)
+}
+
+func (s spec) users() {
+	s.protected(http.MethodGet, "/admin/users", &openapi.Operation{
+		OperationID: "listUsers",
+		Summary:     "List users",
+		Description: "Without portfolio:all, only the borrowers assigned to the caller are listed.",
+		Tags:        []string{tagUsers},
+		Parameters:  queryParameters(domain.UserQuerySchema),
+		Responses:   openapi.Responses{"200": s.page("A page of users.", domain.Page[domain.User]{})},
+	}, []domain.Permission{domain.PermissionUsersRead}, http.StatusBadRequest)
+	s.protected(http.MethodGet, "/admin/users/:id", &openapi.Operation{
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.tagged(s.json("The user.", domain.User{}))},
+	}, []domain.Permission{domain.PermissionUsersRead}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPatch, "/admin/users/:id", &openapi.Operation{
+		OperationID: "updateUser",
+		Summary:     "Rename a user",
+		Description: "The names left out keep their value. Admins can only be renamed by the owner.",
+		Tags:        []string{tagUsers},
+		Parameters:  []openapi.Parameter{ifMatch()},
+		RequestBody: s.body(domain.UpdateUserRequest{}),
+		Responses:   openapi.Responses{"200": s.tagged(s.json("The updated user.", domain.User{}))},
+	}, []domain.Permission{domain.PermissionUsersWrite}, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed)
+	s.protected(http.MethodDelete, "/admin/users/:id", &openapi.Operation{
+		OperationID: "deleteUser",
+		Summary:     "Delete a user",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.message("The user was deleted.")},
+	}, []domain.Permission{domain.PermissionUsersDelete}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodGet, "/admin/users/:id/audit", &openapi.Operation{
+		OperationID: "getAuditTrail",
+		Summary:     "List the recorded changes to a user, newest first",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.json("The audit trail.", []domain.AuditEntry{})},
+	}, []domain.Permission{domain.PermissionUsersRead}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPost, "/admin/users/:id/suspend", &openapi.Operation{
+		OperationID: "suspendUser",
+		Summary:     "Block a user from signing in",
+		Tags:        []string{tagUsers},
+		RequestBody: s.body(domain.SuspendUserRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The suspended user.", domain.User{})},
+	}, []domain.Permission{domain.PermissionUsersSuspend}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPost, "/admin/users/:id/reactivate", &openapi.Operation{
+		OperationID: "reactivateUser",
+		Summary:     "Lift the suspension of a user",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.json("The reactivated user.", domain.User{})},
+	}, []domain.Permission{domain.PermissionUsersSuspend}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPost, "/admin/users/:id/impersonate", &openapi.Operation{
+		OperationID: "impersonateUser",
+		Summary:     "Get a short lived access token to act as a user",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.json("The impersonation token.", domain.ImpersonationResponse{})},
+	}, []domain.Permission{domain.PermissionUsersImpersonate}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodGet, "/admin/roles", &openapi.Operation{
+		OperationID: "listRoles",
+		Summary:     "List the assignable roles with their permissions",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.json("The roles.", []domain.RoleResponse{})},
+	}, []domain.Permission{domain.PermissionRolesAssign})
+	s.protected(http.MethodPut, "/admin/users/:id/role", &openapi.Operation{
+		OperationID: "assignRole",
+		Summary:     "Change the role of a user",
+		Description: "The access tokens of the user are revoked; a refresh issues tokens with the permissions of the new role.",
+		Tags:        []string{tagUsers},
+		RequestBody: s.body(domain.AssignRoleRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The updated user.", domain.User{})},
+	}, []domain.Permission{domain.PermissionRolesAssign}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPost, "/admin/users/:id/unlock", &openapi.Operation{
+		OperationID: "unlockUser",
+		Summary:     "Unlock a locked out account",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.message("The account is unlocked.")},
+	}, []domain.Permission{domain.PermissionUsersWrite}, http.StatusBadRequest)
+	s.protected(http.MethodPost, "/admin/users/:id/erase", &openapi.Operation{
+		OperationID: "eraseUser",
+		Summary:     "Pseudonymize the personal data of a user",
+		Tags:        []string{tagUsers},
+		Responses:   openapi.Responses{"200": s.message("The data was erased.")},
+	}, []domain.Permission{domain.PermissionUsersDelete}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodGet, "/admin/search", &openapi.Operation{
+		OperationID: "search",
+		Summary:     "Search borrowers and loans, best match first",
+		Description: "Loans match by their ID, their status or the name and email of their borrower.",
+		Tags:        []string{tagUsers},
+		Parameters: []openapi.Parameter{
+			{Name: "q", In: openapi.InQuery, Required: true, Description: "The search terms.", Schema: openapi.String()},
+			{Name: "limit", In: openapi.InQuery, Description: "The maximum number of results.", Schema: openapi.Integer()},
+		},
+		Responses: openapi.Responses{"200": s.json("The matching records.", domain.SearchResponse{})},
+	}, []domain.Permission{domain.PermissionUsersRead, domain.PermissionLoansRead}, http.StatusBadRequest)
+}
+
+func (s spec) kyc() {
+	s.protected(http.MethodGet, "/users/kyc", &openapi.Operation{
+		OperationID: "getKYC",
+		Summary:     "Get the identity verification of the signed in user",
+		Tags:        []string{tagKYC},
+		Responses:   openapi.Responses{"200": s.json("The verification.", domain.KYC{})},
+	}, nil)
+	upload := s.doc.SchemaOf(domain.KYCDocumentUpload{})
+	form := *s.doc.Resolve(upload)
+	form.Properties = map[string]*openapi.Schema{"file": openapi.Binary()}
+	for name, property := range s.doc.Resolve(upload).Properties {
+		form.Properties[name] = property
+	}
+	form.Required = append([]string{"file"}, form.Required...)
+	s.protected(http.MethodPost, "/users/kyc/documents", &openapi.Operation{
+		OperationID: "uploadKYCDocument",
+		Summary:     "Upload a document for identity verification",
+		Tags:        []string{tagKYC},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"multipart/form-data": {Schema: &form}},
+		},
+		Responses: openapi.Responses{"201": s.json("The stored document.", domain.KYCDocument{})},
+	}, nil, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType)
+	s.protected(http.MethodPost, "/users/kyc/submit", &openapi.Operation{
+		OperationID: "submitKYC",
+		Summary:     "Submit the uploaded documents for review",
+		Tags:        []string{tagKYC},
+		Responses:   openapi.Responses{"200": s.json("The submitted verification.", domain.KYC{})},
+	}, nil, http.StatusBadRequest)
+	s.protected(http.MethodGet, "/admin/kyc", &openapi.Operation{
+		OperationID: "listKYC",
+		Summary:     "List identity verifications by status",
+		Tags:        []string{tagKYC},
+		Parameters: []openapi.Parameter{{
+			Name:        "status",
+			In:          openapi.InQuery,
+			Description: "Defaults to submitted.",
+			Schema: &openapi.Schema{Type: openapi.Types{"string"}, Enum: []string{
+				string(domain.KYCStatusNotStarted), string(domain.KYCStatusSubmitted), string(domain.KYCStatusApproved),
+				string(domain.KYCStatusRejected), string(domain.KYCStatusExpired),
+			}},
+		}},
+		Responses: openapi.Responses{"200": s.json("The verifications, only those of their borrowers for a loan officer.", []domain.KYC{})},
+	}, []domain.Permission{domain.PermissionKYCRead}, http.StatusBadRequest)
+	s.protected(http.MethodGet, "/admin/users/:id/kyc", &openapi.Operation{
+		OperationID: "getUserKYC",
+		Summary:     "Get the identity verification of a user",
+		Description: "Not found until the user starts a verification.",
+		Tags:        []string{tagKYC},
+		Responses:   openapi.Responses{"200": s.json("The verification.", domain.KYC{})},
+	}, []domain.Permission{domain.PermissionKYCRead}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodGet, "/admin/users/:id/kyc/documents/:document", &openapi.Operation{
+		OperationID: "getKYCDocument",
+		Summary:     "Download a document of a user",
+		Tags:        []string{tagKYC},
+		Responses: openapi.Responses{"200": &openapi.Response{
+			Description: "The document, with the content type it was uploaded with.",
+			Content:     map[string]openapi.MediaType{"*/*": {Schema: openapi.Binary()}},
+		}},
+	}, []domain.Permission{domain.PermissionKYCRead}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPost, "/admin/users/:id/kyc/review", &openapi.Operation{
+		OperationID: "reviewKYC",
+		Summary:     "Approve or reject a submitted verification",
+		Tags:        []string{tagKYC},
+		RequestBody: s.body(domain.KYCReviewRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The reviewed verification.", domain.KYC{})},
+	}, []domain.Permission{domain.PermissionKYCReview}, http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (s spec) loans() {
+	s.protected(http.MethodGet, "/admin/loans", &openapi.Operation{
+		OperationID: "listLoans",
+		Summary:     "List loans",
+		Description: "Without portfolio:all, only the loans of the borrowers assigned to the caller are listed.",
+		Tags:        []string{tagLoans},
+		Parameters:  queryParameters(domain.LoanQuerySchema),
+		Responses:   openapi.Responses{"200": s.page("A page of loans.", domain.Page[domain.Loan]{})},
+	}, []domain.Permission{domain.PermissionLoansRead}, http.StatusBadRequest)
+	s.protected(http.MethodPost, "/admin/loans", &openapi.Operation{
+		OperationID: "createLoan",
+		Summary:     "Open a loan for a borrower, pending approval",
+		Tags:        []string{tagLoans},
+		RequestBody: s.body(domain.CreateLoanRequest{}),
+		Responses:   openapi.Responses{"201": s.json("The new loan.", domain.Loan{})},
+	}, []domain.Permission{domain.PermissionLoansCreate}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodGet, "/admin/loans/:id", &openapi.Operation{
+		OperationID: "getLoan",
+		Summary:     "Get a loan",
+		Tags:        []string{tagLoans},
+		Responses:   openapi.Responses{"200": s.tagged(s.json("The loan.", domain.Loan{}))},
+	}, []domain.Permission{domain.PermissionLoansRead}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPost, "/admin/loans/:id/approve", &openapi.Operation{
+		OperationID: "approveLoan",
+		Summary:     "Approve a pending loan",
+		Tags:        []string{tagLoans},
+		Parameters:  []openapi.Parameter{ifMatch()},
+		Responses:   openapi.Responses{"200": s.tagged(s.json("The approved loan.", domain.Loan{}))},
+	}, []domain.Permission{domain.PermissionLoansApprove}, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed)
+	s.protected(http.MethodPost, "/admin/loans/:id/disburse", &openapi.Operation{
+		OperationID: "disburseLoan",
+		Summary:     "Pay out an approved loan",
+		Description: "The borrower must hold an approved identity verification.",
+		Tags:        []string{tagLoans},
+		Parameters:  []openapi.Parameter{ifMatch()},
+		Responses:   openapi.Responses{"200": s.tagged(s.json("The disbursed loan.", domain.Loan{}))},
+	}, []domain.Permission{domain.PermissionLoansDisburse}, http.StatusBadRequest, http.StatusNotFound, http.StatusPreconditionFailed)
+	s.protected(http.MethodGet, "/admin/users/:id/loans", &openapi.Operation{
+		OperationID: "listUserLoans",
+		Summary:     "List the loans of a borrower, newest first",
+		Tags:        []string{tagLoans},
+		Responses:   openapi.Responses{"200": s.json("The loans.", []domain.Loan{})},
+	}, []domain.Permission{domain.PermissionLoansRead}, http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (s spec) portfolio() {
+	s.protected(http.MethodGet, "/officers/me/portfolio", &openapi.Operation{
+		OperationID: "getMyPortfolio",
+		Summary:     "List the loans of the borrowers assigned to the signed in officer",
+		Description: "Each loan comes with its outstanding amount, its next instalment and the instalments overdue.",
+		Tags:        []string{tagPortfolio},
+		Parameters:  queryParameters(domain.LoanQuerySchema),
+		Responses:   openapi.Responses{"200": s.page("A page of loans.", domain.Page[domain.PortfolioLoan]{})},
+	}, []domain.Permission{domain.PermissionPortfolioRead}, http.StatusBadRequest)
+	s.protected(http.MethodGet, "/admin/branches", &openapi.Operation{
+		OperationID: "listBranches",
+		Summary:     "List branches",
+		Tags:        []string{tagPortfolio},
+		Responses:   openapi.Responses{"200": s.json("The branches.", []domain.Branch{})},
+	}, []domain.Permission{domain.PermissionUsersRead})
+	s.protected(http.MethodPost, "/admin/branches", &openapi.Operation{
+		OperationID: "createBranch",
+		Summary:     "Create a branch",
+		Tags:        []string{tagPortfolio},
+		RequestBody: s.body(domain.CreateBranchRequest{}),
+		Responses:   openapi.Responses{"201": s.json("The new branch.", domain.Branch{})},
+	}, []domain.Permission{domain.PermissionBranchesManage}, http.StatusBadRequest)
+	s.protected(http.MethodPatch, "/admin/branches/:id", &openapi.Operation{
+		OperationID: "updateBranch",
+		Summary:     "Rename, close or reopen a branch",
+		Tags:        []string{tagPortfolio},
+		RequestBody: s.body(domain.UpdateBranchRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The updated branch.", domain.Branch{})},
+	}, []domain.Permission{domain.PermissionBranchesManage}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPut, "/admin/users/:id/branch", &openapi.Operation{
+		OperationID: "assignBranch",
+		Summary:     "Move a user to a branch",
+		Tags:        []string{tagPortfolio},
+		RequestBody: s.body(domain.AssignBranchRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The updated user.", domain.User{})},
+	}, []domain.Permission{domain.PermissionBranchesManage}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodPut, "/admin/users/:id/officer", &openapi.Operation{
+		OperationID: "assignOfficer",
+		Summary:     "Assign a borrower to a loan officer",
+		Tags:        []string{tagPortfolio},
+		RequestBody: s.body(domain.AssignOfficerRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The updated borrower.", domain.User{})},
+	}, []domain.Permission{domain.PermissionPortfolioAssign}, http.StatusBadRequest, http.StatusNotFound)
+	s.protected(http.MethodGet, "/admin/users/:id/officer-history", &openapi.Operation{
+		OperationID: "getAssignmentHistory",
+		Summary:     "List the officers a borrower was assigned to, newest first",
+		Tags:        []string{tagPortfolio},
+		Responses:   openapi.Responses{"200": s.json("The assignments.", []domain.OfficerAssignment{})},
+	}, []domain.Permission{domain.PermissionUsersRead}, http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (s spec) tenants() {
+	s.protected(http.MethodGet, "/admin/tenants", &openapi.Operation{
+		OperationID: "listTenants",
+		Summary:     "List tenants",
+		Tags:        []string{tagTenants},
+		Responses:   openapi.Responses{"200": s.json("The tenants.", []domain.Tenant{})},
+	}, []domain.Permission{domain.PermissionTenantsManage})
+	s.protected(http.MethodPost, "/admin/tenants", &openapi.Operation{
+		OperationID: "createTenant",
+		Summary:     "Create a tenant",
+		Tags:        []string{tagTenants},
+		RequestBody: s.body(domain.CreateTenantRequest{}),
+		Responses:   openapi.Responses{"201": s.json("The new tenant.", domain.Tenant{})},
+	}, []domain.Permission{domain.PermissionTenantsManage}, http.StatusBadRequest)
+	s.protected(http.MethodPatch, "/admin/tenants/:id", &openapi.Operation{
+		OperationID: "updateTenant",
+		Summary:     "Rename, deactivate or reactivate a tenant",
+		Tags:        []string{tagTenants},
+		RequestBody: s.body(domain.UpdateTenantRequest{}),
+		Responses:   openapi.Responses{"200": s.json("The updated tenant.", domain.Tenant{})},
+	}, []domain.Permission{domain.PermissionTenantsManage}, http.StatusBadRequest, http.StatusNotFound)
+}
+
+func (s spec) docs() {
+	s.doc.Add(http.MethodGet, "/openapi.json", &openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "Get this document",
+		Tags:        []string{tagDocs},
+		Responses: openapi.Responses{"200": {
+			Description: "The OpenAPI document.",
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: openapi.Types{"object"}}}},
+		}},
+	})
+	s.doc.Add(http.MethodGet, "/docs", &openapi.Operation{
+		OperationID: "getDocs",
+		Summary:     "Read the API reference",
+		Tags:        []string{tagDocs},
+		Responses: openapi.Responses{"200": {
+			Description: "The API reference page.",
+			Content:     map[string]openapi.MediaType{"text/html": {Schema: openapi.String()}},
+		}},
+	})
+}
+
+// public adds an operation of the public router, which picks the tenant
+// from the X-Tenant header and limits requests per client address.
+func (s spec) public(method string, route string, op *openapi.Operation, problems ...int) {
+	op.Parameters = append(op.Parameters, openapi.Parameter{
+		Name:        domain.TenantHeader,
+		In:          openapi.InHeader,
+		Description: "The slug of the tenant, the default tenant without it.",
+		Schema:      openapi.String(),
+	})
+	problems = append(problems, http.StatusNotFound, http.StatusTooManyRequests)
+	s.add(method, route, op, problems)
+}
+
+// protected adds an operation of the protected router, which requires an
+// access token holding permissions and makes unsafe requests idempotent.
+// The token of a suspended user is refused with a 403.
+func (s spec) protected(method string, route string, op *openapi.Operation, permissions []domain.Permission, problems ...int) {
+	op.Security = []openapi.SecurityRequirement{{bearerAuth: {}}}
+	problems = append(problems, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests)
+	if len(permissions) > 0 {
+		names := make([]string, len(permissions))
+		for i, p := range permissions {
+			names[i] = string(p)
+		}
+		op.Description = strings.TrimSpace(op.Description + " Requires the " + strings.Join(names, ", ") + " permission.")
+	}
+	if method != http.MethodGet {
+		op.Parameters = append(op.Parameters, openapi.Parameter{
+			Name:        domain.IdempotencyHeader,
+			In:          openapi.InHeader,
+			Description: "Makes the request safe to retry: a retry with the same key gets the first successful response back, headers included. Error responses are not kept.",
+			Schema:      &openapi.Schema{Type: openapi.Types{"string"}, MaxLength: intPtr(255)},
+		})
+		problems = append(problems, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+	s.add(method, route, op, problems)
+}
+
+func (s spec) add(method string, route string, op *openapi.Operation, problems []int) {
+	problems = append(problems, http.StatusInternalServerError)
+	for _, status := range problems {
+		key := fmt.Sprint(status)
+		if _, ok := op.Responses[key]; !ok {
+			op.Responses[key] = &openapi.Response{Ref: "#/components/responses/" + problemName(status)}
+		}
+	}
+	s.doc.Add(method, route, op)
+}
+
+// body is a required JSON request body of the type of v.
+func (s spec) body(v interface{}) *openapi.RequestBody {
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  map[string]openapi.MediaType{"application/json": {Schema: s.doc.SchemaOf(v)}},
+	}
+}
+
+// json is a JSON response of the type of v.
+func (s spec) json(description string, v interface{}) *openapi.Response {
+	return &openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: s.doc.SchemaOf(v)}},
+	}
+}
+
+func (s spec) message(description string) *openapi.Response {
+	return &openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Ref: "#/components/schemas/Message"}}},
+	}
+}
+
+// page is a response written by writePage.
+func (s spec) page(description string, v interface{}) *openapi.Response {
+	response := s.json(description, v)
+	response.Headers = map[string]*openapi.Header{
+		"X-Total-Count": {Description: "The number of matching records on all pages.", Schema: openapi.Integer()},
+	}
+	return response
+}
+
+// tagged adds the ETag header of versioned records to a response.
+func (s spec) tagged(response *openapi.Response) *openapi.Response {
+	response.Headers = map[string]*openapi.Header{
+		"ETag": {Description: "The version of the record, for If-Match.", Schema: openapi.String()},
+	}
+	return response
+}
+
+func ifMatch() openapi.Parameter {
+	return openapi.Parameter{
+		Name:        "If-Match",
+		In:          openapi.InHeader,
+		Description: "The ETag of the version the change is based on. The change is rejected with 412 when the record changed since.",
+		Schema:      openapi.String(),
+	}
+}
+
+// queryParameters describes the filters, sort and paging parameters of a
+// list query read by package query.
+func queryParameters(schema domain.QuerySchema) []openapi.Parameter {
+	names := make([]string, 0, len(schema.Fields))
+	for name := range schema.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []openapi.Parameter
+	var sortable []string
+	for _, name := range names {
+		field := schema.Fields[name]
+		if field.Sortable {
+			sortable = append(sortable, name, "-"+name)
+		}
+		valueSchema := openapi.String()
+		switch field.Type {
+		case domain.FieldBool:
+			valueSchema = &openapi.Schema{Type: openapi.Types{"string"}, Enum: []string{"true", "false"}}
+		case domain.FieldTime:
+			valueSchema = &openapi.Schema{
+				Type:        openapi.Types{"string"},
+				AnyOf:       []*openapi.Schema{{Format: "date-time"}, {Format: "date"}},
+				Description: "An RFC 3339 time or a date.",
+			}
+		}
+
+		// eq and in share the bare field name
+		switch eq, in := hasOp(field.Ops, domain.OpEq), hasOp(field.Ops, domain.OpIn); {
+		case eq && in:
+			params = append(params, openapi.Parameter{Name: name, In: openapi.InQuery, Description: "Equal to the value, or to one of a comma separated list of values.", Schema: valueSchema})
+		case eq:
+			params = append(params, openapi.Parameter{Name: name, In: openapi.InQuery, Description: "Equal to the value.", Schema: valueSchema})
+		case in:
+			params = append(params, openapi.Parameter{Name: name, In: openapi.InQuery, Description: "One of a comma separated list of values.", Schema: valueSchema})
+		}
+		for _, op := range field.Ops {
+			if op == domain.OpEq || op == domain.OpIn {
+				continue
+			}
+			params = append(params, openapi.Parameter{
+				Name:        name + "[" + string(op) + "]",
+				In:          openapi.InQuery,
+				Description: fmt.Sprintf("Applies the %s operator.", op),
+				Schema:      valueSchema,
+			})
+		}
+	}
+
+	return append(params,
+		openapi.Parameter{
+			Name:        "sort",
+			In:          openapi.InQuery,
+			Description: "The field to sort by, descending with a leading -.",
+			Schema:      &openapi.Schema{Type: openapi.Types{"string"}, Enum: sortable},
+		},
+		openapi.Parameter{
+			Name:        "limit",
+			In:          openapi.InQuery,
+			Description: "The size of the page.",
+			Schema:      &openapi.Schema{Type: openapi.Types{"integer"}, Minimum: floatPtr(1), Maximum: floatPtr(domain.MaxPageLimit)},
+		},
+		openapi.Parameter{
+			Name:        "cursor",
+			In:          openapi.InQuery,
+			Description: "The next_cursor of the previous page.",
+			Schema:      openapi.String(),
+		},
+	)
+}
+
+func hasOp(ops []domain.Operator, op domain.Operator) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func problemName(status int) string {
+	return strings.ReplaceAll(http.StatusText(status), " ", "")
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func floatPtr(n float64) *float64 {
+	return &n
+}
@@ -5,17 +5,20 @@ import (
 
 	"github.com/dagota12/Loan-Tracker/api/controller"
 	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/security"
 	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/dagota12/Loan-Tracker/usecase"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewPasswordRouter(env *bootstrap.Env, timeout time.Duration, db *mongo.Database, group *gin.RouterGroup) {
-	userRepo := repository.NewResetPasswordRepository(db, "users", "password-reset")
-	userUsecase := usecase.NewResetPasswordUsecase(userRepo, timeout)
-	userController := controller.NewResetPasswordController(env, userUsecase)
+// NewPasswordRouter registers the password reset routes, which throttle
+// guards as they send and check reset codes.
+func NewPasswordRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, mailer emailutil.Mailer, passwordPolicy *security.PasswordPolicy, throttle gin.HandlerFunc, group *gin.RouterGroup) {
+	userUsecase := usecase.NewResetPasswordUsecase(repos.ResetPassword, repos.Tx, timeout, env.OtpMaxAttempts, passwordPolicy)
+	lockoutUsecase := usecase.NewLockoutUsecase(repos.LoginAttempts, repos.Users, env, mailer)
+	userController := controller.NewResetPasswordController(env, mailer, userUsecase, lockoutUsecase)
 
-	group.POST("/users/reset-password", userController.ResetPassword)
-	group.POST("/users/forgot-password", userController.ForgotPassword)
+	group.POST("/users/reset-password", throttle, userController.ResetPassword)
+	group.POST("/users/forgot-password", throttle, userController.ForgotPassword)
 }
@@ -0,0 +1,36 @@
+package route
+
+import (
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+func NewPortfolioRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, group *gin.RouterGroup) {
+	branchController := controller.NewBranchController(usecase.NewBranchUsecase(repos.Branches, timeout))
+	portfolioUsecase := usecase.NewPortfolioUsecase(
+		repos.Users,
+		repos.Branches,
+		repos.OfficerAssignments,
+		repos.Loans,
+		repos.Audit,
+		timeout,
+	)
+	portfolioController := controller.NewPortfolioController(portfolioUsecase)
+
+	group.GET("/officers/me/portfolio", middleware.RequirePermission(domain.PermissionPortfolioRead), portfolioController.GetMyPortfolio)
+
+	admin := group.Group("/admin")
+	admin.GET("/branches", middleware.RequirePermission(domain.PermissionUsersRead), branchController.GetBranches)
+	admin.POST("/branches", middleware.RequirePermission(domain.PermissionBranchesManage), branchController.CreateBranch)
+	admin.PATCH("/branches/:id", middleware.RequirePermission(domain.PermissionBranchesManage), branchController.UpdateBranch)
+	admin.PUT("/users/:id/branch", middleware.RequirePermission(domain.PermissionBranchesManage), portfolioController.AssignBranch)
+	admin.PUT("/users/:id/officer", middleware.RequirePermission(domain.PermissionPortfolioAssign), portfolioController.AssignOfficer)
+	admin.GET("/users/:id/officer-history", middleware.RequirePermission(domain.PermissionUsersRead), middleware.RestrictToPortfolio(), portfolioController.GetAssignmentHistory)
+}
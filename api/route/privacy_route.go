@@ -0,0 +1,38 @@
+package route
+
+import (
+	"log"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/storage"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+func NewPrivacyRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, group *gin.RouterGroup) {
+	documents, err := storage.NewLocal(env.KYCStorageDir)
+	if err != nil {
+		log.Fatal("KYC document storage can't be opened: ", err)
+	}
+
+	privacyUsecase := usecase.NewPrivacyUsecase(
+		repos.Users,
+		repos.KYC,
+		repos.Audit,
+		repos.LoginAttempts,
+		repos.MagicLinks,
+		repos.ResetPassword,
+		repos.Tx,
+		documents,
+		env,
+	)
+	privacyController := controller.NewPrivacyController(privacyUsecase)
+
+	group.GET("/users/profile/export", middleware.RejectImpersonation(), privacyController.ExportProfile)
+	group.POST("/admin/users/:id/erase", middleware.RequirePermission(domain.PermissionUsersDelete), middleware.RejectImpersonation(), privacyController.EraseUser)
+}
@@ -1,39 +1,119 @@
 package route
 
 import (
+	"context"
+	"log"
 	"time"
 
 	"github.com/dagota12/Loan-Tracker/api/controller"
 	"github.com/dagota12/Loan-Tracker/api/middleware"
 	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/ratelimit"
+	"github.com/dagota12/Loan-Tracker/internal/security"
+	"github.com/dagota12/Loan-Tracker/internal/storage"
 	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/dagota12/Loan-Tracker/usecase"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func Setup(env *bootstrap.Env, timeout time.Duration, db *mongo.Database, gin *gin.Engine) {
+func Setup(env *bootstrap.Env, timeout time.Duration, repos repository.Set, mailer emailutil.Mailer, gin *gin.Engine) {
+	passwordPolicy, err := security.NewPasswordPolicy(env)
+	if err != nil {
+		log.Fatal("Password policy can't be loaded: ", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := usecase.EnsurePrimaryTenant(ctx, repos.Tenants, repos.Users, env.DefaultTenantSlug); err != nil {
+		log.Fatal("Default tenant can't be created: ", err)
+	}
+	// the tenant of every authenticated request is checked to be active
+	tenantCache := time.Duration(env.TenantCacheSec) * time.Second
+	tenantUsecase := usecase.NewCachedTenantUsecase(usecase.NewTenantUsecase(repos.Tenants, timeout), tenantCache)
+
+	documents, err := storage.NewLocal(env.KYCStorageDir)
+	if err != nil {
+		log.Fatal("KYC document storage can't be opened: ", err)
+	}
+	// disbursing a loan checks the verification of the borrower
+	kycUsecase := usecase.NewKYCUsecase(repos.KYC, repos.Users, repos.Audit, documents, env, mailer)
+
+	// handlers pass the gin context on to the usecases, so it has to expose
+	// the tenant scope stored in the request context
+	gin.ContextWithFallback = true
+	// errors recorded by any handler or middleware are answered as problem
+	// details, so this has to come before everything else
+	gin.Use(middleware.ErrorMiddleware())
+
+	// requests are checked against the document, and so are responses in
+	// tests, to catch the handlers drifting from it
+	doc := OpenAPI()
+	validator := middleware.OpenAPIMiddleware(doc, env.AppEnv == "test")
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+
+	// the sign in and password reset endpoints are the brute force targets,
+	// so they get a strict quota per client address on top of the general one
+	authPolicy := ratelimit.Policy{
+		Name:      "auth",
+		Limit:     env.RateLimitAuthRequests,
+		Window:    time.Duration(env.RateLimitAuthWindowSec) * time.Second,
+		Algorithm: ratelimit.SlidingWindow,
+	}
+	apiPolicy := ratelimit.Policy{
+		Name:      "api",
+		Limit:     env.RateLimitAPIRequests,
+		Window:    time.Duration(env.RateLimitAPIWindowSec) * time.Second,
+		Algorithm: ratelimit.TokenBucket,
+	}
+	for _, policy := range []ratelimit.Policy{authPolicy, apiPolicy} {
+		if err := policy.Validate(); err != nil {
+			log.Fatal("Rate limit policy can't be loaded: ", err)
+		}
+	}
+	throttle := middleware.RateLimitMiddleware(limiter, authPolicy, middleware.KeyByIP)
 
 	publicRouter := gin.Group("")
+	publicRouter.Use(middleware.RateLimitMiddleware(limiter, apiPolicy, middleware.KeyByIP))
+	publicRouter.Use(middleware.TenantMiddleware(tenantUsecase, env.DefaultTenantSlug))
+	publicRouter.Use(validator)
 
 	// All Public APIs
-	NewSignupRouter(env, timeout, db, publicRouter)
-	NewAuthRouter(env, timeout, db, publicRouter)
-	NewPasswordRouter(env, timeout, db, publicRouter)
+	NewSignupRouter(env, timeout, repos, mailer, passwordPolicy, throttle, publicRouter)
+	NewAuthRouter(env, timeout, repos, mailer, throttle, publicRouter)
+	NewPasswordRouter(env, timeout, repos, mailer, passwordPolicy, throttle, publicRouter)
+	NewEmailChangeRouter(env, timeout, repos, mailer, passwordPolicy, publicRouter)
 
 	protectedRouter := gin.Group("")
-	protectedRouter.Use(middleware.JwtAuthMiddleware(env.AccessTokenSecret))
+	protectedRouter.Use(middleware.JwtAuthMiddleware(env.AccessTokenSecret, usecase.NewAuthUsease(repos.Users), tenantUsecase))
+	protectedRouter.Use(middleware.RateLimitMiddleware(limiter, apiPolicy, middleware.KeyByUser))
+	protectedRouter.Use(middleware.IdempotencyMiddleware(usecase.NewIdempotencyUsecase(repos.Idempotency, env)))
+	// after idempotency, so that a rejected request is answered the same
+	// way when it is retried
+	protectedRouter.Use(validator)
+
+	NewUsersRouter(env, timeout, repos, mailer, passwordPolicy, protectedRouter)
+	NewKYCRouter(kycUsecase, protectedRouter)
+	NewLoanRouter(timeout, repos, kycUsecase, protectedRouter)
+	NewPrivacyRouter(env, timeout, repos, protectedRouter)
+	NewSearchRouter(env, timeout, repos, protectedRouter)
+	NewTenantRouter(tenantUsecase, protectedRouter)
+	NewPortfolioRouter(env, timeout, repos, protectedRouter)
 
-	NewUsersRouter(env, timeout, db, protectedRouter)
+	NewDocsRouter(doc, gin.Group(""))
 }
 
-func NewSignupRouter(env *bootstrap.Env, timeout time.Duration, db *mongo.Database, group *gin.RouterGroup) {
-	userRepo := repository.NewUserRepository(db)
-	signupUsecase := usecase.NewSignupUsecase(userRepo, time.Duration(env.ContextTimeout))
+// NewSignupRouter registers the sign up routes; throttle guards the
+// creation of accounts.
+func NewSignupRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, mailer emailutil.Mailer, passwordPolicy *security.PasswordPolicy, throttle gin.HandlerFunc, group *gin.RouterGroup) {
+	signupUsecase := usecase.NewSignupUsecase(repos.Users, repos.Tenants, timeout, passwordPolicy, env, mailer)
 	sc := controller.SignupController{
 		SignupUsecase: signupUsecase,
 		Env:           env,
 	}
 
-	group.POST("/users/register", sc.Signup)
+	group.POST("/users/register", throttle, sc.Signup)
+	group.GET("/users/verify-email/:token", sc.VerifyEmail)
+	group.POST("/users/verify-email/resend", sc.ResendVerificationEmail)
 }
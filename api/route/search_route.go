@@ -0,0 +1,20 @@
+package route
+
+import (
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+func NewSearchRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, group *gin.RouterGroup) {
+	searchUsecase := usecase.NewSearchUsecase(repos.Search, timeout)
+	searchController := controller.NewSearchController(searchUsecase)
+
+	group.GET("/admin/search", middleware.RequirePermission(domain.PermissionUsersRead, domain.PermissionLoansRead), middleware.RestrictToPortfolio(), searchController.Search)
+}
@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/dagota12/Loan-Tracker/api/controller"
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func NewTenantRouter(tenantUsecase domain.TenantUsecase, group *gin.RouterGroup) {
+	tenantController := controller.NewTenantController(tenantUsecase)
+
+	admin := group.Group("/admin", middleware.RequirePermission(domain.PermissionTenantsManage))
+	admin.GET("/tenants", tenantController.GetTenants)
+	admin.POST("/tenants", tenantController.CreateTenant)
+	admin.PATCH("/tenants/:id", tenantController.UpdateTenant)
+}
@@ -6,24 +6,44 @@ import (
 	"github.com/dagota12/Loan-Tracker/api/controller"
 	"github.com/dagota12/Loan-Tracker/api/middleware"
 	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/security"
 	"github.com/dagota12/Loan-Tracker/repository"
 	"github.com/dagota12/Loan-Tracker/usecase"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewUsersRouter(env *bootstrap.Env, timeout time.Duration, db *mongo.Database, group *gin.RouterGroup) {
-	userRepo := repository.NewUserRepository(db)
-	userUsecase := usecase.NewUserUsecase(userRepo, env)
+func NewUsersRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, mailer emailutil.Mailer, passwordPolicy *security.PasswordPolicy, group *gin.RouterGroup) {
+	userUsecase := usecase.NewUserUsecase(repos.Users, repos.Audit, repos.Tx, env, passwordPolicy, mailer)
 	userController := controller.NewUserController(userUsecase)
+	lockoutUsecase := usecase.NewLockoutUsecase(repos.LoginAttempts, repos.Users, env, mailer)
+	lockoutController := controller.NewLockoutController(lockoutUsecase)
 
-	group.GET("/admin/users", userController.GetAllUsers)
 	group.GET("/users/profile", userController.GetUserProfile)
-	group.GET("/admin/users/:id", userController.GetUser)
-	group.DELETE("/users/:id", userController.DeleteUser)
+	group.PATCH("/users/profile", userController.UpdateProfile)
+	group.POST("/users/profile/email", middleware.RejectImpersonation(), userController.RequestEmailChange)
 
-	//protected routes
-	protected := group.Group("")
-	protected.Use(middleware.JwtAuthMiddleware(env.AccessTokenSecret))
-	protected.POST("/users/update-password", userController.UpdatePassword)
+	admin := group.Group("/admin")
+	admin.GET("/users", middleware.RequirePermission(domain.PermissionUsersRead), middleware.RestrictToPortfolio(), userController.GetAllUsers)
+	admin.GET("/users/:id", middleware.RequirePermission(domain.PermissionUsersRead), middleware.RestrictToPortfolio(), userController.GetUser)
+	admin.GET("/users/:id/audit", middleware.RequirePermission(domain.PermissionUsersRead), middleware.RestrictToPortfolio(), userController.GetAuditTrail)
+	admin.PATCH("/users/:id", middleware.RequirePermission(domain.PermissionUsersWrite), middleware.RestrictToPortfolio(), userController.UpdateUser)
+	admin.DELETE("/users/:id", middleware.RequirePermission(domain.PermissionUsersDelete), userController.DeleteUser)
+	admin.POST("/users/:id/suspend", middleware.RequirePermission(domain.PermissionUsersSuspend), userController.SuspendUser)
+	admin.POST("/users/:id/reactivate", middleware.RequirePermission(domain.PermissionUsersSuspend), userController.ReactivateUser)
+	admin.POST("/users/:id/impersonate", middleware.RequirePermission(domain.PermissionUsersImpersonate), middleware.RejectImpersonation(), userController.ImpersonateUser)
+	admin.GET("/roles", middleware.RequirePermission(domain.PermissionRolesAssign), userController.GetRoles)
+	admin.PUT("/users/:id/role", middleware.RequirePermission(domain.PermissionRolesAssign), userController.AssignRole)
+	admin.POST("/users/:id/unlock", middleware.RequirePermission(domain.PermissionUsersWrite), lockoutController.UnlockUser)
+
+	group.POST("/users/update-password", middleware.RejectImpersonation(), userController.UpdatePassword)
+}
+
+// NewEmailChangeRouter serves the public confirmation link of an email change.
+func NewEmailChangeRouter(env *bootstrap.Env, timeout time.Duration, repos repository.Set, mailer emailutil.Mailer, passwordPolicy *security.PasswordPolicy, group *gin.RouterGroup) {
+	userUsecase := usecase.NewUserUsecase(repos.Users, repos.Audit, repos.Tx, env, passwordPolicy, mailer)
+	userController := controller.NewUserController(userUsecase)
+
+	group.GET("/users/profile/email/confirm/:token", userController.ConfirmEmailChange)
 }
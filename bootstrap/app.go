@@ -1,21 +1,44 @@
 package bootstrap
 
 import (
+	"database/sql"
+	"log"
+
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/postgres"
+	"github.com/dagota12/Loan-Tracker/repository/sqlite"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Application struct {
 	Env   *Env
 	Mongo *mongo.Client
+	SQL   *sql.DB
+	// Repos are stored in the database selected by DB_DRIVER.
+	Repos repository.Set
 }
 
 func App() Application {
 	app := &Application{}
 	app.Env = NewEnv()
-	app.Mongo = NewMongoClient(app.Env)
+
+	switch app.Env.DBDriver {
+	case DriverMongo:
+		app.Mongo = NewMongoClient(app.Env)
+		app.Repos = NewMongoRepositories(app.Env, app.Mongo)
+	case DriverPostgres:
+		app.SQL = NewPostgresDB(app.Env)
+		app.Repos = postgres.NewSet(app.SQL)
+	case DriverSQLite:
+		app.SQL = NewSQLiteDB(app.Env)
+		app.Repos = sqlite.NewSet(app.SQL)
+	default:
+		log.Fatal("Unknown DB_DRIVER: ", app.Env.DBDriver)
+	}
 	return *app
 }
 
 func (app *Application) CloseDBConnection() {
 	CloseMongoDBConnection(app.Mongo)
+	CloseSQLConnection(app.SQL)
 }
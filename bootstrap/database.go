@@ -2,10 +2,16 @@ package bootstrap
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"net/url"
 	"time"
 
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/migrations"
+	"github.com/dagota12/Loan-Tracker/repository/postgres"
+	"github.com/dagota12/Loan-Tracker/repository/sqlite"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -50,3 +56,74 @@ func CloseMongoDBConnection(client *mongo.Client) {
 
 	log.Println("Connection to MongoDB closed.")
 }
+
+// migrationTimeout bounds the migrations run on startup, which may build
+// indexes over whole collections.
+const migrationTimeout = 5 * time.Minute
+
+// NewMongoRepositories returns the repositories stored in the DB_NAME
+// database and applies the migrations it is missing.
+func NewMongoRepositories(env *Env, client *mongo.Client) repository.Set {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	db := client.Database(env.DBName)
+	if err := migrations.Up(ctx, db); err != nil {
+		log.Fatal("Migrations can't be applied: ", err)
+	}
+	return repository.NewMongoSet(db, env.MongoAllowNonTx)
+}
+
+// NewPostgresDB connects to the DB_NAME database and applies the migrations
+// it is missing.
+func NewPostgresDB(env *Env) *sql.DB {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(env.DBUser, env.DBPass),
+		Host:     env.DBHost + ":" + env.DBPort,
+		Path:     "/" + env.DBName,
+		RawQuery: url.Values{"sslmode": {env.DBSSLMode}}.Encode(),
+	}
+	db, err := postgres.Open(ctx, dsn.String())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := postgres.Migrate(ctx, db); err != nil {
+		log.Fatal("Migrations can't be applied: ", err)
+	}
+	return db
+}
+
+// NewSQLiteDB opens the database file at DB_PATH and applies the migrations
+// it is missing.
+func NewSQLiteDB(env *Env) *sql.DB {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	db, err := sqlite.Open(ctx, env.DBPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := sqlite.Migrate(ctx, db); err != nil {
+		log.Fatal("Migrations can't be applied: ", err)
+	}
+	return db
+}
+
+func CloseSQLConnection(db *sql.DB) {
+	if db == nil {
+		return
+	}
+
+	err := db.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Connection to the database closed.")
+}
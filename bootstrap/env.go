@@ -6,15 +6,26 @@ import (
 	"github.com/spf13/viper"
 )
 
+// storage backends selected by DB_DRIVER
+const (
+	DriverMongo    = "mongo"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 type Env struct {
 	AppEnv                     string `mapstructure:"APP_ENV"`
 	ServerAddress              string `mapstructure:"SERVER_ADDRESS"`
 	ContextTimeout             int    `mapstructure:"CONTEXT_TIMEOUT"`
+	DBDriver                   string `mapstructure:"DB_DRIVER"`
 	DBHost                     string `mapstructure:"DB_HOST"`
 	DBPort                     string `mapstructure:"DB_PORT"`
 	DBUser                     string `mapstructure:"DB_USER"`
 	DBPass                     string `mapstructure:"DB_PASS"`
 	DBName                     string `mapstructure:"DB_NAME"`
+	DBSSLMode                  string `mapstructure:"DB_SSL_MODE"`
+	DBPath                     string `mapstructure:"DB_PATH"`
+	MongoAllowNonTx            bool   `mapstructure:"MONGO_ALLOW_NON_TX"`
 	AccessTokenExpiryHour      int    `mapstructure:"ACCESS_TOKEN_EXPIRY_HOUR"`
 	RefreshTokenExpiryHour     int    `mapstructure:"REFRESH_TOKEN_EXPIRY_HOUR"`
 	AccessTokenSecret          string `mapstructure:"ACCESS_TOKEN_SECRET"`
@@ -26,11 +37,42 @@ type Env struct {
 	SmtpHost                   string `mapstructure:"SMTP_HOST"`
 	SenderPassword             string `mapstructure:"SENDER_PASSWORD"`
 	PassResetCodeExpirationMin int    `mapstructure:"PASS_RESET_CODE_EXPIRATION_MIN"`
+	VerificationResendCooldown int    `mapstructure:"VERIFICATION_RESEND_COOLDOWN_SEC"`
+	PublicBaseURL              string `mapstructure:"PUBLIC_BASE_URL"`
+	LoginMaxAttempts           int    `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts         int    `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginLockoutBaseSec        int    `mapstructure:"LOGIN_LOCKOUT_BASE_SEC"`
+	LoginLockoutMaxMin         int    `mapstructure:"LOGIN_LOCKOUT_MAX_MIN"`
+	LoginAttemptWindowMin      int    `mapstructure:"LOGIN_ATTEMPT_WINDOW_MIN"`
+	UnlockTokenExpiryMin       int    `mapstructure:"UNLOCK_TOKEN_EXPIRY_MIN"`
+	OtpMaxAttempts             int    `mapstructure:"OTP_MAX_ATTEMPTS"`
+	MagicLinkExpiryMin         int    `mapstructure:"MAGIC_LINK_EXPIRY_MIN"`
+	EmailChangeExpiryMin       int    `mapstructure:"EMAIL_CHANGE_EXPIRY_MIN"`
+	RateLimitAuthRequests      int    `mapstructure:"RATE_LIMIT_AUTH_REQUESTS"`
+	RateLimitAuthWindowSec     int    `mapstructure:"RATE_LIMIT_AUTH_WINDOW_SEC"`
+	RateLimitAPIRequests       int    `mapstructure:"RATE_LIMIT_API_REQUESTS"`
+	RateLimitAPIWindowSec      int    `mapstructure:"RATE_LIMIT_API_WINDOW_SEC"`
+	IdempotencyKeyTTLMin       int    `mapstructure:"IDEMPOTENCY_KEY_TTL_MIN"`
+	PasswordMinLength          int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength          int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordRequireUpper       bool   `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower       bool   `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit       bool   `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol      bool   `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordHistorySize        int    `mapstructure:"PASSWORD_HISTORY_SIZE"`
+	BreachedPasswordsFile      string `mapstructure:"BREACHED_PASSWORDS_FILE"`
+	KYCStorageDir              string `mapstructure:"KYC_STORAGE_DIR"`
+	KYCMaxUploadMB             int    `mapstructure:"KYC_MAX_UPLOAD_MB"`
+	KYCExpiryCheckMin          int    `mapstructure:"KYC_EXPIRY_CHECK_MIN"`
+	ImpersonationExpiryMin     int    `mapstructure:"IMPERSONATION_EXPIRY_MIN"`
+	DefaultTenantSlug          string `mapstructure:"DEFAULT_TENANT_SLUG"`
+	TenantCacheSec             int    `mapstructure:"TENANT_CACHE_SEC"`
 }
 
 func NewEnv() *Env {
 	env := Env{}
 	viper.SetConfigFile(".env")
+	setDefaults()
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -48,3 +90,48 @@ func NewEnv() *Env {
 
 	return &env
 }
+
+// setDefaults provides values for the optional settings so that an existing
+// .env keeps working when new settings are introduced.
+func setDefaults() {
+	viper.SetDefault("DB_DRIVER", DriverMongo)
+	viper.SetDefault("DB_SSL_MODE", "prefer")
+	viper.SetDefault("DB_PATH", "loan-tracker.db")
+	// a standalone Mongo server cannot roll back a failed unit of work, so
+	// running on one has to be asked for
+	viper.SetDefault("MONGO_ALLOW_NON_TX", false)
+	viper.SetDefault("PUBLIC_BASE_URL", "http://localhost:8080")
+	viper.SetDefault("VERIFICATION_TOKEN_EXPIRY_MIN", 24*60)
+	viper.SetDefault("VERIFICATION_RESEND_COOLDOWN_SEC", 60)
+	viper.SetDefault("LOGIN_MAX_ATTEMPTS", 5)
+	viper.SetDefault("LOGIN_IP_MAX_ATTEMPTS", 20)
+	viper.SetDefault("LOGIN_LOCKOUT_BASE_SEC", 30)
+	viper.SetDefault("LOGIN_LOCKOUT_MAX_MIN", 60)
+	viper.SetDefault("LOGIN_ATTEMPT_WINDOW_MIN", 24*60)
+	viper.SetDefault("UNLOCK_TOKEN_EXPIRY_MIN", 60)
+	viper.SetDefault("OTP_MAX_ATTEMPTS", 5)
+	viper.SetDefault("MAGIC_LINK_EXPIRY_MIN", 15)
+	viper.SetDefault("EMAIL_CHANGE_EXPIRY_MIN", 60)
+	viper.SetDefault("RATE_LIMIT_AUTH_REQUESTS", 10)
+	viper.SetDefault("RATE_LIMIT_AUTH_WINDOW_SEC", 60)
+	viper.SetDefault("RATE_LIMIT_API_REQUESTS", 120)
+	viper.SetDefault("RATE_LIMIT_API_WINDOW_SEC", 60)
+	viper.SetDefault("IDEMPOTENCY_KEY_TTL_MIN", 24*60)
+	// bcrypt ignores everything after the 72nd byte
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 72)
+	viper.SetDefault("PASSWORD_REQUIRE_UPPER", true)
+	viper.SetDefault("PASSWORD_REQUIRE_LOWER", true)
+	viper.SetDefault("PASSWORD_REQUIRE_DIGIT", true)
+	viper.SetDefault("PASSWORD_REQUIRE_SYMBOL", false)
+	viper.SetDefault("PASSWORD_HISTORY_SIZE", 5)
+	viper.SetDefault("KYC_STORAGE_DIR", "uploads/kyc")
+	viper.SetDefault("KYC_MAX_UPLOAD_MB", 10)
+	viper.SetDefault("KYC_EXPIRY_CHECK_MIN", 60)
+	viper.SetDefault("IMPERSONATION_EXPIRY_MIN", 15)
+	// requests without an X-Tenant header belong to the primary tenant
+	viper.SetDefault("DEFAULT_TENANT_SLUG", "default")
+	// how long a deactivated tenant may keep being served by an instance
+	// other than the one that deactivated it
+	viper.SetDefault("TENANT_CACHE_SEC", 30)
+}
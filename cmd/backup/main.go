@@ -0,0 +1,45 @@
+// Command backup copies the SQLite database at DB_PATH to a new file while
+// the server keeps running:
+//
+//	go run ./cmd/backup [destination]
+//
+// The destination defaults to DB_PATH with the current time appended.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/repository/sqlite"
+)
+
+func main() {
+	env := bootstrap.NewEnv()
+	if env.DBDriver != bootstrap.DriverSQLite {
+		log.Fatalf("DB_DRIVER is %q, backups are only taken of %q databases", env.DBDriver, bootstrap.DriverSQLite)
+	}
+	if _, err := os.Stat(env.DBPath); err != nil {
+		log.Fatal(err)
+	}
+
+	destination := fmt.Sprintf("%s.%s.bak", env.DBPath, time.Now().UTC().Format("20060102T150405Z"))
+	if len(os.Args) > 1 {
+		destination = os.Args[1]
+	}
+
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, env.DBPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := sqlite.Backup(ctx, db, destination); err != nil {
+		log.Fatal("Backup failed: ", err)
+	}
+	log.Println("Backup written to", destination)
+}
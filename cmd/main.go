@@ -1,34 +1,49 @@
 package main
 
 import (
+	"log"
 	"time"
 
 	"github.com/dagota12/Loan-Tracker/api/route"
 	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/storage"
+	"github.com/dagota12/Loan-Tracker/usecase"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Initialize the app
+	// Initialize the app and the storage selected by DB_DRIVER
 	app := bootstrap.App()
 
 	// Get the environment variables
 	env := app.Env
 
-	// Connect to the database
-	db := app.Mongo.Database(env.DBName)
-
 	// Close the database connection when the main function is done
 	defer app.CloseDBConnection()
 
 	// Set the timeout for the context of the request
 	timeout := time.Duration(env.ContextTimeout) * time.Second
 
+	// Emails go through the SMTP server of the environment
+	mailer := emailutil.NewSMTP(env)
+
+	// Expire the identity verifications whose documents lapsed, unless
+	// KYC_EXPIRY_CHECK_MIN turns it off
+	if env.KYCExpiryCheckMin > 0 {
+		documents, err := storage.NewLocal(env.KYCStorageDir)
+		if err != nil {
+			log.Fatal("KYC document storage can't be opened: ", err)
+		}
+		kycUsecase := usecase.NewKYCUsecase(app.Repos.KYC, app.Repos.Users, app.Repos.Audit, documents, env, mailer)
+		go usecase.ExpireKYCEvery(kycUsecase, time.Duration(env.KYCExpiryCheckMin)*time.Minute)
+	}
+
 	// Initialize the gin
 	gin := gin.Default()
 
 	// Setup the routes
-	route.Setup(env, timeout, db, gin)
+	route.Setup(env, timeout, app.Repos, mailer, gin)
 
 	// Run the server
 	gin.Run(env.ServerAddress)
@@ -0,0 +1,69 @@
+// Command migrate applies the database migrations without starting the
+// server, or lists them:
+//
+//	go run ./cmd/migrate [up|status]
+//
+// The server applies them on startup too; running them beforehand keeps
+// long index builds out of the deployment.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/repository/migrations"
+)
+
+func main() {
+	command := "up"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	if command != "up" && command != "status" {
+		log.Fatalf("unknown command %q, expected up or status", command)
+	}
+
+	env := bootstrap.NewEnv()
+	switch env.DBDriver {
+	case bootstrap.DriverPostgres, bootstrap.DriverSQLite:
+		if command == "status" {
+			log.Fatalf("status is only available for %q databases", bootstrap.DriverMongo)
+		}
+		// opening the database applies its migrations
+		app := bootstrap.App()
+		app.CloseDBConnection()
+		return
+	case bootstrap.DriverMongo:
+	default:
+		log.Fatal("Unknown DB_DRIVER: ", env.DBDriver)
+	}
+
+	client := bootstrap.NewMongoClient(env)
+	defer bootstrap.CloseMongoDBConnection(client)
+	db := client.Database(env.DBName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if command == "up" {
+		if err := migrations.Up(ctx, db); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	statuses, err := migrations.List(ctx, db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, status := range statuses {
+		applied := "pending"
+		if !status.AppliedAt.IsZero() {
+			applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%4d  %-24s  %s\n", status.Version, status.Name, applied)
+	}
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry records a change made to a user's data and who made it.
+type AuditEntry struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	TenantID  string             `json:"tenant_id" bson:"tenant_id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	ActorID   string             `json:"actor_id" bson:"actor_id"`
+	Action    string             `json:"action" bson:"action"`
+	Changes   []FieldChange      `json:"changes,omitempty" bson:"changes,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// RedactedValue replaces values that must not be kept in the audit trail.
+const RedactedValue = "[redacted]"
+
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old" bson:"old"`
+	New   interface{} `json:"new" bson:"new"`
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry AuditEntry) error
+	ListByUser(ctx context.Context, userID string) ([]AuditEntry, error)
+	// RedactChanges hides the old and new values of the changes recorded
+	// for the user by the given actions.
+	RedactChanges(ctx context.Context, userID string, actions []string) error
+}
+
+// audit actions
+const (
+	AuditActionProfileUpdate   = "profile.update"
+	AuditActionUserUpdate      = "user.update"
+	AuditActionUserDelete      = "user.delete"
+	AuditActionUserErase       = "user.erase"
+	AuditActionUserSuspend     = "user.suspend"
+	AuditActionUserReactivate  = "user.reactivate"
+	AuditActionUserRoleChange  = "user.role_change"
+	AuditActionUserImpersonate = "user.impersonate"
+	AuditActionOfficerAssign   = "user.officer_assign"
+	AuditActionBranchAssign    = "user.branch_assign"
+	AuditActionKYCSubmit       = "kyc.submit"
+	AuditActionKYCReview       = "kyc.review"
+	AuditActionKYCExpire       = "kyc.expire"
+	AuditActionLoanCreate      = "loan.create"
+	AuditActionLoanApprove     = "loan.approve"
+	AuditActionLoanDisburse    = "loan.disburse"
+)
+
+const (
+	CollectionAuditLog = "audit-log"
+)
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Branch is an office of a tenant that loan officers are attached to.
+type Branch struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	TenantID  string             `json:"tenant_id" bson:"tenant_id"`
+	Name      string             `json:"name" bson:"name"`
+	Code      string             `json:"code" bson:"code"`
+	Active    bool               `json:"active" bson:"active"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateBranchRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Code string `json:"code" binding:"required,alphanum,max=20"`
+}
+
+type UpdateBranchRequest struct {
+	Name   *string `json:"name" binding:"omitempty,max=100"`
+	Active *bool   `json:"active"`
+}
+
+type BranchRepository interface {
+	Create(ctx context.Context, branch Branch) (Branch, error)
+	GetByID(ctx context.Context, branchID string) (Branch, error)
+	List(ctx context.Context) ([]Branch, error)
+	Update(ctx context.Context, branchID string, request UpdateBranchRequest) (Branch, error)
+}
+
+type BranchUsecase interface {
+	Create(ctx context.Context, request CreateBranchRequest) (Branch, error)
+	List(ctx context.Context) ([]Branch, error)
+	Update(ctx context.Context, branchID string, request UpdateBranchRequest) (Branch, error)
+}
+
+const CollectionBranches = "branches"
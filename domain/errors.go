@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+)
+
+// ErrorKind says what went wrong with a request, which decides the status
+// it is answered with.
+type ErrorKind int
+
+const (
+	// KindInternal is every error that is not the client's to fix.
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	// KindValidation is a request that is malformed or breaks a rule.
+	KindValidation
+	// KindUnprocessable is a well formed request that cannot apply to the
+	// records it names.
+	KindUnprocessable
+	KindUnauthorized
+	KindForbidden
+	KindPreconditionFailed
+	KindTooLarge
+	KindUnsupportedMediaType
+	KindTooManyRequests
+)
+
+// Error is an error the client can act on. Code identifies it for
+// programs and never changes; Message is meant for people.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Fields maps the invalid fields of a request, by their JSON name, to
+	// the reason they were rejected.
+	Fields map[string]string
+	// Err is the cause, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(code string, message string) error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code string, message string) error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Validation(code string, message string) error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// InvalidFields is a validation error listing the fields that were
+// rejected.
+func InvalidFields(code string, message string, fields map[string]string) error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+func Unprocessable(code string, message string) error {
+	return &Error{Kind: KindUnprocessable, Code: code, Message: message}
+}
+
+func Unauthorized(code string, message string) error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func Forbidden(code string, message string) error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func TooManyRequests(code string, message string) error {
+	return &Error{Kind: KindTooManyRequests, Code: code, Message: message}
+}
+
+// ErrorOf returns the first *Error in the chain of err, or nil for an
+// internal error.
+func ErrorOf(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+	return nil
+}
+
+// Problem is the body of an error response, the problem details of RFC
+// 7807 with the stable error code and the invalid fields added.
+type Problem struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title"`
+	Status int               `json:"status"`
+	Detail string            `json:"detail,omitempty"`
+	Code   string            `json:"code"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ProblemContentType is the media type of a Problem.
+const ProblemContentType = "application/problem+json"
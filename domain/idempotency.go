@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyHeader carries the key a client sends with a request it may
+// retry. Every retry with the same key gets the response of the first try.
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyRecord is the outcome of a request sent with an idempotency
+// key, kept until it expires so that retries can be answered with it.
+type IdempotencyRecord struct {
+	Key string `json:"key" bson:"_id"`
+	// Fingerprint identifies the request the key was first used for.
+	Fingerprint string `json:"fingerprint" bson:"fingerprint"`
+	// Status is 0 while the first request is still being handled.
+	Status      int       `json:"status" bson:"status"`
+	ContentType string `json:"content_type" bson:"content_type"`
+	// Header holds the other response headers a retry is answered with,
+	// such as ETag and Location.
+	Header    map[string]string `json:"header,omitempty" bson:"header,omitempty"`
+	Body      []byte            `json:"body" bson:"body"`
+	CreatedAt time.Time         `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at" bson:"expires_at"`
+}
+
+type IdempotencyRepository interface {
+	// Reserve stores record unless an unexpired record with the same key
+	// exists, in which case it returns that one; reserved reports whether
+	// record was stored.
+	Reserve(ctx context.Context, record IdempotencyRecord) (stored IdempotencyRecord, reserved bool, err error)
+	// Complete stores the response to the request that reserved key.
+	Complete(ctx context.Context, key string, status int, contentType string, header map[string]string, body []byte) error
+	// Release drops the record of key so that the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type IdempotencyUsecase interface {
+	// Begin claims key for the request with fingerprint. It returns the
+	// completed record of an earlier request with the same key and
+	// fingerprint, or a record with status 0 when the request is new and
+	// has to be handled.
+	Begin(ctx context.Context, key string, fingerprint string) (IdempotencyRecord, error)
+	// Finish stores the response to the request that claimed key.
+	Finish(ctx context.Context, key string, status int, contentType string, header map[string]string, body []byte) error
+	// Abandon gives up key after a failure, so that a retry runs again.
+	Abandon(ctx context.Context, key string) error
+}
+
+const (
+	CollectionIdempotencyKeys = "idempotency-keys"
+)
@@ -3,12 +3,33 @@ package domain
 import "github.com/golang-jwt/jwt/v4"
 
 type JwtCustomClaims struct {
-	ID      string `json:"id"`
-	Role    string `json:"role"`
-	IsOwner bool   `json:"is_owner"`
+	ID       string `json:"id"`
+	TenantID string `json:"tid,omitempty"`
+	Role     string `json:"role"`
+	IsOwner  bool   `json:"is_owner"`
+
+	Permissions []string `json:"permissions"`
+	Purpose     string   `json:"purpose,omitempty"`
+	// Act names the admin acting as the user in an impersonation token,
+	// following the actor claim of RFC 8693.
+	Act *ActorClaim `json:"act,omitempty"`
+	// TokenVersion is the TokenVersion of the user the token was issued at.
+	TokenVersion int64 `json:"ver,omitempty"`
 	jwt.RegisteredClaims
 }
+
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
 type JwtCustomRefreshClaims struct {
-	ID string `json:"id"`
+	ID       string `json:"id"`
+	TenantID string `json:"tid,omitempty"`
 	jwt.RegisteredClaims
 }
+
+// token purposes
+const (
+	TokenPurposeUnlock      = "unlock"
+	TokenPurposeVerifyEmail = "verify_email"
+	TokenPurposeMagicLink   = "magic_link"
+)
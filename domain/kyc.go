@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type KYCStatus string
+
+// kyc statuses
+const (
+	KYCStatusNotStarted KYCStatus = "not_started"
+	KYCStatusSubmitted  KYCStatus = "submitted"
+	KYCStatusApproved   KYCStatus = "approved"
+	KYCStatusRejected   KYCStatus = "rejected"
+	KYCStatusExpired    KYCStatus = "expired"
+)
+
+// kyc document types
+const (
+	KYCDocumentPassport       = "passport"
+	KYCDocumentNationalID     = "national_id"
+	KYCDocumentDriversLicense = "drivers_license"
+	KYCDocumentProofOfAddress = "proof_of_address"
+)
+
+// IsIdentityDocument reports whether docType proves identity. Identity
+// documents must carry an expiry date, which bounds the verification.
+func IsIdentityDocument(docType string) bool {
+	switch docType {
+	case KYCDocumentPassport, KYCDocumentNationalID, KYCDocumentDriversLicense:
+		return true
+	}
+	return false
+}
+
+// KYC is the identity verification record of a user.
+type KYC struct {
+	ID              primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	UserID          string             `json:"user_id" bson:"user_id"`
+	TenantID        string             `json:"tenant_id" bson:"tenant_id"`
+	Status          KYCStatus          `json:"status" bson:"status"`
+	Documents       []KYCDocument      `json:"documents" bson:"documents"`
+	RejectionReason string             `json:"rejection_reason,omitempty" bson:"rejection_reason,omitempty"`
+	ReviewedBy      string             `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	SubmittedAt     time.Time          `json:"submitted_at,omitempty" bson:"submitted_at,omitempty"`
+	ReviewedAt      time.Time          `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	// ExpiresAt is when an approval lapses, the expiry of the longest valid
+	// identity document at the time of review.
+	ExpiresAt time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type KYCDocument struct {
+	ID          string    `json:"id" bson:"id"`
+	Type        string    `json:"type" bson:"type"`
+	FileName    string    `json:"file_name" bson:"file_name"`
+	ContentType string    `json:"content_type" bson:"content_type"`
+	Size        int64     `json:"size" bson:"size"`
+	StorageKey  string    `json:"-" bson:"storage_key"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	UploadedAt  time.Time `json:"uploaded_at" bson:"uploaded_at"`
+}
+
+type KYCDocumentUpload struct {
+	Type      string `form:"type" binding:"required,oneof=passport national_id drivers_license proof_of_address"`
+	ExpiresAt string `form:"expires_at" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type KYCReviewRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+	Reason   string `json:"reason" binding:"max=500"`
+}
+
+// KYCTransition moves a record from one status to another and carries the
+// review details that go with the new status.
+type KYCTransition struct {
+	From       KYCStatus
+	To         KYCStatus
+	ReviewerID string
+	Reason     string
+	ExpiresAt  time.Time
+	At         time.Time
+}
+
+type KYCRepository interface {
+	// GetOrCreate returns the record of the user, creating a not started
+	// one if there is none yet.
+	GetOrCreate(ctx context.Context, userID string) (KYC, error)
+	GetByUserID(ctx context.Context, userID string) (KYC, error)
+	AddDocument(ctx context.Context, userID string, document KYCDocument) error
+	// Transition fails with a not found error when the record is no longer
+	// in the From status.
+	Transition(ctx context.Context, userID string, transition KYCTransition) (KYC, error)
+	// ListByStatus returns the records of the users visible with ctx, which
+	// within a portfolio are its borrowers only.
+	ListByStatus(ctx context.Context, status KYCStatus) ([]KYC, error)
+	// ListExpired returns the approved records whose expiry is before now.
+	ListExpired(ctx context.Context, now time.Time) ([]KYC, error)
+	// Delete removes the record of the user and returns it as it was.
+	Delete(ctx context.Context, userID string) (KYC, error)
+}
+
+// DocumentStorage keeps the uploaded files outside of the database.
+type DocumentStorage interface {
+	Save(ctx context.Context, key string, content io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file, succeeding when there is none.
+	Delete(ctx context.Context, key string) error
+}
+
+type KYCUsecase interface {
+	// GetStatus returns the record of the user, marking it expired first when
+	// its documents have expired.
+	GetStatus(ctx context.Context, userID string) (KYC, error)
+	// Get returns the record of the user like GetStatus, but fails with a
+	// not found error instead of creating one.
+	Get(ctx context.Context, userID string) (KYC, error)
+	UploadDocument(ctx context.Context, userID string, upload KYCDocumentUpload, fileName string, content io.Reader) (KYCDocument, error)
+	Submit(ctx context.Context, userID string, actorID string) (KYC, error)
+	ListByStatus(ctx context.Context, status KYCStatus) ([]KYC, error)
+	OpenDocument(ctx context.Context, userID string, documentID string) (KYCDocument, io.ReadCloser, error)
+	Review(ctx context.Context, userID string, reviewerID string, request KYCReviewRequest) (KYC, error)
+	// EnsureVerified returns an error unless the user holds an approved,
+	// unexpired verification. Disbursing funds must be guarded by it.
+	EnsureVerified(ctx context.Context, userID string) error
+	// ExpireDue marks every approved record with expired documents as
+	// expired and asks the users to verify again.
+	ExpireDue(ctx context.Context) (int, error)
+}
+
+const (
+	CollectionKYC = "kyc"
+)
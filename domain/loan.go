@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LoanStatus string
+
+// loan statuses
+const (
+	LoanStatusPending   LoanStatus = "pending"
+	LoanStatusApproved  LoanStatus = "approved"
+	LoanStatusDisbursed LoanStatus = "disbursed"
+)
+
+// Loan is money lent to a borrower, from the application to the payout.
+type Loan struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	TenantID   string             `json:"tenant_id" bson:"tenant_id"`
+	BorrowerID string             `json:"borrower_id" bson:"borrower_id"`
+	// Amount is in the minor unit of the currency of the tenant.
+	Amount      int64      `json:"amount" bson:"amount"`
+	TermMonths  int        `json:"term_months" bson:"term_months"`
+	Status      LoanStatus `json:"status" bson:"status"`
+	CreatedBy   string     `json:"created_by" bson:"created_by"`
+	ApprovedBy  string     `json:"approved_by,omitempty" bson:"approved_by,omitempty"`
+	ApprovedAt  time.Time  `json:"approved_at,omitempty" bson:"approved_at,omitempty"`
+	DisbursedBy string     `json:"disbursed_by,omitempty" bson:"disbursed_by,omitempty"`
+	DisbursedAt time.Time  `json:"disbursed_at,omitempty" bson:"disbursed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" bson:"updated_at"`
+	// Version goes up by one with every change to the loan.
+	Version int64 `json:"version" bson:"version"`
+}
+
+type CreateLoanRequest struct {
+	BorrowerID string `json:"borrower_id" binding:"required"`
+	Amount     int64  `json:"amount" binding:"required,min=1"`
+	TermMonths int    `json:"term_months" binding:"required,min=1,max=360"`
+}
+
+// LoanTransition moves a loan from one status to another on behalf of
+// ActorID. Version is the version of the loan the transition is based on;
+// version 0 applies to any version.
+type LoanTransition struct {
+	From    LoanStatus
+	To      LoanStatus
+	Version int64
+	ActorID string
+	At      time.Time
+}
+
+type LoanRepository interface {
+	Create(ctx context.Context, loan Loan) (Loan, error)
+	GetByID(ctx context.Context, loanID string) (Loan, error)
+	// ListByBorrower returns the loans of the borrower, newest first.
+	ListByBorrower(ctx context.Context, borrowerID string) ([]Loan, error)
+	// List returns one page of the loans of the borrowers visible with ctx,
+	// which within a portfolio are its borrowers only.
+	List(ctx context.Context, spec QuerySpec) (Page[Loan], error)
+	// Transition fails with a conflict when the loan is no longer at the
+	// Version of the transition, and with a not found error when it is no
+	// longer in the From status.
+	Transition(ctx context.Context, loanID string, transition LoanTransition) (Loan, error)
+}
+
+// LoanUsecase manages the loans of the borrowers the caller can see: a
+// loan of a borrower outside their portfolio is not found.
+type LoanUsecase interface {
+	Create(ctx context.Context, actorID string, request CreateLoanRequest) (Loan, error)
+	Get(ctx context.Context, loanID string) (Loan, error)
+	ListByBorrower(ctx context.Context, borrowerID string) ([]Loan, error)
+	List(ctx context.Context, spec QuerySpec) (Page[Loan], error)
+	// Approve and Disburse move the loan at version, or at any version
+	// when version is 0, failing with a conflict otherwise.
+	Approve(ctx context.Context, loanID string, actorID string, version int64) (Loan, error)
+	// Disburse pays out an approved loan, which requires the borrower to
+	// hold an approved identity verification.
+	Disburse(ctx context.Context, loanID string, actorID string, version int64) (Loan, error)
+}
+
+const CollectionLoans = "loans"
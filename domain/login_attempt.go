@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LoginAttempt tracks consecutive failed authentication attempts for a key,
+// which is either an account ("<scope>:account:<email>") or a client
+// ("<scope>:ip:<addr>"). UnlockToken is the hash of the unlock link last
+// emailed for a locked account.
+type LoginAttempt struct {
+	Key         string    `json:"key" bson:"_id"`
+	Failures    int       `json:"failures" bson:"failures"`
+	LastFailure time.Time `json:"last_failure" bson:"last_failure"`
+	LockedUntil time.Time `json:"locked_until" bson:"locked_until"`
+	UnlockToken string    `json:"-" bson:"unlock_token,omitempty"`
+}
+
+// LockoutScope separates what the failures are counted for, so that wrong
+// password reset codes do not lock sign in and the other way around.
+type LockoutScope string
+
+const (
+	LockoutLogin LockoutScope = "login"
+	LockoutOTP   LockoutScope = "otp"
+)
+
+type LoginAttemptRepository interface {
+	// Get returns the attempt record for key, or a zero record if there is none.
+	Get(ctx context.Context, key string) (LoginAttempt, error)
+	// RecordFailure increments the failure counter of key, restarting it when
+	// the previous failure happened before resetBefore.
+	RecordFailure(ctx context.Context, key string, at time.Time, resetBefore time.Time) (LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	// SetUnlockToken stores the hash of the unlock token issued for key.
+	SetUnlockToken(ctx context.Context, key string, tokenHash string) error
+	// ConsumeUnlockToken resets key if tokenHash is the hash of its unlock
+	// token and reports whether it did.
+	ConsumeUnlockToken(ctx context.Context, key string, tokenHash string) (bool, error)
+}
+
+type LockoutUsecase interface {
+	// Check returns how long the caller has to wait before email may be tried
+	// again from ip in scope; zero means the attempt is allowed.
+	Check(ctx context.Context, scope LockoutScope, email string, ip string) (time.Duration, error)
+	RegisterFailure(ctx context.Context, scope LockoutScope, email string, ip string) error
+	RegisterSuccess(ctx context.Context, scope LockoutScope, email string) error
+	Unlock(ctx context.Context, userID string) error
+	UnlockWithToken(ctx context.Context, token string) error
+}
+
+const (
+	CollectionLoginAttempts = "login-attempts"
+)
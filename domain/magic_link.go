@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MagicLink is a single use, passwordless sign in link. Only hashes of the
+// link token and of the browser nonce are stored.
+type MagicLink struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	TenantID  string             `json:"tenant_id" bson:"tenant_id"`
+	UserID    string             `json:"user_id" bson:"user_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	NonceHash string             `json:"-" bson:"nonce_hash"`
+	Used      bool               `json:"used" bson:"used"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkRepository interface {
+	// Create stores link, replacing any link previously issued to the same user.
+	Create(ctx context.Context, link MagicLink) error
+	// Consume marks the unused, unexpired link matching both hashes as used
+	// and returns it.
+	Consume(ctx context.Context, tokenHash string, nonceHash string, now time.Time) (MagicLink, error)
+	// DeleteByUser removes every link issued to the user.
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+type MagicLinkUsecase interface {
+	// RequestLink emails a sign in link to email when it belongs to an active
+	// account. It always returns a nonce for the requesting browser, so the
+	// response does not reveal whether the account exists.
+	RequestLink(ctx context.Context, email string) (nonce string, err error)
+	// Redeem consumes the link token presented together with the browser
+	// nonce and returns the user it was issued to.
+	Redeem(ctx context.Context, token string, nonce string) (User, error)
+}
+
+const (
+	CollectionMagicLinks = "magic-links"
+	MagicLinkNonceCookie = "magic_link_nonce"
+)
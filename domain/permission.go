@@ -0,0 +1,132 @@
+package domain
+
+// roles
+const (
+	// RoleSuperAdmin manages tenants on top of administering its own one.
+	RoleSuperAdmin  = "super_admin"
+	RoleAdmin       = "admin"
+	RoleLoanOfficer = "loan_officer"
+	RoleAuditor     = "auditor"
+	RoleCollector   = "collector"
+	RoleBorrower    = "borrower"
+
+	// RoleUser is the role given to accounts created before roles were
+	// introduced; it carries the same permissions as RoleBorrower.
+	RoleUser = "user"
+)
+
+type Permission string
+
+// permissions
+const (
+	PermissionUsersRead   Permission = "users:read"
+	PermissionUsersWrite  Permission = "users:write"
+	PermissionUsersDelete Permission = "users:delete"
+	PermissionRolesAssign Permission = "roles:assign"
+
+	PermissionTenantsManage  Permission = "tenants:manage"
+	PermissionBranchesManage Permission = "branches:manage"
+
+	// PermissionPortfolioRead lets a loan officer list their own borrowers.
+	// Without PermissionPortfolioAll, the users they can look up are
+	// limited to those borrowers.
+	PermissionPortfolioRead   Permission = "portfolio:read"
+	PermissionPortfolioAll    Permission = "portfolio:all"
+	PermissionPortfolioAssign Permission = "portfolio:assign"
+
+	PermissionUsersSuspend     Permission = "users:suspend"
+	PermissionUsersImpersonate Permission = "users:impersonate"
+
+	PermissionProfileRead  Permission = "profile:read"
+	PermissionProfileWrite Permission = "profile:write"
+
+	PermissionKYCRead   Permission = "kyc:read"
+	PermissionKYCReview Permission = "kyc:review"
+
+	PermissionLoansRead     Permission = "loans:read"
+	PermissionLoansApply    Permission = "loans:apply"
+	PermissionLoansCreate   Permission = "loans:create"
+	PermissionLoansApprove  Permission = "loans:approve"
+	PermissionLoansDisburse Permission = "loans:disburse"
+
+	PermissionRepaymentsRead   Permission = "repayments:read"
+	PermissionRepaymentsRecord Permission = "repayments:record"
+
+	PermissionLedgerRead   Permission = "ledger:read"
+	PermissionLedgerExport Permission = "ledger:export"
+)
+
+var adminPermissions = []Permission{
+	PermissionUsersRead, PermissionUsersWrite, PermissionUsersDelete, PermissionRolesAssign,
+	PermissionUsersSuspend, PermissionUsersImpersonate,
+	PermissionBranchesManage, PermissionPortfolioAll, PermissionPortfolioAssign,
+	PermissionProfileRead, PermissionProfileWrite,
+	PermissionKYCRead, PermissionKYCReview,
+	PermissionLoansRead, PermissionLoansCreate, PermissionLoansApprove, PermissionLoansDisburse,
+	PermissionRepaymentsRead, PermissionRepaymentsRecord,
+	PermissionLedgerRead, PermissionLedgerExport,
+}
+
+// RolePermissions maps every known role to the permissions it grants.
+var RolePermissions = map[string][]Permission{
+	RoleSuperAdmin: append([]Permission{PermissionTenantsManage}, adminPermissions...),
+	RoleAdmin:      adminPermissions,
+	RoleLoanOfficer: {
+		PermissionUsersRead, PermissionPortfolioRead,
+		PermissionProfileRead, PermissionProfileWrite,
+		PermissionKYCRead, PermissionKYCReview,
+		PermissionLoansRead, PermissionLoansCreate, PermissionLoansApprove,
+		PermissionRepaymentsRead,
+	},
+	RoleAuditor: {
+		PermissionUsersRead, PermissionPortfolioAll,
+		PermissionProfileRead,
+		PermissionKYCRead,
+		PermissionLoansRead,
+		PermissionRepaymentsRead,
+		PermissionLedgerRead, PermissionLedgerExport,
+	},
+	RoleCollector: {
+		PermissionProfileRead, PermissionProfileWrite,
+		PermissionLoansRead,
+		PermissionRepaymentsRead, PermissionRepaymentsRecord,
+	},
+	RoleBorrower: {
+		PermissionProfileRead, PermissionProfileWrite,
+		PermissionLoansApply,
+	},
+}
+
+// IsValidRole reports whether role is one that can be assigned to a user.
+// The super admin role only goes to the owner of the primary tenant.
+func IsValidRole(role string) bool {
+	if role == RoleUser || role == RoleSuperAdmin {
+		return false
+	}
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// PermissionsForRole returns the permission names granted by role.
+// Unknown roles grant nothing.
+func PermissionsForRole(role string) []string {
+	if role == RoleUser {
+		role = RoleBorrower
+	}
+	perms := RolePermissions[role]
+	names := make([]string, 0, len(perms))
+	for _, p := range perms {
+		names = append(names, string(p))
+	}
+	return names
+}
+
+// role assignment
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type RoleResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OfficerAssignment records a borrower being handed to a loan officer.
+type OfficerAssignment struct {
+	ID                primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	TenantID          string             `json:"tenant_id" bson:"tenant_id"`
+	BorrowerID        string             `json:"borrower_id" bson:"borrower_id"`
+	OfficerID         string             `json:"officer_id" bson:"officer_id"`
+	PreviousOfficerID string             `json:"previous_officer_id,omitempty" bson:"previous_officer_id,omitempty"`
+	BranchID          string             `json:"branch_id,omitempty" bson:"branch_id,omitempty"`
+	ActorID           string             `json:"actor_id" bson:"actor_id"`
+	Reason            string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	AssignedAt        time.Time          `json:"assigned_at" bson:"assigned_at"`
+}
+
+// PortfolioLoan is a loan in an officer's portfolio with what its borrower
+// owes on it, in the same unit as the amount of the loan.
+type PortfolioLoan struct {
+	Loan
+	// Outstanding is the part of the amount not repaid yet.
+	Outstanding int64 `json:"outstanding"`
+	// Due is the next instalment, payable at NextDueAt.
+	Due       int64      `json:"due"`
+	NextDueAt *time.Time `json:"next_due_at,omitempty"`
+	// Overdue is the sum of the instalments whose date has passed.
+	Overdue int64 `json:"overdue"`
+}
+
+type AssignOfficerRequest struct {
+	OfficerID string `json:"officer_id" binding:"required"`
+	Reason    string `json:"reason" binding:"max=500"`
+}
+
+type AssignBranchRequest struct {
+	BranchID string `json:"branch_id" binding:"required"`
+}
+
+type OfficerAssignmentRepository interface {
+	Create(ctx context.Context, assignment OfficerAssignment) error
+	ListByBorrower(ctx context.Context, borrowerID string) ([]OfficerAssignment, error)
+}
+
+type PortfolioUsecase interface {
+	// AssignOfficer hands the borrower to a loan officer, replacing the
+	// officer they had.
+	AssignOfficer(ctx context.Context, actorID string, borrowerID string, request AssignOfficerRequest) (User, error)
+	// AssignBranch attaches the loan officer to a branch.
+	AssignBranch(ctx context.Context, actorID string, officerID string, branchID string) (User, error)
+	History(ctx context.Context, borrowerID string) ([]OfficerAssignment, error)
+	// Portfolio lists the loans of the borrowers assigned to the officer,
+	// with what is owed on each.
+	Portfolio(ctx context.Context, officerID string, spec QuerySpec) (Page[PortfolioLoan], error)
+}
+
+const CollectionOfficerAssignments = "officer-assignments"
+
+type portfolioContextKey struct{}
+
+// WithPortfolio restricts the users found with the returned context to the
+// borrowers assigned to officerID.
+func WithPortfolio(ctx context.Context, officerID string) context.Context {
+	return context.WithValue(ctx, portfolioContextKey{}, officerID)
+}
+
+// PortfolioFromContext returns the officer whose portfolio ctx is
+// restricted to.
+func PortfolioFromContext(ctx context.Context) (string, bool) {
+	officerID, ok := ctx.Value(portfolioContextKey{}).(string)
+	return officerID, ok
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// UserExport is everything held about a user, as handed out on request.
+type UserExport struct {
+	ExportedAt time.Time    `json:"exported_at"`
+	User       User         `json:"user"`
+	KYC        *KYC         `json:"kyc,omitempty"`
+	AuditTrail []AuditEntry `json:"audit_trail"`
+}
+
+type PrivacyUsecase interface {
+	Export(ctx context.Context, userID string) (UserExport, error)
+	// WriteArchive writes the export of the user as a ZIP archive holding
+	// the JSON export and the uploaded documents.
+	WriteArchive(ctx context.Context, userID string, w io.Writer) error
+	// Erase pseudonymizes the personal data of a user and deletes their
+	// KYC record and documents, sign-in links and reset codes. Records that
+	// must be retained, such as loans, ledger entries and the audit trail of
+	// KYC reviews, keep referring to the user by ID.
+	Erase(ctx context.Context, actorID string, userID string) error
+}
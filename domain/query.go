@@ -0,0 +1,94 @@
+package domain
+
+type Operator string
+
+// query operators
+const (
+	OpEq     Operator = "eq"
+	OpIn     Operator = "in"
+	OpGte    Operator = "gte"
+	OpLte    Operator = "lte"
+	OpPrefix Operator = "prefix"
+)
+
+// Condition restricts a list to the items whose Field compares to Value.
+// Value is a string, bool or time.Time, or a slice of them for OpIn.
+type Condition struct {
+	Field string
+	Op    Operator
+	Value interface{}
+}
+
+// QuerySpec describes one page of a filtered and sorted list. Pages are
+// addressed by an opaque cursor rather than an offset so that they stay
+// stable while items are added.
+type QuerySpec struct {
+	Conditions []Condition
+	SortBy     string
+	SortDesc   bool
+	Limit      int
+	Cursor     string
+}
+
+// Page is one page of a list. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+type FieldType int
+
+const (
+	FieldString FieldType = iota
+	FieldBool
+	FieldTime
+)
+
+// QueryField describes how a list can be filtered and sorted on a field.
+type QueryField struct {
+	Type     FieldType
+	Ops      []Operator
+	Sortable bool
+}
+
+// QuerySchema lists the fields a list endpoint accepts. Field names are the
+// names the fields are stored under.
+type QuerySchema struct {
+	Fields      map[string]QueryField
+	DefaultSort string
+	DefaultDesc bool
+}
+
+// UserQuerySchema is the schema of the admin user listing.
+var UserQuerySchema = QuerySchema{
+	Fields: map[string]QueryField{
+		"role":       {Type: FieldString, Ops: []Operator{OpEq, OpIn}},
+		"active":     {Type: FieldBool, Ops: []Operator{OpEq}},
+		"suspended":  {Type: FieldBool, Ops: []Operator{OpEq}},
+		"branch_id":  {Type: FieldString, Ops: []Operator{OpEq, OpIn}},
+		"officer_id": {Type: FieldString, Ops: []Operator{OpEq, OpIn}},
+		"email":      {Type: FieldString, Ops: []Operator{OpEq, OpPrefix}, Sortable: true},
+		"created_at": {Type: FieldTime, Ops: []Operator{OpGte, OpLte}, Sortable: true},
+		"last_login": {Type: FieldTime, Ops: []Operator{OpGte, OpLte}, Sortable: true},
+	},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+}
+
+// LoanQuerySchema is the schema of the loan listings.
+var LoanQuerySchema = QuerySchema{
+	Fields: map[string]QueryField{
+		"status":      {Type: FieldString, Ops: []Operator{OpEq, OpIn}},
+		"borrower_id": {Type: FieldString, Ops: []Operator{OpEq, OpIn}},
+		"created_by":  {Type: FieldString, Ops: []Operator{OpEq}},
+		"created_at":  {Type: FieldTime, Ops: []Operator{OpGte, OpLte}, Sortable: true},
+	},
+	DefaultSort: "created_at",
+	DefaultDesc: true,
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "context"
+
+// search result types
+const (
+	SearchResultBorrower = "borrower"
+	SearchResultLoan     = "loan"
+)
+
+type SearchResult struct {
+	Type       string      `json:"type"`
+	ID         string      `json:"id"`
+	Title      string      `json:"title"`
+	Score      float64     `json:"score"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// SearchResponse is the answer to a search.
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
+
+// Highlight points out where the search terms matched in a field.
+type Highlight struct {
+	Field  string      `json:"field"`
+	Text   string      `json:"text"`
+	Ranges []TextRange `json:"ranges"`
+}
+
+// TextRange is a half open range of character (not byte) offsets.
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// SearchIndex finds the records matching a free text query, best match
+// first.
+type SearchIndex interface {
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+type SearchUsecase interface {
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+const (
+	MinSearchQueryLength = 2
+	MaxSearchQueryLength = 100
+)
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tenant is an organization, such as a microfinance branch, whose users and
+// records are kept apart from every other tenant.
+type Tenant struct {
+	ID   primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Name string             `json:"name" bson:"name"`
+	Slug string             `json:"slug" bson:"slug"`
+	// OwnerEmail is the address whose account becomes the owner of the
+	// tenant. When empty, the first account created becomes the owner.
+	OwnerEmail string `json:"owner_email,omitempty" bson:"owner_email,omitempty"`
+	// Primary marks the tenant created when the platform is first set up.
+	// Its owner is the super admin.
+	Primary   bool      `json:"primary" bson:"primary"`
+	Active    bool      `json:"active" bson:"active"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateTenantRequest struct {
+	Name       string `json:"name" binding:"required,max=100"`
+	Slug       string `json:"slug" binding:"required,min=2,max=40,hostname_rfc1123"`
+	OwnerEmail string `json:"owner_email" binding:"required,email"`
+}
+
+type UpdateTenantRequest struct {
+	Name   *string `json:"name" binding:"omitempty,max=100"`
+	Active *bool   `json:"active"`
+}
+
+// TenantRepository is not scoped by tenant; it is what the scope is
+// resolved from.
+type TenantRepository interface {
+	// EnsurePrimary returns the tenant with slug, creating it as the primary
+	// tenant if it does not exist yet.
+	EnsurePrimary(ctx context.Context, slug string) (Tenant, error)
+	Create(ctx context.Context, tenant Tenant) (Tenant, error)
+	GetByID(ctx context.Context, tenantID string) (Tenant, error)
+	GetBySlug(ctx context.Context, slug string) (Tenant, error)
+	List(ctx context.Context) ([]Tenant, error)
+	Update(ctx context.Context, tenantID string, request UpdateTenantRequest) (Tenant, error)
+	// ClaimOwner atomically sets the owner email of the tenant to email
+	// unless it has one already, and returns the tenant as it is afterwards.
+	ClaimOwner(ctx context.Context, tenantID string, email string) (Tenant, error)
+}
+
+type TenantUsecase interface {
+	Create(ctx context.Context, request CreateTenantRequest) (Tenant, error)
+	List(ctx context.Context) ([]Tenant, error)
+	Update(ctx context.Context, tenantID string, request UpdateTenantRequest) (Tenant, error)
+	// Resolve and GetActive return the tenant only while it is active.
+	Resolve(ctx context.Context, slug string) (Tenant, error)
+	GetActive(ctx context.Context, tenantID string) (Tenant, error)
+}
+
+const (
+	CollectionTenants = "tenants"
+
+	// TenantHeader selects the tenant of an unauthenticated request by slug.
+	TenantHeader = "X-Tenant"
+)
+
+type tenantContextKey struct{}
+type allTenantsContextKey struct{}
+
+// WithTenant scopes every repository call made with the returned context to
+// tenantID.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey{}).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// WithAllTenants lifts the tenant scope for the lookups that cannot know the
+// tenant in advance: records found by an unguessable secret, such as a
+// sign in link, and background jobs. The tenant of the record found must
+// then be used for everything else.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsContextKey{}, true)
+}
+
+// IsAllTenants reports whether ctx was made by WithAllTenants.
+func IsAllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsContextKey{}).(bool)
+	return all
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+// TxManager runs work on several repositories as one unit: either all of
+// it is stored or none of it is.
+type TxManager interface {
+	// WithinTx runs fn in a transaction. The repositories called with the
+	// ctx passed to fn take part in it. The transaction is committed when
+	// fn returns nil and rolled back otherwise. A call made within another
+	// transaction joins it.
+	//
+	// fn runs again when the transaction fails on a transient error, so it
+	// must not have effects outside the repositories, such as sending mail.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
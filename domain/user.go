@@ -8,19 +8,48 @@ import (
 )
 
 type User struct {
-	ID          primitive.ObjectID `json:"_id"  bson:"_id,omitempty"`
-	FirstName   string             `json:"first_name" bson:"first_name" binding:"required,min=3,max=30"`
-	LastName    string             `json:"last_name" bson:"last_name" binding:"max=30"`
-	Email       string             `json:"email" bson:"email" binding:"required,email"`
-	Active      bool               `json:"active" bson:"active"`
-	Password    string             `json:"-" bson:"password"`
-	VerifyToken string             `json:"-" bson:"verify_token"`
-	IsOwner     bool               `json:"is_owner" bson:"is_owner"`
-	Tokens      []string           `json:"-" bson:"refresh_tokens"`
-	Role        string             `joson:"role" bson:"role"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-	LastLogin   time.Time          `json:"last_login" bson:"last_login"`
+	ID                   primitive.ObjectID `json:"_id"  bson:"_id,omitempty"`
+	TenantID             string             `json:"tenant_id" bson:"tenant_id"`
+	FirstName            string             `json:"first_name" bson:"first_name" binding:"required,min=3,max=30"`
+	LastName             string             `json:"last_name" bson:"last_name" binding:"max=30"`
+	Email                string             `json:"email" bson:"email" binding:"required,email"`
+	Active               bool               `json:"active" bson:"active"`
+	Suspended            bool               `json:"suspended" bson:"suspended"`
+	SuspendedAt          time.Time          `json:"suspended_at,omitempty" bson:"suspended_at,omitempty"`
+	SuspendReason        string             `json:"suspend_reason,omitempty" bson:"suspend_reason,omitempty"`
+	Password             string             `json:"-" bson:"password"`
+	PasswordHistory      []string           `json:"-" bson:"password_history"`
+	VerifyToken          string             `json:"-" bson:"verify_token"`
+	VerifySentAt         time.Time          `json:"-" bson:"verify_sent_at"`
+	PendingEmail         string             `json:"pending_email,omitempty" bson:"pending_email,omitempty"`
+	EmailChangeToken     string             `json:"-" bson:"email_change_token,omitempty"`
+	EmailChangeExpiresAt time.Time          `json:"-" bson:"email_change_expires_at,omitempty"`
+	IsOwner              bool               `json:"is_owner" bson:"is_owner"`
+	Tokens               []string           `json:"-" bson:"refresh_tokens"`
+	Role                 string             `json:"role" bson:"role"`
+	BranchID             string             `json:"branch_id,omitempty" bson:"branch_id,omitempty"`
+	OfficerID            string             `json:"officer_id,omitempty" bson:"officer_id,omitempty"`
+	Profile              Profile            `json:"profile" bson:"profile"`
+	CreatedAt            time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at" bson:"updated_at"`
+	LastLogin            time.Time          `json:"last_login" bson:"last_login"`
+	DeletedAt            time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	ErasedAt             time.Time          `json:"erased_at,omitempty" bson:"erased_at,omitempty"`
+	// Version goes up by one with every change to the data of the user;
+	// sign-ins and password changes leave it alone.
+	Version int64 `json:"version" bson:"version"`
+	// TokenVersion goes up by one whenever every session of the user is
+	// revoked; access tokens issued before no longer authenticate.
+	TokenVersion int64 `json:"-" bson:"token_version"`
+}
+
+// personal data left on an erased user
+const ErasedName = "Erased"
+
+// ErasedEmail is the placeholder address of an erased user. It stays unique
+// and can never receive mail.
+func ErasedEmail(userID string) string {
+	return "erased+" + userID + "@invalid"
 }
 
 type UserUpdate struct {
@@ -28,6 +57,27 @@ type UserUpdate struct {
 	LastName  string    `json:"last_name" bson:"last_name"`
 	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
 }
+
+// UpdateUserRequest renames a user. The names left out keep their value.
+type UpdateUserRequest struct {
+	FirstName *string `json:"first_name" binding:"omitnil,min=3,max=30"`
+	LastName  *string `json:"last_name" binding:"omitnil,max=30"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type ImpersonationResponse struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
 type UserForm struct {
 	FirstName string    `json:"first_name" bson:"first_name" binding:"required,min=3,max=30"`
 	LastName  string    `json:"last_name" bson:"last_name" binding:"max=30"`
@@ -39,14 +89,32 @@ type UserForm struct {
 
 // user repository
 type UserRepository interface {
-	GetAll(ctx context.Context) ([]User, error)
+	List(ctx context.Context, spec QuerySpec) (Page[User], error)
 	GetByID(ctx context.Context, userID string) (User, error)
 	GetByEmail(ctx context.Context, email string) (User, error)
 	Create(ctx context.Context, user User) (User, error)
-	Update(ctx context.Context, userID string, user UserUpdate) (User, error)
+	// Update and UpdateProfile only apply to the user at version, failing
+	// with a conflict otherwise. Version 0 applies to any version.
+	Update(ctx context.Context, userID string, version int64, user UserUpdate) (User, error)
+	// Delete marks the user as deleted; deleted users are left out of every
+	// other query.
 	Delete(ctx context.Context, userID string) error
+	// Erase replaces the personal fields of a user, deleted or not, with
+	// placeholders and returns the user as it was before.
+	Erase(ctx context.Context, userID string) (User, error)
 
 	IsOwner(ctx context.Context, userID string) (bool, error)
+	// Count returns the number of users of the tenant that are not deleted.
+	Count(ctx context.Context) (int64, error)
+	// UpdateRole also revokes the access tokens of the user, which carry
+	// the permissions of the old role.
+	UpdateRole(ctx context.Context, userID string, role string) (User, error)
+	SetOfficer(ctx context.Context, userID string, officerID string) (User, error)
+	SetBranch(ctx context.Context, userID string, branchID string) (User, error)
+	// SetSuspended suspends or reactivates a user. Suspending also revokes
+	// every refresh token of the user.
+	SetSuspended(ctx context.Context, userID string, suspended bool, reason string) (User, error)
+	UpdateProfile(ctx context.Context, userID string, version int64, profile EditableProfile) (User, error)
 
 	RevokeRefreshToken(ctx context.Context, userID, refreshToken string) error
 	UpdateRefreshToken(ctx context.Context, userID string, refreshToken string) error
@@ -54,18 +122,40 @@ type UserRepository interface {
 
 	IsUserActive(ctx context.Context, userID string) (bool, error)
 	ActivateUser(ctx context.Context, userID string) error
+	UpdateVerifyToken(ctx context.Context, userID string, tokenHash string, sentAt time.Time) error
 
 	ResetUserPassword(ctx context.Context, userID string, resetPassword ResetPasswordRequest) error
 	UpdateUserPassword(ctx context.Context, userID string, updatePassword UpdatePassword) error
+
+	SetPendingEmail(ctx context.Context, userID string, email string, tokenHash string, expiresAt time.Time) error
+	GetByEmailChangeToken(ctx context.Context, tokenHash string) (User, error)
+	// ConfirmEmail switches to the pending email matching tokenHash and
+	// revokes every session of the user.
+	ConfirmEmail(ctx context.Context, userID string, tokenHash string) error
+	// RevokeAllRefreshTokens revokes every session of the user: its refresh
+	// tokens and, by moving TokenVersion on, its access tokens.
+	RevokeAllRefreshTokens(ctx context.Context, userID string) error
 }
 
 type UserUsecase interface {
-	GetAll(ctx context.Context) ([]User, error)
+	List(ctx context.Context, spec QuerySpec) (Page[User], error)
 	GetByID(ctx context.Context, userID string) (User, error)
 	GetByEmail(ctx context.Context, email string) (User, error)
 	Create(ctx context.Context, user User) (User, error)
-	Update(ctx context.Context, userID string, user UserUpdate) (User, error)
-	Delete(ctx context.Context, userID string) error
+	// Update, Delete, AssignRole, Suspend, Reactivate and Impersonate are
+	// administrative actions taken by actorID on the user userID. Update
+	// and UpdateProfile change the user at version, or at any version when
+	// it is 0.
+	Update(ctx context.Context, actorID string, userID string, version int64, request UpdateUserRequest) (User, error)
+	Delete(ctx context.Context, actorID string, userID string) error
+	AssignRole(ctx context.Context, actorID string, userID string, role string) (User, error)
+	Suspend(ctx context.Context, actorID string, userID string, reason string) (User, error)
+	Reactivate(ctx context.Context, actorID string, userID string) (User, error)
+	Impersonate(ctx context.Context, actorID string, userID string) (ImpersonationResponse, error)
 	ResetUserPassword(ctx context.Context, userID string, resetPassword ResetPasswordRequest) error
 	UpdateUserPassword(ctx context.Context, userID string, updatePassword UpdatePassword) error
+	UpdateProfile(ctx context.Context, userID string, actorID string, version int64, patch []byte) (User, error)
+	GetAuditTrail(ctx context.Context, userID string) ([]AuditEntry, error)
+	RequestEmailChange(ctx context.Context, userID string, request ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, token string) error
 }
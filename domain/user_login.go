@@ -20,4 +20,9 @@ type AuthUsecase interface {
 	CreateAccessToken(user User, secret string, expiry int) (accessToken string, err error)
 	CreateRefreshToken(user User, secret string, expiry int) (refreshToken string, err error)
 	UpdateRefreshToken(ctx context.Context, userID string, refreshToken string) error
+	RefreshTokenExist(ctx context.Context, userID string, refreshToken string) (bool, error)
+	RevokeRefreshToken(ctx context.Context, userID string, refreshToken string) error
+	// CheckSession returns an error unless an access token issued to userID
+	// at tokenVersion still authenticates and the user is not suspended.
+	CheckSession(ctx context.Context, userID string, tokenVersion int64) error
 }
@@ -9,7 +9,7 @@ import (
 type ResetPasswordRequest struct {
 	Email       string `json:"email" binding:"required"`
 	Code        string `json:"code" binding:"required"`
-	NewPassword string `json:"password" bson:"password" binding:"required,min=4,max=30"`
+	NewPassword string `json:"password" bson:"password" binding:"required"`
 }
 
 type ForgotPasswordRequest struct {
@@ -17,20 +17,25 @@ type ForgotPasswordRequest struct {
 }
 
 type UpdatePassword struct {
-	OldPassword string `json:"old_password" bson:"old_password" binding:"required,min=4,max=30"`
-	NewPassword string `json:"password" bson:"password" binding:"required,min=4,max=30"`
+	OldPassword string `json:"old_password" bson:"old_password" binding:"required"`
+	NewPassword string `json:"password" bson:"password" binding:"required"`
 }
 type OtpSave struct {
 	Email     string    `json:"email" binding:"required"`
 	Code      string    `json:"code" binding:"required"`
 	ExpiresAt time.Time `json:"expiresat" sql:"expiresat"`
+	Attempts  int       `json:"attempts" bson:"attempts"`
+	TenantID  string    `json:"-" bson:"tenant_id"`
 }
 type ResetPasswordUsecase interface {
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	// ResetPassword sets the new password and deletes the reset code of
+	// the user as one unit.
 	ResetPassword(ctx context.Context, userID string, resetPassword *ResetPasswordRequest) error
 	SaveOtp(ctx context.Context, otp *OtpSave) error
 	DeleteOtp(ctx context.Context, email string) error
 	GetOTPByEmail(ctx context.Context, email string) (*OtpSave, error)
+	RegisterInvalidOtp(ctx context.Context, email string) (exhausted bool, err error)
 }
 
 type ResetPasswordRepository interface {
@@ -39,8 +44,15 @@ type ResetPasswordRepository interface {
 	SaveOtp(c context.Context, otp *OtpSave) error
 	GetOTPByEmail(c context.Context, email string) (*OtpSave, error)
 	DeleteOtp(c context.Context, email string) error
+	IncrementOtpAttempts(c context.Context, email string) (int, error)
+	// DeleteOtps removes every reset code of email.
+	DeleteOtps(c context.Context, email string) error
 }
 
 const (
 	CollectionResetPassword = "resetPassword"
+
+	// MaxPasswordHistory is how many previous password hashes are kept per
+	// user for the password reuse check.
+	MaxPasswordHistory = 24
 )
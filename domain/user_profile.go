@@ -0,0 +1,37 @@
+package domain
+
+// extended profile needed for lending
+
+type Address struct {
+	Street     string `json:"street,omitempty" bson:"street,omitempty" binding:"max=120"`
+	City       string `json:"city,omitempty" bson:"city,omitempty" binding:"max=60"`
+	Region     string `json:"region,omitempty" bson:"region,omitempty" binding:"max=60"`
+	PostalCode string `json:"postal_code,omitempty" bson:"postal_code,omitempty" binding:"max=20"`
+	Country    string `json:"country,omitempty" bson:"country,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+}
+
+type Employment struct {
+	Status    string `json:"status,omitempty" bson:"status,omitempty" binding:"omitempty,oneof=employed self_employed unemployed retired student"`
+	Employer  string `json:"employer,omitempty" bson:"employer,omitempty" binding:"max=120"`
+	JobTitle  string `json:"job_title,omitempty" bson:"job_title,omitempty" binding:"max=80"`
+	StartDate string `json:"start_date,omitempty" bson:"start_date,omitempty" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type Profile struct {
+	Phone         string      `json:"phone,omitempty" bson:"phone,omitempty" binding:"omitempty,e164"`
+	Address       *Address    `json:"address,omitempty" bson:"address,omitempty"`
+	DateOfBirth   string      `json:"date_of_birth,omitempty" bson:"date_of_birth,omitempty" binding:"omitempty,datetime=2006-01-02"`
+	NationalID    string      `json:"national_id,omitempty" bson:"national_id,omitempty" binding:"omitempty,alphanum,min=5,max=20"`
+	Employment    *Employment `json:"employment,omitempty" bson:"employment,omitempty"`
+	MonthlyIncome *float64    `json:"monthly_income,omitempty" bson:"monthly_income,omitempty" binding:"omitempty,gte=0"`
+}
+
+// EditableProfile is the document a user edits through PATCH /users/profile.
+type EditableProfile struct {
+	FirstName string `json:"first_name" bson:"first_name" binding:"required,min=3,max=30"`
+	LastName  string `json:"last_name" bson:"last_name" binding:"max=30"`
+	Profile   `bson:"profile"`
+}
+
+// MinBorrowerAge is the minimum age, in years, of a user with a date of birth.
+const MinBorrowerAge = 18
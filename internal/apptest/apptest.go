@@ -0,0 +1,183 @@
+// Package apptest boots the whole application in-process over in-memory
+// repositories, for end-to-end tests of the HTTP API.
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/api/route"
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/memory"
+	"github.com/gin-gonic/gin"
+)
+
+// App is an application with its own repositories and outbox.
+type App struct {
+	Env    *bootstrap.Env
+	Repos  repository.Set
+	Outbox *emailutil.Outbox
+	Router *gin.Engine
+}
+
+// NewEnv returns the settings of a test application. Rate limits are high
+// enough not to get in the way.
+func NewEnv(t *testing.T) *bootstrap.Env {
+	return &bootstrap.Env{
+		AppEnv:                     "test",
+		ContextTimeout:             5,
+		AccessTokenExpiryHour:      1,
+		RefreshTokenExpiryHour:     24,
+		AccessTokenSecret:          "test-access-secret",
+		VerificationTokenExpiryMin: 60,
+		VerificationTokenSecret:    "test-verification-secret",
+		RefreshTokenSecret:         "test-refresh-secret",
+		PassResetCodeExpirationMin: 10,
+		VerificationResendCooldown: 60,
+		PublicBaseURL:              "http://app.test",
+		LoginMaxAttempts:           5,
+		LoginIPMaxAttempts:         20,
+		LoginLockoutBaseSec:        30,
+		LoginLockoutMaxMin:         60,
+		LoginAttemptWindowMin:      60,
+		UnlockTokenExpiryMin:       60,
+		OtpMaxAttempts:             5,
+		MagicLinkExpiryMin:         15,
+		EmailChangeExpiryMin:       60,
+		RateLimitAuthRequests:      10000,
+		RateLimitAuthWindowSec:     60,
+		RateLimitAPIRequests:       10000,
+		RateLimitAPIWindowSec:      60,
+		IdempotencyKeyTTLMin:       60,
+		PasswordMinLength:          8,
+		PasswordMaxLength:          72,
+		PasswordRequireUpper:       true,
+		PasswordRequireLower:       true,
+		PasswordRequireDigit:       true,
+		PasswordHistorySize:        5,
+		KYCStorageDir:              t.TempDir(),
+		KYCMaxUploadMB:             1,
+		ImpersonationExpiryMin:     15,
+		DefaultTenantSlug:          "default",
+		TenantCacheSec:             30,
+	}
+}
+
+// New boots an application with the settings of NewEnv. Emails go to the
+// outbox of the app.
+func New(t *testing.T) *App {
+	return NewWithEnv(t, NewEnv(t))
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// NewWithEnv boots an application with env.
+func NewWithEnv(t *testing.T, env *bootstrap.Env) *App {
+	app := &App{
+		Env:    env,
+		Repos:  memory.NewSet(),
+		Outbox: &emailutil.Outbox{},
+		Router: gin.New(),
+	}
+	timeout := time.Duration(env.ContextTimeout) * time.Second
+	route.Setup(env, timeout, app.Repos, app.Outbox, app.Router)
+	return app
+}
+
+// Request sends a request to the app. body, when not nil, is sent as JSON
+// and token, when not empty, as the bearer token.
+func (a *App) Request(t *testing.T, method string, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encoding the request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return a.Do(req)
+}
+
+// Do sends req to the app.
+func (a *App) Do(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	a.Router.ServeHTTP(rec, req)
+	return rec
+}
+
+// Decode decodes the JSON body of rec into v after checking its status.
+func Decode(t *testing.T, rec *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("expected status %d, got %d: %s", status, rec.Code, rec.Body.String())
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+}
+
+// LinkPath returns the path of the link to prefix in the latest email sent
+// to email, e.g. the verification link for "/users/verify-email/".
+func (a *App) LinkPath(t *testing.T, email string, prefix string) string {
+	t.Helper()
+	sent, ok := a.Outbox.Last(email)
+	if !ok {
+		t.Fatalf("no email was sent to %s", email)
+	}
+	link := regexp.MustCompile(regexp.QuoteMeta(a.Env.PublicBaseURL+prefix) + `[^"'\s<]+`).FindString(sent.Body)
+	if link == "" {
+		t.Fatalf("the email %q to %s has no %s link", sent.Subject, email, prefix)
+	}
+	return link[len(a.Env.PublicBaseURL):]
+}
+
+// Register signs up a user and verifies their email.
+func (a *App) Register(t *testing.T, firstName string, email string, password string) {
+	t.Helper()
+	form := map[string]string{
+		"first_name": firstName,
+		"last_name":  "Test",
+		"email":      email,
+		"password":   password,
+	}
+	Decode(t, a.Request(t, http.MethodPost, "/users/register", form, ""), http.StatusCreated, nil)
+	Decode(t, a.Request(t, http.MethodGet, a.LinkPath(t, email, "/users/verify-email/"), nil, ""), http.StatusOK, nil)
+}
+
+// Login signs a user in and returns their access token.
+func (a *App) Login(t *testing.T, email string, password string) string {
+	t.Helper()
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	body := map[string]string{"email": email, "password": password}
+	Decode(t, a.Request(t, http.MethodPost, "/users/login", body, ""), http.StatusOK, &response)
+	if response.AccessToken == "" {
+		t.Fatal("the login returned no access token")
+	}
+	return response.AccessToken
+}
@@ -0,0 +1,1070 @@
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/api/middleware"
+	"github.com/dagota12/Loan-Tracker/api/route"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/openapi"
+	"github.com/dagota12/Loan-Tracker/internal/security"
+	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/memory"
+	"github.com/dagota12/Loan-Tracker/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSignupLoginProfile(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+
+	// unverified or wrong credentials are rejected
+	rec := app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "abebe@example.com", "password": "Wr0ngSecret"}, "")
+	if rec.Code == http.StatusOK {
+		t.Fatal("a wrong password should be rejected")
+	}
+
+	token := app.Login(t, "abebe@example.com", "Sup3rSecret")
+
+	var profile struct {
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		Active    bool   `json:"active"`
+	}
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusOK, &profile)
+	if profile.Email != "abebe@example.com" || profile.FirstName != "Abebe" || !profile.Active {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+
+	if rec := app.Request(t, http.MethodGet, "/users/profile", nil, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
+
+func TestTenantsAreIsolated(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	now := time.Now()
+	_, err := app.Repos.Tenants.Create(context.Background(), domain.Tenant{Name: "Acme", Slug: "acme", Active: true, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("creating a tenant: %v", err)
+	}
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+
+	body := strings.NewReader(`{"email":"abebe@example.com","password":"Sup3rSecret"}`)
+	req := httptest.NewRequest(http.MethodPost, "/users/login", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(domain.TenantHeader, "acme")
+	if rec := app.Do(req); rec.Code == http.StatusOK {
+		t.Error("a user of the default tenant should not sign in to another tenant")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+	req.Header.Set(domain.TenantHeader, "unknown")
+	if rec := app.Do(req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
+
+func TestInactiveTenantTokens(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	admin := app.Login(t, "abebe@example.com", "Sup3rSecret")
+
+	now := time.Now()
+	acme, err := app.Repos.Tenants.Create(context.Background(), domain.Tenant{Name: "Acme", Slug: "acme", Active: true, CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("creating a tenant: %v", err)
+	}
+	user := domain.User{FirstName: "Almaz", Email: "almaz@example.com", Active: true, Role: domain.RoleBorrower, CreatedAt: now, UpdatedAt: now}
+	user, err = app.Repos.Users.Create(domain.WithTenant(context.Background(), acme.ID.Hex()), user)
+	if err != nil {
+		t.Fatalf("creating a user: %v", err)
+	}
+	token, err := tokenutil.CreateAccessToken(user, app.Env.AccessTokenSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusOK, nil)
+
+	Decode(t, app.Request(t, http.MethodPatch, "/admin/tenants/"+acme.ID.Hex(), map[string]bool{"active": false}, admin), http.StatusOK, nil)
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusForbidden, &p)
+	if p.Code != "tenant_inactive" {
+		t.Errorf("expected the token of a deactivated tenant to be refused, got %+v", p)
+	}
+}
+
+func TestLegacyOwnerBecomesSuperAdmin(t *testing.T) {
+	repos := memory.NewSet()
+	ctx := context.Background()
+
+	// an install from before tenants, whose records were moved into the
+	// primary tenant
+	primary, err := repos.Tenants.EnsurePrimary(ctx, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	owner := domain.User{FirstName: "Abebe", Email: "abebe@example.com", Active: true, IsOwner: true, Role: domain.RoleAdmin, CreatedAt: now, UpdatedAt: now}
+	owner, err = repos.Users.Create(domain.WithTenant(ctx, primary.ID.Hex()), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := usecase.EnsurePrimaryTenant(ctx, repos.Tenants, repos.Users, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant.OwnerEmail != owner.Email {
+		t.Errorf("expected the owner email %q, got %q", owner.Email, tenant.OwnerEmail)
+	}
+	promoted, err := repos.Users.GetByID(domain.WithTenant(ctx, primary.ID.Hex()), owner.ID.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if promoted.Role != domain.RoleSuperAdmin {
+		t.Errorf("expected the owner to be promoted to super admin, got %q", promoted.Role)
+	}
+}
+
+func TestProfileETag(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	token := app.Login(t, "abebe@example.com", "Sup3rSecret")
+
+	rec := app.Request(t, http.MethodGet, "/users/profile", nil, token)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected a tagged profile, got %d with ETag %q", rec.Code, etag)
+	}
+
+	patch := func(ifMatch string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/users/profile", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		return app.Do(req)
+	}
+
+	rec = patch(etag, `{"first_name":"Almaz"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("a patch at the current version should apply, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if next := rec.Header().Get("ETag"); next == "" || next == etag {
+		t.Errorf("the patch should return a new ETag, got %q after %q", next, etag)
+	}
+
+	// the first ETag is stale now
+	if rec := patch(etag, `{"first_name":"Stale"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a stale If-Match, got %d", rec.Code)
+	}
+	if rec := patch(`W/`+etag, `{"first_name":"Weak"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a weak If-Match, got %d", rec.Code)
+	}
+	if rec := patch("", `{"last_name":"Tesfaye"}`); rec.Code != http.StatusOK {
+		t.Errorf("a patch without If-Match should apply, got %d", rec.Code)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	token := app.Login(t, "abebe@example.com", "Sup3rSecret")
+
+	patch := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/users/profile", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(domain.IdempotencyHeader, key)
+		return app.Do(req)
+	}
+
+	first := patch("key-1", `{"first_name":"Almaz"}`)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected the first request to succeed, got %d: %s", first.Code, first.Body.String())
+	}
+
+	// a change in between must not leak into the replayed response
+	if rec := patch("key-2", `{"first_name":"Tigist"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected a request with another key to succeed, got %d", rec.Code)
+	}
+
+	retry := patch("key-1", `{"first_name":"Almaz"}`)
+	if retry.Code != http.StatusOK || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected a replayed response, got %d with headers %v", retry.Code, retry.Header())
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("the replay should match the first response:\n%s\n%s", first.Body.String(), retry.Body.String())
+	}
+	if etag := first.Header().Get("ETag"); etag == "" || retry.Header().Get("ETag") != etag {
+		t.Errorf("the replay should carry the ETag %q of the first response, got %q", etag, retry.Header().Get("ETag"))
+	}
+
+	if rec := patch("key-1", `{"first_name":"Other"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 when a key is reused for another request, got %d", rec.Code)
+	}
+
+	// a rejected request gives its key back, so that a fixed one can use it
+	if rec := patch("key-3", `{"first_name":"A"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid name to be rejected, got %d", rec.Code)
+	}
+	if rec := patch("key-3", `{"first_name":"Abeba"}`); rec.Code != http.StatusOK || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected the fixed request to be handled, got %d with headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	problem := func(rec *httptest.ResponseRecorder, status int) domain.Problem {
+		t.Helper()
+		if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, domain.ProblemContentType) {
+			t.Errorf("expected a problem, got content type %q", contentType)
+		}
+		var p domain.Problem
+		Decode(t, rec, status, &p)
+		if p.Status != status {
+			t.Errorf("the problem should repeat the status %d, got %d", status, p.Status)
+		}
+		return p
+	}
+
+	form := map[string]string{"first_name": "Ab", "last_name": "Test", "email": "not an email", "password": "Sup3rSecret"}
+	p := problem(app.Request(t, http.MethodPost, "/users/register", form, ""), http.StatusBadRequest)
+	if p.Code != "invalid_request" || p.Fields["first_name"] == "" || p.Fields["email"] == "" || len(p.Fields) != 2 {
+		t.Errorf("expected the invalid fields to be listed, got %+v", p)
+	}
+
+	// an unknown email is not told apart from a wrong password
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	unknown := problem(app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "nobody@example.com", "password": "Sup3rSecret"}, ""), http.StatusUnauthorized)
+	wrong := problem(app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "abebe@example.com", "password": "Wr0ngSecret"}, ""), http.StatusUnauthorized)
+	if unknown.Code != "invalid_credentials" || unknown.Detail != wrong.Detail || unknown.Code != wrong.Code {
+		t.Errorf("expected the same invalid_credentials problem, got %+v and %+v", unknown, wrong)
+	}
+
+	problem(app.Request(t, http.MethodGet, "/users/profile", nil, ""), http.StatusUnauthorized)
+
+	token := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	req := httptest.NewRequest(http.MethodPatch, "/users/profile", strings.NewReader(`{"first_name":"Almaz"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(domain.IdempotencyHeader, "key-1")
+	if p := problem(app.Do(req), http.StatusUnsupportedMediaType); p.Code != "unsupported_media_type" {
+		t.Errorf("expected unsupported_media_type, got %+v", p)
+	}
+
+	// a problem is not stored, so a retry of an idempotent request runs again
+	req.Body = io.NopCloser(strings.NewReader(`{"first_name":"Almaz"}`))
+	rec := app.Do(req)
+	if p := problem(rec, http.StatusUnsupportedMediaType); p.Code != "unsupported_media_type" || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected the problem to be answered again, got %+v", p)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	login := func(password string) int {
+		return app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "abebe@example.com", "password": password}, "").Code
+	}
+
+	// wrong reset codes lock password resets, not sign in
+	Decode(t, app.Request(t, http.MethodPost, "/users/forgot-password", map[string]string{"email": "abebe@example.com"}, ""), http.StatusOK, nil)
+	for i := 0; i < app.Env.LoginMaxAttempts; i++ {
+		app.Request(t, http.MethodPost, "/users/reset-password", map[string]string{"email": "abebe@example.com", "code": "000000", "password": "N3wSecret1"}, "")
+	}
+	if rec := app.Request(t, http.MethodPost, "/users/reset-password", map[string]string{"email": "abebe@example.com", "code": "000000", "password": "N3wSecret1"}, ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected password resets to be locked, got %d", rec.Code)
+	}
+	if code := login("Sup3rSecret"); code != http.StatusOK {
+		t.Fatalf("wrong reset codes should not lock sign in, got %d", code)
+	}
+
+	for i := 0; i < app.Env.LoginMaxAttempts; i++ {
+		login("Wr0ngSecret")
+	}
+	if code := login("Sup3rSecret"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected sign in to be locked, got %d", code)
+	}
+
+	unlock := app.LinkPath(t, "abebe@example.com", "/users/unlock/")
+	Decode(t, app.Request(t, http.MethodGet, unlock, nil, ""), http.StatusOK, nil)
+	if rec := app.Request(t, http.MethodGet, unlock, nil, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("an unlock link should work once, got %d", rec.Code)
+	}
+	if code := login("Sup3rSecret"); code != http.StatusOK {
+		t.Errorf("expected the account to be unlocked, got %d", code)
+	}
+}
+
+func TestAuthRateLimit(t *testing.T) {
+	env := NewEnv(t)
+	env.RateLimitAuthRequests = 2
+	app := NewWithEnv(t, env)
+
+	for i := 0; i < 2; i++ {
+		app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "abebe@example.com", "password": "Sup3rSecret"}, "")
+	}
+	if rec := app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "abebe@example.com", "password": "Sup3rSecret"}, ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected sign in to be throttled, got %d", rec.Code)
+	}
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	form := domain.SignupRequest{FirstName: "Bekele", LastName: "Test", Email: "bekele@example.com", Password: "Sup3rSecret"}
+	if rec := app.Request(t, http.MethodPost, "/users/register", form, ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected sign up to be throttled, got %d", rec.Code)
+	}
+
+	// the strict quota is only for the routes checking credentials
+	for i := 0; i < 3; i++ {
+		rec := app.Request(t, http.MethodPost, "/users/verify-email/resend", map[string]string{"email": "abebe@example.com"}, "")
+		if rec.Code == http.StatusTooManyRequests {
+			t.Fatalf("request %d to resend the verification email was throttled", i+1)
+		}
+	}
+}
+
+func TestForgotPasswordHidesAccounts(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	sent := len(app.Outbox.Emails())
+	known := app.Request(t, http.MethodPost, "/users/forgot-password", map[string]string{"email": "abebe@example.com"}, "")
+	if len(app.Outbox.Emails()) != sent+1 {
+		t.Error("expected the code sent to the account")
+	}
+	unknown := app.Request(t, http.MethodPost, "/users/forgot-password", map[string]string{"email": "nobody@example.com"}, "")
+	if len(app.Outbox.Emails()) != sent+1 {
+		t.Error("expected no email to an address without an account")
+	}
+	if known.Code != http.StatusOK || unknown.Code != http.StatusOK || known.Body.String() != unknown.Body.String() {
+		t.Errorf("expected the same answer for a known and an unknown email, got %d %s and %d %s", known.Code, known.Body.String(), unknown.Code, unknown.Body.String())
+	}
+}
+
+func TestEmailChangeRevokesSessions(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	token := app.Login(t, "abebe@example.com", "Sup3rSecret")
+
+	change := map[string]string{"email": "almaz@example.com", "password": "Sup3rSecret"}
+	if rec := app.Request(t, http.MethodPost, "/users/profile/email", change, token); rec.Code >= http.StatusBadRequest {
+		t.Fatalf("requesting the email change failed with %d: %s", rec.Code, rec.Body.String())
+	}
+	Decode(t, app.Request(t, http.MethodGet, app.LinkPath(t, "almaz@example.com", "/users/profile/email/confirm/"), nil, ""), http.StatusOK, nil)
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusUnauthorized, &p)
+	if p.Code != "session_revoked" {
+		t.Errorf("expected the access token to be revoked, got %+v", p)
+	}
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, "almaz@example.com", "Sup3rSecret")), http.StatusOK, nil)
+}
+
+func TestAdminRenameUser(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	owner := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	ids := map[string]string{}
+	for _, email := range []string{"almaz@example.com", "kebede@example.com", "tigist@example.com"} {
+		app.Register(t, "Member", email, "Sup3rSecret")
+		var user domain.User
+		Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, email, "Sup3rSecret")), http.StatusOK, &user)
+		ids[email] = user.ID.Hex()
+	}
+	for _, email := range []string{"almaz@example.com", "kebede@example.com"} {
+		Decode(t, app.Request(t, http.MethodPut, "/admin/users/"+ids[email]+"/role", domain.AssignRoleRequest{Role: domain.RoleAdmin}, owner), http.StatusOK, nil)
+	}
+	admin := app.Login(t, "almaz@example.com", "Sup3rSecret")
+	borrower := "/admin/users/" + ids["tigist@example.com"]
+
+	// the names left out keep their value
+	rec := app.Request(t, http.MethodPatch, borrower, map[string]string{"last_name": "Bekele"}, admin)
+	var user domain.User
+	Decode(t, rec, http.StatusOK, &user)
+	if user.FirstName != "Member" || user.LastName != "Bekele" {
+		t.Errorf("expected only the last name changed, got %q %q", user.FirstName, user.LastName)
+	}
+	etag := rec.Header().Get("ETag")
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodPatch, borrower, map[string]string{"first_name": ""}, admin), http.StatusBadRequest, &p)
+	if p.Fields["first_name"] == "" {
+		t.Errorf("expected an empty first name to be refused, got %+v", p)
+	}
+	Decode(t, app.Request(t, http.MethodPatch, "/admin/users/"+ids["kebede@example.com"], map[string]string{"first_name": "Renamed"}, admin), http.StatusForbidden, &p)
+	if p.Code != "owner_required" {
+		t.Errorf("only the owner should rename another admin, got %+v", p)
+	}
+	var owned domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, owner), http.StatusOK, &owned)
+	Decode(t, app.Request(t, http.MethodPatch, "/admin/users/"+owned.ID.Hex(), map[string]string{"first_name": "Renamed"}, admin), http.StatusForbidden, &p)
+	if p.Code != "owner_protected" {
+		t.Errorf("the owner should not be renamed, got %+v", p)
+	}
+
+	var trail []domain.AuditEntry
+	Decode(t, app.Request(t, http.MethodGet, borrower+"/audit", nil, admin), http.StatusOK, &trail)
+	if len(trail) == 0 || trail[0].Action != domain.AuditActionUserUpdate || len(trail[0].Changes) != 1 || trail[0].Changes[0].Field != "last_name" {
+		t.Errorf("expected the rename in the audit trail, got %+v", trail)
+	}
+
+	patch := func(ifMatch string, body string) int {
+		req := httptest.NewRequest(http.MethodPatch, borrower, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+admin)
+		req.Header.Set("If-Match", ifMatch)
+		return app.Do(req).Code
+	}
+	if code := patch(etag, `{"first_name":"Tigist"}`); code != http.StatusOK {
+		t.Fatalf("a rename at the current version should apply, got %d", code)
+	}
+	if code := patch(etag, `{"first_name":"Stale"}`); code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for a stale If-Match, got %d", code)
+	}
+}
+
+func TestDemotionRevokesTokens(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	owner := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	var user domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, "almaz@example.com", "Sup3rSecret")), http.StatusOK, &user)
+	role := "/admin/users/" + user.ID.Hex() + "/role"
+	Decode(t, app.Request(t, http.MethodPut, role, domain.AssignRoleRequest{Role: domain.RoleAdmin}, owner), http.StatusOK, nil)
+	admin := app.Login(t, "almaz@example.com", "Sup3rSecret")
+	Decode(t, app.Request(t, http.MethodGet, "/admin/users", nil, admin), http.StatusOK, nil)
+
+	// the token carries the permissions of the admin role
+	Decode(t, app.Request(t, http.MethodPut, role, domain.AssignRoleRequest{Role: domain.RoleBorrower}, owner), http.StatusOK, nil)
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodGet, "/admin/users", nil, admin), http.StatusUnauthorized, &p)
+	if p.Code != "session_revoked" {
+		t.Errorf("expected the token of the demoted admin to be revoked, got %+v", p)
+	}
+	Decode(t, app.Request(t, http.MethodGet, "/admin/users", nil, app.Login(t, "almaz@example.com", "Sup3rSecret")), http.StatusForbidden, nil)
+}
+
+func TestImpersonationIsAttributedToTheAdmin(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	owner := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	var admin domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, owner), http.StatusOK, &admin)
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	var user domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, "almaz@example.com", "Sup3rSecret")), http.StatusOK, &user)
+
+	var impersonation domain.ImpersonationResponse
+	Decode(t, app.Request(t, http.MethodPost, "/admin/users/"+user.ID.Hex()+"/impersonate", nil, owner), http.StatusOK, &impersonation)
+	claims, err := tokenutil.ExtractUserClaimsFromToken(impersonation.AccessToken, app.Env.AccessTokenSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, _ := claims["act"].(map[string]interface{})
+	if claims["id"] != user.ID.Hex() || act["sub"] != admin.ID.Hex() {
+		t.Fatalf("expected a token for Almaz naming Abebe as the actor, got %+v", claims)
+	}
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/profile", strings.NewReader(`{"first_name":"Almazi"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("Authorization", "Bearer "+impersonation.AccessToken)
+	Decode(t, app.Do(req), http.StatusOK, nil)
+	Decode(t, app.Request(t, http.MethodPost, "/users/update-password", domain.UpdatePassword{OldPassword: "Sup3rSecret", NewPassword: "N3wSecret!"}, impersonation.AccessToken), http.StatusForbidden, nil)
+
+	var trail []domain.AuditEntry
+	Decode(t, app.Request(t, http.MethodGet, "/admin/users/"+user.ID.Hex()+"/audit", nil, owner), http.StatusOK, &trail)
+	actors := make(map[string]string)
+	for _, entry := range trail {
+		actors[entry.Action] = entry.ActorID
+	}
+	if actors[domain.AuditActionUserImpersonate] != admin.ID.Hex() {
+		t.Errorf("expected the impersonation audited with Abebe as the actor, got %+v", trail)
+	}
+	if actors[domain.AuditActionProfileUpdate] != admin.ID.Hex() {
+		t.Errorf("expected the change made with the token audited with Abebe as the actor, got %+v", trail)
+	}
+}
+
+func TestSuspensionBlocksTokens(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	admin := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	token := app.Login(t, "almaz@example.com", "Sup3rSecret")
+	var user domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusOK, &user)
+
+	suspend := domain.SuspendUserRequest{Reason: "fraud review"}
+	Decode(t, app.Request(t, http.MethodPost, "/admin/users/"+user.ID.Hex()+"/suspend", suspend, admin), http.StatusOK, nil)
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusForbidden, &p)
+	if p.Code != "account_suspended" {
+		t.Errorf("expected the token of a suspended user to be refused, got %+v", p)
+	}
+
+	Decode(t, app.Request(t, http.MethodPost, "/admin/users/"+user.ID.Hex()+"/reactivate", nil, admin), http.StatusOK, nil)
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusOK, nil)
+}
+
+func TestEraseIsAuthorizedLikeDelete(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	owner := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	ids := map[string]string{}
+	for _, email := range []string{"almaz@example.com", "kebede@example.com"} {
+		app.Register(t, "Admin", email, "Sup3rSecret")
+		var user domain.User
+		Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, email, "Sup3rSecret")), http.StatusOK, &user)
+		ids[email] = user.ID.Hex()
+		Decode(t, app.Request(t, http.MethodPut, "/admin/users/"+ids[email]+"/role", domain.AssignRoleRequest{Role: domain.RoleAdmin}, owner), http.StatusOK, nil)
+	}
+	admin := app.Login(t, "almaz@example.com", "Sup3rSecret")
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodPost, "/admin/users/"+ids["kebede@example.com"]+"/erase", nil, admin), http.StatusForbidden, &p)
+	if p.Code != "owner_required" {
+		t.Errorf("only the owner should erase another admin, got %+v", p)
+	}
+	Decode(t, app.Request(t, http.MethodPost, "/admin/users/"+ids["kebede@example.com"]+"/erase", nil, owner), http.StatusOK, nil)
+}
+
+func TestEraseRemovesPersonalData(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	owner := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	token := app.Login(t, "almaz@example.com", "Sup3rSecret")
+	var user domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, token), http.StatusOK, &user)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("type", "passport")
+	form.WriteField("expires_at", time.Now().AddDate(2, 0, 0).Format("2006-01-02"))
+	file, _ := form.CreateFormFile("file", "passport.pdf")
+	file.Write([]byte("%PDF-1.4 passport of Almaz"))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/users/kyc/documents", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	Decode(t, app.Do(req), http.StatusCreated, nil)
+	Decode(t, app.Request(t, http.MethodPost, "/users/forgot-password", map[string]string{"email": "almaz@example.com"}, ""), http.StatusOK, nil)
+	documents := func() int {
+		count := 0
+		filepath.WalkDir(app.Env.KYCStorageDir, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				count++
+			}
+			return err
+		})
+		return count
+	}
+	if documents() != 1 {
+		t.Fatalf("expected the uploaded document stored, found %d files", documents())
+	}
+
+	Decode(t, app.Request(t, http.MethodPost, "/admin/users/"+user.ID.Hex()+"/erase", nil, owner), http.StatusOK, nil)
+
+	ctx := domain.WithTenant(context.Background(), user.TenantID)
+	if _, err := app.Repos.KYC.GetByUserID(ctx, user.ID.Hex()); !errors.Is(err, repository.ErrKYCNotFound) {
+		t.Errorf("expected the KYC record deleted, got %v", err)
+	}
+	if _, err := app.Repos.ResetPassword.GetOTPByEmail(ctx, "almaz@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("expected the reset code deleted, got %v", err)
+	}
+	if n := documents(); n != 0 {
+		t.Errorf("expected the documents deleted, found %d files", n)
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	var login domain.LoginResponse
+	Decode(t, app.Request(t, http.MethodPost, "/users/login", map[string]string{"email": "abebe@example.com", "password": "Sup3rSecret"}, ""), http.StatusOK, &login)
+
+	var refreshed domain.RefreshTokenResponse
+	Decode(t, app.Request(t, http.MethodPost, "/users/token/refresh", map[string]string{"refreshToken": login.RefreshToken}, ""), http.StatusOK, &refreshed)
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken {
+		t.Errorf("expected a new refresh token, got %q", refreshed.RefreshToken)
+	}
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodPost, "/users/token/refresh", map[string]string{"refreshToken": login.RefreshToken}, ""), http.StatusUnauthorized, &p)
+	if p.Code != "refresh_token_revoked" {
+		t.Errorf("a refresh token should be used once, got %+v", p)
+	}
+}
+
+func TestMagicLink(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	rec := app.Request(t, http.MethodPost, "/users/login/magic-link", map[string]string{"email": "abebe@example.com"}, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected the link to be sent, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var nonce string
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == domain.MagicLinkNonceCookie {
+			nonce = cookie.Value
+		}
+	}
+	if nonce == "" {
+		t.Fatal("expected the nonce cookie to be set")
+	}
+	path := app.LinkPath(t, "abebe@example.com", "/users/login/magic-link/")
+
+	callback := func(path string, nonce string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if nonce != "" {
+			req.AddCookie(&http.Cookie{Name: domain.MagicLinkNonceCookie, Value: nonce})
+		}
+		return app.Do(req)
+	}
+	rejected := func(rec *httptest.ResponseRecorder, why string) {
+		t.Helper()
+		var p domain.Problem
+		Decode(t, rec, http.StatusUnauthorized, &p)
+		if p.Code != "invalid_magic_link" {
+			t.Errorf("expected %s to be rejected as an invalid link, got %+v", why, p)
+		}
+	}
+
+	// another browser does not hold the nonce, which leaves the link usable
+	rejected(callback(path, ""), "a link without the nonce")
+	rejected(callback(path, "wrong"), "a link with a wrong nonce")
+
+	var tokens domain.LoginResponse
+	Decode(t, callback(path, nonce), http.StatusOK, &tokens)
+	var user domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, tokens.AccessToken), http.StatusOK, &user)
+	if user.Email != "abebe@example.com" {
+		t.Errorf("expected the link to sign Abebe in, got %+v", user)
+	}
+
+	rejected(callback(path, nonce), "a link used twice")
+
+	// a link stored as expired, with a token that is still valid
+	token, err := tokenutil.CreateMagicLinkToken(&user, app.Env.VerificationTokenSecret, app.Env.MagicLinkExpiryMin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	err = app.Repos.MagicLinks.Create(domain.WithTenant(context.Background(), user.TenantID), domain.MagicLink{
+		UserID:    user.ID.Hex(),
+		TokenHash: security.HashToken(token),
+		NonceHash: security.HashToken(nonce),
+		ExpiresAt: past,
+		CreatedAt: past.Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected(callback("/users/login/magic-link/"+token, nonce), "an expired link")
+}
+
+func TestDisbursementRequiresKYC(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	admin := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	var borrower domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, "almaz@example.com", "Sup3rSecret")), http.StatusOK, &borrower)
+	borrowerID := borrower.ID.Hex()
+
+	// looking the verification up does not start one
+	for i := 0; i < 2; i++ {
+		var p domain.Problem
+		Decode(t, app.Request(t, http.MethodGet, "/admin/users/"+borrowerID+"/kyc", nil, admin), http.StatusNotFound, &p)
+		if p.Code != "kyc_not_found" {
+			t.Errorf("expected kyc_not_found, got %+v", p)
+		}
+	}
+
+	var loan domain.Loan
+	request := domain.CreateLoanRequest{BorrowerID: borrowerID, Amount: 100000, TermMonths: 12}
+	Decode(t, app.Request(t, http.MethodPost, "/admin/loans", request, admin), http.StatusCreated, &loan)
+	path := "/admin/loans/" + loan.ID.Hex()
+	Decode(t, app.Request(t, http.MethodPost, path+"/approve", nil, admin), http.StatusOK, nil)
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodPost, path+"/disburse", nil, admin), http.StatusForbidden, &p)
+	if p.Code != "kyc_not_verified" {
+		t.Errorf("expected the disbursement to wait for the verification, got %+v", p)
+	}
+
+	tenant, err := app.Repos.Tenants.GetBySlug(context.Background(), app.Env.DefaultTenantSlug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := domain.WithTenant(context.Background(), tenant.ID.Hex())
+	now := time.Now()
+	if _, err := app.Repos.KYC.GetOrCreate(ctx, borrowerID); err != nil {
+		t.Fatal(err)
+	}
+	for _, transition := range []domain.KYCTransition{
+		{From: domain.KYCStatusNotStarted, To: domain.KYCStatusSubmitted, At: now},
+		{From: domain.KYCStatusSubmitted, To: domain.KYCStatusApproved, ReviewerID: "reviewer", At: now, ExpiresAt: now.AddDate(1, 0, 0)},
+	} {
+		if _, err := app.Repos.KYC.Transition(ctx, borrowerID, transition); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	Decode(t, app.Request(t, http.MethodPost, path+"/disburse", nil, admin), http.StatusOK, &loan)
+	if loan.Status != domain.LoanStatusDisbursed {
+		t.Errorf("expected the loan to be disbursed, got %+v", loan)
+	}
+	Decode(t, app.Request(t, http.MethodPost, path+"/disburse", nil, admin), http.StatusConflict, nil)
+}
+
+func TestLoanETag(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	admin := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	app.Register(t, "Almaz", "almaz@example.com", "Sup3rSecret")
+	var borrower domain.User
+	Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, "almaz@example.com", "Sup3rSecret")), http.StatusOK, &borrower)
+
+	var loan domain.Loan
+	Decode(t, app.Request(t, http.MethodPost, "/admin/loans", domain.CreateLoanRequest{BorrowerID: borrower.ID.Hex(), Amount: 10000, TermMonths: 12}, admin), http.StatusCreated, &loan)
+	path := "/admin/loans/" + loan.ID.Hex()
+	rec := app.Request(t, http.MethodGet, path, nil, admin)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag != `"1"` {
+		t.Fatalf("expected the loan tagged with version 1, got %d with ETag %q", rec.Code, etag)
+	}
+
+	post := func(action string, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path+"/"+action, nil)
+		req.Header.Set("Authorization", "Bearer "+admin)
+		req.Header.Set("If-Match", ifMatch)
+		return app.Do(req)
+	}
+
+	rec = post("approve", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("an approval at the current version should apply, got %d with ETag %q: %s", rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	// the first ETag is stale now
+	rec = post("disburse", etag)
+	if rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("expected the current ETag with the failed precondition, got %q", rec.Header().Get("ETag"))
+	}
+	var p domain.Problem
+	Decode(t, rec, http.StatusPreconditionFailed, &p)
+	if p.Code != "version_conflict" {
+		t.Errorf("expected a version conflict for a stale If-Match, got %+v", p)
+	}
+}
+
+func TestPortfolioRestrictsKYCAndAudit(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	admin := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	ids := make(map[string]string)
+	for _, name := range []string{"olana", "almaz", "bekele"} {
+		app.Register(t, name, name+"@example.com", "Sup3rSecret")
+		var user domain.User
+		Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, name+"@example.com", "Sup3rSecret")), http.StatusOK, &user)
+		ids[name] = user.ID.Hex()
+	}
+	Decode(t, app.Request(t, http.MethodPut, "/admin/users/"+ids["olana"]+"/role", domain.AssignRoleRequest{Role: domain.RoleLoanOfficer}, admin), http.StatusOK, nil)
+	Decode(t, app.Request(t, http.MethodPut, "/admin/users/"+ids["almaz"]+"/officer", domain.AssignOfficerRequest{OfficerID: ids["olana"]}, admin), http.StatusOK, nil)
+	officer := app.Login(t, "olana@example.com", "Sup3rSecret")
+
+	tenant, err := app.Repos.Tenants.GetBySlug(context.Background(), app.Env.DefaultTenantSlug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := domain.WithTenant(context.Background(), tenant.ID.Hex())
+	for _, name := range []string{"almaz", "bekele"} {
+		if _, err := app.Repos.KYC.GetOrCreate(ctx, ids[name]); err != nil {
+			t.Fatal(err)
+		}
+		submit := domain.KYCTransition{From: domain.KYCStatusNotStarted, To: domain.KYCStatusSubmitted, At: time.Now()}
+		if _, err := app.Repos.KYC.Transition(ctx, ids[name], submit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var records []domain.KYC
+	Decode(t, app.Request(t, http.MethodGet, "/admin/kyc", nil, officer), http.StatusOK, &records)
+	if len(records) != 1 || records[0].UserID != ids["almaz"] {
+		t.Errorf("expected the verifications of the portfolio only, got %+v", records)
+	}
+	Decode(t, app.Request(t, http.MethodGet, "/admin/users/"+ids["almaz"]+"/kyc", nil, officer), http.StatusOK, nil)
+	Decode(t, app.Request(t, http.MethodGet, "/admin/users/"+ids["almaz"]+"/audit", nil, officer), http.StatusOK, nil)
+
+	other := "/admin/users/" + ids["bekele"]
+	for _, rec := range []*httptest.ResponseRecorder{
+		app.Request(t, http.MethodGet, other+"/kyc", nil, officer),
+		app.Request(t, http.MethodGet, other+"/kyc/documents/any", nil, officer),
+		app.Request(t, http.MethodPost, other+"/kyc/review", domain.KYCReviewRequest{Decision: "approve"}, officer),
+		app.Request(t, http.MethodGet, other+"/audit", nil, officer),
+	} {
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a borrower outside the portfolio, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestOfficerPortfolio(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	admin := app.Login(t, "abebe@example.com", "Sup3rSecret")
+	ids := make(map[string]string)
+	for _, name := range []string{"olana", "almaz", "bekele"} {
+		app.Register(t, name, name+"@example.com", "Sup3rSecret")
+		var user domain.User
+		Decode(t, app.Request(t, http.MethodGet, "/users/profile", nil, app.Login(t, name+"@example.com", "Sup3rSecret")), http.StatusOK, &user)
+		ids[name] = user.ID.Hex()
+	}
+	Decode(t, app.Request(t, http.MethodPut, "/admin/users/"+ids["olana"]+"/role", domain.AssignRoleRequest{Role: domain.RoleLoanOfficer}, admin), http.StatusOK, nil)
+	Decode(t, app.Request(t, http.MethodPut, "/admin/users/"+ids["almaz"]+"/officer", domain.AssignOfficerRequest{OfficerID: ids["olana"]}, admin), http.StatusOK, nil)
+	officer := app.Login(t, "olana@example.com", "Sup3rSecret")
+
+	var pending domain.Loan
+	Decode(t, app.Request(t, http.MethodPost, "/admin/loans", domain.CreateLoanRequest{BorrowerID: ids["almaz"], Amount: 5000, TermMonths: 6}, officer), http.StatusCreated, &pending)
+	Decode(t, app.Request(t, http.MethodPost, "/admin/loans", domain.CreateLoanRequest{BorrowerID: ids["bekele"], Amount: 7000, TermMonths: 6}, admin), http.StatusCreated, nil)
+
+	// three instalments of 333, 333 and 334, the first two past
+	tenant, err := app.Repos.Tenants.GetBySlug(context.Background(), app.Env.DefaultTenantSlug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	disbursedAt := now.AddDate(0, -2, -1)
+	disbursed, err := app.Repos.Loans.Create(domain.WithTenant(context.Background(), tenant.ID.Hex()), domain.Loan{
+		BorrowerID:  ids["almaz"],
+		Amount:      1000,
+		TermMonths:  3,
+		Status:      domain.LoanStatusDisbursed,
+		DisbursedAt: disbursedAt,
+		CreatedAt:   now.Add(-time.Hour),
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var portfolio domain.Page[domain.PortfolioLoan]
+	Decode(t, app.Request(t, http.MethodGet, "/officers/me/portfolio", nil, officer), http.StatusOK, &portfolio)
+	if portfolio.Total != 2 || len(portfolio.Items) != 2 || portfolio.Items[0].ID != pending.ID || portfolio.Items[1].ID != disbursed.ID {
+		t.Fatalf("expected the loans of the portfolio newest first, got %+v", portfolio)
+	}
+	if owed := portfolio.Items[0]; owed.Outstanding != 0 || owed.Due != 0 || owed.Overdue != 0 || owed.NextDueAt != nil {
+		t.Errorf("expected nothing owed on a pending loan, got %+v", owed)
+	}
+	owed := portfolio.Items[1]
+	if owed.Outstanding != 1000 || owed.Overdue != 666 || owed.Due != 334 || owed.NextDueAt == nil || !owed.NextDueAt.Equal(disbursedAt.AddDate(0, 3, 0).Truncate(time.Millisecond)) {
+		t.Errorf("unexpected amounts owed on the disbursed loan: %+v", owed)
+	}
+
+	var loans domain.Page[domain.Loan]
+	Decode(t, app.Request(t, http.MethodGet, "/admin/loans", nil, officer), http.StatusOK, &loans)
+	if loans.Total != 2 {
+		t.Errorf("expected the officer to list the loans of the portfolio only, got %+v", loans)
+	}
+	Decode(t, app.Request(t, http.MethodGet, "/admin/loans?status=pending", nil, admin), http.StatusOK, &loans)
+	if loans.Total != 2 {
+		t.Errorf("expected the admin to list every pending loan, got %+v", loans)
+	}
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+	doc := route.OpenAPI()
+
+	registered := map[string]bool{}
+	for _, r := range app.Router.Routes() {
+		registered[r.Method+" "+r.Path] = true
+		if doc.Operation(r.Method, r.Path) == nil {
+			t.Errorf("%s %s has no entry in the OpenAPI document", r.Method, r.Path)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			route := strings.ToUpper(method) + " " + ginPath(path)
+			if !registered[route] {
+				t.Errorf("the OpenAPI document describes %s, which is not routed", route)
+			}
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+
+	rec := app.Request(t, http.MethodGet, "/openapi.json", nil, "")
+	var spec map[string]interface{}
+	Decode(t, rec, http.StatusOK, &spec)
+	if spec["openapi"] != openapi.Version {
+		t.Errorf("expected OpenAPI %s, got %v", openapi.Version, spec["openapi"])
+	}
+
+	// every reference points into the document
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				var target interface{} = spec
+				for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+					object, _ := target.(map[string]interface{})
+					target = object[part]
+				}
+				if target == nil {
+					t.Errorf("%s does not resolve", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+
+	rec = app.Request(t, http.MethodGet, "/docs", nil, "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Errorf("expected the docs page, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+// ginPath turns an OpenAPI path template back into a route path.
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	t.Parallel()
+	app := New(t)
+	app.Register(t, "Abebe", "abebe@example.com", "Sup3rSecret")
+	token := app.Login(t, "abebe@example.com", "Sup3rSecret")
+
+	var p domain.Problem
+	Decode(t, app.Request(t, http.MethodGet, "/admin/search?limit=ten", nil, token), http.StatusBadRequest, &p)
+	if p.Code != "invalid_request" || p.Fields["q"] != "is required" || p.Fields["limit"] != "must be an integer" {
+		t.Errorf("expected the invalid parameters to be listed, got %+v", p)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users/login", strings.NewReader(`{"email":"abebe@example.com","password":"Sup3rSecret"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	Decode(t, app.Do(req), http.StatusUnsupportedMediaType, &p)
+	if p.Code != "unsupported_media_type" {
+		t.Errorf("expected an undocumented content type to be rejected, got %+v", p)
+	}
+
+	p = domain.Problem{}
+	Decode(t, app.Request(t, http.MethodPost, "/users/login", map[string]interface{}{"email": "abebe@example.com", "password": 42}, ""), http.StatusBadRequest, &p)
+	if p.Fields["password"] != "must be a string" {
+		t.Errorf("expected the body to be checked against its schema, got %+v", p)
+	}
+}
+
+func TestOpenAPIResponseCheck(t *testing.T) {
+	doc := openapi.New(openapi.Info{Title: "test", Version: "1"})
+	doc.Add(http.MethodGet, "/greeting", &openapi.Operation{
+		OperationID: "greet",
+		Responses: openapi.Responses{"200": {
+			Description: "A greeting.",
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: openapi.Object(map[string]*openapi.Schema{"message": openapi.String()})}},
+		}},
+	})
+
+	router := gin.New()
+	router.Use(middleware.ErrorMiddleware(), middleware.OpenAPIMiddleware(doc, true))
+	var response interface{}
+	router.GET("/greeting", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, response)
+	})
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/greeting", nil))
+		return rec
+	}
+
+	response = gin.H{"message": "hello"}
+	if rec := get(); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "hello") {
+		t.Errorf("expected a documented response to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	response = gin.H{"message": "hello", "from": "handler"}
+	var p domain.Problem
+	Decode(t, get(), http.StatusInternalServerError, &p)
+	if p.Code != "invalid_response" || p.Fields["from"] != "is not documented" {
+		t.Errorf("expected the drift to be reported, got %+v", p)
+	}
+}
@@ -0,0 +1,152 @@
+package emailutil
+
+import (
+	"fmt"
+	"html"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+)
+
+// EmailChangeConfirmationTemplate generates an HTML email template asking to confirm a new email address.
+func EmailChangeConfirmationTemplate(url string, env *bootstrap.Env) string {
+	return fmt.Sprintf(
+		`<html>
+        <head>
+            <style>
+                body {
+                    font-family: Arial, sans-serif;
+                    background-color: #f4f4f4;
+                    color: #333333;
+                    margin: 0;
+                    padding: 0;
+                }
+                .container {
+                    width: 100%%;
+                    max-width: 600px;
+                    margin: 0 auto;
+                    background-color: #ffffff;
+                    padding: 20px;
+                    box-shadow: 0 0 10px rgba(0, 0, 0, 0.1);
+                }
+                .header {
+                    text-align: center;
+                    padding: 10px 0;
+                    background-color: #4CAF50;
+                    color: white;
+                }
+                .content {
+                    padding: 20px;
+                    text-align: center;
+                }
+                .content p {
+                    font-size: 16px;
+                    line-height: 1.5;
+                }
+                .content a {
+                    display: inline-block;
+                    margin-top: 20px;
+                    padding: 10px 20px;
+                    color: white;
+                    background-color: #4CAF50;
+                    text-decoration: none;
+                    border-radius: 5px;
+                }
+                .footer {
+                    text-align: center;
+                    padding: 10px 0;
+                    font-size: 12px;
+                    color: #999999;
+                }
+            </style>
+        </head>
+        <body>
+            <div class="container">
+                <div class="header">
+                    <h1>Confirm Your New Email</h1>
+                </div>
+                <div class="content">
+                    <p>Please click the button below to use this address for your account. The link is valid for %v minutes.</p>
+                    <a href='%v'>Confirm Email</a>
+                    <p>You will be signed out of all devices once the change is confirmed.</p>
+                </div>
+                <div class="footer">
+                    <p>&copy; 2024 Your Company. All rights reserved.</p>
+                </div>
+            </div>
+        </body>
+    </html>`,
+		env.EmailChangeExpiryMin,
+		url,
+	)
+}
+
+// EmailChangeNoticeTemplate generates an HTML email template warning the current address about a requested email change.
+func EmailChangeNoticeTemplate(newEmail string) string {
+	return fmt.Sprintf(
+		`<html>
+        <head>
+            <style>
+                body {
+                    font-family: Arial, sans-serif;
+                    background-color: #f4f4f4;
+                    color: #333333;
+                    margin: 0;
+                    padding: 0;
+                }
+                .container {
+                    width: 100%%;
+                    max-width: 600px;
+                    margin: 0 auto;
+                    background-color: #ffffff;
+                    padding: 20px;
+                    box-shadow: 0 0 10px rgba(0, 0, 0, 0.1);
+                }
+                .header {
+                    text-align: center;
+                    padding: 10px 0;
+                    background-color: #E53935;
+                    color: white;
+                }
+                .content {
+                    padding: 20px;
+                    text-align: center;
+                }
+                .content p {
+                    font-size: 16px;
+                    line-height: 1.5;
+                }
+                .content a {
+                    display: inline-block;
+                    margin-top: 20px;
+                    padding: 10px 20px;
+                    color: white;
+                    background-color: #4CAF50;
+                    text-decoration: none;
+                    border-radius: 5px;
+                }
+                .footer {
+                    text-align: center;
+                    padding: 10px 0;
+                    font-size: 12px;
+                    color: #999999;
+                }
+            </style>
+        </head>
+        <body>
+            <div class="container">
+                <div class="header">
+                    <h1>Email Change Requested</h1>
+                </div>
+                <div class="content">
+                    <p>Someone asked to change the email address of your account to %v.</p>
+                    <p>The change only happens once the new address is confirmed. If this was not you, reset your password right away.</p>
+                </div>
+                <div class="footer">
+                    <p>&copy; 2024 Your Company. All rights reserved.</p>
+                </div>
+            </div>
+        </body>
+    </html>`,
+		html.EscapeString(newEmail),
+	)
+}
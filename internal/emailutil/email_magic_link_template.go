@@ -0,0 +1,80 @@
+package emailutil
+
+import (
+	"fmt"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+)
+
+// MagicLinkEmailTemplate generates an HTML email template with a single use sign in link.
+func MagicLinkEmailTemplate(url string, env *bootstrap.Env) string {
+	return fmt.Sprintf(
+		`<html>
+        <head>
+            <style>
+                body {
+                    font-family: Arial, sans-serif;
+                    background-color: #f4f4f4;
+                    color: #333333;
+                    margin: 0;
+                    padding: 0;
+                }
+                .container {
+                    width: 100%%;
+                    max-width: 600px;
+                    margin: 0 auto;
+                    background-color: #ffffff;
+                    padding: 20px;
+                    box-shadow: 0 0 10px rgba(0, 0, 0, 0.1);
+                }
+                .header {
+                    text-align: center;
+                    padding: 10px 0;
+                    background-color: #4CAF50;
+                    color: white;
+                }
+                .content {
+                    padding: 20px;
+                    text-align: center;
+                }
+                .content p {
+                    font-size: 16px;
+                    line-height: 1.5;
+                }
+                .content a {
+                    display: inline-block;
+                    margin-top: 20px;
+                    padding: 10px 20px;
+                    color: white;
+                    background-color: #4CAF50;
+                    text-decoration: none;
+                    border-radius: 5px;
+                }
+                .footer {
+                    text-align: center;
+                    padding: 10px 0;
+                    font-size: 12px;
+                    color: #999999;
+                }
+            </style>
+        </head>
+        <body>
+            <div class="container">
+                <div class="header">
+                    <h1>Sign In to Your Account</h1>
+                </div>
+                <div class="content">
+                    <p>Click the button below to sign in. The link works once and is valid for %v minutes.</p>
+                    <a href='%v'>Sign In</a>
+                    <p>Open it in the same browser you requested it from. If you did not request it, you can ignore this email.</p>
+                </div>
+                <div class="footer">
+                    <p>&copy; 2024 Your Company. All rights reserved.</p>
+                </div>
+            </div>
+        </body>
+    </html>`,
+		env.MagicLinkExpiryMin,
+		url,
+	)
+}
@@ -2,53 +2,85 @@ package emailutil
 
 import (
 	"fmt"
-	"log"
 	"net/smtp"
 
 	"github.com/dagota12/Loan-Tracker/bootstrap"
 )
 
-func SendVerificationEmail(recipientEmail string, VerificationToken string, env *bootstrap.Env) error {
-	// Email configuration
-	from := env.SenderEmail
-	password := env.SenderPassword
-	smtpHost := env.SmtpHost
-	smtpPort := env.SmtpPort
+func SendVerificationEmail(mailer Mailer, recipientEmail string, VerificationToken string, env *bootstrap.Env) error {
+	url := fmt.Sprintf("%s/users/verify-email/%v", env.PublicBaseURL, VerificationToken)
+	return mailer.Send(recipientEmail, "Account Verification", Emailtemplate(url))
+}
 
-	subject := "Subject: Account Verification\n"
-	mime := "MIME-Version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	url := fmt.Sprintf("http://localhost:8080/verify-email/%v", VerificationToken)
-	body := Emailtemplate(url)
-	message := []byte(subject + mime + "\n" + body)
-	auth := smtp.PlainAuth("", from, password, smtpHost)
+func SendOtpVerificationEmail(mailer Mailer, recipientEmail string, otp string, env *bootstrap.Env) error {
+	return mailer.Send(recipientEmail, "Account Verification", OTPEmailTemplate(otp, env))
+}
 
-	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{recipientEmail}, message)
-	if err != nil {
-		log.Fatalf("Failed to send email: %v", err)
-	}
+// SendUnlockEmail tells the user their account was locked after repeated
+// failed sign-in attempts and links to the unlock endpoint.
+func SendUnlockEmail(mailer Mailer, recipientEmail string, unlockToken string, env *bootstrap.Env) error {
+	url := fmt.Sprintf("%s/users/unlock/%s", env.PublicBaseURL, unlockToken)
+	return mailer.Send(recipientEmail, "Account Locked", UnlockEmailTemplate(url, env))
+}
 
-	return nil
+// SendMagicLinkEmail sends a single use link that signs the user in.
+func SendMagicLinkEmail(mailer Mailer, recipientEmail string, magicLinkToken string, env *bootstrap.Env) error {
+	url := fmt.Sprintf("%s/users/login/magic-link/%s", env.PublicBaseURL, magicLinkToken)
+	return mailer.Send(recipientEmail, "Your Sign In Link", MagicLinkEmailTemplate(url, env))
+}
 
+// SendEmailChangeConfirmation asks the owner of a new email address to
+// confirm it before it replaces the current one.
+func SendEmailChangeConfirmation(mailer Mailer, recipientEmail string, token string, env *bootstrap.Env) error {
+	url := fmt.Sprintf("%s/users/profile/email/confirm/%s", env.PublicBaseURL, token)
+	return mailer.Send(recipientEmail, "Confirm Your New Email Address", EmailChangeConfirmationTemplate(url, env))
 }
 
-func SendOtpVerificationEmail(recipientEmail string, otp string, env *bootstrap.Env) error {
+// SendEmailChangeNotice warns the current address that a change to
+// newEmail was requested.
+func SendEmailChangeNotice(mailer Mailer, recipientEmail string, newEmail string, env *bootstrap.Env) error {
+	return mailer.Send(recipientEmail, "Email Change Requested", EmailChangeNoticeTemplate(newEmail))
+}
+
+// SendKYCExpiredEmail asks the user to verify their identity again after
+// their documents expired.
+func SendKYCExpiredEmail(mailer Mailer, recipientEmail string, env *bootstrap.Env) error {
+	url := fmt.Sprintf("%s/users/kyc", env.PublicBaseURL)
+	return mailer.Send(recipientEmail, "Please Verify Your Identity Again", KYCExpiredEmailTemplate(url))
+}
+
+// Mailer delivers an email, e.g. through SMTP or into an Outbox in tests.
+type Mailer interface {
+	Send(recipientEmail string, subject string, body string) error
+}
+
+// SMTP is a Mailer sending HTML emails through the SMTP server of the
+// environment.
+type SMTP struct {
+	Env *bootstrap.Env
+}
+
+func NewSMTP(env *bootstrap.Env) *SMTP {
+	return &SMTP{Env: env}
+}
+
+// Send implements Mailer.
+func (m *SMTP) Send(recipientEmail string, subject string, body string) error {
 	// Email configuration
-	from := env.SenderEmail
-	password := env.SenderPassword
-	smtpHost := env.SmtpHost
-	smtpPort := env.SmtpPort
+	from := m.Env.SenderEmail
+	password := m.Env.SenderPassword
+	smtpHost := m.Env.SmtpHost
+	smtpPort := m.Env.SmtpPort
 
-	subject := "Subject: Account Verification\n"
+	header := "Subject: " + subject + "\n"
 	mime := "MIME-Version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	body := OTPEmailTemplate(otp, env)
-	message := []byte(subject + mime + "\n" + body)
+	message := []byte(header + mime + "\n" + body)
 	auth := smtp.PlainAuth("", from, password, smtpHost)
 
 	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{recipientEmail}, message)
 	if err != nil {
-		log.Fatalf("Failed to send email: %v", err)
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
 	return nil
-
 }
@@ -0,0 +1,43 @@
+package emailutil
+
+import "sync"
+
+// Email is a message kept by an Outbox.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Outbox is a Mailer that keeps the emails instead of sending them.
+type Outbox struct {
+	mu     sync.Mutex
+	emails []Email
+}
+
+// Send implements Mailer.
+func (o *Outbox) Send(recipientEmail string, subject string, body string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.emails = append(o.emails, Email{To: recipientEmail, Subject: subject, Body: body})
+	return nil
+}
+
+// Emails returns every email sent so far, oldest first.
+func (o *Outbox) Emails() []Email {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Email(nil), o.emails...)
+}
+
+// Last returns the latest email sent to recipientEmail.
+func (o *Outbox) Last(recipientEmail string) (Email, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.emails) - 1; i >= 0; i-- {
+		if o.emails[i].To == recipientEmail {
+			return o.emails[i], true
+		}
+	}
+	return Email{}, false
+}
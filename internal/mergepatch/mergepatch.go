@@ -0,0 +1,49 @@
+// Package mergepatch applies JSON Merge Patch documents (RFC 7396).
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidPatch = errors.New("invalid merge patch document")
+
+// Apply returns doc with patch merged into it: members of patch replace
+// those of doc, objects are merged recursively and null removes a member.
+// A patch that is not an object replaces doc entirely.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	var docValue interface{}
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &docValue); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(merge(docValue, patchValue))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = merge(targetObject[key], value)
+	}
+	return targetObject
+}
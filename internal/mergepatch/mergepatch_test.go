@@ -0,0 +1,51 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// cases from RFC 7396, appendix A
+func TestApply(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("Apply(%s, %s) returned an error: %v", tt.doc, tt.patch, err)
+			continue
+		}
+
+		var gotValue, wantValue interface{}
+		json.Unmarshal(got, &gotValue)
+		json.Unmarshal([]byte(tt.want), &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("Apply(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApplyInvalidPatch(t *testing.T) {
+	if _, err := Apply([]byte(`{}`), []byte(`{"a":`)); err != ErrInvalidPatch {
+		t.Errorf("expected ErrInvalidPatch, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package openapi describes the HTTP API as an OpenAPI 3.1 document. The
+// schemas of request and response bodies are generated from the Go types
+// that encode and decode them, so the document cannot drift from them.
+package openapi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Version is the version of the OpenAPI specification documents follow.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document. Paths are keyed by their OpenAPI
+// template, e.g. /users/{id}.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower case HTTP methods of a path to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   Responses             `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+// Responses maps status codes to responses.
+type Responses map[string]*Response
+
+// SecurityRequirement maps the names of security schemes to their scopes.
+type SecurityRequirement map[string][]string
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Parameter locations.
+const (
+	InPath   = "path"
+	InQuery  = "query"
+	InHeader = "header"
+)
+
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Response is a response, or a reference to one of the components when Ref
+// is set.
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]*Header   `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// New returns a document without any paths.
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			Responses:       map[string]*Response{},
+			SecuritySchemes: map[string]*SecurityScheme{},
+		},
+	}
+}
+
+// Add documents the operation served for method on route, a path in the
+// syntax of the router (/users/:id). The path parameters of the route are
+// added to the operation unless it describes them itself.
+func (d *Document) Add(method string, route string, op *Operation) {
+	path := PathOf(route)
+	item, ok := d.Paths[path]
+	if !ok {
+		item = PathItem{}
+		d.Paths[path] = item
+	}
+	key := strings.ToLower(method)
+	if _, ok := item[key]; ok {
+		panic(fmt.Sprintf("openapi: %s %s is documented twice", method, route))
+	}
+
+	var params []Parameter
+	for _, name := range pathParams(route) {
+		if !hasParameter(op.Parameters, name, InPath) {
+			params = append(params, Parameter{Name: name, In: InPath, Required: true, Schema: String()})
+		}
+	}
+	op.Parameters = append(params, op.Parameters...)
+	item[key] = op
+}
+
+// Operation returns the operation documented for method on route, a path
+// in the syntax of the router, or nil.
+func (d *Document) Operation(method string, route string) *Operation {
+	return d.Paths[PathOf(route)][strings.ToLower(method)]
+}
+
+// Response returns the response of a component referenced by r, or r.
+func (d *Document) Response(r *Response) *Response {
+	if name, ok := strings.CutPrefix(r.Ref, "#/components/responses/"); ok {
+		return d.Components.Responses[name]
+	}
+	return r
+}
+
+// PathOf turns a path in the syntax of the router into an OpenAPI path
+// template: /users/:id becomes /users/{id}.
+func PathOf(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(route string) []string {
+	var names []string
+	for _, segment := range strings.Split(route, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+		}
+	}
+	return names
+}
+
+func hasParameter(params []Parameter, name string, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,404 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is a JSON Schema (draft 2020-12), or a reference to one of the
+// components when Ref is set.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Types are the JSON types a value may have. A single type is written as a
+// string, as most tools expect.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// Has reports whether a value may have the JSON type name.
+func (t Types) Has(name string) bool {
+	for _, n := range t {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func String() *Schema {
+	return &Schema{Type: Types{"string"}}
+}
+
+func Integer() *Schema {
+	return &Schema{Type: Types{"integer"}}
+}
+
+func Binary() *Schema {
+	return &Schema{Type: Types{"string"}, Format: "binary"}
+}
+
+// Object is an object schema with the given properties, all of them
+// required.
+func Object(properties map[string]*Schema) *Schema {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return &Schema{Type: Types{"object"}, Properties: properties, Required: required}
+}
+
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: Types{"array"}, Items: items}
+}
+
+// Nullable allows null in place of a value matching s.
+func Nullable(s *Schema) *Schema {
+	switch {
+	case allowsNull(s):
+		return s
+	case s.Ref != "":
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+	case len(s.AnyOf) > 0 && len(s.Type) == 0:
+		return &Schema{AnyOf: append(append([]*Schema{}, s.AnyOf...), &Schema{Type: Types{"null"}})}
+	}
+	nullable := cloneSchema(s)
+	nullable.Type = append(nullable.Type, "null")
+	return nullable
+}
+
+// allowsNull reports whether null matches s. A schema without a type or
+// alternatives matches anything.
+func allowsNull(s *Schema) bool {
+	if s.Ref != "" {
+		return false
+	}
+	if len(s.AnyOf) > 0 {
+		for _, alternative := range s.AnyOf {
+			if allowsNull(alternative) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(s.Type) == 0 || s.Type.Has("null")
+}
+
+// Resolve returns the component schema referenced by s, or s.
+func (d *Document) Resolve(s *Schema) *Schema {
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok {
+		return d.Components.Schemas[name]
+	}
+	return s
+}
+
+// SchemaOf returns the schema of the JSON encoding of values of the type of
+// v. Struct types are added to the components and referenced. Fields are
+// named like encoding/json names them, falling back to their form tag, and
+// their binding tags become validation keywords.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v), false)
+}
+
+// PatchOf returns the schema of a JSON Merge Patch (RFC 7396) of values of
+// the type of v: every field is optional and may be null, to remove it.
+func (d *Document) PatchOf(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v), true)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	rawType      = reflect.TypeOf(json.RawMessage{})
+)
+
+func (d *Document) schemaOf(t reflect.Type, patch bool) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case objectIDType:
+		return &Schema{Type: Types{"string"}, Pattern: "^[0-9a-f]{24}$"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return Nullable(d.schemaOf(t.Elem(), patch))
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, Format: "byte"}
+		}
+		// a nil slice is encoded as null
+		return Nullable(ArrayOf(d.schemaOf(t.Elem(), patch)))
+	case reflect.Array:
+		return ArrayOf(d.schemaOf(t.Elem(), patch))
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: d.schemaOf(t.Elem(), patch)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t, patch)
+		}
+		return d.component(t, patch)
+	}
+	return &Schema{}
+}
+
+// component adds the schema of the named struct type t to the components
+// and returns a reference to it.
+func (d *Document) component(t reflect.Type, patch bool) *Schema {
+	name := componentName(t)
+	if patch {
+		name += "Patch"
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := d.Components.Schemas[name]; ok {
+		return ref
+	}
+	// a placeholder ends the recursion of types that contain themselves
+	d.Components.Schemas[name] = &Schema{}
+	d.Components.Schemas[name] = d.structSchema(t, patch)
+	return ref
+}
+
+// componentName names a struct type after its Go name; an instance of a
+// generic type is named after its type arguments first, e.g. UserPage for
+// Page[User].
+func componentName(t reflect.Type) string {
+	name, args, ok := strings.Cut(t.Name(), "[")
+	if !ok {
+		return name
+	}
+	var prefix string
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		prefix += arg[strings.LastIndex(arg, ".")+1:]
+	}
+	return prefix + name
+}
+
+func (d *Document) structSchema(t reflect.Type, patch bool) *Schema {
+	s := &Schema{Type: Types{"object"}, Properties: map[string]*Schema{}}
+	d.addFields(s, t, patch)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (d *Document) addFields(s *Schema, t reflect.Type, patch bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(s, embedded, patch)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema, required := d.fieldSchema(field, patch)
+		if patch {
+			fieldSchema = Nullable(fieldSchema)
+		} else if required {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fieldSchema
+	}
+}
+
+// fieldName returns the name of a field in JSON, which is empty for an
+// embedded struct whose fields are inlined. It reports false for fields
+// that are never encoded.
+func fieldName(field reflect.StructField) (string, bool) {
+	for _, tag := range []string{"json", "form"} {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		name := strings.SplitN(value, ",", 2)[0]
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+	if field.Anonymous {
+		return "", true
+	}
+	return field.Name, true
+}
+
+// fieldSchema returns the schema of a field with its binding rules applied,
+// and whether the rules require the field.
+func (d *Document) fieldSchema(field reflect.StructField, patch bool) (*Schema, bool) {
+	t := field.Type
+	nullable := t.Kind() == reflect.Pointer
+	if nullable {
+		t = t.Elem()
+	}
+	s := d.schemaOf(t, patch)
+	if s.Ref != "" {
+		if nullable {
+			s = Nullable(s)
+		}
+		return s, false
+	}
+	s = cloneSchema(s)
+
+	var required, omitEmpty bool
+rules:
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// the rest applies to the elements
+			break rules
+		case "required":
+			required = true
+		case "omitempty":
+			omitEmpty = true
+		default:
+			applyRule(s, name, param)
+		}
+	}
+	// an empty value skips the rules that follow omitempty
+	if omitEmpty && s.Type.Has("string") && constrained(s) {
+		s = &Schema{AnyOf: []*Schema{{Type: Types{"string"}, MaxLength: intPtr(0)}, s}}
+	}
+	if nullable {
+		s = Nullable(s)
+	}
+	return s, required
+}
+
+// applyRule adds the validation keyword matching a binding rule, if there
+// is one.
+func applyRule(s *Schema, name string, param string) {
+	isString := s.Type.Has("string")
+	isArray := s.Type.Has("array")
+	switch name {
+	case "min", "gte":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		switch {
+		case isString:
+			s.MinLength = intPtr(int(n))
+		case isArray:
+			s.MinItems = intPtr(int(n))
+		default:
+			s.Minimum = &n
+		}
+	case "max", "lte":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		switch {
+		case isString:
+			s.MaxLength = intPtr(int(n))
+		case isArray:
+			s.MaxItems = intPtr(int(n))
+		default:
+			s.Maximum = &n
+		}
+	case "len":
+		n, err := strconv.Atoi(param)
+		if err == nil && isString {
+			s.MinLength, s.MaxLength = intPtr(n), intPtr(n)
+		}
+	case "oneof":
+		s.Enum = strings.Fields(param)
+	case "email":
+		s.Format = "email"
+	case "hostname_rfc1123":
+		s.Format = "hostname"
+	case "datetime":
+		if param == "2006-01-02" {
+			s.Format = "date"
+		}
+	case "alphanum":
+		s.Pattern = "^[a-zA-Z0-9]+$"
+	case "e164":
+		s.Pattern = `^\+[1-9][0-9]{1,14}$`
+	case "iso3166_1_alpha2":
+		s.Pattern = "^[A-Z]{2}$"
+	}
+}
+
+// constrained reports whether s rejects some strings.
+func constrained(s *Schema) bool {
+	return s.Format != "" || s.Pattern != "" || len(s.Enum) > 0 || s.MinLength != nil
+}
+
+func cloneSchema(s *Schema) *Schema {
+	clone := *s
+	clone.Type = append(Types{}, s.Type...)
+	return &clone
+}
+
+func intPtr(n int) *int {
+	return &n
+}
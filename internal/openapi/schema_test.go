@@ -0,0 +1,139 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City    string `json:"city,omitempty" binding:"max=60"`
+	Country string `json:"country,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+}
+
+type Embedded struct {
+	Phone string `json:"phone,omitempty" binding:"omitempty,e164"`
+}
+
+type Person struct {
+	Name     string    `json:"name" binding:"required,min=3,max=30"`
+	Email    string    `json:"email" binding:"required,email"`
+	Role     string    `json:"role" binding:"omitempty,oneof=admin user"`
+	Secret   string    `json:"-"`
+	Age      *int      `json:"age,omitempty" binding:"omitempty,gte=0"`
+	Address  *address  `json:"address,omitempty"`
+	Tags     []string  `json:"tags"`
+	JoinedAt time.Time `json:"joined_at"`
+	Embedded
+}
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+
+	ref := doc.SchemaOf(Person{})
+	if ref.Ref != "#/components/schemas/Person" {
+		t.Fatalf("expected a reference to Person, got %+v", ref)
+	}
+	person := doc.Resolve(ref)
+
+	if !reflect.DeepEqual(person.Required, []string{"email", "name"}) {
+		t.Errorf("got required %v", person.Required)
+	}
+	if _, ok := person.Properties["Secret"]; ok {
+		t.Error("a field tagged json:\"-\" should not be described")
+	}
+	if _, ok := person.Properties["phone"]; !ok {
+		t.Error("the fields of an embedded struct should be inlined")
+	}
+
+	name := person.Properties["name"]
+	if *name.MinLength != 3 || *name.MaxLength != 30 {
+		t.Errorf("got name %+v", name)
+	}
+	if person.Properties["email"].Format != "email" {
+		t.Errorf("got email %+v", person.Properties["email"])
+	}
+	if age := person.Properties["age"]; !reflect.DeepEqual(age.Type, Types{"integer", "null"}) || *age.Minimum != 0 {
+		t.Errorf("got age %+v", age)
+	}
+	if joined := person.Properties["joined_at"]; joined.Format != "date-time" {
+		t.Errorf("got joined_at %+v", joined)
+	}
+	if tags := person.Properties["tags"]; !reflect.DeepEqual(tags.Type, Types{"array", "null"}) {
+		t.Errorf("a slice may be null, got %+v", tags)
+	}
+
+	// omitempty lets the empty string skip the other rules
+	role := person.Properties["role"]
+	if len(role.AnyOf) != 2 || *role.AnyOf[0].MaxLength != 0 || !reflect.DeepEqual(role.AnyOf[1].Enum, []string{"admin", "user"}) {
+		t.Errorf("got role %+v", role)
+	}
+
+	address := person.Properties["address"]
+	if len(address.AnyOf) != 2 || address.AnyOf[0].Ref != "#/components/schemas/address" {
+		t.Errorf("a pointer to a struct should reference it or be null, got %+v", address)
+	}
+	if city := doc.Components.Schemas["address"].Properties["city"]; *city.MaxLength != 60 {
+		t.Errorf("got city %+v", city)
+	}
+}
+
+func TestPatchOf(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+
+	patch := doc.Resolve(doc.PatchOf(Person{}))
+	if len(patch.Required) != 0 {
+		t.Errorf("a patch should not require fields, got %v", patch.Required)
+	}
+	if name := patch.Properties["name"]; !name.Type.Has("null") {
+		t.Errorf("a patch should be able to remove a field, got %+v", name)
+	}
+	if _, ok := doc.Components.Schemas["addressPatch"]; !ok {
+		t.Error("nested structs should be described as patches too")
+	}
+}
+
+func TestGenericComponentName(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+
+	if ref := doc.SchemaOf(Page[Person]{}); ref.Ref != "#/components/schemas/PersonPage" {
+		t.Errorf("got %q", ref.Ref)
+	}
+}
+
+func TestTypesJSON(t *testing.T) {
+	for _, types := range []Types{{"string"}, {"string", "null"}} {
+		data, err := json.Marshal(types)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Types
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, types) {
+			t.Errorf("%s decoded to %v", data, decoded)
+		}
+	}
+	if data, _ := json.Marshal(Types{"string"}); string(data) != `"string"` {
+		t.Errorf("a single type should be a string, got %s", data)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("GET", "/users/:id/documents/:document", &Operation{OperationID: "get", Responses: Responses{}})
+
+	op := doc.Operation("GET", "/users/:id/documents/:document")
+	if op == nil || doc.Paths["/users/{id}/documents/{document}"]["get"] != op {
+		t.Fatalf("the operation should be found by its route, got paths %v", doc.Paths)
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Name != "id" || op.Parameters[1].Name != "document" || !op.Parameters[0].Required {
+		t.Errorf("got parameters %+v", op.Parameters)
+	}
+}
@@ -0,0 +1,311 @@
+package openapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Violations maps the paths of the values that do not match a schema to
+// what is wrong with them. A property is named after its object joined by a
+// dot, an item after its array with its index in brackets, and the value
+// validated itself has the empty path.
+type Violations map[string]string
+
+// Validate checks value, as decoded by encoding/json, against s. Objects
+// may have properties their schema does not describe.
+func (d *Document) Validate(s *Schema, value interface{}) Violations {
+	v := validator{doc: d, violations: Violations{}}
+	v.validate(s, value, "")
+	return v.violations
+}
+
+// ValidateStrict checks value like Validate, except that the properties of
+// an object have to be described by its schema, as they are in the values
+// the API writes.
+func (d *Document) ValidateStrict(s *Schema, value interface{}) Violations {
+	v := validator{doc: d, strict: true, violations: Violations{}}
+	v.validate(s, value, "")
+	return v.violations
+}
+
+// ParameterValue decodes the raw value of a parameter to the JSON value s
+// expects: a number or a boolean when s allows one and raw is one. Any other
+// value is a string.
+func (d *Document) ParameterValue(s *Schema, raw string) interface{} {
+	s = d.Resolve(s)
+	if s == nil {
+		return raw
+	}
+	if (s.Type.Has("integer") || s.Type.Has("number")) && isNumber(raw) {
+		return json.Number(raw)
+	}
+	if s.Type.Has("boolean") {
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+func isNumber(raw string) bool {
+	_, err := strconv.ParseFloat(raw, 64)
+	return err == nil && json.Valid([]byte(raw))
+}
+
+type validator struct {
+	doc        *Document
+	strict     bool
+	violations Violations
+}
+
+func (v *validator) add(path string, format string, args ...interface{}) {
+	// the first violation of a value is the one worth fixing first
+	if _, ok := v.violations[path]; !ok {
+		v.violations[path] = fmt.Sprintf(format, args...)
+	}
+}
+
+func (v *validator) validate(s *Schema, value interface{}, path string) {
+	s = v.doc.Resolve(s)
+	if s == nil {
+		return
+	}
+	if !v.allowsType(s, value) {
+		v.add(path, "must be %s", describeTypes(s.Type))
+		return
+	}
+	if len(s.AnyOf) > 0 {
+		v.anyOf(s.AnyOf, value, path)
+	}
+
+	switch value := value.(type) {
+	case string:
+		v.validateString(s, value, path)
+	case json.Number, float64:
+		v.validateNumber(s, toFloat(value), path)
+	case []interface{}:
+		if s.MinItems != nil && len(value) < *s.MinItems {
+			v.add(path, "must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(value) > *s.MaxItems {
+			v.add(path, "must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range value {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case map[string]interface{}:
+		v.validateObject(s, value, path)
+	}
+}
+
+// anyOf reports the violations of the alternative value comes closest to
+// when it matches none of them: one of its type, with the fewest
+// violations, and of those the last, as alternatives go from the most
+// lenient to the most constrained.
+func (v *validator) anyOf(alternatives []*Schema, value interface{}, path string) {
+	var closest Violations
+	closestOfType := false
+	for _, alternative := range alternatives {
+		attempt := validator{doc: v.doc, strict: v.strict, violations: Violations{}}
+		attempt.validate(alternative, value, path)
+		if len(attempt.violations) == 0 {
+			return
+		}
+		ofType := v.allowsType(alternative, value)
+		if closest == nil || ofType && !closestOfType || ofType == closestOfType && len(attempt.violations) <= len(closest) {
+			closest, closestOfType = attempt.violations, ofType
+		}
+	}
+	for p, message := range closest {
+		v.add(p, "%s", message)
+	}
+}
+
+// allowsType reports whether s allows the JSON type of value, ignoring
+// its alternatives.
+func (v *validator) allowsType(s *Schema, value interface{}) bool {
+	s = v.doc.Resolve(s)
+	if s == nil || len(s.Type) == 0 {
+		return true
+	}
+	t := typeOf(value)
+	return s.Type.Has(t) || t == "integer" && s.Type.Has("number")
+}
+
+func (v *validator) validateString(s *Schema, value string, path string) {
+	if len(s.Enum) > 0 && !contains(s.Enum, value) {
+		v.add(path, "must be one of: %s", strings.Join(s.Enum, " "))
+	}
+	length := utf8.RuneCountInString(value)
+	if s.MinLength != nil && length < *s.MinLength {
+		v.add(path, "must be at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		v.add(path, "must be at most %d characters", *s.MaxLength)
+	}
+	if s.Pattern != "" {
+		if re := compilePattern(s.Pattern); re != nil && !re.MatchString(value) {
+			v.add(path, "must match %s", s.Pattern)
+		}
+	}
+	if message, ok := checkFormat(s.Format, value); !ok {
+		v.add(path, "%s", message)
+	}
+}
+
+func (v *validator) validateNumber(s *Schema, value float64, path string) {
+	if s.Minimum != nil && value < *s.Minimum {
+		v.add(path, "must be greater than or equal to %s", formatFloat(*s.Minimum))
+	}
+	if s.Maximum != nil && value > *s.Maximum {
+		v.add(path, "must be less than or equal to %s", formatFloat(*s.Maximum))
+	}
+}
+
+func (v *validator) validateObject(s *Schema, value map[string]interface{}, path string) {
+	for _, name := range s.Required {
+		if _, ok := value[name]; !ok {
+			v.add(join(path, name), "is required")
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, described := s.Properties[name]
+		switch {
+		case described:
+			v.validate(property, value[name], join(path, name))
+		case s.AdditionalProperties != nil:
+			v.validate(s.AdditionalProperties, value[name], join(path, name))
+		case v.strict && len(s.Properties) > 0:
+			v.add(join(path, name), "is not documented")
+		}
+	}
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// typeOf returns the JSON type of a value decoded by encoding/json. A
+// number without a fraction is an integer.
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, float64:
+		if f := toFloat(value); f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func toFloat(value interface{}) float64 {
+	switch value := value.(type) {
+	case json.Number:
+		f, _ := value.Float64()
+		return f
+	case float64:
+		return value
+	}
+	return math.NaN()
+}
+
+func describeTypes(types Types) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "null":
+			names[i] = "null"
+		case "integer", "object", "array":
+			names[i] = "an " + t
+		default:
+			names[i] = "a " + t
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// checkFormat checks the formats the document uses; any other format
+// accepts every string.
+func checkFormat(format string, value string) (string, bool) {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return "must be an email address", err == nil && address.Address == value
+	case "date":
+		_, err := time.Parse(time.DateOnly, value)
+		return "must be a date in YYYY-MM-DD format", err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return "must be an RFC 3339 date and time", err == nil
+	case "hostname":
+		return "must be a hostname", len(value) <= 253 && hostnamePattern.MatchString(value)
+	case "byte":
+		_, err := base64.StdEncoding.DecodeString(value)
+		return "must be base64 encoded", err == nil
+	}
+	return "", true
+}
+
+var hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+
+// patterns caches the compiled patterns of the document, which are checked
+// on every request.
+var patterns sync.Map
+
+// compilePattern returns the compiled pattern, or nil when Go cannot
+// compile it.
+func compilePattern(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	patterns.Store(pattern, re)
+	return re
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestValidate(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	person := doc.SchemaOf(Person{})
+
+	valid := `{"name":"Abebe","email":"abebe@example.com","role":"","tags":null,"joined_at":"2024-01-02T03:04:05Z","address":{"country":"ET"},"unknown":1}`
+	if violations := doc.Validate(person, decode(t, valid)); len(violations) != 0 {
+		t.Errorf("expected no violations, got %v", violations)
+	}
+
+	invalid := `{"name":"Ab","email":"not an email","role":"guest","age":1.5,"tags":["a",2],"joined_at":"yesterday","address":{"country":"et"}}`
+	want := Violations{
+		"name":            "must be at least 3 characters",
+		"email":           "must be an email address",
+		"role":            "must be one of: admin user",
+		"age":             "must be an integer or null",
+		"tags[1]":         "must be a string",
+		"joined_at":       "must be an RFC 3339 date and time",
+		"address.country": "must match ^[A-Z]{2}$",
+	}
+	if violations := doc.Validate(person, decode(t, invalid)); !reflect.DeepEqual(violations, want) {
+		t.Errorf("got violations %v", violations)
+	}
+
+	if violations := doc.Validate(person, decode(t, `{}`)); violations["name"] != "is required" || violations["email"] != "is required" {
+		t.Errorf("expected the required fields to be reported, got %v", violations)
+	}
+	if violations := doc.Validate(person, decode(t, `[]`)); violations[""] != "must be an object" {
+		t.Errorf("expected the value itself to be reported, got %v", violations)
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	person := doc.SchemaOf(Person{})
+
+	value := decode(t, `{"name":"Abebe","email":"abebe@example.com","nickname":"Abe","address":{"zip":"1000"}}`)
+	if violations := doc.ValidateStrict(person, value); violations["nickname"] != "is not documented" || violations["address.zip"] != "is not documented" {
+		t.Errorf("expected the undocumented properties to be reported, got %v", violations)
+	}
+	if violations := doc.Validate(person, value); len(violations) != 0 {
+		t.Errorf("undocumented properties are allowed unless strict, got %v", violations)
+	}
+}
+
+func TestParameterValue(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	limit := &Schema{Type: Types{"integer"}, Minimum: floatPtr(1)}
+
+	if violations := doc.Validate(limit, doc.ParameterValue(limit, "10")); len(violations) != 0 {
+		t.Errorf("expected 10 to be a valid integer, got %v", violations)
+	}
+	for raw, message := range map[string]string{"0": "must be greater than or equal to 1", "ten": "must be an integer", "1e": "must be an integer"} {
+		if violations := doc.Validate(limit, doc.ParameterValue(limit, raw)); violations[""] != message {
+			t.Errorf("%q: got violations %v", raw, violations)
+		}
+	}
+	if value := doc.ParameterValue(String(), "10"); value != "10" {
+		t.Errorf("a string parameter should stay a string, got %#v", value)
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
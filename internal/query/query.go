@@ -0,0 +1,188 @@
+// Package query reads list queries from request parameters and encodes the
+// cursors that address their pages.
+//
+// A query string looks like
+//
+//	?role=admin,loan_officer&created_at[gte]=2024-01-01&email[prefix]=jo&sort=-created_at&limit=20&cursor=...
+//
+// where a bare field is an equality (or, with a comma separated list, a
+// membership) test, and field[op] applies any other operator.
+package query
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrInvalidQuery  = domain.Validation("invalid_query", "invalid query")
+	ErrInvalidCursor = domain.Validation("invalid_cursor", "invalid cursor")
+)
+
+// parameters that are not filters
+const (
+	paramSort   = "sort"
+	paramLimit  = "limit"
+	paramCursor = "cursor"
+)
+
+// Parse builds the query spec described by values, rejecting fields and
+// operators that schema does not allow.
+func Parse(values url.Values, schema domain.QuerySchema) (domain.QuerySpec, error) {
+	spec := domain.QuerySpec{
+		SortBy:   schema.DefaultSort,
+		SortDesc: schema.DefaultDesc,
+		Limit:    domain.DefaultPageLimit,
+		Cursor:   values.Get(paramCursor),
+	}
+
+	for key, vals := range values {
+		switch key {
+		case paramCursor:
+			continue
+		case paramSort:
+			err := parseSort(&spec, vals[0], schema)
+			if err != nil {
+				return domain.QuerySpec{}, err
+			}
+			continue
+		case paramLimit:
+			limit, err := strconv.Atoi(vals[0])
+			if err != nil || limit < 1 || limit > domain.MaxPageLimit {
+				return domain.QuerySpec{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, domain.MaxPageLimit)
+			}
+			spec.Limit = limit
+			continue
+		}
+
+		condition, err := parseCondition(key, vals[0], schema)
+		if err != nil {
+			return domain.QuerySpec{}, err
+		}
+		spec.Conditions = append(spec.Conditions, condition)
+	}
+	return spec, nil
+}
+
+func parseSort(spec *domain.QuerySpec, sort string, schema domain.QuerySchema) error {
+	desc := strings.HasPrefix(sort, "-")
+	field := strings.TrimPrefix(sort, "-")
+	if def, ok := schema.Fields[field]; !ok || !def.Sortable {
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidQuery, field)
+	}
+	spec.SortBy = field
+	spec.SortDesc = desc
+	return nil
+}
+
+func parseCondition(key string, raw string, schema domain.QuerySchema) (domain.Condition, error) {
+	field, op := key, domain.OpEq
+	if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+		field, op = key[:i], domain.Operator(key[i+1:len(key)-1])
+	}
+	if op == domain.OpEq && strings.Contains(raw, ",") {
+		op = domain.OpIn
+	}
+
+	def, ok := schema.Fields[field]
+	if !ok {
+		return domain.Condition{}, fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, field)
+	}
+	if !allows(def, op) {
+		return domain.Condition{}, fmt.Errorf("%w: field %q does not support %q", ErrInvalidQuery, field, op)
+	}
+
+	if op == domain.OpIn {
+		parts := strings.Split(raw, ",")
+		values := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			value, err := parseValue(field, def.Type, part)
+			if err != nil {
+				return domain.Condition{}, err
+			}
+			values = append(values, value)
+		}
+		return domain.Condition{Field: field, Op: op, Value: values}, nil
+	}
+
+	value, err := parseValue(field, def.Type, raw)
+	if err != nil {
+		return domain.Condition{}, err
+	}
+	return domain.Condition{Field: field, Op: op, Value: value}, nil
+}
+
+func allows(def domain.QueryField, op domain.Operator) bool {
+	for _, allowed := range def.Ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseValue(field string, fieldType domain.FieldType, raw string) (interface{}, error) {
+	switch fieldType {
+	case domain.FieldBool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q must be true or false", ErrInvalidQuery, field)
+		}
+		return value, nil
+	case domain.FieldTime:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %q must be a date or an RFC 3339 time", ErrInvalidQuery, field)
+	default:
+		return raw, nil
+	}
+}
+
+// cursor is the position after the last item of a page: the value of the
+// sort field and the ID that breaks ties between equal values.
+type cursor struct {
+	Sort  string      `bson:"s"`
+	Value interface{} `bson:"v"`
+	ID    interface{} `bson:"id"`
+}
+
+// EncodeCursor returns the cursor of the page that follows the item with the
+// given sort value and ID. Values keep their BSON types through the cursor.
+func EncodeCursor(spec domain.QuerySpec, value interface{}, id interface{}) (string, error) {
+	data, err := bson.Marshal(cursor{Sort: sortKey(spec), Value: value, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor returns the sort value and ID held by the cursor of spec. The
+// cursor must have been made for the same sort order.
+func DecodeCursor(spec domain.QuerySpec) (value interface{}, id interface{}, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(spec.Cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := bson.Unmarshal(data, &c); err != nil || c.Sort != sortKey(spec) {
+		return nil, nil, ErrInvalidCursor
+	}
+	return c.Value, c.ID, nil
+}
+
+func sortKey(spec domain.QuerySpec) string {
+	if spec.SortDesc {
+		return "-" + spec.SortBy
+	}
+	return spec.SortBy
+}
@@ -0,0 +1,95 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParse(t *testing.T) {
+	values, _ := url.ParseQuery("role=admin,loan_officer&active=true&created_at[gte]=2024-01-02&email[prefix]=jo&sort=email&limit=5")
+
+	spec, err := Parse(values, domain.UserQuerySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.SortBy != "email" || spec.SortDesc || spec.Limit != 5 {
+		t.Errorf("got sort %q desc %v limit %d", spec.SortBy, spec.SortDesc, spec.Limit)
+	}
+
+	want := map[string]domain.Condition{
+		"role":       {Field: "role", Op: domain.OpIn, Value: []interface{}{"admin", "loan_officer"}},
+		"active":     {Field: "active", Op: domain.OpEq, Value: true},
+		"created_at": {Field: "created_at", Op: domain.OpGte, Value: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		"email":      {Field: "email", Op: domain.OpPrefix, Value: "jo"},
+	}
+	if len(spec.Conditions) != len(want) {
+		t.Fatalf("got %d conditions, want %d", len(spec.Conditions), len(want))
+	}
+	for _, condition := range spec.Conditions {
+		if !reflect.DeepEqual(condition, want[condition.Field]) {
+			t.Errorf("got %+v, want %+v", condition, want[condition.Field])
+		}
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	spec, err := Parse(url.Values{}, domain.UserQuerySchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.SortBy != "created_at" || !spec.SortDesc || spec.Limit != domain.DefaultPageLimit {
+		t.Errorf("got sort %q desc %v limit %d", spec.SortBy, spec.SortDesc, spec.Limit)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, raw := range []string{
+		"password=x",
+		"role[prefix]=ad",
+		"active=maybe",
+		"created_at[gte]=yesterday",
+		"sort=role",
+		"limit=0",
+		"limit=1000",
+	} {
+		values, _ := url.ParseQuery(raw)
+		_, err := Parse(values, domain.UserQuerySchema)
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Parse(%q) returned %v, want %v", raw, err, ErrInvalidQuery)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	spec := domain.QuerySpec{SortBy: "created_at", SortDesc: true}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	id := primitive.NewObjectID()
+
+	spec.Cursor, _ = EncodeCursor(spec, at, id)
+	value, gotID, err := DecodeCursor(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != primitive.NewDateTimeFromTime(at) {
+		t.Errorf("got value %v, want %v", value, at)
+	}
+	if gotID != id {
+		t.Errorf("got id %v, want %v", gotID, id)
+	}
+
+	// a cursor only applies to the order it was made for
+	spec.SortDesc = false
+	if _, _, err := DecodeCursor(spec); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("got %v, want %v", err, ErrInvalidCursor)
+	}
+	spec.Cursor = "not a cursor"
+	if _, _, err := DecodeCursor(spec); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("got %v, want %v", err, ErrInvalidCursor)
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+var (
+	// TokenBucket refills Limit tokens evenly over Window and lets bursts of
+	// up to Limit requests through.
+	TokenBucket Algorithm = tokenBucket{}
+	// SlidingWindow approximates a rolling window by weighting the count of
+	// the previous fixed window by how much of it still overlaps.
+	SlidingWindow Algorithm = slidingWindow{}
+)
+
+type tokenBucket struct{}
+
+func (tokenBucket) take(state *State, policy Policy, now time.Time) Result {
+	limit := float64(policy.Limit)
+	rate := limit / policy.Window.Seconds()
+
+	if state.UpdatedAt.IsZero() {
+		state.Tokens = limit
+	} else if elapsed := now.Sub(state.UpdatedAt).Seconds(); elapsed > 0 {
+		state.Tokens = math.Min(limit, state.Tokens+elapsed*rate)
+	}
+	state.UpdatedAt = now
+
+	result := Result{Limit: policy.Limit}
+	if state.Tokens >= 1 {
+		state.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - state.Tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(state.Tokens))
+	result.Reset = seconds((limit - state.Tokens) / rate)
+	return result
+}
+
+type slidingWindow struct{}
+
+func (slidingWindow) take(state *State, policy Policy, now time.Time) Result {
+	window := policy.Window
+	current := now.Truncate(window)
+
+	if !state.WindowStart.Equal(current) {
+		if state.WindowStart.Equal(current.Add(-window)) {
+			state.PrevCount = state.Count
+		} else {
+			state.PrevCount = 0
+		}
+		state.Count = 0
+		state.WindowStart = current
+	}
+
+	elapsed := now.Sub(current)
+	overlap := 1 - elapsed.Seconds()/window.Seconds()
+	estimate := float64(state.PrevCount)*overlap + float64(state.Count)
+
+	result := Result{Limit: policy.Limit}
+	if estimate+1 <= float64(policy.Limit) {
+		state.Count++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = slidingRetryAfter(state, policy, elapsed)
+	}
+
+	result.Remaining = policy.Limit - int(math.Ceil(estimate))
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	result.Reset = window - elapsed
+	if state.Count > 0 {
+		result.Reset += window
+	}
+	return result
+}
+
+// slidingRetryAfter returns how long until the weighted estimate leaves room
+// for one more request.
+func slidingRetryAfter(state *State, policy Policy, elapsed time.Duration) time.Duration {
+	window := policy.Window
+	limit := float64(policy.Limit)
+
+	// room frees up in the current window as the previous one slides out
+	if float64(state.Count)+1 <= limit && state.PrevCount > 0 {
+		fraction := 1 - (limit-float64(state.Count)-1)/float64(state.PrevCount)
+		return time.Duration(fraction*float64(window)) - elapsed
+	}
+
+	// otherwise the current window has to slide out in the next one
+	wait := window - elapsed
+	if state.Count > 0 {
+		fraction := 1 - (limit-1)/float64(state.Count)
+		if fraction > 0 {
+			wait += time.Duration(fraction * float64(window))
+		}
+	}
+	return wait
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
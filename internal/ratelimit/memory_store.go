@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops expired keys.
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// MemoryStore keeps limiter state in process. It is only suitable when a
+// single instance of the API is running.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+// Update implements Store.
+func (m *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state *State)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) > sweepInterval {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	entry, ok := m.entries[key]
+	if !ok || now.After(entry.expires) {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+	fn(&entry.state)
+	entry.expires = now.Add(ttl)
+	return nil
+}
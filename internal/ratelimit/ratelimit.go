@@ -0,0 +1,96 @@
+// Package ratelimit decides whether a request may proceed under a policy
+// such as "10 requests per minute", keeping per-key state in a Store.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Policy describes how many requests a single key may make per window.
+type Policy struct {
+	Name      string
+	Limit     int
+	Window    time.Duration
+	Algorithm Algorithm
+}
+
+// Validate reports a policy that cannot be enforced: one without a
+// positive limit or window.
+func (p Policy) Validate() error {
+	if p.Limit <= 0 {
+		return fmt.Errorf("policy %q: the limit must be positive, got %d", p.Name, p.Limit)
+	}
+	if p.Window <= 0 {
+		return fmt.Errorf("policy %q: the window must be positive, got %v", p.Name, p.Window)
+	}
+	return nil
+}
+
+// Result is the outcome of a single Allow call.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the full quota is available again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request would be allowed; it is
+	// zero when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// State is the per key bookkeeping used by the algorithms.
+type State struct {
+	// token bucket
+	Tokens    float64   `json:"tokens" bson:"tokens"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+
+	// sliding window
+	WindowStart time.Time `json:"window_start" bson:"window_start"`
+	Count       int       `json:"count" bson:"count"`
+	PrevCount   int       `json:"prev_count" bson:"prev_count"`
+}
+
+// Store persists State per key. Update must run fn atomically with respect
+// to other updates of the same key; shared stores (Redis, Mongo, ...) do so
+// with a transaction or a server side script. Keys that are not updated
+// for ttl may be discarded.
+type Store interface {
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state *State)) error
+}
+
+// Algorithm is a rate limiting strategy; use TokenBucket or SlidingWindow.
+type Algorithm interface {
+	take(state *State, policy Policy, now time.Time) Result
+}
+
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow consumes one request for key under policy.
+func (l *Limiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	algorithm := policy.Algorithm
+	if algorithm == nil {
+		algorithm = TokenBucket
+	}
+
+	var result Result
+	now := l.now()
+	err := l.store.Update(ctx, policy.Name+":"+key, 2*policy.Window, func(state *State) {
+		result = algorithm.take(state, policy, now)
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
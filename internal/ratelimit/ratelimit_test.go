@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter() (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	limiter := NewLimiter(store)
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func TestTokenBucket(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	policy := Policy{Name: "test", Limit: 3, Window: 3 * time.Second, Algorithm: TokenBucket}
+
+	for i := 0; i < 3; i++ {
+		res, err := limiter.Allow(context.Background(), "k", policy)
+		if err != nil {
+			t.Fatalf("Allow returned an error: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+		if res.Remaining != 2-i {
+			t.Errorf("request %d: expected %d remaining, got %d", i+1, 2-i, res.Remaining)
+		}
+	}
+
+	res, _ := limiter.Allow(context.Background(), "k", policy)
+	if res.Allowed {
+		t.Fatal("request over the limit should be rejected")
+	}
+	if res.RetryAfter != time.Second {
+		t.Errorf("expected retry after 1s, got %v", res.RetryAfter)
+	}
+
+	clock.Advance(time.Second)
+	if res, _ := limiter.Allow(context.Background(), "k", policy); !res.Allowed {
+		t.Error("request should be allowed once a token was refilled")
+	}
+
+	if res, _ := limiter.Allow(context.Background(), "other", policy); !res.Allowed {
+		t.Error("keys must not share a bucket")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	policy := Policy{Name: "test", Limit: 4, Window: time.Minute, Algorithm: SlidingWindow}
+
+	for i := 0; i < 4; i++ {
+		if res, _ := limiter.Allow(context.Background(), "k", policy); !res.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	res, _ := limiter.Allow(context.Background(), "k", policy)
+	if res.Allowed {
+		t.Fatal("request over the limit should be rejected")
+	}
+	// the next window needs a quarter of the previous one to slide out
+	if want := time.Minute + 15*time.Second; res.RetryAfter != want {
+		t.Errorf("expected retry after %v, got %v", want, res.RetryAfter)
+	}
+
+	clock.Advance(time.Minute)
+	if res, _ := limiter.Allow(context.Background(), "k", policy); res.Allowed {
+		t.Error("previous window should still count fully at the window boundary")
+	}
+
+	clock.Advance(30 * time.Second)
+	res, _ = limiter.Allow(context.Background(), "k", policy)
+	if !res.Allowed {
+		t.Fatal("request should be allowed once half of the previous window slid out")
+	}
+	if res.Remaining != 1 {
+		t.Errorf("expected 1 remaining, got %d", res.Remaining)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if res, _ := limiter.Allow(context.Background(), "k", policy); !res.Allowed || res.Remaining != 3 {
+		t.Errorf("expected a fresh window, got %+v", res)
+	}
+}
+
+func TestMemoryStoreExpiresKeys(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	policy := Policy{Name: "test", Limit: 1, Window: time.Hour, Algorithm: TokenBucket}
+
+	limiter.Allow(context.Background(), "k", policy)
+	clock.Advance(3 * time.Hour)
+	limiter.Allow(context.Background(), "other", policy)
+
+	store := limiter.store.(*MemoryStore)
+	if _, ok := store.entries["test:k"]; ok {
+		t.Error("expired key should have been swept")
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := (Policy{Name: "ok", Limit: 1, Window: time.Second}).Validate(); err != nil {
+		t.Errorf("expected a valid policy, got %v", err)
+	}
+	for _, policy := range []Policy{{Name: "no limit", Window: time.Second}, {Name: "no window", Limit: 1}, {Name: "negative", Limit: -1, Window: time.Second}} {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("%s: expected an error", policy.Name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Container runs a container with docker for the rest of the test, for the
+// suites whose server is not given by the environment, and returns its ID
+// and the host address port is published on. run holds the arguments of
+// docker run, the image and its command. The test is skipped when docker is
+// not available.
+func Container(t *testing.T, port string, run ...string) (id string, addr string) {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	args := append([]string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + port}, run...)
+	out, err := exec.Command("docker", args...).Output()
+	if err != nil {
+		t.Fatalf("starting %v: %v", run, commandError(err))
+	}
+	id = strings.TrimSpace(string(out))
+	t.Cleanup(func() { exec.Command("docker", "rm", "--force", id).Run() })
+
+	out, err = exec.Command("docker", "port", id, port).Output()
+	if err != nil {
+		t.Fatalf("finding the port of %v: %v", run, commandError(err))
+	}
+	addr, _, _ = strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return id, addr
+}
+
+// Exec runs cmd in the container until it succeeds or wait is over, and
+// returns its output.
+func Exec(t *testing.T, id string, wait time.Duration, cmd ...string) string {
+	t.Helper()
+	deadline := time.Now().Add(wait)
+	for {
+		out, err := exec.Command("docker", append([]string{"exec", id}, cmd...)...).Output()
+		if err == nil {
+			return string(out)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("running %v: %v", cmd, commandError(err))
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// commandError adds what the command printed on stderr to err.
+func commandError(err error) error {
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exit.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,994 @@
+// Package repotest is the contract every implementation of the
+// repositories has to satisfy. Each implementation runs it from its own
+// tests with a factory returning an empty repository.Set.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Factory returns an empty set of repositories for one test.
+type Factory func(t *testing.T) repository.Set
+
+// Run runs the whole contract against the repositories made by newSet.
+func Run(t *testing.T, newSet Factory) {
+	t.Run("Users", func(t *testing.T) { TestUsers(t, newSet) })
+	t.Run("UniqueEmail", func(t *testing.T) { TestUniqueEmail(t, newSet) })
+	t.Run("Versions", func(t *testing.T) { TestVersions(t, newSet) })
+	t.Run("UserList", func(t *testing.T) { TestUserList(t, newSet) })
+	t.Run("ResetPassword", func(t *testing.T) { TestResetPassword(t, newSet) })
+	t.Run("Audit", func(t *testing.T) { TestAudit(t, newSet) })
+	t.Run("KYC", func(t *testing.T) { TestKYC(t, newSet) })
+	t.Run("MagicLinks", func(t *testing.T) { TestMagicLinks(t, newSet) })
+	t.Run("LoginAttempts", func(t *testing.T) { TestLoginAttempts(t, newSet) })
+	t.Run("Idempotency", func(t *testing.T) { TestIdempotency(t, newSet) })
+	t.Run("Tenants", func(t *testing.T) { TestTenants(t, newSet) })
+	t.Run("Branches", func(t *testing.T) { TestBranches(t, newSet) })
+	t.Run("Loans", func(t *testing.T) { TestLoans(t, newSet) })
+	t.Run("Portfolio", func(t *testing.T) { TestPortfolio(t, newSet) })
+	t.Run("Search", func(t *testing.T) { TestSearch(t, newSet) })
+	t.Run("Tx", func(t *testing.T) { TestTx(t, newSet) })
+}
+
+// tenant ids used by the contract
+const (
+	tenantA = "tenant-a"
+	tenantB = "tenant-b"
+)
+
+func inTenant(tenantID string) context.Context {
+	return domain.WithTenant(context.Background(), tenantID)
+}
+
+func newUser(email string, role string) domain.User {
+	now := time.Now().UTC()
+	return domain.User{
+		FirstName: "Abebe",
+		LastName:  "Kebede",
+		Email:     email,
+		Active:    true,
+		Password:  "hash-0",
+		Tokens:    []string{},
+		Role:      role,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func mustCreate(t *testing.T, ctx context.Context, users domain.UserRepository, user domain.User) domain.User {
+	t.Helper()
+	created, err := users.Create(ctx, user)
+	if err != nil {
+		t.Fatalf("Create returned an error: %v", err)
+	}
+	if created.ID.IsZero() {
+		t.Fatal("Create should assign an ID")
+	}
+	return created
+}
+
+func expectErr(t *testing.T, err error, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
+
+func mustNotErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestUsers checks the user repository.
+func TestUsers(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	users := repos.Users
+	ctx := inTenant(tenantA)
+
+	_, err := users.Create(context.Background(), newUser("none@example.com", domain.RoleBorrower))
+	expectErr(t, err, repository.ErrNoTenant)
+
+	user := mustCreate(t, ctx, users, newUser("abebe@example.com", domain.RoleBorrower))
+	id := user.ID.Hex()
+	if user.TenantID != tenantA {
+		t.Errorf("expected the user in %q, got %q", tenantA, user.TenantID)
+	}
+
+	got, err := users.GetByEmail(ctx, "abebe@example.com")
+	mustNotErr(t, err)
+	if got.ID != user.ID {
+		t.Errorf("GetByEmail returned user %s, expected %s", got.ID.Hex(), id)
+	}
+	_, err = users.GetByID(inTenant(tenantB), id)
+	expectErr(t, err, repository.ErrUserNotFound)
+	_, err = users.GetByID(context.Background(), id)
+	expectErr(t, err, repository.ErrUserNotFound)
+	_, err = users.GetByID(ctx, "not-an-id")
+	expectErr(t, err, repository.ErrInvalidID)
+	_, err = users.GetByID(ctx, primitive.NewObjectID().Hex())
+	expectErr(t, err, repository.ErrUserNotFound)
+	if _, err := users.GetByID(domain.WithAllTenants(context.Background()), id); err != nil {
+		t.Errorf("every tenant should see the user: %v", err)
+	}
+
+	// refresh tokens
+	mustNotErr(t, users.UpdateRefreshToken(ctx, id, "r1"))
+	mustNotErr(t, users.UpdateRefreshToken(ctx, id, "r2"))
+	if ok, _ := users.RefreshTokenExist(ctx, id, "r1"); !ok {
+		t.Error("r1 should exist")
+	}
+	mustNotErr(t, users.RevokeRefreshToken(ctx, id, "r1"))
+	if ok, _ := users.RefreshTokenExist(ctx, id, "r1"); ok {
+		t.Error("r1 should be revoked")
+	}
+	expectErr(t, users.RevokeRefreshToken(ctx, id, "r1"), repository.ErrUserNotFound)
+	mustNotErr(t, users.RevokeAllRefreshTokens(ctx, id))
+	if ok, _ := users.RefreshTokenExist(ctx, id, "r2"); ok {
+		t.Error("r2 should be revoked")
+	}
+	if got, _ := users.GetByID(ctx, id); got.TokenVersion != 1 {
+		t.Errorf("revoking every session should revoke the access tokens, got token version %d", got.TokenVersion)
+	}
+
+	// activation consumes the verification token
+	_, err = users.UpdateRole(ctx, id, domain.RoleLoanOfficer)
+	mustNotErr(t, err)
+	mustNotErr(t, users.UpdateVerifyToken(ctx, id, "verify-hash", time.Now()))
+	mustNotErr(t, users.ActivateUser(ctx, id))
+	got, _ = users.GetByID(ctx, id)
+	if got.VerifyToken != "" || got.Role != domain.RoleLoanOfficer {
+		t.Errorf("unexpected user after activation: token %q, role %q", got.VerifyToken, got.Role)
+	}
+	expectErr(t, users.ActivateUser(ctx, primitive.NewObjectID().Hex()), repository.ErrUserNotFound)
+
+	// the password history keeps the previous hashes, newest first
+	for i := 1; i <= domain.MaxPasswordHistory+1; i++ {
+		mustNotErr(t, users.UpdateUserPassword(ctx, id, domain.UpdatePassword{NewPassword: "hash-" + string(rune('0'+i))}))
+	}
+	got, _ = users.GetByID(ctx, id)
+	if len(got.PasswordHistory) != domain.MaxPasswordHistory {
+		t.Fatalf("expected %d old passwords, got %v", domain.MaxPasswordHistory, got.PasswordHistory)
+	}
+	if got.PasswordHistory[0] != "hash-"+string(rune('0'+domain.MaxPasswordHistory)) {
+		t.Errorf("the newest old password should come first, got %v", got.PasswordHistory)
+	}
+
+	// suspension revokes the refresh tokens
+	mustNotErr(t, users.UpdateRefreshToken(ctx, id, "r3"))
+	got, err = users.SetSuspended(ctx, id, true, "fraud")
+	mustNotErr(t, err)
+	if !got.Suspended || got.SuspendReason != "fraud" || len(got.Tokens) != 0 {
+		t.Errorf("unexpected suspended user: %+v", got)
+	}
+	got, err = users.SetSuspended(ctx, id, false, "")
+	mustNotErr(t, err)
+	if got.Suspended || got.SuspendReason != "" || !got.SuspendedAt.IsZero() {
+		t.Errorf("unexpected reactivated user: %+v", got)
+	}
+
+	// email change
+	mustNotErr(t, users.SetPendingEmail(ctx, id, "new@example.com", "change-hash", time.Now().Add(time.Hour)))
+	got, err = users.GetByEmailChangeToken(ctx, "change-hash")
+	mustNotErr(t, err)
+	expectErr(t, users.ConfirmEmail(ctx, id, "other-hash"), repository.ErrUserNotFound)
+	mustNotErr(t, users.ConfirmEmail(ctx, id, "change-hash"))
+	got, _ = users.GetByID(ctx, id)
+	if got.Email != "new@example.com" || got.PendingEmail != "" || got.EmailChangeToken != "" {
+		t.Errorf("unexpected user after email change: %+v", got)
+	}
+
+	if count, _ := users.Count(ctx); count != 1 {
+		t.Errorf("expected a count of 1, got %d", count)
+	}
+	// deleted users disappear, from the count too
+	mustNotErr(t, users.Delete(ctx, id))
+	_, err = users.GetByID(ctx, id)
+	expectErr(t, err, repository.ErrUserNotFound)
+	expectErr(t, users.Delete(ctx, id), repository.ErrUserNotFound)
+	if count, _ := users.Count(ctx); count != 0 {
+		t.Errorf("deleted users should not be counted, got %d", count)
+	}
+	if count, _ := users.Count(inTenant(tenantB)); count != 0 {
+		t.Errorf("expected no users in %q, got %d", tenantB, count)
+	}
+
+	// erasure works on deleted users, once
+	before, err := users.Erase(ctx, id)
+	mustNotErr(t, err)
+	if before.Email != "new@example.com" {
+		t.Errorf("Erase should return the user as it was, got %q", before.Email)
+	}
+	_, err = users.Erase(ctx, id)
+	expectErr(t, err, repository.ErrUserNotFound)
+}
+
+// TestUniqueEmail checks that an email belongs to one user per tenant,
+// apart from deleted users.
+func TestUniqueEmail(t *testing.T, newSet Factory) {
+	users := newSet(t).Users
+	ctx := inTenant(tenantA)
+
+	first := mustCreate(t, ctx, users, newUser("abebe@example.com", domain.RoleBorrower))
+	_, err := users.Create(ctx, newUser("abebe@example.com", domain.RoleBorrower))
+	expectErr(t, err, repository.ErrEmailTaken)
+	mustCreate(t, inTenant(tenantB), users, newUser("abebe@example.com", domain.RoleBorrower))
+
+	// an email change cannot land on a taken address
+	other := mustCreate(t, ctx, users, newUser("almaz@example.com", domain.RoleBorrower))
+	mustNotErr(t, users.SetPendingEmail(ctx, other.ID.Hex(), "abebe@example.com", "change-hash", time.Now().Add(time.Hour)))
+	expectErr(t, users.ConfirmEmail(ctx, other.ID.Hex(), "change-hash"), repository.ErrEmailTaken)
+	if got, _ := users.GetByID(ctx, other.ID.Hex()); got.Email != "almaz@example.com" {
+		t.Errorf("a refused change should keep the email, got %q", got.Email)
+	}
+
+	// the address of a deleted user is free again
+	mustNotErr(t, users.Delete(ctx, first.ID.Hex()))
+	mustCreate(t, ctx, users, newUser("abebe@example.com", domain.RoleBorrower))
+}
+
+// TestVersions checks that changes to a user bump its version and that
+// versioned updates refuse a stale version.
+func TestVersions(t *testing.T, newSet Factory) {
+	users := newSet(t).Users
+	ctx := inTenant(tenantA)
+
+	user := mustCreate(t, ctx, users, newUser("abebe@example.com", domain.RoleBorrower))
+	id := user.ID.Hex()
+	if user.Version != 1 {
+		t.Fatalf("a new user should be at version 1, got %d", user.Version)
+	}
+
+	// sign-ins do not count as changes
+	mustNotErr(t, users.UpdateRefreshToken(ctx, id, "r1"))
+	got, err := users.UpdateRole(ctx, id, domain.RoleLoanOfficer)
+	mustNotErr(t, err)
+	if got.Version != 2 {
+		t.Fatalf("a role change should bump the version to 2, got %d", got.Version)
+	}
+	if got.TokenVersion != user.TokenVersion+1 {
+		t.Errorf("a role change should revoke the access tokens, got token version %d", got.TokenVersion)
+	}
+
+	profile := domain.EditableProfile{FirstName: "Almaz", LastName: "Kebede"}
+	updated, err := users.UpdateProfile(ctx, id, 2, profile)
+	mustNotErr(t, err)
+	if updated.Version != 3 || updated.FirstName != "Almaz" {
+		t.Fatalf("UpdateProfile at the current version should apply, got version %d and %q", updated.Version, updated.FirstName)
+	}
+
+	_, err = users.UpdateProfile(ctx, id, 2, domain.EditableProfile{FirstName: "Stale", LastName: "Kebede"})
+	expectErr(t, err, repository.ErrVersionConflict)
+	var conflict *repository.ConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != 2 || conflict.Current != 3 {
+		t.Fatalf("expected a conflict from version 2 to 3, got %v", err)
+	}
+	_, err = users.Update(ctx, id, 2, domain.UserUpdate{FirstName: "Stale", LastName: "Kebede"})
+	expectErr(t, err, repository.ErrVersionConflict)
+	if got, _ := users.GetByID(ctx, id); got.FirstName != "Almaz" {
+		t.Errorf("a refused update should keep the user, got %q", got.FirstName)
+	}
+
+	// version 0 applies to any version
+	updated, err = users.Update(ctx, id, 0, domain.UserUpdate{FirstName: "Abebe", LastName: "Kebede", UpdatedAt: time.Now().UTC()})
+	mustNotErr(t, err)
+	if updated.Version != 4 || updated.FirstName != "Abebe" {
+		t.Fatalf("an unconditional update should apply, got version %d and %q", updated.Version, updated.FirstName)
+	}
+
+	_, err = users.Update(ctx, primitive.NewObjectID().Hex(), 1, domain.UserUpdate{FirstName: "Nobody"})
+	expectErr(t, err, repository.ErrUserNotFound)
+}
+
+// TestUserList checks filtering and paging of the user listing.
+func TestUserList(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	users := repos.Users
+	ctx := inTenant(tenantA)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	emails := []string{"a@x.io", "b@x.io", "c@x.io", "d@x.io", "e@x.io"}
+	for i, email := range emails {
+		user := newUser(email, domain.RoleBorrower)
+		user.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if i%2 == 1 {
+			user.Role = domain.RoleAuditor
+		}
+		mustCreate(t, ctx, users, user)
+	}
+	mustCreate(t, inTenant(tenantB), users, newUser("z@x.io", domain.RoleBorrower))
+
+	spec := domain.QuerySpec{SortBy: "created_at", SortDesc: true, Limit: 2}
+	var seen []string
+	for {
+		page, err := users.List(ctx, spec)
+		mustNotErr(t, err)
+		if page.Total != int64(len(emails)) {
+			t.Fatalf("expected a total of %d, got %d", len(emails), page.Total)
+		}
+		for _, user := range page.Items {
+			seen = append(seen, user.Email)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		spec.Cursor = page.NextCursor
+	}
+	want := []string{"e@x.io", "d@x.io", "c@x.io", "b@x.io", "a@x.io"}
+	if len(seen) != len(want) {
+		t.Fatalf("expected %v, got %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, seen)
+		}
+	}
+
+	spec = domain.QuerySpec{
+		Conditions: []domain.Condition{
+			{Field: "role", Op: domain.OpIn, Value: []string{domain.RoleBorrower}},
+			{Field: "created_at", Op: domain.OpGte, Value: base.Add(time.Hour)},
+		},
+		SortBy: "email",
+		Limit:  10,
+	}
+	page, err := users.List(ctx, spec)
+	mustNotErr(t, err)
+	if page.Total != 2 || len(page.Items) != 2 || page.Items[0].Email != "c@x.io" {
+		t.Errorf("unexpected filtered page: %+v", page)
+	}
+
+	spec = domain.QuerySpec{
+		Conditions: []domain.Condition{{Field: "email", Op: domain.OpPrefix, Value: "D@"}},
+		SortBy:     "email",
+		Limit:      10,
+	}
+	page, err = users.List(ctx, spec)
+	mustNotErr(t, err)
+	if page.Total != 1 || page.Items[0].Email != "d@x.io" {
+		t.Errorf("prefix filters ignore case, got %+v", page)
+	}
+}
+
+// TestResetPassword checks the one time passwords of the password reset.
+func TestResetPassword(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+	user := mustCreate(t, ctx, repos.Users, newUser("otp@example.com", domain.RoleBorrower))
+
+	found, err := repos.ResetPassword.GetUserByEmail(ctx, "otp@example.com")
+	mustNotErr(t, err)
+	if found.ID != user.ID {
+		t.Errorf("GetUserByEmail returned %s", found.ID.Hex())
+	}
+	_, err = repos.ResetPassword.GetUserByEmail(inTenant(tenantB), "otp@example.com")
+	expectErr(t, err, repository.ErrUserNotFound)
+
+	otp := &domain.OtpSave{Email: "otp@example.com", Code: "123456", ExpiresAt: time.Now().Add(time.Minute)}
+	mustNotErr(t, repos.ResetPassword.SaveOtp(ctx, otp))
+	_, err = repos.ResetPassword.GetOTPByEmail(inTenant(tenantB), "otp@example.com")
+	expectErr(t, err, repository.ErrUserNotFound)
+
+	attempts, err := repos.ResetPassword.IncrementOtpAttempts(ctx, "otp@example.com")
+	mustNotErr(t, err)
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+	saved, err := repos.ResetPassword.GetOTPByEmail(ctx, "otp@example.com")
+	mustNotErr(t, err)
+	if saved.Code != "123456" || saved.Attempts != 1 {
+		t.Errorf("unexpected otp: %+v", saved)
+	}
+
+	mustNotErr(t, repos.ResetPassword.DeleteOtp(ctx, "otp@example.com"))
+	_, err = repos.ResetPassword.GetOTPByEmail(ctx, "otp@example.com")
+	expectErr(t, err, repository.ErrUserNotFound)
+
+	// DeleteOtps removes every code of the email in the tenant
+	for _, code := range []string{"111111", "222222"} {
+		mustNotErr(t, repos.ResetPassword.SaveOtp(ctx, &domain.OtpSave{Email: "otp@example.com", Code: code, ExpiresAt: time.Now().Add(time.Minute)}))
+	}
+	mustNotErr(t, repos.ResetPassword.SaveOtp(inTenant(tenantB), &domain.OtpSave{Email: "otp@example.com", Code: "333333", ExpiresAt: time.Now().Add(time.Minute)}))
+	mustNotErr(t, repos.ResetPassword.DeleteOtps(ctx, "otp@example.com"))
+	_, err = repos.ResetPassword.GetOTPByEmail(ctx, "otp@example.com")
+	expectErr(t, err, repository.ErrUserNotFound)
+	_, err = repos.ResetPassword.GetOTPByEmail(inTenant(tenantB), "otp@example.com")
+	mustNotErr(t, err)
+
+	mustNotErr(t, repos.ResetPassword.ResetPassword(ctx, user.ID.Hex(), &domain.ResetPasswordRequest{NewPassword: "hash-1"}))
+	got, _ := repos.Users.GetByID(ctx, user.ID.Hex())
+	if got.Password != "hash-1" || len(got.PasswordHistory) != 1 || got.PasswordHistory[0] != "hash-0" {
+		t.Errorf("unexpected passwords: %q %v", got.Password, got.PasswordHistory)
+	}
+	expectErr(t, repos.ResetPassword.ResetPassword(ctx, primitive.NewObjectID().Hex(), &domain.ResetPasswordRequest{}), repository.ErrUserNotFound)
+}
+
+// TestAudit checks the audit trail.
+func TestAudit(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, action := range []string{domain.AuditActionProfileUpdate, domain.AuditActionUserRoleChange} {
+		entry := domain.AuditEntry{
+			UserID:    "u1",
+			ActorID:   "u1",
+			Action:    action,
+			Changes:   []domain.FieldChange{{Field: "first_name", Old: "a", New: "b"}},
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		mustNotErr(t, repos.Audit.Create(ctx, entry))
+	}
+	expectErr(t, repos.Audit.Create(context.Background(), domain.AuditEntry{UserID: "u1"}), repository.ErrNoTenant)
+
+	entries, err := repos.Audit.ListByUser(ctx, "u1")
+	mustNotErr(t, err)
+	if len(entries) != 2 || entries[0].Action != domain.AuditActionUserRoleChange {
+		t.Fatalf("expected the newest entry first, got %+v", entries)
+	}
+	if entries, _ := repos.Audit.ListByUser(inTenant(tenantB), "u1"); len(entries) != 0 {
+		t.Errorf("another tenant should not see the entries, got %d", len(entries))
+	}
+
+	mustNotErr(t, repos.Audit.RedactChanges(ctx, "u1", []string{domain.AuditActionProfileUpdate}))
+	entries, _ = repos.Audit.ListByUser(ctx, "u1")
+	if entries[1].Changes[0].Old != domain.RedactedValue || entries[1].Changes[0].New != domain.RedactedValue {
+		t.Errorf("the profile change should be redacted, got %+v", entries[1].Changes)
+	}
+	if entries[0].Changes[0].Old != "a" {
+		t.Errorf("the role change should be kept, got %+v", entries[0].Changes)
+	}
+}
+
+// TestKYC checks the KYC records and their transitions.
+func TestKYC(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+
+	_, err := repos.KYC.GetByUserID(ctx, "u1")
+	expectErr(t, err, repository.ErrKYCNotFound)
+
+	record, err := repos.KYC.GetOrCreate(ctx, "u1")
+	mustNotErr(t, err)
+	if record.Status != domain.KYCStatusNotStarted || record.TenantID != tenantA {
+		t.Errorf("unexpected new record: %+v", record)
+	}
+	again, err := repos.KYC.GetOrCreate(ctx, "u1")
+	mustNotErr(t, err)
+	if again.ID != record.ID {
+		t.Error("GetOrCreate should return the existing record")
+	}
+
+	mustNotErr(t, repos.KYC.AddDocument(ctx, "u1", domain.KYCDocument{ID: "d1", Type: "passport"}))
+	expectErr(t, repos.KYC.AddDocument(inTenant(tenantB), "u1", domain.KYCDocument{ID: "d2"}), repository.ErrKYCNotFound)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	submit := domain.KYCTransition{From: domain.KYCStatusNotStarted, To: domain.KYCStatusSubmitted, At: now}
+	record, err = repos.KYC.Transition(ctx, "u1", submit)
+	mustNotErr(t, err)
+	if record.Status != domain.KYCStatusSubmitted || len(record.Documents) != 1 {
+		t.Errorf("unexpected submitted record: %+v", record)
+	}
+	_, err = repos.KYC.Transition(ctx, "u1", submit)
+	expectErr(t, err, repository.ErrKYCNotFound)
+
+	approve := domain.KYCTransition{
+		From:       domain.KYCStatusSubmitted,
+		To:         domain.KYCStatusApproved,
+		ReviewerID: "r1",
+		At:         now,
+		ExpiresAt:  now.Add(-time.Minute),
+	}
+	_, err = repos.KYC.Transition(ctx, "u1", approve)
+	mustNotErr(t, err)
+
+	submitted, _ := repos.KYC.ListByStatus(ctx, domain.KYCStatusSubmitted)
+	approved, _ := repos.KYC.ListByStatus(ctx, domain.KYCStatusApproved)
+	if len(submitted) != 0 || len(approved) != 1 {
+		t.Errorf("expected one approved record, got %d submitted and %d approved", len(submitted), len(approved))
+	}
+	expired, err := repos.KYC.ListExpired(domain.WithAllTenants(context.Background()), now)
+	mustNotErr(t, err)
+	if len(expired) != 1 || expired[0].UserID != "u1" {
+		t.Errorf("expected the approval to have lapsed, got %+v", expired)
+	}
+
+	_, err = repos.KYC.Delete(inTenant(tenantB), "u1")
+	expectErr(t, err, repository.ErrKYCNotFound)
+	deleted, err := repos.KYC.Delete(ctx, "u1")
+	mustNotErr(t, err)
+	if deleted.ID != record.ID || len(deleted.Documents) != 1 || deleted.Documents[0].ID != "d1" {
+		t.Errorf("Delete should return the record as it was, got %+v", deleted)
+	}
+	_, err = repos.KYC.GetByUserID(ctx, "u1")
+	expectErr(t, err, repository.ErrKYCNotFound)
+}
+
+// TestMagicLinks checks that magic links are single use.
+func TestMagicLinks(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+	now := time.Now()
+
+	first := domain.MagicLink{UserID: "u1", TokenHash: "t1", NonceHash: "n1", ExpiresAt: now.Add(time.Minute), CreatedAt: now}
+	mustNotErr(t, repos.MagicLinks.Create(ctx, first))
+	second := domain.MagicLink{UserID: "u1", TokenHash: "t2", NonceHash: "n2", ExpiresAt: now.Add(time.Minute), CreatedAt: now}
+	mustNotErr(t, repos.MagicLinks.Create(ctx, second))
+
+	_, err := repos.MagicLinks.Consume(ctx, "t1", "n1", now)
+	expectErr(t, err, repository.ErrMagicLinkNotFound)
+	_, err = repos.MagicLinks.Consume(ctx, "t2", "wrong", now)
+	expectErr(t, err, repository.ErrMagicLinkNotFound)
+	_, err = repos.MagicLinks.Consume(inTenant(tenantB), "t2", "n2", now)
+	expectErr(t, err, repository.ErrMagicLinkNotFound)
+	_, err = repos.MagicLinks.Consume(ctx, "t2", "n2", now.Add(2*time.Minute))
+	expectErr(t, err, repository.ErrMagicLinkNotFound)
+
+	link, err := repos.MagicLinks.Consume(ctx, "t2", "n2", now)
+	mustNotErr(t, err)
+	if !link.Used || link.UserID != "u1" {
+		t.Errorf("unexpected consumed link: %+v", link)
+	}
+	_, err = repos.MagicLinks.Consume(ctx, "t2", "n2", now)
+	expectErr(t, err, repository.ErrMagicLinkNotFound)
+
+	third := domain.MagicLink{UserID: "u1", TokenHash: "t3", NonceHash: "n3", ExpiresAt: now.Add(time.Minute), CreatedAt: now}
+	mustNotErr(t, repos.MagicLinks.Create(ctx, third))
+	other := domain.MagicLink{UserID: "u2", TokenHash: "t4", NonceHash: "n4", ExpiresAt: now.Add(time.Minute), CreatedAt: now}
+	mustNotErr(t, repos.MagicLinks.Create(ctx, other))
+	mustNotErr(t, repos.MagicLinks.DeleteByUser(ctx, "u1"))
+	_, err = repos.MagicLinks.Consume(ctx, "t3", "n3", now)
+	expectErr(t, err, repository.ErrMagicLinkNotFound)
+	_, err = repos.MagicLinks.Consume(ctx, "t4", "n4", now)
+	mustNotErr(t, err)
+}
+
+// TestLoginAttempts checks the failed login counters.
+func TestLoginAttempts(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	attempts := repos.LoginAttempts
+	ctx := inTenant(tenantA)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	attempt, err := attempts.Get(ctx, "email:a@x.io")
+	mustNotErr(t, err)
+	if attempt.Key != "email:a@x.io" || attempt.Failures != 0 {
+		t.Errorf("expected an empty record, got %+v", attempt)
+	}
+
+	for i := 1; i <= 3; i++ {
+		attempt, err = attempts.RecordFailure(ctx, "email:a@x.io", now, now.Add(-time.Hour))
+		mustNotErr(t, err)
+		if attempt.Failures != i || attempt.Key != "email:a@x.io" {
+			t.Fatalf("failure %d: unexpected record %+v", i, attempt)
+		}
+	}
+	attempt, _ = attempts.RecordFailure(ctx, "email:a@x.io", now.Add(2*time.Hour), now.Add(time.Hour))
+	if attempt.Failures != 1 {
+		t.Errorf("a stale counter should restart, got %d failures", attempt.Failures)
+	}
+
+	mustNotErr(t, attempts.Lock(ctx, "email:a@x.io", now.Add(time.Minute)))
+	attempt, _ = attempts.Get(ctx, "email:a@x.io")
+	if !attempt.LockedUntil.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected the lock to be stored, got %v", attempt.LockedUntil)
+	}
+	if other, _ := attempts.Get(inTenant(tenantB), "email:a@x.io"); other.Failures != 0 {
+		t.Errorf("tenants must not share counters, got %+v", other)
+	}
+
+	mustNotErr(t, attempts.Reset(ctx, "email:a@x.io"))
+	if attempt, _ := attempts.Get(ctx, "email:a@x.io"); attempt.Failures != 0 {
+		t.Errorf("expected the record to be reset, got %+v", attempt)
+	}
+
+	_, err = attempts.RecordFailure(ctx, "email:a@x.io", now, now.Add(-time.Hour))
+	mustNotErr(t, err)
+	mustNotErr(t, attempts.SetUnlockToken(ctx, "email:a@x.io", "hash"))
+	if unlocked, err := attempts.ConsumeUnlockToken(ctx, "email:a@x.io", "other"); err != nil || unlocked {
+		t.Errorf("a wrong token must not unlock, got %v %v", unlocked, err)
+	}
+	if unlocked, err := attempts.ConsumeUnlockToken(ctx, "email:a@x.io", "hash"); err != nil || !unlocked {
+		t.Errorf("expected the token to unlock, got %v %v", unlocked, err)
+	}
+	if unlocked, _ := attempts.ConsumeUnlockToken(ctx, "email:a@x.io", "hash"); unlocked {
+		t.Error("a token must unlock once")
+	}
+	if attempt, _ := attempts.Get(ctx, "email:a@x.io"); attempt.Failures != 0 {
+		t.Errorf("expected the record to be reset, got %+v", attempt)
+	}
+}
+
+// TestIdempotency checks that a key is reserved once per tenant until it
+// is released or expires, and that the stored response is returned.
+func TestIdempotency(t *testing.T, newSet Factory) {
+	keys := newSet(t).Idempotency
+	ctx := inTenant(tenantA)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	record := func(key string, fingerprint string, expiresAt time.Time) domain.IdempotencyRecord {
+		return domain.IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: now, ExpiresAt: expiresAt}
+	}
+
+	_, reserved, err := keys.Reserve(ctx, record("k1", "f1", now.Add(time.Hour)))
+	mustNotErr(t, err)
+	if !reserved {
+		t.Fatal("a new key should be reserved")
+	}
+
+	stored, reserved, err := keys.Reserve(ctx, record("k1", "f2", now.Add(time.Hour)))
+	mustNotErr(t, err)
+	if reserved || stored.Key != "k1" || stored.Fingerprint != "f1" || stored.Status != 0 {
+		t.Fatalf("expected the pending record of the first request, got %v %+v", reserved, stored)
+	}
+
+	header := map[string]string{"Location": "/loans/1", "ETag": `"1"`}
+	mustNotErr(t, keys.Complete(ctx, "k1", 201, "application/json", header, []byte(`{"id":1}`)))
+	stored, _, err = keys.Reserve(ctx, record("k1", "f1", now.Add(time.Hour)))
+	mustNotErr(t, err)
+	if stored.Status != 201 || stored.ContentType != "application/json" || !reflect.DeepEqual(stored.Header, header) || string(stored.Body) != `{"id":1}` {
+		t.Errorf("expected the stored response, got %+v", stored)
+	}
+	// a completed response is never overwritten
+	mustNotErr(t, keys.Complete(ctx, "k1", 500, "text/plain", nil, nil))
+	if stored, _, _ := keys.Reserve(ctx, record("k1", "f1", now.Add(time.Hour))); stored.Status != 201 {
+		t.Errorf("the first response should be kept, got status %d", stored.Status)
+	}
+
+	if _, reserved, _ := keys.Reserve(inTenant(tenantB), record("k1", "f1", now.Add(time.Hour))); !reserved {
+		t.Error("tenants must not share keys")
+	}
+
+	mustNotErr(t, keys.Release(ctx, "k1"))
+	if _, reserved, _ := keys.Reserve(ctx, record("k1", "f3", now.Add(time.Hour))); !reserved {
+		t.Error("a released key should be reserved again")
+	}
+
+	_, _, err = keys.Reserve(ctx, record("k2", "f1", now.Add(-time.Second)))
+	mustNotErr(t, err)
+	if _, reserved, _ := keys.Reserve(ctx, record("k2", "f2", now.Add(time.Hour))); !reserved {
+		t.Error("an expired key should be reserved again")
+	}
+}
+
+// TestTenants checks the tenant registry.
+func TestTenants(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := context.Background()
+
+	primary, err := repos.Tenants.EnsurePrimary(ctx, "default")
+	mustNotErr(t, err)
+	if !primary.Primary || !primary.Active {
+		t.Errorf("unexpected primary tenant: %+v", primary)
+	}
+	again, err := repos.Tenants.EnsurePrimary(ctx, "default")
+	mustNotErr(t, err)
+	if again.ID != primary.ID {
+		t.Error("EnsurePrimary should keep the existing tenant")
+	}
+
+	now := time.Now()
+	acme, err := repos.Tenants.Create(ctx, domain.Tenant{Name: "Acme", Slug: "acme", Active: true, CreatedAt: now, UpdatedAt: now})
+	mustNotErr(t, err)
+	_, err = repos.Tenants.Create(ctx, domain.Tenant{Name: "Acme 2", Slug: "acme"})
+	expectErr(t, err, repository.ErrTenantSlugTaken)
+
+	found, err := repos.Tenants.GetBySlug(ctx, "acme")
+	mustNotErr(t, err)
+	if found.ID != acme.ID {
+		t.Errorf("GetBySlug returned %s", found.ID.Hex())
+	}
+	_, err = repos.Tenants.GetByID(ctx, "bad")
+	expectErr(t, err, repository.ErrTenantNotFound)
+
+	inactive := false
+	updated, err := repos.Tenants.Update(ctx, acme.ID.Hex(), domain.UpdateTenantRequest{Active: &inactive})
+	mustNotErr(t, err)
+	if updated.Active || updated.Name != "Acme" {
+		t.Errorf("unexpected updated tenant: %+v", updated)
+	}
+
+	claimed, err := repos.Tenants.ClaimOwner(ctx, primary.ID.Hex(), "first@x.io")
+	mustNotErr(t, err)
+	if claimed.OwnerEmail != "first@x.io" {
+		t.Errorf("expected the owner to be claimed, got %+v", claimed)
+	}
+	claimed, err = repos.Tenants.ClaimOwner(ctx, primary.ID.Hex(), "second@x.io")
+	mustNotErr(t, err)
+	if claimed.OwnerEmail != "first@x.io" {
+		t.Errorf("the owner must be claimed once, got %+v", claimed)
+	}
+
+	tenants, err := repos.Tenants.List(ctx)
+	mustNotErr(t, err)
+	if len(tenants) != 2 || tenants[0].Slug != "default" {
+		t.Errorf("expected the tenants in creation order, got %+v", tenants)
+	}
+}
+
+// TestBranches checks branches and their codes.
+func TestBranches(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+
+	east, err := repos.Branches.Create(ctx, domain.Branch{Name: "East", Code: "E1", Active: true})
+	mustNotErr(t, err)
+	_, err = repos.Branches.Create(ctx, domain.Branch{Name: "East again", Code: "E1"})
+	expectErr(t, err, repository.ErrBranchCodeTaken)
+	_, err = repos.Branches.Create(inTenant(tenantB), domain.Branch{Name: "East", Code: "E1"})
+	mustNotErr(t, err)
+	_, err = repos.Branches.Create(ctx, domain.Branch{Name: "Central", Code: "C1"})
+	mustNotErr(t, err)
+
+	branches, err := repos.Branches.List(ctx)
+	mustNotErr(t, err)
+	if len(branches) != 2 || branches[0].Name != "Central" {
+		t.Errorf("expected the branches by name, got %+v", branches)
+	}
+
+	_, err = repos.Branches.GetByID(inTenant(tenantB), east.ID.Hex())
+	expectErr(t, err, repository.ErrBranchNotFound)
+	name := "East side"
+	updated, err := repos.Branches.Update(ctx, east.ID.Hex(), domain.UpdateBranchRequest{Name: &name})
+	mustNotErr(t, err)
+	if updated.Name != name || !updated.Active {
+		t.Errorf("unexpected updated branch: %+v", updated)
+	}
+}
+
+// TestLoans checks that loans move from one status to the next only once.
+func TestLoans(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	older, err := repos.Loans.Create(ctx, domain.Loan{BorrowerID: "b1", Amount: 5000, TermMonths: 6, Status: domain.LoanStatusPending, CreatedAt: now.Add(-time.Hour), UpdatedAt: now})
+	mustNotErr(t, err)
+	loan, err := repos.Loans.Create(ctx, domain.Loan{BorrowerID: "b1", Amount: 10000, TermMonths: 12, Status: domain.LoanStatusPending, CreatedAt: now, UpdatedAt: now})
+	mustNotErr(t, err)
+	_, err = repos.Loans.Create(inTenant(tenantB), domain.Loan{BorrowerID: "b1", Amount: 1, TermMonths: 1, Status: domain.LoanStatusPending, CreatedAt: now, UpdatedAt: now})
+	mustNotErr(t, err)
+
+	loans, err := repos.Loans.ListByBorrower(ctx, "b1")
+	mustNotErr(t, err)
+	if len(loans) != 2 || loans[0].ID != loan.ID || loans[1].ID != older.ID {
+		t.Errorf("expected the loans of the tenant newest first, got %+v", loans)
+	}
+	spec := domain.QuerySpec{SortBy: "created_at", SortDesc: true, Limit: 1}
+	page, err := repos.Loans.List(ctx, spec)
+	mustNotErr(t, err)
+	if page.Total != 2 || len(page.Items) != 1 || page.Items[0].ID != loan.ID || page.NextCursor == "" {
+		t.Fatalf("unexpected first page of loans: %+v", page)
+	}
+	spec.Cursor = page.NextCursor
+	page, err = repos.Loans.List(ctx, spec)
+	mustNotErr(t, err)
+	if len(page.Items) != 1 || page.Items[0].ID != older.ID || page.NextCursor != "" {
+		t.Errorf("unexpected last page of loans: %+v", page)
+	}
+	_, err = repos.Loans.GetByID(inTenant(tenantB), loan.ID.Hex())
+	expectErr(t, err, repository.ErrLoanNotFound)
+	_, err = repos.Loans.GetByID(ctx, "not an id")
+	expectErr(t, err, repository.ErrLoanNotFound)
+
+	if loan.Version != 1 {
+		t.Errorf("expected a new loan at version 1, got %d", loan.Version)
+	}
+
+	approve := domain.LoanTransition{From: domain.LoanStatusPending, To: domain.LoanStatusApproved, Version: 1, ActorID: "o1", At: now}
+	approved, err := repos.Loans.Transition(ctx, loan.ID.Hex(), approve)
+	mustNotErr(t, err)
+	if approved.Status != domain.LoanStatusApproved || approved.ApprovedBy != "o1" || !approved.ApprovedAt.Equal(now) || approved.Amount != 10000 || approved.Version != 2 {
+		t.Errorf("unexpected approved loan: %+v", approved)
+	}
+	_, err = repos.Loans.Transition(ctx, loan.ID.Hex(), approve)
+	var conflict *repository.ConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != 1 || conflict.Current != 2 {
+		t.Errorf("expected a conflict with version 2, got %v", err)
+	}
+	approve.Version = 0
+	_, err = repos.Loans.Transition(ctx, loan.ID.Hex(), approve)
+	expectErr(t, err, repository.ErrLoanNotFound)
+
+	disburse := domain.LoanTransition{From: domain.LoanStatusApproved, To: domain.LoanStatusDisbursed, ActorID: "a1", At: now}
+	_, err = repos.Loans.Transition(ctx, loan.ID.Hex(), disburse)
+	mustNotErr(t, err)
+	stored, err := repos.Loans.GetByID(ctx, loan.ID.Hex())
+	mustNotErr(t, err)
+	if stored.Status != domain.LoanStatusDisbursed || stored.DisbursedBy != "a1" || stored.ApprovedBy != "o1" {
+		t.Errorf("unexpected disbursed loan: %+v", stored)
+	}
+}
+
+// TestPortfolio checks officer assignments and portfolio scoping.
+func TestPortfolio(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+
+	officer := mustCreate(t, ctx, repos.Users, newUser("officer@x.io", domain.RoleLoanOfficer))
+	mine := mustCreate(t, ctx, repos.Users, newUser("mine@x.io", domain.RoleBorrower))
+	other := mustCreate(t, ctx, repos.Users, newUser("other@x.io", domain.RoleBorrower))
+
+	updated, err := repos.Users.SetOfficer(ctx, mine.ID.Hex(), officer.ID.Hex())
+	mustNotErr(t, err)
+	if updated.OfficerID != officer.ID.Hex() {
+		t.Errorf("unexpected officer %q", updated.OfficerID)
+	}
+	updated, err = repos.Users.SetBranch(ctx, mine.ID.Hex(), "b1")
+	mustNotErr(t, err)
+	if updated.BranchID != "b1" {
+		t.Errorf("unexpected branch %q", updated.BranchID)
+	}
+
+	portfolio := domain.WithPortfolio(ctx, officer.ID.Hex())
+	if _, err := repos.Users.GetByID(portfolio, mine.ID.Hex()); err != nil {
+		t.Errorf("the officer should see their borrower: %v", err)
+	}
+	_, err = repos.Users.GetByID(portfolio, other.ID.Hex())
+	expectErr(t, err, repository.ErrUserNotFound)
+	page, err := repos.Users.List(portfolio, domain.QuerySpec{SortBy: "created_at", Limit: 10})
+	mustNotErr(t, err)
+	if page.Total != 1 {
+		t.Errorf("expected one borrower in the portfolio, got %d", page.Total)
+	}
+	for _, user := range []domain.User{mine, other} {
+		_, err := repos.KYC.GetOrCreate(ctx, user.ID.Hex())
+		mustNotErr(t, err)
+	}
+	records, err := repos.KYC.ListByStatus(portfolio, domain.KYCStatusNotStarted)
+	mustNotErr(t, err)
+	if len(records) != 1 || records[0].UserID != mine.ID.Hex() {
+		t.Errorf("expected the verification of the borrower in the portfolio only, got %+v", records)
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, user := range []domain.User{mine, other} {
+		_, err := repos.Loans.Create(ctx, domain.Loan{BorrowerID: user.ID.Hex(), Amount: 100, TermMonths: 1, Status: domain.LoanStatusPending, CreatedAt: now, UpdatedAt: now})
+		mustNotErr(t, err)
+	}
+	loans, err := repos.Loans.List(portfolio, domain.QuerySpec{SortBy: "created_at", Limit: 10})
+	mustNotErr(t, err)
+	if loans.Total != 1 || len(loans.Items) != 1 || loans.Items[0].BorrowerID != mine.ID.Hex() {
+		t.Errorf("expected the loan of the borrower in the portfolio only, got %+v", loans)
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, officerID := range []string{"o1", "o2"} {
+		assignment := domain.OfficerAssignment{BorrowerID: mine.ID.Hex(), OfficerID: officerID, AssignedAt: base.Add(time.Duration(i) * time.Hour)}
+		mustNotErr(t, repos.OfficerAssignments.Create(ctx, assignment))
+	}
+	history, err := repos.OfficerAssignments.ListByBorrower(ctx, mine.ID.Hex())
+	mustNotErr(t, err)
+	if len(history) != 2 || history[0].OfficerID != "o2" {
+		t.Errorf("expected the latest assignment first, got %+v", history)
+	}
+	if history, _ := repos.OfficerAssignments.ListByBorrower(inTenant(tenantB), mine.ID.Hex()); len(history) != 0 {
+		t.Errorf("another tenant should not see the history, got %d", len(history))
+	}
+}
+
+// TestSearch checks that the search finds the borrowers and loans of the
+// tenant only.
+func TestSearch(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+
+	borrower := newUser("almaz@example.com", domain.RoleBorrower)
+	borrower.FirstName = "Almaz"
+	borrower.LastName = "Tesfaye"
+	borrower = mustCreate(t, ctx, repos.Users, borrower)
+	staff := newUser("almaz.staff@example.com", domain.RoleAdmin)
+	staff.FirstName = "Almaz"
+	mustCreate(t, ctx, repos.Users, staff)
+	mustCreate(t, inTenant(tenantB), repos.Users, newUser("almaz@example.com", domain.RoleBorrower))
+	other := mustCreate(t, ctx, repos.Users, newUser("other@example.com", domain.RoleBorrower))
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	newLoan := func(borrowerID string) domain.Loan {
+		loan, err := repos.Loans.Create(ctx, domain.Loan{BorrowerID: borrowerID, Amount: 5000, TermMonths: 6, Status: domain.LoanStatusPending, CreatedAt: now, UpdatedAt: now})
+		mustNotErr(t, err)
+		return loan
+	}
+	loan := newLoan(borrower.ID.Hex())
+	otherLoan := newLoan(other.ID.Hex())
+
+	types := func(results []domain.SearchResult) []string {
+		found := make([]string, 0, len(results))
+		for _, result := range results {
+			found = append(found, result.Type+" "+result.ID)
+		}
+		return found
+	}
+	want := []string{domain.SearchResultBorrower + " " + borrower.ID.Hex(), domain.SearchResultLoan + " " + loan.ID.Hex()}
+
+	results, err := repos.Search.Search(ctx, "almaz tesfaye", 10)
+	mustNotErr(t, err)
+	if !reflect.DeepEqual(types(results), want) || results[0].Title == "" {
+		t.Fatalf("expected the borrower then their loan, got %+v", results)
+	}
+	results, err = repos.Search.Search(ctx, "alm", 10)
+	mustNotErr(t, err)
+	if !reflect.DeepEqual(types(results), want) {
+		t.Errorf("expected a prefix match, got %+v", results)
+	}
+	// quotes and dashes do not make phrases or negations
+	results, err = repos.Search.Search(ctx, `"almaz -tesfaye`, 10)
+	mustNotErr(t, err)
+	if !reflect.DeepEqual(types(results), want) {
+		t.Errorf("expected punctuation to be ignored, got %+v", results)
+	}
+	if results, _ := repos.Search.Search(ctx, "nobody", 10); len(results) != 0 {
+		t.Errorf("expected no results, got %+v", results)
+	}
+
+	results, err = repos.Search.Search(ctx, otherLoan.ID.Hex(), 10)
+	mustNotErr(t, err)
+	if !reflect.DeepEqual(types(results), []string{domain.SearchResultLoan + " " + otherLoan.ID.Hex()}) {
+		t.Errorf("expected the loan by its ID, got %+v", results)
+	}
+	if results, _ := repos.Search.Search(inTenant(tenantB), otherLoan.ID.Hex(), 10); len(results) != 0 {
+		t.Errorf("expected no loans of another tenant, got %+v", results)
+	}
+	if results, _ := repos.Search.Search(domain.WithPortfolio(ctx, "o1"), otherLoan.ID.Hex(), 10); len(results) != 0 {
+		t.Errorf("expected no loans outside the portfolio, got %+v", results)
+	}
+}
+
+// TestTx checks that the work done within a transaction is stored all or
+// nothing.
+func TestTx(t *testing.T, newSet Factory) {
+	repos := newSet(t)
+	ctx := inTenant(tenantA)
+	user := mustCreate(t, ctx, repos.Users, newUser("tx@example.com", domain.RoleBorrower))
+	id := user.ID.Hex()
+	otp := &domain.OtpSave{Email: "tx@example.com", Code: "123456", ExpiresAt: time.Now().Add(time.Minute)}
+	mustNotErr(t, repos.ResetPassword.SaveOtp(ctx, otp))
+
+	// a failure undoes everything, including a nested transaction
+	failure := errors.New("failure")
+	err := repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+			return repos.ResetPassword.ResetPassword(ctx, id, &domain.ResetPasswordRequest{NewPassword: "hash-1"})
+		})
+		if err != nil {
+			return err
+		}
+		// the transaction sees its own writes
+		got, err := repos.Users.GetByID(ctx, id)
+		mustNotErr(t, err)
+		if got.Password != "hash-1" {
+			t.Errorf("expected the new password within the transaction, got %q", got.Password)
+		}
+		mustNotErr(t, repos.ResetPassword.DeleteOtp(ctx, "tx@example.com"))
+		return failure
+	})
+	expectErr(t, err, failure)
+	got, _ := repos.Users.GetByID(ctx, id)
+	if got.Password != "hash-0" {
+		t.Errorf("expected the password rolled back, got %q", got.Password)
+	}
+	_, err = repos.ResetPassword.GetOTPByEmail(ctx, "tx@example.com")
+	mustNotErr(t, err)
+
+	err = repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := repos.ResetPassword.ResetPassword(ctx, id, &domain.ResetPasswordRequest{NewPassword: "hash-1"})
+		if err != nil {
+			return err
+		}
+		return repos.ResetPassword.DeleteOtp(ctx, "tx@example.com")
+	})
+	mustNotErr(t, err)
+	got, _ = repos.Users.GetByID(ctx, id)
+	if got.Password != "hash-1" {
+		t.Errorf("expected the password committed, got %q", got.Password)
+	}
+	_, err = repos.ResetPassword.GetOTPByEmail(ctx, "tx@example.com")
+	expectErr(t, err, repository.ErrUserNotFound)
+}
@@ -0,0 +1,252 @@
+// Package search ranks records against free text queries and points out
+// where the terms matched. It also provides an in-memory index for tests
+// and for running without a database.
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Document is a searchable record. Fields holds the searchable text by
+// field name.
+type Document struct {
+	Type   string
+	ID     string
+	Title  string
+	Fields map[string]string
+}
+
+// field weights; identifiers that are only ever searched in full weigh the
+// most
+var fieldWeights = map[string]float64{
+	"name":        3,
+	"email":       2,
+	"phone":       5,
+	"national_id": 5,
+	"loan_id":     5,
+}
+
+// BorrowerDocument returns the searchable fields of a borrower.
+func BorrowerDocument(user domain.User) Document {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	return Document{
+		Type:  domain.SearchResultBorrower,
+		ID:    user.ID.Hex(),
+		Title: name,
+		Fields: map[string]string{
+			"name":        name,
+			"email":       user.Email,
+			"phone":       user.Profile.Phone,
+			"national_id": user.Profile.NationalID,
+		},
+	}
+}
+
+// LoanDocument returns the searchable fields of a loan, which is found by
+// its ID, its status or its borrower.
+func LoanDocument(loan domain.Loan, borrower domain.User) Document {
+	name := strings.TrimSpace(borrower.FirstName + " " + borrower.LastName)
+	return Document{
+		Type:  domain.SearchResultLoan,
+		ID:    loan.ID.Hex(),
+		Title: "Loan of " + name,
+		Fields: map[string]string{
+			"loan_id":        loan.ID.Hex(),
+			"status":         string(loan.Status),
+			"borrower":       name,
+			"borrower_email": borrower.Email,
+		},
+	}
+}
+
+// IDs returns the terms that are record IDs, which the indexes look up
+// directly rather than by text.
+func IDs(terms []string) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0)
+	for _, term := range terms {
+		if id, err := primitive.ObjectIDFromHex(term); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Terms splits a query into lower case terms.
+func Terms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// Score rates how well doc matches terms. A term scores fully when it is a
+// whole word of a field and half when it only starts one. Every term must
+// match somewhere, otherwise the score is zero.
+func Score(doc Document, terms []string) (float64, []domain.Highlight) {
+	if len(terms) == 0 {
+		return 0, nil
+	}
+
+	score := 0.0
+	matched := make([]bool, len(terms))
+	highlights := make([]domain.Highlight, 0)
+
+	fields := make([]string, 0, len(doc.Fields))
+	for field := range doc.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		text := doc.Fields[field]
+		weight := fieldWeights[field]
+		if weight == 0 {
+			weight = 1
+		}
+
+		var ranges []domain.TextRange
+		for _, word := range words(text) {
+			for i, term := range terms {
+				switch {
+				case word.text == term:
+					score += weight
+				case strings.HasPrefix(word.text, term):
+					score += weight / 2
+				default:
+					continue
+				}
+				matched[i] = true
+				ranges = append(ranges, domain.TextRange{Start: word.start, End: word.start + len([]rune(term))})
+			}
+		}
+		if len(ranges) > 0 {
+			highlights = append(highlights, domain.Highlight{Field: field, Text: text, Ranges: mergeRanges(ranges)})
+		}
+	}
+
+	for _, ok := range matched {
+		if !ok {
+			return 0, nil
+		}
+	}
+	return score, highlights
+}
+
+type word struct {
+	text  string
+	start int
+}
+
+// words splits text like Terms does, keeping the character offset of
+// every word.
+func words(text string) []word {
+	var result []word
+	var current []rune
+	start := 0
+	for i, r := range []rune(text) {
+		if isSeparator(r) {
+			if len(current) > 0 {
+				result = append(result, word{text: string(current), start: start})
+				current = current[:0]
+			}
+			continue
+		}
+		if len(current) == 0 {
+			start = i
+		}
+		current = append(current, unicode.ToLower(r))
+	}
+	if len(current) > 0 {
+		result = append(result, word{text: string(current), start: start})
+	}
+	return result
+}
+
+func mergeRanges(ranges []domain.TextRange) []domain.TextRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Rank scores every document, drops the ones that do not match and returns
+// the best limit results.
+func Rank(docs []Document, terms []string, limit int) []domain.SearchResult {
+	results := make([]domain.SearchResult, 0)
+	for _, doc := range docs {
+		score, highlights := Score(doc, terms)
+		if score == 0 {
+			continue
+		}
+		results = append(results, domain.SearchResult{
+			Type:       doc.Type,
+			ID:         doc.ID,
+			Title:      doc.Title,
+			Score:      score,
+			Highlights: highlights,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Index is an in-memory domain.SearchIndex.
+type Index struct {
+	mu   sync.RWMutex
+	docs map[string]Document
+}
+
+func NewIndex() *Index {
+	return &Index{docs: make(map[string]Document)}
+}
+
+// Put adds doc to the index, replacing the document of the same type and ID.
+func (idx *Index) Put(doc Document) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.docs[doc.Type+"/"+doc.ID] = doc
+}
+
+func (idx *Index) Delete(docType string, id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.docs, docType+"/"+id)
+}
+
+// Search implements domain.SearchIndex.
+func (idx *Index) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	idx.mu.RLock()
+	docs := make([]Document, 0, len(idx.docs))
+	for _, doc := range idx.docs {
+		docs = append(docs, doc)
+	}
+	idx.mu.RUnlock()
+
+	return Rank(docs, Terms(query), limit), nil
+}
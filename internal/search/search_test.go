@@ -0,0 +1,95 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testIndex() *Index {
+	idx := NewIndex()
+	idx.Put(Document{Type: domain.SearchResultBorrower, ID: "1", Title: "John Smith", Fields: map[string]string{
+		"name": "John Smith", "email": "john@example.com", "phone": "+251911000001",
+	}})
+	idx.Put(Document{Type: domain.SearchResultBorrower, ID: "2", Title: "Johnny Appleseed", Fields: map[string]string{
+		"name": "Johnny Appleseed", "email": "apple@example.com", "national_id": "AB12345",
+	}})
+	idx.Put(Document{Type: domain.SearchResultBorrower, ID: "3", Title: "Mary Jones", Fields: map[string]string{
+		"name": "Mary Jones", "email": "mary@example.com",
+	}})
+	return idx
+}
+
+func TestSearchRanksWholeWordsFirst(t *testing.T) {
+	results, err := testIndex().Search(context.Background(), "john", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "2"}) {
+		t.Errorf("got %v, want [1 2]", ids)
+	}
+}
+
+func TestSearchRequiresEveryTerm(t *testing.T) {
+	results, _ := testIndex().Search(context.Background(), "john apple", 10)
+	if len(results) != 1 || results[0].ID != "2" {
+		t.Errorf("got %+v, want only 2", results)
+	}
+}
+
+func TestSearchIdentifiers(t *testing.T) {
+	results, _ := testIndex().Search(context.Background(), "ab12345", 10)
+	if len(results) != 1 || results[0].ID != "2" {
+		t.Fatalf("got %+v, want only 2", results)
+	}
+
+	want := []domain.Highlight{{Field: "national_id", Text: "AB12345", Ranges: []domain.TextRange{{Start: 0, End: 7}}}}
+	if !reflect.DeepEqual(results[0].Highlights, want) {
+		t.Errorf("got %+v, want %+v", results[0].Highlights, want)
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	results, _ := testIndex().Search(context.Background(), "smith jo", 10)
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+
+	want := []domain.Highlight{
+		{Field: "email", Text: "john@example.com", Ranges: []domain.TextRange{{Start: 0, End: 2}}},
+		{Field: "name", Text: "John Smith", Ranges: []domain.TextRange{{Start: 0, End: 2}, {Start: 5, End: 10}}},
+	}
+	if !reflect.DeepEqual(results[0].Highlights, want) {
+		t.Errorf("got %+v, want %+v", results[0].Highlights, want)
+	}
+}
+
+func TestSearchLimitAndDelete(t *testing.T) {
+	idx := testIndex()
+	results, _ := idx.Search(context.Background(), "example", 2)
+	if len(results) != 2 {
+		t.Errorf("got %d results, want 2", len(results))
+	}
+
+	idx.Delete(domain.SearchResultBorrower, "3")
+	results, _ = idx.Search(context.Background(), "mary", 10)
+	if len(results) != 0 {
+		t.Errorf("got %+v after delete, want none", results)
+	}
+}
+
+func TestIDs(t *testing.T) {
+	id := primitive.NewObjectID()
+	got := IDs(Terms("loan " + id.Hex() + " 1234"))
+	if !reflect.DeepEqual(got, []primitive.ObjectID{id}) {
+		t.Errorf("got %v, want [%s]", got, id.Hex())
+	}
+}
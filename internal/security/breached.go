@@ -0,0 +1,77 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// rangePrefixLength is the number of hex characters of the SHA-1 hash used
+// as the k-anonymity range key, the same split the Pwned Passwords API uses.
+const rangePrefixLength = 5
+
+// BreachedPasswordList is a local copy of breached password hashes, indexed
+// by SHA-1 prefix so that lookups work like the Pwned Passwords range API.
+type BreachedPasswordList struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswordList reads a file of upper or lower case SHA-1 hashes,
+// one per line, optionally followed by ":<count>" as in the Pwned Passwords
+// downloads. Blank lines and lines starting with "#" are ignored.
+func LoadBreachedPasswordList(path string) (*BreachedPasswordList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedPasswordList{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(entry, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-1 hash", path, line)
+		}
+		list.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (l *BreachedPasswordList) add(hash string) {
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+	if l.ranges[prefix] == nil {
+		l.ranges[prefix] = make(map[string]struct{})
+	}
+	l.ranges[prefix][suffix] = struct{}{}
+}
+
+// Range returns the hash suffixes known for a 5 character SHA-1 prefix.
+func (l *BreachedPasswordList) Range(prefix string) []string {
+	suffixes := make([]string, 0, len(l.ranges[strings.ToUpper(prefix)]))
+	for suffix := range l.ranges[strings.ToUpper(prefix)] {
+		suffixes = append(suffixes, suffix)
+	}
+	return suffixes
+}
+
+// IsBreached implements BreachedPasswordChecker.
+func (l *BreachedPasswordList) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, found := l.ranges[hash[:rangePrefixLength]][hash[rangePrefixLength:]]
+	return found, nil
+}
@@ -0,0 +1,25 @@
+package security
+
+import "time"
+
+// LockoutDelay returns how long a key with the given number of consecutive
+// failures stays locked. Up to threshold-1 failures are free; from then on
+// the delay starts at base and doubles with every further failure, capped
+// at max.
+func LockoutDelay(failures, threshold int, base, max time.Duration) time.Duration {
+	if threshold <= 0 || failures < threshold {
+		return 0
+	}
+
+	delay := base
+	for i := threshold; i < failures; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
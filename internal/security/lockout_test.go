@@ -0,0 +1,38 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDelay(t *testing.T) {
+	base := 30 * time.Second
+	max := 10 * time.Minute
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{7, 2 * time.Minute},
+		{9, 8 * time.Minute},
+		{10, max},
+		{100, max},
+	}
+
+	for _, tt := range tests {
+		got := LockoutDelay(tt.failures, 5, base, max)
+		if got != tt.want {
+			t.Errorf("LockoutDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutDelayDisabled(t *testing.T) {
+	if got := LockoutDelay(50, 0, time.Second, time.Minute); got != 0 {
+		t.Errorf("expected no delay when threshold is disabled, got %v", got)
+	}
+}
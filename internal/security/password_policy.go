@@ -0,0 +1,179 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+// bcryptMaxLength is how many bytes of a password bcrypt hashes; it
+// ignores the rest.
+const bcryptMaxLength = 72
+
+// minPersonalInfoLength is the shortest personal value (name, email local
+// part) a password is checked against; shorter values match too often.
+const minPersonalInfoLength = 3
+
+// BreachedPasswordChecker reports whether a password is known to have
+// appeared in a data breach.
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// PasswordPolicy describes what makes a password acceptable.
+type PasswordPolicy struct {
+	MinLength int
+	// MaxLength is counted in bytes, as bcrypt ignores what follows the
+	// first 72 of them.
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is how many of the most recent passwords, the current one
+	// included, may not be reused. Zero disables the check.
+	HistorySize int
+	// Breached is consulted when set.
+	Breached BreachedPasswordChecker
+}
+
+// PolicyError lists every rule a password broke.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// Unwrap presents the rejection as a validation error of the password field.
+func (e *PolicyError) Unwrap() error {
+	return domain.InvalidFields("weak_password", "password does not meet the policy", map[string]string{
+		"password": strings.Join(e.Violations, "; "),
+	})
+}
+
+// NewPasswordPolicy builds the policy configured in env, loading the
+// breached password list when one is configured.
+func NewPasswordPolicy(env *bootstrap.Env) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:     env.PasswordMinLength,
+		MaxLength:     env.PasswordMaxLength,
+		RequireUpper:  env.PasswordRequireUpper,
+		RequireLower:  env.PasswordRequireLower,
+		RequireDigit:  env.PasswordRequireDigit,
+		RequireSymbol: env.PasswordRequireSymbol,
+		HistorySize:   env.PasswordHistorySize,
+	}
+	if policy.MaxLength > bcryptMaxLength {
+		return nil, fmt.Errorf("the maximum password length is %d bytes, as bcrypt ignores the rest; got %d", bcryptMaxLength, policy.MaxLength)
+	}
+
+	if env.BreachedPasswordsFile != "" {
+		list, err := LoadBreachedPasswordList(env.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = list
+	}
+	return policy, nil
+}
+
+// Validate checks password against the policy. personal holds values such
+// as the email and names of the user, which the password may not contain.
+// A *PolicyError is returned when the password is rejected.
+func (p *PasswordPolicy) Validate(password string, personal ...string) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if containsPersonalInfo(password, personal) {
+		violations = append(violations, "must not contain your name or email")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violations = append(violations, "has appeared in a data breach, choose a different one")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// CheckReuse rejects password when it matches one of the most recent
+// password hashes, which are ordered newest first.
+func (p *PasswordPolicy) CheckReuse(password string, hashes []string) error {
+	if p.HistorySize <= 0 {
+		return nil
+	}
+	if len(hashes) > p.HistorySize {
+		hashes = hashes[:p.HistorySize]
+	}
+
+	for _, hash := range hashes {
+		if hash != "" && CheckPasswordHash(password, hash) {
+			return &PolicyError{Violations: []string{fmt.Sprintf("must not match any of your last %d passwords", p.HistorySize)}}
+		}
+	}
+	return nil
+}
+
+func containsPersonalInfo(password string, personal []string) bool {
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		candidates := []string{value}
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			candidates = append(candidates, local)
+		}
+
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= minPersonalInfoLength && strings.Contains(lowered, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package security
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := &PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    72,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	}
+
+	tests := []struct {
+		name       string
+		password   string
+		violations int
+	}{
+		{"valid", "Correct8Horse", 0},
+		{"too short", "Ab1", 1},
+		{"no upper", "lowercase123", 1},
+		{"no digit or upper", "lowercaseonly", 2},
+		{"contains email", "Abebe2024Secure", 1},
+		{"contains name", "Kebede2024Secure", 1},
+		{"too long in bytes", "Ab1" + strings.Repeat("é", 35), 1},
+	}
+
+	for _, tt := range tests {
+		err := policy.Validate(tt.password, "abebe@example.com", "Kebede")
+		if tt.violations == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+
+		var policyErr *PolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("%s: expected a PolicyError, got %v", tt.name, err)
+			continue
+		}
+		if len(policyErr.Violations) != tt.violations {
+			t.Errorf("%s: expected %d violations, got %v", tt.name, tt.violations, policyErr.Violations)
+		}
+	}
+}
+
+func TestPasswordPolicyCheckReuse(t *testing.T) {
+	policy := &PasswordPolicy{HistorySize: 2}
+
+	current, _ := HashPassword("current")
+	previous, _ := HashPassword("previous")
+	older, _ := HashPassword("older")
+	history := []string{current, previous, older}
+
+	if err := policy.CheckReuse("previous", history); err == nil {
+		t.Error("expected reuse of a recent password to be rejected")
+	}
+	if err := policy.CheckReuse("older", history); err != nil {
+		t.Errorf("password outside the history window should be allowed, got %v", err)
+	}
+}
+
+func TestBreachedPasswordList(t *testing.T) {
+	// SHA-1 of "password" and "letmein"
+	content := "# sample\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\nb7a875fc1ea228b9061041b7cec4bd3c52ab3ce3\n"
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadBreachedPasswordList(path)
+	if err != nil {
+		t.Fatalf("LoadBreachedPasswordList returned an error: %v", err)
+	}
+
+	for _, password := range []string{"password", "letmein"} {
+		if breached, _ := list.IsBreached(password); !breached {
+			t.Errorf("expected %q to be reported as breached", password)
+		}
+	}
+	if breached, _ := list.IsBreached("Correct8Horse"); breached {
+		t.Error("unexpected breach report")
+	}
+	if suffixes := list.Range("5baa6"); len(suffixes) != 1 {
+		t.Errorf("expected one suffix in range 5BAA6, got %v", suffixes)
+	}
+
+	policy := &PasswordPolicy{Breached: list}
+	if err := policy.Validate("password"); err == nil {
+		t.Error("expected the policy to reject a breached password")
+	}
+}
+
+func TestLoadBreachedPasswordListRejectsGarbage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte("not-a-hash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadBreachedPasswordList(path); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+}
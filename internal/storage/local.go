@@ -0,0 +1,88 @@
+// Package storage keeps uploaded files outside of the database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Local stores files below a directory of the local file system.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a Local storage rooted at dir, creating it if needed.
+func NewLocal(dir string) (*Local, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{root: dir}, nil
+}
+
+// Save writes content to key, replacing any previous file.
+func (l *Local) Save(ctx context.Context, key string, content io.Reader) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so a failed upload never leaves a
+	// partial document behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open returns the content stored under key.
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes the file stored under key. A missing file is not an
+// error.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path resolves key below the root, rejecting keys that escape it.
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || clean == "/" || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
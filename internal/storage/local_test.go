@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalSaveOpen(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	err = store.Save(ctx, "user/doc.pdf", strings.NewReader("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := store.Open(ctx, "user/doc.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	got, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "content" {
+		t.Errorf("Open returned %q, want %q", got, "content")
+	}
+}
+
+func TestLocalFailedSaveLeavesNothing(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	failure := errors.New("read failed")
+	err = store.Save(ctx, "user/doc.pdf", io.MultiReader(strings.NewReader("part"), &failingReader{failure}))
+	if !errors.Is(err, failure) {
+		t.Fatalf("Save returned %v, want %v", err, failure)
+	}
+
+	_, err = store.Open(ctx, "user/doc.pdf")
+	if err == nil {
+		t.Error("Open found a partially saved file")
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "/", "../secret", "user/../../secret"} {
+		err := store.Save(context.Background(), key, strings.NewReader("x"))
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Save(%q) returned %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+type failingReader struct {
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	return 0, f.err
+}
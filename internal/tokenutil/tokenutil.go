@@ -1,19 +1,24 @@
 package tokenutil
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/dagota12/Loan-Tracker/domain"
 	jwt "github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func CreateAccessToken(user domain.User, secret string, expiry int) (accessToken string, err error) {
 	exp := time.Now().Add(time.Hour * time.Duration(expiry))
 	claims := &domain.JwtCustomClaims{
-		Role:    user.Role,
-		IsOwner: user.IsOwner,
-		ID:      user.ID.Hex(),
+		Role:         user.Role,
+		IsOwner:      user.IsOwner,
+		ID:           user.ID.Hex(),
+		TenantID:     user.TenantID,
+		Permissions:  domain.PermissionsForRole(user.Role),
+		TokenVersion: user.TokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
@@ -26,10 +31,35 @@ func CreateAccessToken(user domain.User, secret string, expiry int) (accessToken
 	return t, err
 }
 
-func CreateVerificationToken(user *domain.User, secret string, expiry int) (accessToken string, err error) {
-	exp := time.Now().Add(time.Hour * time.Duration(expiry))
+// CreateImpersonationToken signs an access token for user that records
+// actorID as the admin acting on their behalf. It carries no refresh token.
+func CreateImpersonationToken(user domain.User, actorID string, secret string, expiryMin int) (accessToken string, expiresAt time.Time, err error) {
+	exp := time.Now().Add(time.Minute * time.Duration(expiryMin))
+	claims := &domain.JwtCustomClaims{
+		Role:         user.Role,
+		ID:           user.ID.Hex(),
+		TenantID:     user.TenantID,
+		Permissions:  domain.PermissionsForRole(user.Role),
+		Act:          &domain.ActorClaim{Subject: actorID},
+		TokenVersion: user.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return t, exp, nil
+}
+
+func CreateVerificationToken(user *domain.User, secret string, expiryMin int) (accessToken string, err error) {
+	exp := time.Now().Add(time.Minute * time.Duration(expiryMin))
 	claims := &domain.JwtCustomClaims{
-		ID: user.ID.Hex(),
+		ID:       user.ID.Hex(),
+		TenantID: user.TenantID,
+		Purpose:  domain.TokenPurposeVerifyEmail,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
@@ -41,11 +71,52 @@ func CreateVerificationToken(user *domain.User, secret string, expiry int) (acce
 	}
 	return t, err
 }
+
+// CreateUnlockToken signs a short lived token that unlocks the account of
+// user. Every token gets a unique ID, so that only the last one issued
+// matches the hash stored with the lock.
+func CreateUnlockToken(user *domain.User, secret string, expiryMin int) (unlockToken string, err error) {
+	exp := time.Now().Add(time.Minute * time.Duration(expiryMin))
+	claims := &domain.JwtCustomClaims{
+		ID:       user.ID.Hex(),
+		TenantID: user.TenantID,
+		Purpose:  domain.TokenPurposeUnlock,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// CreateMagicLinkToken signs the token of a passwordless sign in link. Every
+// token gets a unique ID so that it can be tracked as single use.
+func CreateMagicLinkToken(user *domain.User, secret string, expiryMin int) (magicLinkToken string, err error) {
+	exp := time.Now().Add(time.Minute * time.Duration(expiryMin))
+	claims := &domain.JwtCustomClaims{
+		ID:       user.ID.Hex(),
+		TenantID: user.TenantID,
+		Purpose:  domain.TokenPurposeMagicLink,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// CreateRefreshToken signs a refresh token of user. Every token gets a
+// unique ID, so that a token rotated within the same second differs from
+// the one it replaces.
 func CreateRefreshToken(user *domain.User, secret string, expiry int) (refreshToken string, err error) {
 	exp := time.Now().Add(time.Hour * time.Duration(expiry))
 	claimsRefresh := &domain.JwtCustomRefreshClaims{
-		ID: user.ID.Hex(),
+		ID:       user.ID.Hex(),
+		TenantID: user.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
 	}
@@ -104,3 +175,10 @@ func ExtractUserClaimsFromToken(requestToken string, secret string) (jwt.MapClai
 
 	return claims, nil
 }
+
+// WithTokenTenant scopes ctx to the tenant the token of claims was issued
+// for. A token without one scopes ctx to no tenant at all.
+func WithTokenTenant(ctx context.Context, claims jwt.MapClaims) context.Context {
+	tenantID, _ := claims["tid"].(string)
+	return domain.WithTenant(ctx, tenantID)
+}
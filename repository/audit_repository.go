@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditRepository struct {
+	entries *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) domain.AuditRepository {
+	return &auditRepository{
+		entries: db.Collection(domain.CollectionAuditLog),
+	}
+}
+
+// Create implements domain.AuditRepository.
+func (ar *auditRepository) Create(ctx context.Context, entry domain.AuditEntry) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	entry.TenantID = tenantID
+
+	_, err = ar.entries.InsertOne(ctx, entry)
+	return err
+}
+
+// ListByUser implements domain.AuditRepository.
+func (ar *auditRepository) ListByUser(ctx context.Context, userID string) ([]domain.AuditEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := ar.entries.Find(ctx, scoped(ctx, bson.M{"user_id": userID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := make([]domain.AuditEntry, 0)
+	err = cursor.All(ctx, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// RedactChanges implements domain.AuditRepository.
+func (ar *auditRepository) RedactChanges(ctx context.Context, userID string, actions []string) error {
+	filter := bson.M{"user_id": userID, "action": bson.M{"$in": actions}, "changes": bson.M{"$ne": nil}}
+	update := bson.M{"$set": bson.M{
+		"changes.$[].old": domain.RedactedValue,
+		"changes.$[].new": domain.RedactedValue,
+	}}
+	_, err := ar.entries.UpdateMany(ctx, scoped(ctx, filter), update)
+	return err
+}
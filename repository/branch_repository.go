@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrBranchNotFound  = domain.NotFound("branch_not_found", "branch not found")
+	ErrBranchCodeTaken = domain.Conflict("branch_code_taken", "branch code already taken")
+)
+
+type branchRepository struct {
+	branches *mongo.Collection
+}
+
+func NewBranchRepository(db *mongo.Database) domain.BranchRepository {
+	return &branchRepository{
+		branches: db.Collection(domain.CollectionBranches),
+	}
+}
+
+// Create implements domain.BranchRepository.
+func (br *branchRepository) Create(ctx context.Context, branch domain.Branch) (domain.Branch, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	branch.TenantID = tenantID
+
+	res, err := br.branches.InsertOne(ctx, branch)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Branch{}, ErrBranchCodeTaken
+	}
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	branch.ID = res.InsertedID.(primitive.ObjectID)
+	return branch, nil
+}
+
+// GetByID implements domain.BranchRepository.
+func (br *branchRepository) GetByID(ctx context.Context, branchID string) (domain.Branch, error) {
+	objID, err := primitive.ObjectIDFromHex(branchID)
+	if err != nil {
+		return domain.Branch{}, ErrBranchNotFound
+	}
+
+	var branch domain.Branch
+	err = br.branches.FindOne(ctx, scoped(ctx, bson.M{"_id": objID})).Decode(&branch)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Branch{}, ErrBranchNotFound
+	}
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	return branch, nil
+}
+
+// List implements domain.BranchRepository.
+func (br *branchRepository) List(ctx context.Context) ([]domain.Branch, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := br.branches.Find(ctx, scoped(ctx, bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	branches := make([]domain.Branch, 0)
+	err = cursor.All(ctx, &branches)
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+// Update implements domain.BranchRepository.
+func (br *branchRepository) Update(ctx context.Context, branchID string, request domain.UpdateBranchRequest) (domain.Branch, error) {
+	objID, err := primitive.ObjectIDFromHex(branchID)
+	if err != nil {
+		return domain.Branch{}, ErrBranchNotFound
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if request.Name != nil {
+		set["name"] = *request.Name
+	}
+	if request.Active != nil {
+		set["active"] = *request.Active
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var branch domain.Branch
+	err = br.branches.FindOneAndUpdate(ctx, scoped(ctx, bson.M{"_id": objID}), bson.M{"$set": set}, opts).Decode(&branch)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Branch{}, ErrBranchNotFound
+	}
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	return branch, nil
+}
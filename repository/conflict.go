@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+// ErrVersionConflict is the cause of every *ConflictError.
+var ErrVersionConflict = domain.Conflict("version_conflict", "version conflict")
+
+// ConflictError is returned by an update based on a version of a record
+// that is no longer the stored one: someone else changed the record in
+// the meantime.
+type ConflictError struct {
+	// Expected is the version the update was based on.
+	Expected int64
+	// Current is the version stored.
+	Current int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("the record was changed: expected version %d, found version %d", e.Expected, e.Current)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
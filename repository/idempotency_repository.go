@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxReserveAttempts bounds how often Reserve retries when the record it
+// collided with disappears before it can be read.
+const maxReserveAttempts = 3
+
+type idempotencyRepository struct {
+	keys *mongo.Collection
+}
+
+func NewIdempotencyRepository(db *mongo.Database) domain.IdempotencyRepository {
+	return &idempotencyRepository{
+		keys: db.Collection(domain.CollectionIdempotencyKeys),
+	}
+}
+
+// Reserve implements domain.IdempotencyRepository.
+// Expired records are also removed by a TTL index, but only once a minute.
+func (ir *idempotencyRepository) Reserve(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	key := record.Key
+	record.Key = tenantKey(ctx, key)
+
+	for range maxReserveAttempts {
+		expired := bson.M{"_id": record.Key, "expires_at": bson.M{"$lte": time.Now()}}
+		if _, err := ir.keys.DeleteOne(ctx, expired); err != nil {
+			return domain.IdempotencyRecord{}, false, err
+		}
+
+		_, err := ir.keys.InsertOne(ctx, record)
+		if err == nil {
+			record.Key = key
+			return record, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return domain.IdempotencyRecord{}, false, err
+		}
+
+		var stored domain.IdempotencyRecord
+		err = ir.keys.FindOne(ctx, bson.M{"_id": record.Key}).Decode(&stored)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// released in the meantime
+			continue
+		}
+		if err != nil {
+			return domain.IdempotencyRecord{}, false, err
+		}
+		stored.Key = key
+		return stored, false, nil
+	}
+	return domain.IdempotencyRecord{}, false, errors.New("idempotency key keeps changing")
+}
+
+// Complete implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Complete(ctx context.Context, key string, status int, contentType string, header map[string]string, body []byte) error {
+	update := bson.M{"$set": bson.M{"status": status, "content_type": contentType, "header": header, "body": body}}
+	_, err := ir.keys.UpdateOne(ctx, bson.M{"_id": tenantKey(ctx, key), "status": 0}, update)
+	return err
+}
+
+// Release implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := ir.keys.DeleteOne(ctx, bson.M{"_id": tenantKey(ctx, key)})
+	return err
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrKYCNotFound = domain.NotFound("kyc_not_found", "kyc record not found")
+
+type kycRepository struct {
+	records *mongo.Collection
+	users   *mongo.Collection
+}
+
+func NewKYCRepository(db *mongo.Database) domain.KYCRepository {
+	return &kycRepository{
+		records: db.Collection(domain.CollectionKYC),
+		users:   db.Collection("users"),
+	}
+}
+
+// GetOrCreate implements domain.KYCRepository.
+func (kr *kycRepository) GetOrCreate(ctx context.Context, userID string) (domain.KYC, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	now := time.Now()
+	update := bson.M{"$setOnInsert": bson.M{
+		"tenant_id":  tenantID,
+		"user_id":    userID,
+		"status":     domain.KYCStatusNotStarted,
+		"documents":  []domain.KYCDocument{},
+		"created_at": now,
+		"updated_at": now,
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var record domain.KYC
+	err = kr.records.FindOneAndUpdate(ctx, scoped(ctx, bson.M{"user_id": userID}), update, opts).Decode(&record)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return record, nil
+}
+
+// GetByUserID implements domain.KYCRepository.
+func (kr *kycRepository) GetByUserID(ctx context.Context, userID string) (domain.KYC, error) {
+	var record domain.KYC
+	err := kr.records.FindOne(ctx, scoped(ctx, bson.M{"user_id": userID})).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.KYC{}, ErrKYCNotFound
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return record, nil
+}
+
+// AddDocument implements domain.KYCRepository.
+func (kr *kycRepository) AddDocument(ctx context.Context, userID string, document domain.KYCDocument) error {
+	update := bson.M{
+		"$push": bson.M{"documents": document},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	result, err := kr.records.UpdateOne(ctx, scoped(ctx, bson.M{"user_id": userID}), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrKYCNotFound
+	}
+	return nil
+}
+
+// Delete implements domain.KYCRepository.
+func (kr *kycRepository) Delete(ctx context.Context, userID string) (domain.KYC, error) {
+	var record domain.KYC
+	err := kr.records.FindOneAndDelete(ctx, scoped(ctx, bson.M{"user_id": userID})).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.KYC{}, ErrKYCNotFound
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return record, nil
+}
+
+// Transition implements domain.KYCRepository.
+func (kr *kycRepository) Transition(ctx context.Context, userID string, transition domain.KYCTransition) (domain.KYC, error) {
+	set := bson.M{
+		"status":     transition.To,
+		"updated_at": transition.At,
+	}
+	unset := bson.M{}
+	switch transition.To {
+	case domain.KYCStatusSubmitted:
+		set["submitted_at"] = transition.At
+		unset["rejection_reason"] = ""
+		unset["reviewed_by"] = ""
+		unset["reviewed_at"] = ""
+	case domain.KYCStatusApproved:
+		set["reviewed_by"] = transition.ReviewerID
+		set["reviewed_at"] = transition.At
+		set["expires_at"] = transition.ExpiresAt
+		unset["rejection_reason"] = ""
+	case domain.KYCStatusRejected:
+		set["reviewed_by"] = transition.ReviewerID
+		set["reviewed_at"] = transition.At
+		set["rejection_reason"] = transition.Reason
+		unset["expires_at"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	filter := scoped(ctx, bson.M{"user_id": userID, "status": transition.From})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var record domain.KYC
+	err := kr.records.FindOneAndUpdate(ctx, filter, update, opts).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.KYC{}, ErrKYCNotFound
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return record, nil
+}
+
+// ListByStatus implements domain.KYCRepository.
+func (kr *kycRepository) ListByStatus(ctx context.Context, status domain.KYCStatus) ([]domain.KYC, error) {
+	filter := bson.M{"status": status}
+	if _, ok := domain.PortfolioFromContext(ctx); ok {
+		userIDs, err := portfolio(ctx, kr.users)
+		if err != nil {
+			return nil, err
+		}
+		filter["user_id"] = bson.M{"$in": userIDs}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "submitted_at", Value: 1}})
+	return kr.find(ctx, filter, opts)
+}
+
+// ListExpired implements domain.KYCRepository.
+func (kr *kycRepository) ListExpired(ctx context.Context, now time.Time) ([]domain.KYC, error) {
+	filter := bson.M{
+		"status":     domain.KYCStatusApproved,
+		"expires_at": bson.M{"$lte": now},
+	}
+	return kr.find(ctx, filter)
+}
+
+func (kr *kycRepository) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]domain.KYC, error) {
+	cursor, err := kr.records.Find(ctx, scoped(ctx, filter), opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := make([]domain.KYC, 0)
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrLoanNotFound = domain.NotFound("loan_not_found", "loan not found")
+
+type loanRepository struct {
+	loans *mongo.Collection
+	users *mongo.Collection
+}
+
+func NewLoanRepository(db *mongo.Database) domain.LoanRepository {
+	return &loanRepository{
+		loans: db.Collection(domain.CollectionLoans),
+		users: db.Collection("users"),
+	}
+}
+
+// Create implements domain.LoanRepository.
+func (lr *loanRepository) Create(ctx context.Context, loan domain.Loan) (domain.Loan, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	loan.TenantID = tenantID
+	if loan.ID.IsZero() {
+		loan.ID = primitive.NewObjectID()
+	}
+	loan.Version = 1
+
+	_, err = lr.loans.InsertOne(ctx, loan)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return loan, nil
+}
+
+// GetByID implements domain.LoanRepository.
+func (lr *loanRepository) GetByID(ctx context.Context, loanID string) (domain.Loan, error) {
+	objID, err := primitive.ObjectIDFromHex(loanID)
+	if err != nil {
+		return domain.Loan{}, ErrLoanNotFound
+	}
+
+	var loan domain.Loan
+	err = lr.loans.FindOne(ctx, scoped(ctx, bson.M{"_id": objID})).Decode(&loan)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Loan{}, ErrLoanNotFound
+	}
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return loan, nil
+}
+
+// ListByBorrower implements domain.LoanRepository.
+func (lr *loanRepository) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.Loan, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := lr.loans.Find(ctx, scoped(ctx, bson.M{"borrower_id": borrowerID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	loans := make([]domain.Loan, 0)
+	err = cursor.All(ctx, &loans)
+	if err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// List implements domain.LoanRepository.
+func (lr *loanRepository) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.Loan], error) {
+	filter := bson.M{}
+	if _, ok := domain.PortfolioFromContext(ctx); ok {
+		borrowerIDs, err := portfolio(ctx, lr.users)
+		if err != nil {
+			return domain.Page[domain.Loan]{}, err
+		}
+		filter["borrower_id"] = bson.M{"$in": borrowerIDs}
+	}
+	return findPage[domain.Loan](ctx, lr.loans, scoped(ctx, filter), spec)
+}
+
+// Transition implements domain.LoanRepository.
+func (lr *loanRepository) Transition(ctx context.Context, loanID string, transition domain.LoanTransition) (domain.Loan, error) {
+	objID, err := primitive.ObjectIDFromHex(loanID)
+	if err != nil {
+		return domain.Loan{}, ErrLoanNotFound
+	}
+
+	set := bson.M{
+		"status":     transition.To,
+		"updated_at": transition.At,
+	}
+	switch transition.To {
+	case domain.LoanStatusApproved:
+		set["approved_by"] = transition.ActorID
+		set["approved_at"] = transition.At
+	case domain.LoanStatusDisbursed:
+		set["disbursed_by"] = transition.ActorID
+		set["disbursed_at"] = transition.At
+	}
+	filter := bson.M{"_id": objID, "status": transition.From}
+	if transition.Version != 0 {
+		filter["version"] = transition.Version
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": int64(1)}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var loan domain.Loan
+	err = lr.loans.FindOneAndUpdate(ctx, scoped(ctx, filter), update, opts).Decode(&loan)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Loan{}, lr.conflictOrNotFound(ctx, objID, transition.Version)
+	}
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return loan, nil
+}
+
+// conflictOrNotFound tells why a transition of the loan objID based on
+// version matched nothing: the loan is at another version, or it is gone
+// or in another status.
+func (lr *loanRepository) conflictOrNotFound(ctx context.Context, objID primitive.ObjectID, version int64) error {
+	if version == 0 {
+		return ErrLoanNotFound
+	}
+
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1})
+	err := lr.loans.FindOne(ctx, scoped(ctx, bson.M{"_id": objID}), opts).Decode(&current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrLoanNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current.Version != version {
+		return &ConflictError{Expected: version, Current: current.Version}
+	}
+	return ErrLoanNotFound
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginAttemptRepository struct {
+	attempts *mongo.Collection
+}
+
+func NewLoginAttemptRepository(db *mongo.Database) domain.LoginAttemptRepository {
+	return &loginAttemptRepository{
+		attempts: db.Collection(domain.CollectionLoginAttempts),
+	}
+}
+
+// Get implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Get(ctx context.Context, key string) (domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	err := lr.attempts.FindOne(ctx, bson.M{"_id": tenantKey(ctx, key)}).Decode(&attempt)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.LoginAttempt{Key: key}, nil
+	}
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	attempt.Key = key
+	return attempt, nil
+}
+
+// RecordFailure implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, resetBefore time.Time) (domain.LoginAttempt, error) {
+	// a pipeline update lets the counter restart atomically once it is stale
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures": bson.M{"$cond": bson.A{
+				bson.M{"$lt": bson.A{bson.M{"$ifNull": bson.A{"$last_failure", time.Time{}}}, resetBefore}},
+				1,
+				bson.M{"$add": bson.A{"$failures", 1}},
+			}},
+			"last_failure": at,
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var attempt domain.LoginAttempt
+	err := lr.attempts.FindOneAndUpdate(ctx, bson.M{"_id": tenantKey(ctx, key)}, update, opts).Decode(&attempt)
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	attempt.Key = key
+	return attempt, nil
+}
+
+// Lock implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := lr.attempts.UpdateOne(ctx, bson.M{"_id": tenantKey(ctx, key)}, bson.M{"$set": bson.M{"locked_until": until}})
+	return err
+}
+
+// Reset implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	_, err := lr.attempts.DeleteOne(ctx, bson.M{"_id": tenantKey(ctx, key)})
+	return err
+}
+
+// SetUnlockToken implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) SetUnlockToken(ctx context.Context, key string, tokenHash string) error {
+	_, err := lr.attempts.UpdateOne(ctx, bson.M{"_id": tenantKey(ctx, key)}, bson.M{"$set": bson.M{"unlock_token": tokenHash}})
+	return err
+}
+
+// ConsumeUnlockToken implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) ConsumeUnlockToken(ctx context.Context, key string, tokenHash string) (bool, error) {
+	result, err := lr.attempts.DeleteOne(ctx, bson.M{"_id": tenantKey(ctx, key), "unlock_token": tokenHash})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrMagicLinkNotFound = domain.NotFound("magic_link_not_found", "magic link not found")
+
+type magicLinkRepository struct {
+	links *mongo.Collection
+}
+
+func NewMagicLinkRepository(db *mongo.Database) domain.MagicLinkRepository {
+	return &magicLinkRepository{
+		links: db.Collection(domain.CollectionMagicLinks),
+	}
+}
+
+// Create implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) Create(ctx context.Context, link domain.MagicLink) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	link.TenantID = tenantID
+
+	_, err = mr.links.DeleteMany(ctx, scoped(ctx, bson.M{"user_id": link.UserID}))
+	if err != nil {
+		return err
+	}
+
+	_, err = mr.links.InsertOne(ctx, link)
+	return err
+}
+
+// DeleteByUser implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := mr.links.DeleteMany(ctx, scoped(ctx, bson.M{"user_id": userID}))
+	return err
+}
+
+// Consume implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) Consume(ctx context.Context, tokenHash string, nonceHash string, now time.Time) (domain.MagicLink, error) {
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"nonce_hash": nonceHash,
+		"used":       false,
+		"expires_at": bson.M{"$gt": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var link domain.MagicLink
+	err := mr.links.FindOneAndUpdate(ctx, scoped(ctx, filter), bson.M{"$set": bson.M{"used": true}}, opts).Decode(&link)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.MagicLink{}, ErrMagicLinkNotFound
+	}
+	if err != nil {
+		return domain.MagicLink{}, err
+	}
+	return link, nil
+}
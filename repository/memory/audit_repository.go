@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type auditRepository struct {
+	s *store
+}
+
+// Create implements domain.AuditRepository.
+func (ar *auditRepository) Create(ctx context.Context, entry domain.AuditEntry) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	entry.TenantID = tenantID
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+
+	ar.s.mu.Lock()
+	defer ar.s.mu.Unlock()
+	trackID(ctx, ar.s, &ar.s.audit, entry.ID)
+	ar.s.audit = append(ar.s.audit, clone(entry))
+	return nil
+}
+
+// ListByUser implements domain.AuditRepository.
+func (ar *auditRepository) ListByUser(ctx context.Context, userID string) ([]domain.AuditEntry, error) {
+	ar.s.mu.Lock()
+	defer ar.s.mu.Unlock()
+
+	entries := make([]domain.AuditEntry, 0)
+	for _, entry := range ar.s.audit {
+		if entry.UserID == userID && inScope(ctx, entry.TenantID) {
+			entries = append(entries, clone(entry))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// RedactChanges implements domain.AuditRepository.
+func (ar *auditRepository) RedactChanges(ctx context.Context, userID string, actions []string) error {
+	ar.s.mu.Lock()
+	defer ar.s.mu.Unlock()
+
+	for i, entry := range ar.s.audit {
+		if entry.UserID != userID || !inScope(ctx, entry.TenantID) || !contains(actions, entry.Action) {
+			continue
+		}
+		trackID(ctx, ar.s, &ar.s.audit, entry.ID)
+		for j := range entry.Changes {
+			ar.s.audit[i].Changes[j].Old = domain.RedactedValue
+			ar.s.audit[i].Changes[j].New = domain.RedactedValue
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type branchRepository struct {
+	s *store
+}
+
+// Create implements domain.BranchRepository.
+func (br *branchRepository) Create(ctx context.Context, branch domain.Branch) (domain.Branch, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	branch.TenantID = tenantID
+
+	br.s.mu.Lock()
+	defer br.s.mu.Unlock()
+
+	for _, existing := range br.s.branches {
+		if existing.TenantID == tenantID && existing.Code == branch.Code {
+			return domain.Branch{}, repository.ErrBranchCodeTaken
+		}
+	}
+	if branch.ID.IsZero() {
+		branch.ID = primitive.NewObjectID()
+	}
+	trackID(ctx, br.s, &br.s.branches, branch.ID)
+	br.s.branches = append(br.s.branches, clone(branch))
+	return branch, nil
+}
+
+// GetByID implements domain.BranchRepository.
+func (br *branchRepository) GetByID(ctx context.Context, branchID string) (domain.Branch, error) {
+	objID, err := primitive.ObjectIDFromHex(branchID)
+	if err != nil {
+		return domain.Branch{}, repository.ErrBranchNotFound
+	}
+
+	br.s.mu.Lock()
+	defer br.s.mu.Unlock()
+
+	for _, branch := range br.s.branches {
+		if branch.ID == objID && inScope(ctx, branch.TenantID) {
+			return clone(branch), nil
+		}
+	}
+	return domain.Branch{}, repository.ErrBranchNotFound
+}
+
+// List implements domain.BranchRepository.
+func (br *branchRepository) List(ctx context.Context) ([]domain.Branch, error) {
+	br.s.mu.Lock()
+	defer br.s.mu.Unlock()
+
+	branches := make([]domain.Branch, 0)
+	for _, branch := range br.s.branches {
+		if inScope(ctx, branch.TenantID) {
+			branches = append(branches, clone(branch))
+		}
+	}
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].Name < branches[j].Name
+	})
+	return branches, nil
+}
+
+// Update implements domain.BranchRepository.
+func (br *branchRepository) Update(ctx context.Context, branchID string, request domain.UpdateBranchRequest) (domain.Branch, error) {
+	objID, err := primitive.ObjectIDFromHex(branchID)
+	if err != nil {
+		return domain.Branch{}, repository.ErrBranchNotFound
+	}
+
+	br.s.mu.Lock()
+	defer br.s.mu.Unlock()
+
+	for i, branch := range br.s.branches {
+		if branch.ID != objID || !inScope(ctx, branch.TenantID) {
+			continue
+		}
+		trackID(ctx, br.s, &br.s.branches, branch.ID)
+		branch.UpdatedAt = time.Now()
+		if request.Name != nil {
+			branch.Name = *request.Name
+		}
+		if request.Active != nil {
+			branch.Active = *request.Active
+		}
+		br.s.branches[i] = clone(branch)
+		return clone(branch), nil
+	}
+	return domain.Branch{}, repository.ErrBranchNotFound
+}
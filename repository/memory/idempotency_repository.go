@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+type idempotencyRepository struct {
+	s *store
+}
+
+// Reserve implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Reserve(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	ir.s.mu.Lock()
+	defer ir.s.mu.Unlock()
+
+	id := tenantKey(ctx, record.Key)
+	if stored, ok := ir.s.idempotency[id]; ok && stored.ExpiresAt.After(time.Now()) {
+		stored = clone(stored)
+		stored.Key = record.Key
+		return stored, false, nil
+	}
+
+	trackKey(ctx, ir.s, ir.s.idempotency, id)
+	stored := clone(record)
+	stored.Key = id
+	ir.s.idempotency[id] = stored
+	return clone(record), true, nil
+}
+
+// Complete implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Complete(ctx context.Context, key string, status int, contentType string, header map[string]string, body []byte) error {
+	ir.s.mu.Lock()
+	defer ir.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	if record, ok := ir.s.idempotency[id]; ok && record.Status == 0 {
+		trackKey(ctx, ir.s, ir.s.idempotency, id)
+		record.Status = status
+		record.ContentType = contentType
+		record.Header = header
+		record.Body = body
+		ir.s.idempotency[id] = clone(record)
+	}
+	return nil
+}
+
+// Release implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Release(ctx context.Context, key string) error {
+	ir.s.mu.Lock()
+	defer ir.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	trackKey(ctx, ir.s, ir.s.idempotency, id)
+	delete(ir.s.idempotency, id)
+	return nil
+}
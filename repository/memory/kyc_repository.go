@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type kycRepository struct {
+	s *store
+}
+
+// find returns the index of the record of userID, or -1. The caller holds
+// the lock.
+func (kr *kycRepository) find(ctx context.Context, userID string) int {
+	for i, record := range kr.s.kyc {
+		if record.UserID == userID && inScope(ctx, record.TenantID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetOrCreate implements domain.KYCRepository.
+func (kr *kycRepository) GetOrCreate(ctx context.Context, userID string) (domain.KYC, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	kr.s.mu.Lock()
+	defer kr.s.mu.Unlock()
+
+	if i := kr.find(ctx, userID); i >= 0 {
+		return clone(kr.s.kyc[i]), nil
+	}
+	now := time.Now()
+	record := clone(domain.KYC{
+		ID:        primitive.NewObjectID(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		Status:    domain.KYCStatusNotStarted,
+		Documents: []domain.KYCDocument{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	trackID(ctx, kr.s, &kr.s.kyc, record.ID)
+	kr.s.kyc = append(kr.s.kyc, record)
+	return clone(record), nil
+}
+
+// GetByUserID implements domain.KYCRepository.
+func (kr *kycRepository) GetByUserID(ctx context.Context, userID string) (domain.KYC, error) {
+	kr.s.mu.Lock()
+	defer kr.s.mu.Unlock()
+
+	i := kr.find(ctx, userID)
+	if i < 0 {
+		return domain.KYC{}, repository.ErrKYCNotFound
+	}
+	return clone(kr.s.kyc[i]), nil
+}
+
+// AddDocument implements domain.KYCRepository.
+func (kr *kycRepository) AddDocument(ctx context.Context, userID string, document domain.KYCDocument) error {
+	kr.s.mu.Lock()
+	defer kr.s.mu.Unlock()
+
+	i := kr.find(ctx, userID)
+	if i < 0 {
+		return repository.ErrKYCNotFound
+	}
+	record := kr.s.kyc[i]
+	trackID(ctx, kr.s, &kr.s.kyc, record.ID)
+	record.Documents = append(record.Documents, document)
+	record.UpdatedAt = time.Now()
+	kr.s.kyc[i] = clone(record)
+	return nil
+}
+
+// Delete implements domain.KYCRepository.
+func (kr *kycRepository) Delete(ctx context.Context, userID string) (domain.KYC, error) {
+	kr.s.mu.Lock()
+	defer kr.s.mu.Unlock()
+
+	i := kr.find(ctx, userID)
+	if i < 0 {
+		return domain.KYC{}, repository.ErrKYCNotFound
+	}
+	record := kr.s.kyc[i]
+	trackID(ctx, kr.s, &kr.s.kyc, record.ID)
+	kr.s.kyc = append(kr.s.kyc[:i], kr.s.kyc[i+1:]...)
+	return clone(record), nil
+}
+
+// Transition implements domain.KYCRepository.
+func (kr *kycRepository) Transition(ctx context.Context, userID string, transition domain.KYCTransition) (domain.KYC, error) {
+	kr.s.mu.Lock()
+	defer kr.s.mu.Unlock()
+
+	i := kr.find(ctx, userID)
+	if i < 0 || kr.s.kyc[i].Status != transition.From {
+		return domain.KYC{}, repository.ErrKYCNotFound
+	}
+
+	record := kr.s.kyc[i]
+	trackID(ctx, kr.s, &kr.s.kyc, record.ID)
+	record.Status = transition.To
+	record.UpdatedAt = transition.At
+	switch transition.To {
+	case domain.KYCStatusSubmitted:
+		record.SubmittedAt = transition.At
+		record.RejectionReason = ""
+		record.ReviewedBy = ""
+		record.ReviewedAt = time.Time{}
+	case domain.KYCStatusApproved:
+		record.ReviewedBy = transition.ReviewerID
+		record.ReviewedAt = transition.At
+		record.ExpiresAt = transition.ExpiresAt
+		record.RejectionReason = ""
+	case domain.KYCStatusRejected:
+		record.ReviewedBy = transition.ReviewerID
+		record.ReviewedAt = transition.At
+		record.RejectionReason = transition.Reason
+		record.ExpiresAt = time.Time{}
+	}
+	kr.s.kyc[i] = clone(record)
+	return clone(record), nil
+}
+
+// ListByStatus implements domain.KYCRepository.
+func (kr *kycRepository) ListByStatus(ctx context.Context, status domain.KYCStatus) ([]domain.KYC, error) {
+	records := kr.filter(ctx, func(record domain.KYC) bool {
+		return record.Status == status && inPortfolio(ctx, kr.s, record.UserID)
+	})
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].SubmittedAt.Before(records[j].SubmittedAt)
+	})
+	return records, nil
+}
+
+// ListExpired implements domain.KYCRepository.
+func (kr *kycRepository) ListExpired(ctx context.Context, now time.Time) ([]domain.KYC, error) {
+	return kr.filter(ctx, func(record domain.KYC) bool {
+		return record.Status == domain.KYCStatusApproved && !record.ExpiresAt.IsZero() && !record.ExpiresAt.After(now)
+	}), nil
+}
+
+func (kr *kycRepository) filter(ctx context.Context, match func(domain.KYC) bool) []domain.KYC {
+	kr.s.mu.Lock()
+	defer kr.s.mu.Unlock()
+
+	records := make([]domain.KYC, 0)
+	for _, record := range kr.s.kyc {
+		if inScope(ctx, record.TenantID) && match(record) {
+			records = append(records, clone(record))
+		}
+	}
+	return records
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type loanRepository struct {
+	s *store
+}
+
+// find returns the index of the loan, or -1. The caller holds the lock.
+func (lr *loanRepository) find(ctx context.Context, loanID string) int {
+	objID, err := primitive.ObjectIDFromHex(loanID)
+	if err != nil {
+		return -1
+	}
+	for i, loan := range lr.s.loans {
+		if loan.ID == objID && inScope(ctx, loan.TenantID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Create implements domain.LoanRepository.
+func (lr *loanRepository) Create(ctx context.Context, loan domain.Loan) (domain.Loan, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	loan.TenantID = tenantID
+	if loan.ID.IsZero() {
+		loan.ID = primitive.NewObjectID()
+	}
+	loan.Version = 1
+
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	trackID(ctx, lr.s, &lr.s.loans, loan.ID)
+	lr.s.loans = append(lr.s.loans, clone(loan))
+	return loan, nil
+}
+
+// GetByID implements domain.LoanRepository.
+func (lr *loanRepository) GetByID(ctx context.Context, loanID string) (domain.Loan, error) {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	i := lr.find(ctx, loanID)
+	if i < 0 {
+		return domain.Loan{}, repository.ErrLoanNotFound
+	}
+	return clone(lr.s.loans[i]), nil
+}
+
+// ListByBorrower implements domain.LoanRepository.
+func (lr *loanRepository) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.Loan, error) {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	loans := make([]domain.Loan, 0)
+	for _, loan := range lr.s.loans {
+		if loan.BorrowerID == borrowerID && inScope(ctx, loan.TenantID) {
+			loans = append(loans, clone(loan))
+		}
+	}
+	sort.SliceStable(loans, func(i, j int) bool {
+		return loans[i].CreatedAt.After(loans[j].CreatedAt)
+	})
+	return loans, nil
+}
+
+// List implements domain.LoanRepository.
+func (lr *loanRepository) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.Loan], error) {
+	lr.s.mu.Lock()
+	loans := make([]domain.Loan, 0, len(lr.s.loans))
+	for _, loan := range lr.s.loans {
+		if inScope(ctx, loan.TenantID) && inPortfolio(ctx, lr.s, loan.BorrowerID) {
+			loans = append(loans, loan)
+		}
+	}
+	lr.s.mu.Unlock()
+
+	return findPage(loans, spec)
+}
+
+// Transition implements domain.LoanRepository.
+func (lr *loanRepository) Transition(ctx context.Context, loanID string, transition domain.LoanTransition) (domain.Loan, error) {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	i := lr.find(ctx, loanID)
+	if i < 0 {
+		return domain.Loan{}, repository.ErrLoanNotFound
+	}
+	if current := lr.s.loans[i].Version; transition.Version != 0 && current != transition.Version {
+		return domain.Loan{}, &repository.ConflictError{Expected: transition.Version, Current: current}
+	}
+	if lr.s.loans[i].Status != transition.From {
+		return domain.Loan{}, repository.ErrLoanNotFound
+	}
+
+	loan := lr.s.loans[i]
+	trackID(ctx, lr.s, &lr.s.loans, loan.ID)
+	loan.Status = transition.To
+	loan.Version++
+	loan.UpdatedAt = transition.At
+	switch transition.To {
+	case domain.LoanStatusApproved:
+		loan.ApprovedBy = transition.ActorID
+		loan.ApprovedAt = transition.At
+	case domain.LoanStatusDisbursed:
+		loan.DisbursedBy = transition.ActorID
+		loan.DisbursedAt = transition.At
+	}
+	lr.s.loans[i] = clone(loan)
+	return clone(loan), nil
+}
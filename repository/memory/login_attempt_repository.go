@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+type loginAttemptRepository struct {
+	s *store
+}
+
+// tenantKey prefixes key with the tenant of ctx like the Mongo repository.
+func tenantKey(ctx context.Context, key string) string {
+	tenantID, _ := domain.TenantFromContext(ctx)
+	return tenantID + ":" + key
+}
+
+// Get implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Get(ctx context.Context, key string) (domain.LoginAttempt, error) {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	attempt, ok := lr.s.attempts[tenantKey(ctx, key)]
+	if !ok {
+		return domain.LoginAttempt{Key: key}, nil
+	}
+	attempt.Key = key
+	return attempt, nil
+}
+
+// RecordFailure implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, resetBefore time.Time) (domain.LoginAttempt, error) {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	trackKey(ctx, lr.s, lr.s.attempts, id)
+	attempt := lr.s.attempts[id]
+	attempt.Key = id
+	if attempt.LastFailure.Before(resetBefore) {
+		attempt.Failures = 1
+	} else {
+		attempt.Failures++
+	}
+	attempt.LastFailure = at
+	attempt = clone(attempt)
+	lr.s.attempts[id] = attempt
+
+	attempt.Key = key
+	return attempt, nil
+}
+
+// Lock implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	if attempt, ok := lr.s.attempts[id]; ok {
+		trackKey(ctx, lr.s, lr.s.attempts, id)
+		attempt.LockedUntil = until
+		lr.s.attempts[id] = clone(attempt)
+	}
+	return nil
+}
+
+// Reset implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	trackKey(ctx, lr.s, lr.s.attempts, id)
+	delete(lr.s.attempts, id)
+	return nil
+}
+
+// SetUnlockToken implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) SetUnlockToken(ctx context.Context, key string, tokenHash string) error {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	if attempt, ok := lr.s.attempts[id]; ok {
+		trackKey(ctx, lr.s, lr.s.attempts, id)
+		attempt.UnlockToken = tokenHash
+		lr.s.attempts[id] = clone(attempt)
+	}
+	return nil
+}
+
+// ConsumeUnlockToken implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) ConsumeUnlockToken(ctx context.Context, key string, tokenHash string) (bool, error) {
+	lr.s.mu.Lock()
+	defer lr.s.mu.Unlock()
+
+	id := tenantKey(ctx, key)
+	attempt, ok := lr.s.attempts[id]
+	if !ok || tokenHash == "" || attempt.UnlockToken != tokenHash {
+		return false, nil
+	}
+	trackKey(ctx, lr.s, lr.s.attempts, id)
+	delete(lr.s.attempts, id)
+	return true, nil
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type magicLinkRepository struct {
+	s *store
+}
+
+// Create implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) Create(ctx context.Context, link domain.MagicLink) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	link.TenantID = tenantID
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+
+	mr.s.mu.Lock()
+	defer mr.s.mu.Unlock()
+
+	for _, existing := range mr.s.magicLinks {
+		if existing.UserID == link.UserID && existing.TenantID == tenantID {
+			trackID(ctx, mr.s, &mr.s.magicLinks, existing.ID)
+		}
+	}
+	trackID(ctx, mr.s, &mr.s.magicLinks, link.ID)
+	links := mr.s.magicLinks[:0]
+	for _, existing := range mr.s.magicLinks {
+		if existing.UserID != link.UserID || existing.TenantID != tenantID {
+			links = append(links, existing)
+		}
+	}
+	mr.s.magicLinks = append(links, clone(link))
+	return nil
+}
+
+// DeleteByUser implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) DeleteByUser(ctx context.Context, userID string) error {
+	mr.s.mu.Lock()
+	defer mr.s.mu.Unlock()
+
+	issued := func(link domain.MagicLink) bool {
+		return link.UserID == userID && inScope(ctx, link.TenantID)
+	}
+	for _, link := range mr.s.magicLinks {
+		if issued(link) {
+			trackID(ctx, mr.s, &mr.s.magicLinks, link.ID)
+		}
+	}
+	mr.s.magicLinks = slices.DeleteFunc(mr.s.magicLinks, issued)
+	return nil
+}
+
+// Consume implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) Consume(ctx context.Context, tokenHash string, nonceHash string, now time.Time) (domain.MagicLink, error) {
+	mr.s.mu.Lock()
+	defer mr.s.mu.Unlock()
+
+	for i, link := range mr.s.magicLinks {
+		if link.TokenHash != tokenHash || link.NonceHash != nonceHash || link.Used ||
+			!link.ExpiresAt.After(now) || !inScope(ctx, link.TenantID) {
+			continue
+		}
+		trackID(ctx, mr.s, &mr.s.magicLinks, link.ID)
+		mr.s.magicLinks[i].Used = true
+		return clone(mr.s.magicLinks[i]), nil
+	}
+	return domain.MagicLink{}, repository.ErrMagicLinkNotFound
+}
@@ -0,0 +1,117 @@
+// Package memory implements every repository in memory, with the same
+// semantics as the Mongo repositories, for tests and for running the
+// application without a database.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// store holds the records of every repository of a Set. Repositories that
+// share records, such as the users, share them through the store.
+type store struct {
+	mu sync.Mutex
+	records
+
+	// tx lets one transaction run at a time
+	tx sync.Mutex
+}
+
+// records are the contents of a store.
+type records struct {
+	users       []domain.User
+	otps        []domain.OtpSave
+	audit       []domain.AuditEntry
+	kyc         []domain.KYC
+	magicLinks  []domain.MagicLink
+	attempts    map[string]domain.LoginAttempt
+	tenants     []domain.Tenant
+	branches    []domain.Branch
+	loans       []domain.Loan
+	assignments []domain.OfficerAssignment
+	idempotency map[string]domain.IdempotencyRecord
+}
+
+// NewSet returns an empty set of in-memory repositories.
+func NewSet() repository.Set {
+	s := &store{records: records{
+		attempts:    make(map[string]domain.LoginAttempt),
+		idempotency: make(map[string]domain.IdempotencyRecord),
+	}}
+	return repository.Set{
+		Users:              &userRepository{s},
+		ResetPassword:      &resetPasswordRepository{s},
+		Audit:              &auditRepository{s},
+		KYC:                &kycRepository{s},
+		MagicLinks:         &magicLinkRepository{s},
+		LoginAttempts:      &loginAttemptRepository{s},
+		Tenants:            &tenantRepository{s},
+		Branches:           &branchRepository{s},
+		Loans:              &loanRepository{s},
+		OfficerAssignments: &officerAssignmentRepository{s},
+		Search:             &searchIndex{s},
+		Idempotency:        &idempotencyRepository{s},
+		Tx:                 &txManager{s},
+	}
+}
+
+// clone copies v the way a round trip through Mongo does: times lose their
+// sub-millisecond part and nothing is shared with the stored record.
+func clone[T any](v T) T {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var c T
+	if err := bson.Unmarshal(data, &c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// inScope reports whether a record of tenantID is visible with ctx, the
+// way repository.scoped filters records.
+func inScope(ctx context.Context, tenantID string) bool {
+	if id, ok := domain.TenantFromContext(ctx); ok {
+		return tenantID == id
+	}
+	return domain.IsAllTenants(ctx)
+}
+
+// userInScope mirrors the user scope of the Mongo repositories.
+func userInScope(ctx context.Context, user domain.User) bool {
+	if !user.DeletedAt.IsZero() {
+		return false
+	}
+	if officerID, ok := domain.PortfolioFromContext(ctx); ok && user.OfficerID != officerID {
+		return false
+	}
+	return inScope(ctx, user.TenantID)
+}
+
+// inPortfolio reports whether the user is one ctx can see, which within a
+// portfolio are its borrowers only. The caller holds the lock.
+func inPortfolio(ctx context.Context, s *store, userID string) bool {
+	if _, ok := domain.PortfolioFromContext(ctx); !ok {
+		return true
+	}
+	for _, user := range s.users {
+		if user.ID.Hex() == userID {
+			return userInScope(ctx, user)
+		}
+	}
+	return false
+}
+
+func tenantOf(ctx context.Context) (string, error) {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		return "", repository.ErrNoTenant
+	}
+	return tenantID, nil
+}
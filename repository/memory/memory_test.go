@@ -0,0 +1,67 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/repotest"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/memory"
+)
+
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Set { return memory.NewSet() })
+}
+
+// TestRollbackKeepsOtherWrites checks that rolling a transaction back
+// undoes its own writes only, not those made meanwhile outside of it.
+func TestRollbackKeepsOtherWrites(t *testing.T) {
+	repos := memory.NewSet()
+	ctx := domain.WithTenant(context.Background(), "tenant-a")
+	now := time.Now()
+	user := func(email string) domain.User {
+		return domain.User{Email: email, Role: domain.RoleBorrower, Tokens: []string{}, CreatedAt: now, UpdatedAt: now}
+	}
+	inside, err := repos.Users.Create(ctx, user("inside@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("failure")
+	var outside domain.User
+	err = repos.Tx.WithinTx(ctx, func(txCtx context.Context) error {
+		if _, err := repos.Users.UpdateRole(txCtx, inside.ID.Hex(), domain.RoleAdmin); err != nil {
+			return err
+		}
+		if _, err := repos.Users.Create(txCtx, user("created@example.com")); err != nil {
+			return err
+		}
+		// a request running concurrently with the transaction
+		outside, err = repos.Users.Create(ctx, user("outside@example.com"))
+		if err != nil {
+			return err
+		}
+		if _, err := repos.Users.UpdateRole(ctx, outside.ID.Hex(), domain.RoleAdmin); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the failure, got %v", err)
+	}
+
+	got, err := repos.Users.GetByID(ctx, inside.ID.Hex())
+	if err != nil || got.Role != domain.RoleBorrower {
+		t.Errorf("expected the role change rolled back, got %q, %v", got.Role, err)
+	}
+	if _, err := repos.Users.GetByEmail(ctx, "created@example.com"); !errors.Is(err, repository.ErrUserNotFound) {
+		t.Errorf("expected the created user rolled back, got %v", err)
+	}
+	got, err = repos.Users.GetByID(ctx, outside.ID.Hex())
+	if err != nil || got.Role != domain.RoleAdmin {
+		t.Errorf("expected the write outside the transaction kept, got %q, %v", got.Role, err)
+	}
+}
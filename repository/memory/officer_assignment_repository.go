@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type officerAssignmentRepository struct {
+	s *store
+}
+
+// Create implements domain.OfficerAssignmentRepository.
+func (ar *officerAssignmentRepository) Create(ctx context.Context, assignment domain.OfficerAssignment) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	assignment.TenantID = tenantID
+	if assignment.ID.IsZero() {
+		assignment.ID = primitive.NewObjectID()
+	}
+
+	ar.s.mu.Lock()
+	defer ar.s.mu.Unlock()
+	trackID(ctx, ar.s, &ar.s.assignments, assignment.ID)
+	ar.s.assignments = append(ar.s.assignments, clone(assignment))
+	return nil
+}
+
+// ListByBorrower implements domain.OfficerAssignmentRepository.
+func (ar *officerAssignmentRepository) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.OfficerAssignment, error) {
+	ar.s.mu.Lock()
+	defer ar.s.mu.Unlock()
+
+	assignments := make([]domain.OfficerAssignment, 0)
+	for _, assignment := range ar.s.assignments {
+		if assignment.BorrowerID == borrowerID && inScope(ctx, assignment.TenantID) {
+			assignments = append(assignments, clone(assignment))
+		}
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return assignments[i].AssignedAt.After(assignments[j].AssignedAt)
+	})
+	return assignments, nil
+}
@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// findPage returns the page of items described by spec, filtering, sorting
+// and paging on the stored form of the items like the Mongo findPage does.
+func findPage[T any](items []T, spec domain.QuerySpec) (domain.Page[T], error) {
+	raws := make([]bson.Raw, 0, len(items))
+	for _, item := range items {
+		raw, err := bson.Marshal(item)
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+		if matchesAll(raw, spec.Conditions) {
+			raws = append(raws, raw)
+		}
+	}
+	total := int64(len(raws))
+
+	sortDirection := 1
+	if spec.SortDesc {
+		sortDirection = -1
+	}
+	less := func(a, b bson.Raw) bool {
+		if c := compare(a.Lookup(spec.SortBy), b.Lookup(spec.SortBy)); c != 0 {
+			return c*sortDirection < 0
+		}
+		return compare(a.Lookup("_id"), b.Lookup("_id"))*sortDirection < 0
+	}
+	sort.SliceStable(raws, func(i, j int) bool { return less(raws[i], raws[j]) })
+
+	if spec.Cursor != "" {
+		value, id, err := query.DecodeCursor(spec)
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+		afterValue, afterID := rawValue(value), rawValue(id)
+		start := len(raws)
+		for i, raw := range raws {
+			c := compare(raw.Lookup(spec.SortBy), afterValue) * sortDirection
+			if c > 0 || (c == 0 && compare(raw.Lookup("_id"), afterID)*sortDirection > 0) {
+				start = i
+				break
+			}
+		}
+		raws = raws[start:]
+	}
+
+	page := domain.Page[T]{Items: make([]T, 0, spec.Limit), Total: total}
+	if len(raws) > spec.Limit {
+		raws = raws[:spec.Limit]
+		last := raws[len(raws)-1]
+		var err error
+		page.NextCursor, err = query.EncodeCursor(spec, last.Lookup(spec.SortBy), last.Lookup("_id"))
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+	}
+	for _, raw := range raws {
+		var item T
+		if err := bson.Unmarshal(raw, &item); err != nil {
+			return domain.Page[T]{}, err
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+func matchesAll(raw bson.Raw, conditions []domain.Condition) bool {
+	for _, condition := range conditions {
+		if !matches(raw.Lookup(condition.Field), condition) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches follows the Mongo rules: values only compare within the same
+// type, and a missing field matches nothing.
+func matches(field bson.RawValue, condition domain.Condition) bool {
+	switch condition.Op {
+	case domain.OpIn:
+		values := reflect.ValueOf(condition.Value)
+		if values.Kind() != reflect.Slice {
+			return false
+		}
+		for i := 0; i < values.Len(); i++ {
+			if equal(field, rawValue(values.Index(i).Interface())) {
+				return true
+			}
+		}
+		return false
+	case domain.OpGte:
+		value := rawValue(condition.Value)
+		return comparable(field, value) && compare(field, value) >= 0
+	case domain.OpLte:
+		value := rawValue(condition.Value)
+		return comparable(field, value) && compare(field, value) <= 0
+	case domain.OpPrefix:
+		prefix, _ := condition.Value.(string)
+		text, ok := field.StringValueOK()
+		return ok && strings.HasPrefix(strings.ToLower(text), strings.ToLower(prefix))
+	default:
+		return equal(field, rawValue(condition.Value))
+	}
+}
+
+func rawValue(v interface{}) bson.RawValue {
+	if raw, ok := v.(bson.RawValue); ok {
+		return raw
+	}
+	t, data, err := bson.MarshalValue(v)
+	if err != nil {
+		return bson.RawValue{}
+	}
+	return bson.RawValue{Type: t, Value: data}
+}
+
+func equal(a, b bson.RawValue) bool {
+	return comparable(a, b) && compare(a, b) == 0
+}
+
+func comparable(a, b bson.RawValue) bool {
+	return a.Type != 0 && typeOrder(a.Type) == typeOrder(b.Type)
+}
+
+// compare orders values like a Mongo sort: first by type, then by value.
+func compare(a, b bson.RawValue) int {
+	if oa, ob := typeOrder(a.Type), typeOrder(b.Type); oa != ob {
+		return oa - ob
+	}
+	switch a.Type {
+	case 0, bsontype.Null:
+		return 0
+	case bsontype.Double, bsontype.Int32, bsontype.Int64:
+		fa, fb := number(a), number(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case bsontype.String:
+		return strings.Compare(a.StringValue(), b.StringValue())
+	case bsontype.Boolean:
+		ba, bb := a.Boolean(), b.Boolean()
+		switch {
+		case ba == bb:
+			return 0
+		case !ba:
+			return -1
+		}
+		return 1
+	case bsontype.DateTime:
+		da, db := a.DateTime(), b.DateTime()
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	}
+	return bytes.Compare(a.Value, b.Value)
+}
+
+func number(v bson.RawValue) float64 {
+	switch v.Type {
+	case bsontype.Int32:
+		return float64(v.Int32())
+	case bsontype.Int64:
+		return float64(v.Int64())
+	}
+	return v.Double()
+}
+
+// typeOrder is the position of t in the Mongo sort order of types.
+func typeOrder(t bsontype.Type) int {
+	switch t {
+	case 0, bsontype.Null:
+		return 1
+	case bsontype.Double, bsontype.Int32, bsontype.Int64:
+		return 2
+	case bsontype.String:
+		return 3
+	case bsontype.EmbeddedDocument:
+		return 4
+	case bsontype.Array:
+		return 5
+	case bsontype.Binary:
+		return 6
+	case bsontype.ObjectID:
+		return 7
+	case bsontype.Boolean:
+		return 8
+	case bsontype.DateTime:
+		return 9
+	}
+	return 10
+}
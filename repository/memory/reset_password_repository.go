@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+type resetPasswordRepository struct {
+	s *store
+}
+
+// GetUserByEmail implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user, err := (&userRepository{rp.s}).GetByEmail(ctx, email)
+	if err != nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// ResetPassword implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) ResetPassword(ctx context.Context, userID string, resetPassword *domain.ResetPasswordRequest) error {
+	_, err := (&userRepository{rp.s}).update(ctx, userID, setPassword(resetPassword.NewPassword))
+	return err
+}
+
+// SaveOtp implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) SaveOtp(ctx context.Context, otp *domain.OtpSave) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	otp.TenantID = tenantID
+
+	rp.s.mu.Lock()
+	defer rp.s.mu.Unlock()
+	track(ctx, rp.s, &rp.s.otps, sameOtp(*otp))
+	rp.s.otps = append(rp.s.otps, clone(*otp))
+	return nil
+}
+
+// find returns the index of the first OTP of email, or -1. The caller
+// holds the lock.
+func (rp *resetPasswordRepository) find(ctx context.Context, email string) int {
+	for i, otp := range rp.s.otps {
+		if otp.Email == email && inScope(ctx, otp.TenantID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetOTPByEmail implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) GetOTPByEmail(ctx context.Context, email string) (*domain.OtpSave, error) {
+	rp.s.mu.Lock()
+	defer rp.s.mu.Unlock()
+
+	i := rp.find(ctx, email)
+	if i < 0 {
+		return nil, repository.ErrUserNotFound
+	}
+	otp := clone(rp.s.otps[i])
+	return &otp, nil
+}
+
+// DeleteOtp implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) DeleteOtp(ctx context.Context, email string) error {
+	rp.s.mu.Lock()
+	defer rp.s.mu.Unlock()
+
+	if i := rp.find(ctx, email); i >= 0 {
+		track(ctx, rp.s, &rp.s.otps, sameOtp(rp.s.otps[i]))
+		rp.s.otps = append(rp.s.otps[:i], rp.s.otps[i+1:]...)
+	}
+	return nil
+}
+
+// DeleteOtps implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) DeleteOtps(ctx context.Context, email string) error {
+	rp.s.mu.Lock()
+	defer rp.s.mu.Unlock()
+
+	for i := rp.find(ctx, email); i >= 0; i = rp.find(ctx, email) {
+		track(ctx, rp.s, &rp.s.otps, sameOtp(rp.s.otps[i]))
+		rp.s.otps = append(rp.s.otps[:i], rp.s.otps[i+1:]...)
+	}
+	return nil
+}
+
+// IncrementOtpAttempts implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) IncrementOtpAttempts(ctx context.Context, email string) (int, error) {
+	rp.s.mu.Lock()
+	defer rp.s.mu.Unlock()
+
+	i := rp.find(ctx, email)
+	if i < 0 {
+		return 0, repository.ErrUserNotFound
+	}
+	track(ctx, rp.s, &rp.s.otps, sameOtp(rp.s.otps[i]))
+	rp.s.otps[i].Attempts++
+	return rp.s.otps[i].Attempts, nil
+}
+
+// sameOtp matches otp, which has no ID, by its email and code.
+func sameOtp(otp domain.OtpSave) func(domain.OtpSave) bool {
+	return func(other domain.OtpSave) bool {
+		return other.TenantID == otp.TenantID && other.Email == otp.Email && other.Code == otp.Code
+	}
+}
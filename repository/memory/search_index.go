@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/search"
+)
+
+// searchIndex ranks the borrowers and loans of the store directly, where
+// the Mongo repository first narrows them down with its text index.
+type searchIndex struct {
+	s *store
+}
+
+// Search implements domain.SearchIndex.
+func (si *searchIndex) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []domain.SearchResult{}, nil
+	}
+
+	si.s.mu.Lock()
+	docs := make([]search.Document, 0)
+	borrowers := make(map[string]domain.User)
+	for _, user := range si.s.users {
+		if (user.Role == domain.RoleBorrower || user.Role == domain.RoleUser) && userInScope(ctx, user) {
+			borrowers[user.ID.Hex()] = user
+			docs = append(docs, search.BorrowerDocument(user))
+		}
+	}
+	for _, loan := range si.s.loans {
+		if borrower, ok := borrowers[loan.BorrowerID]; ok && inScope(ctx, loan.TenantID) {
+			docs = append(docs, search.LoanDocument(loan, borrower))
+		}
+	}
+	si.s.mu.Unlock()
+
+	return search.Rank(docs, terms, limit), nil
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type tenantRepository struct {
+	s *store
+}
+
+// EnsurePrimary implements domain.TenantRepository.
+func (tr *tenantRepository) EnsurePrimary(ctx context.Context, slug string) (domain.Tenant, error) {
+	tr.s.mu.Lock()
+	defer tr.s.mu.Unlock()
+
+	for _, tenant := range tr.s.tenants {
+		if tenant.Slug == slug {
+			return clone(tenant), nil
+		}
+	}
+	now := time.Now()
+	tenant := clone(domain.Tenant{
+		ID:        primitive.NewObjectID(),
+		Name:      slug,
+		Slug:      slug,
+		Primary:   true,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	trackID(ctx, tr.s, &tr.s.tenants, tenant.ID)
+	tr.s.tenants = append(tr.s.tenants, tenant)
+	return clone(tenant), nil
+}
+
+// Create implements domain.TenantRepository.
+func (tr *tenantRepository) Create(ctx context.Context, tenant domain.Tenant) (domain.Tenant, error) {
+	tr.s.mu.Lock()
+	defer tr.s.mu.Unlock()
+
+	for _, existing := range tr.s.tenants {
+		if existing.Slug == tenant.Slug {
+			return domain.Tenant{}, repository.ErrTenantSlugTaken
+		}
+	}
+	if tenant.ID.IsZero() {
+		tenant.ID = primitive.NewObjectID()
+	}
+	trackID(ctx, tr.s, &tr.s.tenants, tenant.ID)
+	tr.s.tenants = append(tr.s.tenants, clone(tenant))
+	return tenant, nil
+}
+
+// GetByID implements domain.TenantRepository.
+func (tr *tenantRepository) GetByID(ctx context.Context, tenantID string) (domain.Tenant, error) {
+	objID, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return domain.Tenant{}, repository.ErrTenantNotFound
+	}
+	return tr.findOne(func(tenant domain.Tenant) bool { return tenant.ID == objID })
+}
+
+// GetBySlug implements domain.TenantRepository.
+func (tr *tenantRepository) GetBySlug(ctx context.Context, slug string) (domain.Tenant, error) {
+	return tr.findOne(func(tenant domain.Tenant) bool { return tenant.Slug == slug })
+}
+
+// List implements domain.TenantRepository.
+func (tr *tenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
+	tr.s.mu.Lock()
+	defer tr.s.mu.Unlock()
+
+	tenants := make([]domain.Tenant, 0, len(tr.s.tenants))
+	for _, tenant := range tr.s.tenants {
+		tenants = append(tenants, clone(tenant))
+	}
+	sort.SliceStable(tenants, func(i, j int) bool {
+		return tenants[i].CreatedAt.Before(tenants[j].CreatedAt)
+	})
+	return tenants, nil
+}
+
+// Update implements domain.TenantRepository.
+func (tr *tenantRepository) Update(ctx context.Context, tenantID string, request domain.UpdateTenantRequest) (domain.Tenant, error) {
+	objID, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return domain.Tenant{}, repository.ErrTenantNotFound
+	}
+
+	tr.s.mu.Lock()
+	defer tr.s.mu.Unlock()
+
+	for i, tenant := range tr.s.tenants {
+		if tenant.ID != objID {
+			continue
+		}
+		trackID(ctx, tr.s, &tr.s.tenants, tenant.ID)
+		tenant.UpdatedAt = time.Now()
+		if request.Name != nil {
+			tenant.Name = *request.Name
+		}
+		if request.Active != nil {
+			tenant.Active = *request.Active
+		}
+		tr.s.tenants[i] = clone(tenant)
+		return clone(tenant), nil
+	}
+	return domain.Tenant{}, repository.ErrTenantNotFound
+}
+
+// ClaimOwner implements domain.TenantRepository.
+func (tr *tenantRepository) ClaimOwner(ctx context.Context, tenantID string, email string) (domain.Tenant, error) {
+	objID, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return domain.Tenant{}, repository.ErrTenantNotFound
+	}
+
+	tr.s.mu.Lock()
+	defer tr.s.mu.Unlock()
+
+	for i, tenant := range tr.s.tenants {
+		if tenant.ID != objID {
+			continue
+		}
+		if tenant.OwnerEmail == "" {
+			trackID(ctx, tr.s, &tr.s.tenants, tenant.ID)
+			tenant.OwnerEmail = email
+			tenant.UpdatedAt = time.Now()
+			tr.s.tenants[i] = clone(tenant)
+		}
+		return clone(tenant), nil
+	}
+	return domain.Tenant{}, repository.ErrTenantNotFound
+}
+
+func (tr *tenantRepository) findOne(match func(domain.Tenant) bool) (domain.Tenant, error) {
+	tr.s.mu.Lock()
+	defer tr.s.mu.Unlock()
+
+	for _, tenant := range tr.s.tenants {
+		if match(tenant) {
+			return clone(tenant), nil
+		}
+	}
+	return domain.Tenant{}, repository.ErrTenantNotFound
+}
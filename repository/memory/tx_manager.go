@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// txKey marks the context of a transaction, holding its *tx.
+type txKey struct{}
+
+// tx is a running transaction of a store with the steps undoing its
+// writes, oldest first.
+type tx struct {
+	s    *store
+	undo []func()
+}
+
+type txManager struct {
+	s *store
+}
+
+// WithinTx implements domain.TxManager.
+// Transactions run one at a time. Rolling one back undoes the writes made
+// within it only; a record also changed outside of it meanwhile is put
+// back the way the transaction found it.
+func (tm *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tm.s.txOf(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tm.s.tx.Lock()
+	defer tm.s.tx.Unlock()
+
+	t := &tx{s: tm.s}
+	err := fn(context.WithValue(ctx, txKey{}, t))
+	if err != nil {
+		tm.s.mu.Lock()
+		for i := len(t.undo) - 1; i >= 0; i-- {
+			t.undo[i]()
+		}
+		tm.s.mu.Unlock()
+	}
+	return err
+}
+
+// txOf returns the transaction of s ctx runs in, or nil.
+func (s *store) txOf(ctx context.Context) *tx {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.s == s {
+		return t
+	}
+	return nil
+}
+
+// track remembers, when ctx runs in a transaction, the record of list that
+// same matches as it is now, or that there is none, so that rolling the
+// transaction back puts it back. The caller holds the lock and calls track
+// before writing the record.
+func track[T any](ctx context.Context, s *store, list *[]T, same func(T) bool) {
+	t := s.txOf(ctx)
+	if t == nil {
+		return
+	}
+	i := slices.IndexFunc(*list, same)
+	var before T
+	if i >= 0 {
+		before = clone((*list)[i])
+	}
+	t.undo = append(t.undo, func() {
+		j := slices.IndexFunc(*list, same)
+		switch {
+		case i < 0 && j >= 0:
+			*list = slices.Delete(*list, j, j+1)
+		case i >= 0 && j >= 0:
+			(*list)[j] = before
+		case i >= 0:
+			*list = append(*list, before)
+		}
+	})
+}
+
+// trackKey is track for the record of m at key.
+func trackKey[V any](ctx context.Context, s *store, m map[string]V, key string) {
+	t := s.txOf(ctx)
+	if t == nil {
+		return
+	}
+	before, ok := m[key]
+	if ok {
+		before = clone(before)
+	}
+	t.undo = append(t.undo, func() {
+		if ok {
+			m[key] = before
+		} else {
+			delete(m, key)
+		}
+	})
+}
+
+// trackID is track for the record with the ID id.
+func trackID[T any](ctx context.Context, s *store, list *[]T, id primitive.ObjectID) {
+	track(ctx, s, list, func(record T) bool { return idOf(record) == id })
+}
+
+func idOf(record any) primitive.ObjectID {
+	switch record := record.(type) {
+	case domain.User:
+		return record.ID
+	case domain.AuditEntry:
+		return record.ID
+	case domain.KYC:
+		return record.ID
+	case domain.MagicLink:
+		return record.ID
+	case domain.Tenant:
+		return record.ID
+	case domain.Branch:
+		return record.ID
+	case domain.Loan:
+		return record.ID
+	case domain.OfficerAssignment:
+		return record.ID
+	}
+	panic(fmt.Sprintf("memory: %T has no ID", record))
+}
@@ -0,0 +1,431 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type userRepository struct {
+	s *store
+}
+
+// find returns the index of the user userID visible with ctx, or -1.
+// The caller holds the lock.
+func (ur *userRepository) find(ctx context.Context, userID string) (int, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return -1, repository.ErrInvalidID
+	}
+	for i, user := range ur.s.users {
+		if user.ID == objID && userInScope(ctx, user) {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// update applies change to the user userID and returns the updated user.
+func (ur *userRepository) update(ctx context.Context, userID string, change func(*domain.User)) (domain.User, error) {
+	return ur.updateAt(ctx, userID, 0, change)
+}
+
+// updateAt is update for the user at version only, or at any version when
+// version is 0.
+func (ur *userRepository) updateAt(ctx context.Context, userID string, version int64, change func(*domain.User)) (domain.User, error) {
+	ur.s.mu.Lock()
+	defer ur.s.mu.Unlock()
+
+	i, err := ur.find(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if i < 0 {
+		return domain.User{}, repository.ErrUserNotFound
+	}
+	if current := ur.s.users[i].Version; version != 0 && current != version {
+		return domain.User{}, &repository.ConflictError{Expected: version, Current: current}
+	}
+	trackID(ctx, ur.s, &ur.s.users, ur.s.users[i].ID)
+	change(&ur.s.users[i])
+	ur.s.users[i] = clone(ur.s.users[i])
+	return clone(ur.s.users[i]), nil
+}
+
+func (ur *userRepository) findBy(ctx context.Context, match func(domain.User) bool) (domain.User, error) {
+	ur.s.mu.Lock()
+	defer ur.s.mu.Unlock()
+
+	for _, user := range ur.s.users {
+		if userInScope(ctx, user) && match(user) {
+			return clone(user), nil
+		}
+	}
+	return domain.User{}, repository.ErrUserNotFound
+}
+
+// ActivateUser implements domain.UserRepository.
+func (ur *userRepository) ActivateUser(ctx context.Context, userID string) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		user.Active = true
+		user.VerifyToken = ""
+		user.UpdatedAt = time.Now()
+		user.Version++
+	})
+	return err
+}
+
+// UpdateVerifyToken implements domain.UserRepository.
+func (ur *userRepository) UpdateVerifyToken(ctx context.Context, userID string, tokenHash string, sentAt time.Time) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		user.VerifyToken = tokenHash
+		user.VerifySentAt = sentAt
+	})
+	return err
+}
+
+// Create implements domain.UserRepository.
+func (ur *userRepository) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.TenantID = tenantID
+	user.Version = 1
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	ur.s.mu.Lock()
+	defer ur.s.mu.Unlock()
+	if ur.emailTaken(user.TenantID, user.Email, user.ID) {
+		return domain.User{}, repository.ErrEmailTaken
+	}
+	trackID(ctx, ur.s, &ur.s.users, user.ID)
+	ur.s.users = append(ur.s.users, clone(user))
+	return user, nil
+}
+
+// emailTaken reports whether a user of the tenant other than userID that
+// is not deleted has email, which the unique index of the Mongo users
+// rules out. The caller holds the lock.
+func (ur *userRepository) emailTaken(tenantID string, email string, userID primitive.ObjectID) bool {
+	for _, user := range ur.s.users {
+		if user.TenantID == tenantID && user.Email == email && user.ID != userID && user.DeletedAt.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete implements domain.UserRepository.
+func (ur *userRepository) Delete(ctx context.Context, userID string) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		now := time.Now()
+		user.DeletedAt = now
+		user.Tokens = []string{}
+		user.UpdatedAt = now
+		user.Version++
+	})
+	return err
+}
+
+// Erase implements domain.UserRepository.
+func (ur *userRepository) Erase(ctx context.Context, userID string) (domain.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.User{}, repository.ErrInvalidID
+	}
+
+	ur.s.mu.Lock()
+	defer ur.s.mu.Unlock()
+
+	for i, user := range ur.s.users {
+		if user.ID != objID || !user.ErasedAt.IsZero() || !inScope(ctx, user.TenantID) {
+			continue
+		}
+		before := clone(user)
+		trackID(ctx, ur.s, &ur.s.users, user.ID)
+
+		now := time.Now()
+		user.FirstName = domain.ErasedName
+		user.LastName = ""
+		user.Email = domain.ErasedEmail(userID)
+		user.Password = ""
+		user.PasswordHistory = []string{}
+		user.Tokens = []string{}
+		user.Active = false
+		user.ErasedAt = now
+		user.UpdatedAt = now
+		user.Version++
+		if user.DeletedAt.IsZero() {
+			user.DeletedAt = now
+		}
+		user.Profile = domain.Profile{}
+		user.VerifyToken = ""
+		user.PendingEmail = ""
+		user.EmailChangeToken = ""
+		user.EmailChangeExpiresAt = time.Time{}
+		user.SuspendReason = ""
+		ur.s.users[i] = clone(user)
+		return before, nil
+	}
+	return domain.User{}, repository.ErrUserNotFound
+}
+
+// List implements domain.UserRepository.
+func (ur *userRepository) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.User], error) {
+	ur.s.mu.Lock()
+	users := make([]domain.User, 0, len(ur.s.users))
+	for _, user := range ur.s.users {
+		if userInScope(ctx, user) {
+			users = append(users, user)
+		}
+	}
+	ur.s.mu.Unlock()
+
+	return findPage(users, spec)
+}
+
+// GetByEmail implements domain.UserRepository.
+func (ur *userRepository) GetByEmail(ctx context.Context, email string) (domain.User, error) {
+	return ur.findBy(ctx, func(user domain.User) bool { return user.Email == email })
+}
+
+// GetByID implements domain.UserRepository.
+func (ur *userRepository) GetByID(ctx context.Context, userID string) (domain.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.User{}, repository.ErrInvalidID
+	}
+	return ur.findBy(ctx, func(user domain.User) bool { return user.ID == objID })
+}
+
+// IsUserActive implements domain.UserRepository.
+func (ur *userRepository) IsUserActive(ctx context.Context, userID string) (bool, error) {
+	user, err := ur.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.Active, nil
+}
+
+// IsOwner implements domain.UserRepository.
+func (ur *userRepository) IsOwner(ctx context.Context, userID string) (bool, error) {
+	user, err := ur.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsOwner, nil
+}
+
+// UpdateRole implements domain.UserRepository.
+func (ur *userRepository) UpdateRole(ctx context.Context, userID string, role string) (domain.User, error) {
+	return ur.update(ctx, userID, func(user *domain.User) {
+		user.Role = role
+		user.UpdatedAt = time.Now()
+		user.Version++
+		user.TokenVersion++
+	})
+}
+
+// SetOfficer implements domain.UserRepository.
+func (ur *userRepository) SetOfficer(ctx context.Context, userID string, officerID string) (domain.User, error) {
+	return ur.update(ctx, userID, func(user *domain.User) {
+		user.OfficerID = officerID
+		user.UpdatedAt = time.Now()
+		user.Version++
+	})
+}
+
+// SetBranch implements domain.UserRepository.
+func (ur *userRepository) SetBranch(ctx context.Context, userID string, branchID string) (domain.User, error) {
+	return ur.update(ctx, userID, func(user *domain.User) {
+		user.BranchID = branchID
+		user.UpdatedAt = time.Now()
+		user.Version++
+	})
+}
+
+// SetPendingEmail implements domain.UserRepository.
+func (ur *userRepository) SetPendingEmail(ctx context.Context, userID string, email string, tokenHash string, expiresAt time.Time) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		user.PendingEmail = email
+		user.EmailChangeToken = tokenHash
+		user.EmailChangeExpiresAt = expiresAt
+		user.Version++
+	})
+	return err
+}
+
+// GetByEmailChangeToken implements domain.UserRepository.
+func (ur *userRepository) GetByEmailChangeToken(ctx context.Context, tokenHash string) (domain.User, error) {
+	return ur.findBy(ctx, func(user domain.User) bool { return user.EmailChangeToken == tokenHash })
+}
+
+// ConfirmEmail implements domain.UserRepository.
+func (ur *userRepository) ConfirmEmail(ctx context.Context, userID string, tokenHash string) error {
+	ur.s.mu.Lock()
+	defer ur.s.mu.Unlock()
+
+	i, err := ur.find(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if i < 0 || ur.s.users[i].EmailChangeToken != tokenHash {
+		return repository.ErrUserNotFound
+	}
+
+	user := &ur.s.users[i]
+	if ur.emailTaken(user.TenantID, user.PendingEmail, user.ID) {
+		return repository.ErrEmailTaken
+	}
+	trackID(ctx, ur.s, &ur.s.users, user.ID)
+	user.Email = user.PendingEmail
+	user.Tokens = []string{}
+	user.TokenVersion++
+	user.UpdatedAt = time.Now()
+	user.Version++
+	user.PendingEmail = ""
+	user.EmailChangeToken = ""
+	user.EmailChangeExpiresAt = time.Time{}
+	*user = clone(*user)
+	return nil
+}
+
+// RevokeAllRefreshTokens implements domain.UserRepository.
+func (ur *userRepository) RevokeAllRefreshTokens(ctx context.Context, userID string) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		user.Tokens = []string{}
+		user.TokenVersion++
+	})
+	return err
+}
+
+// Count implements domain.UserRepository. Like the Mongo count it includes
+// deleted users.
+func (ur *userRepository) Count(ctx context.Context) (int64, error) {
+	ur.s.mu.Lock()
+	defer ur.s.mu.Unlock()
+
+	var count int64
+	for _, user := range ur.s.users {
+		if userInScope(ctx, user) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// SetSuspended implements domain.UserRepository.
+func (ur *userRepository) SetSuspended(ctx context.Context, userID string, suspended bool, reason string) (domain.User, error) {
+	return ur.update(ctx, userID, func(user *domain.User) {
+		now := time.Now()
+		user.Suspended = suspended
+		user.UpdatedAt = now
+		user.Version++
+		if suspended {
+			user.SuspendedAt = now
+			user.SuspendReason = reason
+			user.Tokens = []string{}
+			return
+		}
+		user.SuspendedAt = time.Time{}
+		user.SuspendReason = ""
+	})
+}
+
+// UpdateProfile implements domain.UserRepository.
+func (ur *userRepository) UpdateProfile(ctx context.Context, userID string, version int64, profile domain.EditableProfile) (domain.User, error) {
+	return ur.updateAt(ctx, userID, version, func(user *domain.User) {
+		user.FirstName = profile.FirstName
+		user.LastName = profile.LastName
+		user.Profile = profile.Profile
+		user.UpdatedAt = time.Now()
+		user.Version++
+	})
+}
+
+// RefreshTokenExist implements domain.UserRepository.
+func (ur *userRepository) RefreshTokenExist(ctx context.Context, userID string, refreshToken string) (bool, error) {
+	user, err := ur.GetByID(ctx, userID)
+	if err == repository.ErrUserNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, token := range user.Tokens {
+		if token == refreshToken {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ResetUserPassword implements domain.UserRepository.
+func (ur *userRepository) ResetUserPassword(ctx context.Context, userID string, resetPassword domain.ResetPasswordRequest) error {
+	_, err := ur.update(ctx, userID, setPassword(resetPassword.NewPassword))
+	return err
+}
+
+// RevokeRefreshToken implements domain.UserRepository.
+func (ur *userRepository) RevokeRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+	revoked := false
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		tokens := user.Tokens[:0]
+		for _, token := range user.Tokens {
+			if token == refreshToken {
+				revoked = true
+				continue
+			}
+			tokens = append(tokens, token)
+		}
+		user.Tokens = tokens
+	})
+	if err == nil && !revoked {
+		return repository.ErrUserNotFound
+	}
+	return err
+}
+
+// Update implements domain.UserRepository.
+func (ur *userRepository) Update(ctx context.Context, userID string, version int64, update domain.UserUpdate) (domain.User, error) {
+	return ur.updateAt(ctx, userID, version, func(user *domain.User) {
+		user.FirstName = update.FirstName
+		user.LastName = update.LastName
+		user.UpdatedAt = update.UpdatedAt
+		user.Version++
+	})
+}
+
+// UpdateRefreshToken implements domain.UserRepository.
+func (ur *userRepository) UpdateRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) {
+		user.Tokens = append(user.Tokens, refreshToken)
+	})
+	return err
+}
+
+// UpdateUserPassword implements domain.UserRepository.
+func (ur *userRepository) UpdateUserPassword(ctx context.Context, userID string, updatePassword domain.UpdatePassword) error {
+	_, err := ur.update(ctx, userID, setPassword(updatePassword.NewPassword))
+	return err
+}
+
+// setPassword sets a new password hash and moves the current one to the
+// front of the password history, like the Mongo passwordUpdate.
+func setPassword(hashedPassword string) func(*domain.User) {
+	return func(user *domain.User) {
+		history := append([]string{user.Password}, user.PasswordHistory...)
+		if len(history) > domain.MaxPasswordHistory {
+			history = history[:domain.MaxPasswordHistory]
+		}
+		user.Password = hashedPassword
+		user.PasswordHistory = history
+		user.UpdatedAt = time.Now()
+	}
+}
@@ -0,0 +1,260 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collections the migrations refer to that have no name in domain
+const (
+	collectionUsers         = "users"
+	collectionPasswordReset = "password-reset"
+)
+
+// All is every migration, oldest first. New migrations go at the end;
+// applied ones are never edited.
+var All = []Migration{
+	{Version: 1, Name: "create_indexes", Up: createIndexes},
+	{Version: 2, Name: "unique_user_email", Up: uniqueUserEmail},
+	{Version: 3, Name: "expire_password_resets", Up: expirePasswordResets},
+	{Version: 4, Name: "backfill_user_defaults", Up: backfillUserDefaults},
+	{Version: 5, Name: "add_validators", Up: addValidators},
+	{Version: 6, Name: "backfill_user_versions", Up: backfillUserVersions},
+	{Version: 7, Name: "expire_idempotency_keys", Up: expireIdempotencyKeys},
+	{Version: 8, Name: "create_loans", Up: createLoans},
+	{Version: 9, Name: "backfill_loan_versions", Up: backfillLoanVersions},
+}
+
+// createIndexes creates the indexes the repositories relied on before
+// migrations existed.
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	// tenant slugs are unique
+	_, err := db.Collection(domain.CollectionTenants).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the text index the search relies on
+	_, err = db.Collection(collectionUsers).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "first_name", Value: "text"},
+			{Key: "last_name", Value: "text"},
+			{Key: "email", Value: "text"},
+			{Key: "profile.phone", Value: "text"},
+			{Key: "profile.national_id", Value: "text"},
+		},
+		Options: options.Index().SetName("users_search"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// branch codes are unique within a tenant
+	_, err = db.Collection(domain.CollectionBranches).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// uniqueUserEmail keeps an email to one user per tenant. Deleted users
+// keep their address, so deleted_at is part of the key: it is missing, and
+// indexed as null, on every user that is not deleted. It fails while two
+// users share an address, which has to be resolved by hand.
+func uniqueUserEmail(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(collectionUsers).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "email", Value: 1}, {Key: "deleted_at", Value: 1}},
+		Options: options.Index().SetName("users_email").SetUnique(true),
+	})
+	return err
+}
+
+// expirePasswordResets lets Mongo delete reset codes once they expire.
+func expirePasswordResets(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(collectionPasswordReset).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresat", Value: 1}},
+		Options: options.Index().SetName("password_reset_ttl").SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// backfillUserDefaults gives the users stored before these fields existed
+// the values new users start with, so that they pass the validators.
+func backfillUserDefaults(ctx context.Context, db *mongo.Database) error {
+	users := db.Collection(collectionUsers)
+	defaults := []struct {
+		field string
+		value any
+	}{
+		{"role", domain.RoleUser},
+		{"suspended", false},
+		{"is_owner", false},
+		{"password_history", bson.A{}},
+		{"refresh_tokens", bson.A{}},
+	}
+	for _, d := range defaults {
+		filter := bson.M{d.field: bson.M{"$exists": false}}
+		if _, err := users.UpdateMany(ctx, filter, bson.M{"$set": bson.M{d.field: d.value}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillUserVersions starts the users stored before versions existed at
+// version 1, where new users start.
+func backfillUserVersions(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"version": bson.M{"$exists": false}}
+	_, err := db.Collection(collectionUsers).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"version": int64(1)}})
+	return err
+}
+
+// expireIdempotencyKeys lets Mongo drop the stored responses of
+// idempotent requests once they expire.
+func expireIdempotencyKeys(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(domain.CollectionIdempotencyKeys).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("idempotency_keys_ttl").SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// createLoans indexes the loans by borrower and validates them like the
+// collections of addValidators.
+func createLoans(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(domain.CollectionLoans).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "borrower_id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("loans_borrower"),
+	})
+	if err != nil {
+		return err
+	}
+	return setValidator(ctx, db, domain.CollectionLoans, bson.M{
+		"bsonType": "object",
+		"required": bson.A{"tenant_id", "borrower_id", "amount", "status", "created_at"},
+		"properties": bson.M{
+			"tenant_id":   str,
+			"borrower_id": str,
+			"amount":      integer,
+			"term_months": integer,
+			"status":      str,
+			"created_at":  date,
+		},
+	})
+}
+
+// backfillLoanVersions starts the loans stored before versions existed at
+// version 1, where new loans start.
+func backfillLoanVersions(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"version": bson.M{"$exists": false}}
+	_, err := db.Collection(domain.CollectionLoans).UpdateMany(ctx, filter, bson.M{"$set": bson.M{"version": int64(1)}})
+	return err
+}
+
+// addValidators makes Mongo reject documents missing what the
+// repositories rely on. The validation level is moderate: documents that
+// were already invalid can still be updated.
+func addValidators(ctx context.Context, db *mongo.Database) error {
+	for name, schema := range validators {
+		if err := setValidator(ctx, db, name, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setValidator(ctx context.Context, db *mongo.Database, name string, schema bson.M) error {
+	validator := bson.M{"$jsonSchema": schema}
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: name},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}).Err()
+	if !isNamespaceNotFound(err) {
+		return err
+	}
+
+	opts := options.CreateCollection().
+		SetValidator(validator).
+		SetValidationLevel("moderate").
+		SetValidationAction("error")
+	err = db.CreateCollection(ctx, name, opts)
+	if isNamespaceExists(err) {
+		// created by another instance in the meantime
+		return setValidator(ctx, db, name, schema)
+	}
+	return err
+}
+
+var (
+	str      = bson.M{"bsonType": "string"}
+	boolean  = bson.M{"bsonType": "bool"}
+	date     = bson.M{"bsonType": "date"}
+	integer  = bson.M{"bsonType": bson.A{"int", "long"}}
+	nullable = bson.M{"bsonType": bson.A{"array", "null"}}
+)
+
+// validators are the JSON schemas of the collections. They only describe
+// the fields the repositories query by or rely on.
+var validators = map[string]bson.M{
+	collectionUsers: {
+		"bsonType": "object",
+		"required": bson.A{"tenant_id", "email", "password", "role", "active", "created_at"},
+		"properties": bson.M{
+			"tenant_id":        str,
+			"first_name":       str,
+			"last_name":        str,
+			"email":            str,
+			"password":         str,
+			"role":             str,
+			"active":           boolean,
+			"suspended":        boolean,
+			"is_owner":         boolean,
+			"password_history": nullable,
+			"refresh_tokens":   nullable,
+			"created_at":       date,
+			"updated_at":       date,
+			"deleted_at":       date,
+			"erased_at":        date,
+		},
+	},
+	collectionPasswordReset: {
+		"bsonType": "object",
+		"required": bson.A{"tenant_id", "email", "code", "expiresat"},
+		"properties": bson.M{
+			"tenant_id": str,
+			"email":     str,
+			"code":      str,
+			"expiresat": date,
+			"attempts":  integer,
+		},
+	},
+	domain.CollectionTenants: {
+		"bsonType": "object",
+		"required": bson.A{"name", "slug", "primary", "active"},
+		"properties": bson.M{
+			"name":    str,
+			"slug":    str,
+			"primary": boolean,
+			"active":  boolean,
+		},
+	},
+	domain.CollectionBranches: {
+		"bsonType": "object",
+		"required": bson.A{"tenant_id", "name", "code"},
+		"properties": bson.M{
+			"tenant_id": str,
+			"name":      str,
+			"code":      str,
+			"active":    boolean,
+		},
+	},
+}
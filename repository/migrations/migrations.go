@@ -0,0 +1,139 @@
+// Package migrations evolves the Mongo database the repositories are
+// stored in. A migration creates indexes, installs validators or rewrites
+// documents; each one is applied once, in version order, and recorded in
+// the schema_migrations collection.
+//
+// Instances starting together may apply the same migration at the same
+// time, so every migration must be safe to run twice.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionMigrations records the applied migrations.
+const CollectionMigrations = "schema_migrations"
+
+// Migration is one step in the evolution of the database.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// Status is a migration and the time it was applied, which is zero while
+// it is pending.
+type Status struct {
+	Migration
+	AppliedAt time.Time
+}
+
+type record struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Up applies the migrations db has not seen yet, oldest first. It stops at
+// the first one that fails.
+func Up(ctx context.Context, db *mongo.Database) error {
+	pending, err := Pending(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if err := m.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+		}
+		applied := record{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+		_, err := db.Collection(CollectionMigrations).ReplaceOne(ctx, bson.M{"_id": m.Version}, applied, options.Replace().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pending returns the migrations db has not seen yet, oldest first.
+func Pending(ctx context.Context, db *mongo.Database) ([]Migration, error) {
+	statuses, err := List(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, status := range statuses {
+		if status.AppliedAt.IsZero() {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// List returns the status of every migration, oldest first.
+func List(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	if err := validate(All); err != nil {
+		return nil, err
+	}
+
+	cursor, err := db.Collection(CollectionMigrations).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int64]time.Time, len(records))
+	for _, r := range records {
+		appliedAt[r.Version] = r.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(All))
+	for _, m := range All {
+		statuses = append(statuses, Status{Migration: m, AppliedAt: appliedAt[m.Version]})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// validate checks that every migration has a version of its own.
+func validate(migrations []Migration) error {
+	seen := make(map[int64]bool, len(migrations))
+	for _, m := range migrations {
+		if m.Version <= 0 || m.Name == "" || m.Up == nil {
+			return fmt.Errorf("migration %d %q is incomplete", m.Version, m.Name)
+		}
+		if seen[m.Version] {
+			return fmt.Errorf("migration version %d is used twice", m.Version)
+		}
+		seen[m.Version] = true
+	}
+	return nil
+}
+
+// codes of the errors returned for a collection that does not exist and
+// for one that is created twice
+const (
+	errNamespaceNotFound = 26
+	errNamespaceExists   = 48
+)
+
+func isNamespaceNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == errNamespaceNotFound
+}
+
+func isNamespaceExists(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == errNamespaceExists
+}
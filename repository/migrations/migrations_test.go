@@ -0,0 +1,90 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestVersions(t *testing.T) {
+	if err := validate(All); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(All); i++ {
+		if All[i].Version <= All[i-1].Version {
+			t.Errorf("migration %d %s comes after version %d", All[i].Version, All[i].Name, All[i-1].Version)
+		}
+	}
+
+	twice := []Migration{All[0], All[0]}
+	if err := validate(twice); err == nil {
+		t.Error("a version used twice should be refused")
+	}
+}
+
+// TestUp applies the migrations twice to a fresh database on the Mongo
+// server at MONGO_TEST_URI.
+func TestUp(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	defer client.Disconnect(context.Background())
+
+	db := client.Database(fmt.Sprintf("loan_tracker_test_%d", time.Now().UnixNano()))
+	defer db.Drop(context.Background())
+
+	// a user stored before the defaults existed
+	_, err = db.Collection(collectionUsers).InsertOne(ctx, bson.M{
+		"tenant_id": "tenant-a", "email": "old@example.com", "password": "hash", "active": true, "created_at": time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := Up(ctx, db); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+	pending, err := Pending(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected every migration applied, %d pending", len(pending))
+	}
+
+	var old bson.M
+	if err := db.Collection(collectionUsers).FindOne(ctx, bson.M{"email": "old@example.com"}).Decode(&old); err != nil {
+		t.Fatal(err)
+	}
+	if old["role"] != "user" {
+		t.Errorf("expected the old user backfilled with role user, got %v", old["role"])
+	}
+
+	_, err = db.Collection(collectionUsers).InsertOne(ctx, bson.M{
+		"tenant_id": "tenant-a", "email": "old@example.com", "password": "hash", "role": "borrower", "active": true, "created_at": time.Now(),
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("expected a duplicate email to be refused, got %v", err)
+	}
+
+	_, err = db.Collection(collectionUsers).InsertOne(ctx, bson.M{"email": "no-tenant@example.com"})
+	if err == nil {
+		t.Error("expected the validator to refuse a user without a tenant")
+	}
+}
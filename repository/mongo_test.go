@@ -0,0 +1,67 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/internal/repotest"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/migrations"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestContract runs the repository contract against the Mongo server at
+// MONGO_TEST_URI or, without one, a single node replica set started with
+// docker. Each test gets a fresh database that is dropped afterwards. The
+// server has to be a replica set for the transactions to roll back.
+func TestContract(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		uri = startMongo(t)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	repotest.Run(t, func(t *testing.T) repository.Set {
+		db := client.Database(fmt.Sprintf("loan_tracker_test_%d", time.Now().UnixNano()))
+		t.Cleanup(func() { db.Drop(context.Background()) })
+
+		if err := migrations.Up(context.Background(), db); err != nil {
+			t.Fatalf("migrating: %v", err)
+		}
+		return repository.NewMongoSet(db, false)
+	})
+}
+
+// startMongo starts a replica set of one node and waits for it to elect
+// itself. The node knows itself by its address in the container, so the
+// client connects to it directly rather than through the set.
+func startMongo(t *testing.T) string {
+	id, addr := repotest.Container(t, "27017", "mongo:7", "--replSet", "rs0", "--bind_ip_all")
+
+	wait := time.Minute
+	repotest.Exec(t, id, wait, "mongosh", "--quiet", "--eval", "rs.initiate()")
+	deadline := time.Now().Add(wait)
+	for {
+		out := repotest.Exec(t, id, wait, "mongosh", "--quiet", "--eval", "db.hello().isWritablePrimary")
+		if strings.TrimSpace(out) == "true" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the replica set elected no primary")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return "mongodb://" + addr + "/?directConnection=true"
+}
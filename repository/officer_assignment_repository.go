@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type officerAssignmentRepository struct {
+	assignments *mongo.Collection
+}
+
+func NewOfficerAssignmentRepository(db *mongo.Database) domain.OfficerAssignmentRepository {
+	return &officerAssignmentRepository{
+		assignments: db.Collection(domain.CollectionOfficerAssignments),
+	}
+}
+
+// Create implements domain.OfficerAssignmentRepository.
+func (ar *officerAssignmentRepository) Create(ctx context.Context, assignment domain.OfficerAssignment) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	assignment.TenantID = tenantID
+
+	_, err = ar.assignments.InsertOne(ctx, assignment)
+	return err
+}
+
+// ListByBorrower implements domain.OfficerAssignmentRepository.
+func (ar *officerAssignmentRepository) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.OfficerAssignment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "assigned_at", Value: -1}})
+	cursor, err := ar.assignments.Find(ctx, scoped(ctx, bson.M{"borrower_id": borrowerID}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	assignments := make([]domain.OfficerAssignment, 0)
+	err = cursor.All(ctx, &assignments)
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
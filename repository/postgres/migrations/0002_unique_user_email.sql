@@ -0,0 +1,3 @@
+-- an email belongs to one user of a tenant; deleted users keep theirs
+DROP INDEX users_tenant_email;
+CREATE UNIQUE INDEX users_tenant_email ON users (tenant_id, email) WHERE deleted_at IS NULL;
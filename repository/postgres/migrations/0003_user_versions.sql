@@ -0,0 +1,2 @@
+-- counts the changes to the data of a user, for optimistic concurrency
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
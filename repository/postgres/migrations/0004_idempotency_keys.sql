@@ -0,0 +1,11 @@
+-- the responses to requests sent with an Idempotency-Key, kept for retries
+CREATE TABLE idempotency_keys (
+    id TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    content_type TEXT NOT NULL,
+    body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,3 @@
+-- goes up whenever every session of a user is revoked, which revokes the
+-- access tokens issued before
+ALTER TABLE users ADD COLUMN token_version BIGINT NOT NULL DEFAULT 0;
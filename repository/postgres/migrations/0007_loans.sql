@@ -0,0 +1,17 @@
+-- loans, from the application to the payout; amounts are in minor units
+CREATE TABLE loans (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    borrower_id TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    term_months INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    approved_by TEXT NOT NULL,
+    approved_at TIMESTAMPTZ,
+    disbursed_by TEXT NOT NULL,
+    disbursed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX loans_tenant_borrower ON loans (tenant_id, borrower_id, created_at);
@@ -0,0 +1,2 @@
+-- counts the changes to a loan, for optimistic concurrency
+ALTER TABLE loans ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- the headers replayed with the response to an idempotent request
+ALTER TABLE idempotency_keys ADD COLUMN header JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,67 @@
+// Package postgres stores the repositories in PostgreSQL.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/sqlstore"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Dialect is the PostgreSQL dialect of the SQL store.
+var Dialect = sqlstore.Dialect{
+	NumberedPlaceholders: true,
+	LockRow:              " FOR UPDATE",
+	// any constant key works, as long as every instance uses the same
+	MigrationLock:     "SELECT pg_advisory_xact_lock(4711)",
+	IsUniqueViolation: isUniqueViolation,
+	IsRetryable:       isRetryable,
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isRetryable reports serialization failures and deadlocks.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// Open connects to the database at dsn, a postgres:// URL or a list of
+// key=value settings.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate brings the schema of db up to date.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	return sqlstore.Migrate(ctx, db, Dialect, scripts)
+}
+
+// NewSet returns the repositories stored in db.
+func NewSet(db *sql.DB) repository.Set {
+	return sqlstore.NewSet(db, Dialect)
+}
@@ -0,0 +1,61 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/internal/repotest"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// TestContract runs the repository contract against the server at
+// POSTGRES_TEST_URL or, without one, a server started with docker. Each
+// test gets a fresh schema that is dropped afterwards.
+func TestContract(t *testing.T) {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		url = startPostgres(t)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	admin, err := postgres.Open(ctx, url)
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", url, err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	repotest.Run(t, func(t *testing.T) repository.Set {
+		schema := fmt.Sprintf("loan_tracker_test_%d", time.Now().UnixNano())
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatalf("creating schema: %v", err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+		config, err := pgx.ParseConfig(url)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", url, err)
+		}
+		config.RuntimeParams["search_path"] = schema
+		db := stdlib.OpenDB(*config)
+		t.Cleanup(func() { db.Close() })
+
+		if err := postgres.Migrate(context.Background(), db); err != nil {
+			t.Fatalf("migrating: %v", err)
+		}
+		return postgres.NewSet(db)
+	})
+}
+
+// startPostgres starts a server and waits for it to accept connections.
+func startPostgres(t *testing.T) string {
+	id, addr := repotest.Container(t, "5432", "--env", "POSTGRES_PASSWORD=test", "postgres:16")
+	repotest.Exec(t, id, time.Minute, "pg_isready", "--username", "postgres", "--host", "127.0.0.1")
+	return "postgres://postgres:test@" + addr + "/postgres?sslmode=disable"
+}
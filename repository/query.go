@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// findPage returns the page of collection described by spec among the
+// documents matching base. Every list endpoint pages through it.
+func findPage[T any](ctx context.Context, collection *mongo.Collection, base bson.M, spec domain.QuerySpec) (domain.Page[T], error) {
+	filter := bson.A{base}
+	for _, condition := range spec.Conditions {
+		filter = append(filter, conditionFilter(condition))
+	}
+
+	total, err := collection.CountDocuments(ctx, bson.M{"$and": filter})
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+
+	// keyset pagination: continue after the last (sort value, _id) pair
+	after := "$gt"
+	direction := 1
+	if spec.SortDesc {
+		after = "$lt"
+		direction = -1
+	}
+	if spec.Cursor != "" {
+		value, id, err := query.DecodeCursor(spec)
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+		filter = append(filter, bson.M{"$or": bson.A{
+			bson.M{spec.SortBy: bson.M{after: value}},
+			bson.M{spec.SortBy: value, "_id": bson.M{after: id}},
+		}})
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: spec.SortBy, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(spec.Limit) + 1)
+	cursor, err := collection.Find(ctx, bson.M{"$and": filter}, opts)
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var raws []bson.Raw
+	for cursor.Next(ctx) {
+		raws = append(raws, append(bson.Raw(nil), cursor.Current...))
+	}
+	if err := cursor.Err(); err != nil {
+		return domain.Page[T]{}, err
+	}
+
+	page := domain.Page[T]{Items: make([]T, 0, len(raws)), Total: total}
+	if len(raws) > spec.Limit {
+		raws = raws[:spec.Limit]
+		last := raws[len(raws)-1]
+		page.NextCursor, err = query.EncodeCursor(spec, last.Lookup(spec.SortBy), last.Lookup("_id"))
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+	}
+	for _, raw := range raws {
+		var item T
+		if err := bson.Unmarshal(raw, &item); err != nil {
+			return domain.Page[T]{}, err
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+func conditionFilter(condition domain.Condition) bson.M {
+	switch condition.Op {
+	case domain.OpIn:
+		return bson.M{condition.Field: bson.M{"$in": condition.Value}}
+	case domain.OpGte:
+		return bson.M{condition.Field: bson.M{"$gte": condition.Value}}
+	case domain.OpLte:
+		return bson.M{condition.Field: bson.M{"$lte": condition.Value}}
+	case domain.OpPrefix:
+		prefix, _ := condition.Value.(string)
+		return bson.M{condition.Field: primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix), Options: "i"}}
+	default:
+		return bson.M{condition.Field: condition.Value}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type resetPasswordRepository struct {
@@ -28,7 +29,7 @@ func NewResetPasswordRepository(db *mongo.Database, userCollection string, reset
 func (rp *resetPasswordRepository) GetUserByEmail(c context.Context, email string) (*domain.User, error) {
 	collection := rp.database.Collection(rp.usersCollection)
 	var user domain.User
-	err := collection.FindOne(c, bson.M{"email": email}).Decode(&user)
+	err := collection.FindOne(c, userScope(c, bson.M{"email": email})).Decode(&user)
 	if err != nil {
 		log.Println("[repo] restePwd", err.Error())
 		return nil, ErrUserNotFound
@@ -42,7 +43,7 @@ func (rp *resetPasswordRepository) ResetPassword(c context.Context, userID strin
 	if err != nil {
 		return ErrInvalidID
 	}
-	res, err := collection.UpdateOne(c, bson.M{"_id": ObjID}, bson.M{"$set": bson.M{"password": resetPassword.NewPassword}})
+	res, err := collection.UpdateOne(c, userScope(c, bson.M{"_id": ObjID}), passwordUpdate(resetPassword.NewPassword))
 	if err != nil {
 		return err
 	}
@@ -55,7 +56,13 @@ func (rp *resetPasswordRepository) ResetPassword(c context.Context, userID strin
 func (rp *resetPasswordRepository) SaveOtp(c context.Context, otp *domain.OtpSave) error {
 	collection := rp.database.Collection(rp.resetCollection)
 
-	_, err := collection.InsertOne(c, otp)
+	tenantID, err := tenantOf(c)
+	if err != nil {
+		return err
+	}
+	otp.TenantID = tenantID
+
+	_, err = collection.InsertOne(c, otp)
 
 	if err != nil {
 		return err
@@ -69,7 +76,7 @@ func (rp *resetPasswordRepository) GetOTPByEmail(c context.Context, email string
 	collection := rp.database.Collection(rp.resetCollection)
 	var otp domain.OtpSave
 
-	err := collection.FindOne(c, bson.M{"email": email}).Decode(&otp)
+	err := collection.FindOne(c, scoped(c, bson.M{"email": email})).Decode(&otp)
 
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, ErrUserNotFound
@@ -86,7 +93,7 @@ func (rp *resetPasswordRepository) DeleteOtp(c context.Context, email string) er
 
 	collection := rp.database.Collection(rp.resetCollection)
 
-	_, err := collection.DeleteOne(c, bson.M{"email": email})
+	_, err := collection.DeleteOne(c, scoped(c, bson.M{"email": email}))
 
 	if err != nil {
 		return err
@@ -94,3 +101,28 @@ func (rp *resetPasswordRepository) DeleteOtp(c context.Context, email string) er
 
 	return err
 }
+
+// DeleteOtps implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) DeleteOtps(c context.Context, email string) error {
+	_, err := rp.database.Collection(rp.resetCollection).DeleteMany(c, scoped(c, bson.M{"email": email}))
+	return err
+}
+
+func (rp *resetPasswordRepository) IncrementOtpAttempts(c context.Context, email string) (int, error) {
+
+	collection := rp.database.Collection(rp.resetCollection)
+	var otp domain.OtpSave
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(c, scoped(c, bson.M{"email": email}), bson.M{"$inc": bson.M{"attempts": 1}}, opts).Decode(&otp)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, ErrUserNotFound
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return otp.Attempts, nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/search"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// number of records fetched per lookup before they are ranked
+const searchCandidates = 200
+
+type searchRepository struct {
+	users *mongo.Collection
+	loans *mongo.Collection
+}
+
+// NewSearchRepository returns a domain.SearchIndex backed by the text index
+// of the users collection.
+func NewSearchRepository(db *mongo.Database) domain.SearchIndex {
+	return &searchRepository{
+		users: db.Collection("users"),
+		loans: db.Collection(domain.CollectionLoans),
+	}
+}
+
+// Search implements domain.SearchIndex.
+// The text index finds whole words; a second lookup finds names and emails
+// starting with the first term, which the text index cannot. Both sets of
+// candidates are then ranked together, along with the loans of the
+// borrowers found and the loans whose ID is in the query.
+func (sr *searchRepository) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []domain.SearchResult{}, nil
+	}
+
+	borrowers := userScope(ctx, bson.M{"role": bson.M{"$in": bson.A{domain.RoleBorrower, domain.RoleUser}}})
+
+	// the terms hold letters and digits only, so none of them reads as a
+	// phrase or a negation
+	textFilter := bson.M{"$and": bson.A{borrowers, bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}}}}
+	textOpts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetLimit(searchCandidates)
+	candidates, err := sr.find(ctx, textFilter, textOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(terms[0]), Options: "i"}
+	prefixFilter := bson.M{"$and": bson.A{borrowers, bson.M{"$or": bson.A{
+		bson.M{"first_name": prefix},
+		bson.M{"last_name": prefix},
+		bson.M{"email": prefix},
+	}}}}
+	more, err := sr.find(ctx, prefixFilter, options.Find().SetLimit(searchCandidates))
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]domain.User)
+	docs := make([]search.Document, 0, len(candidates)+len(more))
+	for _, user := range append(candidates, more...) {
+		if _, ok := found[user.ID.Hex()]; ok {
+			continue
+		}
+		found[user.ID.Hex()] = user
+		docs = append(docs, search.BorrowerDocument(user))
+	}
+
+	loans, err := sr.loanDocuments(ctx, found, search.IDs(terms))
+	if err != nil {
+		return nil, err
+	}
+	return search.Rank(append(docs, loans...), terms, limit), nil
+}
+
+// loanDocuments returns the loans of the borrowers found and the loans with
+// one of ids, leaving out those whose borrower the caller cannot see.
+func (sr *searchRepository) loanDocuments(ctx context.Context, found map[string]domain.User, ids []primitive.ObjectID) ([]search.Document, error) {
+	borrowerIDs := make([]string, 0, len(found))
+	for id := range found {
+		borrowerIDs = append(borrowerIDs, id)
+	}
+	filter := scoped(ctx, bson.M{"$or": bson.A{
+		bson.M{"borrower_id": bson.M{"$in": borrowerIDs}},
+		bson.M{"_id": bson.M{"$in": ids}},
+	}})
+	cursor, err := sr.loans.Find(ctx, filter, options.Find().SetLimit(searchCandidates))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	loans := make([]domain.Loan, 0)
+	err = cursor.All(ctx, &loans)
+	if err != nil {
+		return nil, err
+	}
+
+	// the borrowers of the loans found by ID
+	missing := make([]primitive.ObjectID, 0)
+	for _, loan := range loans {
+		if _, ok := found[loan.BorrowerID]; ok {
+			continue
+		}
+		if id, err := primitive.ObjectIDFromHex(loan.BorrowerID); err == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		users, err := sr.find(ctx, userScope(ctx, bson.M{"_id": bson.M{"$in": missing}}), options.Find())
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			found[user.ID.Hex()] = user
+		}
+	}
+
+	docs := make([]search.Document, 0, len(loans))
+	for _, loan := range loans {
+		if borrower, ok := found[loan.BorrowerID]; ok {
+			docs = append(docs, search.LoanDocument(loan, borrower))
+		}
+	}
+	return docs, nil
+}
+
+func (sr *searchRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]domain.User, error) {
+	cursor, err := sr.users.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := make([]domain.User, 0)
+	err = cursor.All(ctx, &users)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Set holds one implementation of every repository. The routes are built on
+// a Set, so the application runs on whichever storage the Set is made of.
+type Set struct {
+	Users              domain.UserRepository
+	ResetPassword      domain.ResetPasswordRepository
+	Audit              domain.AuditRepository
+	KYC                domain.KYCRepository
+	MagicLinks         domain.MagicLinkRepository
+	LoginAttempts      domain.LoginAttemptRepository
+	Tenants            domain.TenantRepository
+	Branches           domain.BranchRepository
+	Loans              domain.LoanRepository
+	OfficerAssignments domain.OfficerAssignmentRepository
+	Search             domain.SearchIndex
+	Idempotency        domain.IdempotencyRepository
+	Tx                 domain.TxManager
+}
+
+// NewMongoSet returns the repositories stored in db. allowNonTx lets
+// transactions run without one on a standalone server, see NewTxManager.
+func NewMongoSet(db *mongo.Database, allowNonTx bool) Set {
+	return Set{
+		Users:              NewUserRepository(db),
+		ResetPassword:      NewResetPasswordRepository(db, "users", "password-reset"),
+		Audit:              NewAuditRepository(db),
+		KYC:                NewKYCRepository(db),
+		MagicLinks:         NewMagicLinkRepository(db),
+		LoginAttempts:      NewLoginAttemptRepository(db),
+		Tenants:            NewTenantRepository(db),
+		Branches:           NewBranchRepository(db),
+		Loans:              NewLoanRepository(db),
+		OfficerAssignments: NewOfficerAssignmentRepository(db),
+		Search:             NewSearchRepository(db),
+		Idempotency:        NewIdempotencyRepository(db),
+		Tx:                 NewTxManager(db.Client(), allowNonTx),
+	}
+}
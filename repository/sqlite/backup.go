@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"modernc.org/sqlite"
+)
+
+// backupPages is how many pages a backup copies at a time. Between two
+// steps the database is free for writers.
+const backupPages = 256
+
+// ErrBackupExists is returned when the destination of a backup already
+// exists.
+var ErrBackupExists = errors.New("backup destination already exists")
+
+type backuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+}
+
+// Backup copies db to a new database file at path while it stays in use.
+// The copy is a consistent snapshot: when a write lands during the backup,
+// the copy starts over.
+func Backup(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return ErrBackupExists
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		source, ok := driverConn.(backuper)
+		if !ok {
+			return fmt.Errorf("%T is not a SQLite connection", driverConn)
+		}
+		backup, err := source.NewBackup("file:" + path)
+		if err != nil {
+			return err
+		}
+
+		for more := true; more; {
+			if err := ctx.Err(); err != nil {
+				backup.Finish()
+				os.Remove(path)
+				return err
+			}
+			more, err = backup.Step(backupPages)
+			if err != nil {
+				backup.Finish()
+				os.Remove(path)
+				return err
+			}
+		}
+		return backup.Finish()
+	})
+}
@@ -0,0 +1,125 @@
+CREATE TABLE tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    owner_email TEXT NOT NULL DEFAULT '',
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    active BOOLEAN NOT NULL,
+    suspended BOOLEAN NOT NULL,
+    suspended_at DATETIME,
+    suspend_reason TEXT NOT NULL,
+    password TEXT NOT NULL,
+    password_history TEXT NOT NULL,
+    verify_token TEXT NOT NULL,
+    verify_sent_at DATETIME NOT NULL,
+    pending_email TEXT NOT NULL,
+    email_change_token TEXT NOT NULL,
+    email_change_expires_at DATETIME,
+    is_owner BOOLEAN NOT NULL,
+    refresh_tokens TEXT NOT NULL,
+    role TEXT NOT NULL,
+    branch_id TEXT NOT NULL,
+    officer_id TEXT NOT NULL,
+    profile TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    last_login DATETIME NOT NULL,
+    deleted_at DATETIME,
+    erased_at DATETIME
+);
+CREATE INDEX users_tenant_email ON users (tenant_id, email);
+CREATE INDEX users_tenant_created ON users (tenant_id, created_at);
+CREATE INDEX users_tenant_officer ON users (tenant_id, officer_id);
+
+CREATE TABLE password_resets (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    email TEXT NOT NULL,
+    code TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL
+);
+CREATE INDEX password_resets_tenant_email ON password_resets (tenant_id, email);
+
+CREATE TABLE audit_log (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX audit_log_tenant_user ON audit_log (tenant_id, user_id, created_at);
+
+CREATE TABLE kyc (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    documents TEXT NOT NULL,
+    rejection_reason TEXT NOT NULL,
+    reviewed_by TEXT NOT NULL,
+    submitted_at DATETIME,
+    reviewed_at DATETIME,
+    expires_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (tenant_id, user_id)
+);
+CREATE INDEX kyc_status_expires ON kyc (status, expires_at);
+
+CREATE TABLE magic_links (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    nonce_hash TEXT NOT NULL,
+    used BOOLEAN NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX magic_links_token ON magic_links (token_hash);
+CREATE INDEX magic_links_tenant_user ON magic_links (tenant_id, user_id);
+
+CREATE TABLE login_attempts (
+    id TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure DATETIME NOT NULL,
+    locked_until DATETIME NOT NULL
+);
+
+CREATE TABLE branches (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    code TEXT NOT NULL,
+    active BOOLEAN NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE (tenant_id, code)
+);
+
+CREATE TABLE officer_assignments (
+    id TEXT PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    borrower_id TEXT NOT NULL,
+    officer_id TEXT NOT NULL,
+    previous_officer_id TEXT NOT NULL,
+    branch_id TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    assigned_at DATETIME NOT NULL
+);
+CREATE INDEX officer_assignments_tenant_borrower ON officer_assignments (tenant_id, borrower_id, assigned_at);
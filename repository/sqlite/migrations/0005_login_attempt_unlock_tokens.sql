@@ -0,0 +1,3 @@
+-- the hash of the unlock link emailed for a locked account, which is
+-- consumed when the link is used
+ALTER TABLE login_attempts ADD COLUMN unlock_token TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,98 @@
+// Package sqlite stores the repositories in a SQLite database file, for
+// deployments on a single node.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"io/fs"
+	"net/url"
+
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/sqlstore"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Dialect is the SQLite dialect of the SQL store. SQLite locks the whole
+// database for writing, so rows need no locks of their own.
+var Dialect = sqlstore.Dialect{
+	IsUniqueViolation: isUniqueViolation,
+	IsRetryable:       isRetryable,
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// isRetryable reports a database that stayed locked by another writer
+// longer than the busy timeout.
+func isRetryable(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// the primary code is the low byte of an extended one
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// pragmas are run on every connection. WAL lets readers go on while a
+// write is in progress and busy_timeout makes writers wait for each other
+// instead of failing.
+var pragmas = []string{
+	"journal_mode(WAL)",
+	"busy_timeout(5000)",
+	"synchronous(NORMAL)",
+	"foreign_keys(ON)",
+}
+
+// dsn returns the data source name of the database file at path.
+// Transactions take the write lock when they begin, so that two of them
+// never deadlock upgrading their read locks, and times are written in a
+// form that sorts like the times themselves.
+func dsn(path string) string {
+	query := url.Values{
+		"_pragma":      pragmas,
+		"_txlock":      {"immediate"},
+		"_time_format": {"sqlite"},
+	}
+	return "file:" + path + "?" + query.Encode()
+}
+
+// Open opens the database file at path, creating it if needed.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate brings the schema of db up to date.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	return sqlstore.Migrate(ctx, db, Dialect, scripts)
+}
+
+// NewSet returns the repositories stored in db.
+func NewSet(db *sql.DB) repository.Set {
+	return sqlstore.NewSet(db, Dialect)
+}
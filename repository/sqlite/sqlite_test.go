@@ -0,0 +1,82 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dagota12/Loan-Tracker/internal/repotest"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/dagota12/Loan-Tracker/repository/sqlite"
+)
+
+func open(t *testing.T, path string) repository.Set {
+	t.Helper()
+	db, err := sqlite.Open(context.Background(), path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := sqlite.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return sqlite.NewSet(db)
+}
+
+// TestContract runs the repository contract against a fresh database file
+// per test.
+func TestContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Set {
+		return open(t, filepath.Join(t.TempDir(), "loan-tracker.db"))
+	})
+}
+
+func TestMigrateTwice(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "loan-tracker.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := sqlite.Migrate(ctx, db); err != nil {
+			t.Fatalf("migration %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := sqlite.Open(ctx, filepath.Join(dir, "loan-tracker.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := sqlite.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := sqlite.NewSet(db).Tenants.EnsurePrimary(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup := filepath.Join(dir, "backup.db")
+	if err := sqlite.Backup(ctx, db, backup); err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if err := sqlite.Backup(ctx, db, backup); err != sqlite.ErrBackupExists {
+		t.Fatalf("backup over an existing file: got %v, want %v", err, sqlite.ErrBackupExists)
+	}
+
+	restored, err := open(t, backup).Tenants.GetBySlug(ctx, "main")
+	if err != nil {
+		t.Fatalf("reading the backup: %v", err)
+	}
+	if restored.ID != tenant.ID {
+		t.Errorf("restored tenant %s, want %s", restored.ID.Hex(), tenant.ID.Hex())
+	}
+}
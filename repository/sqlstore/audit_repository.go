@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var auditEntries = table[domain.AuditEntry]{
+	name:    "audit_log",
+	columns: []string{"id", "tenant_id", "user_id", "actor_id", "action", "changes", "created_at"},
+	scan: func(row scanner) (domain.AuditEntry, error) {
+		var (
+			entry       domain.AuditEntry
+			id, changes string
+		)
+		err := row.Scan(&id, &entry.TenantID, &entry.UserID, &entry.ActorID, &entry.Action, &changes, &entry.CreatedAt)
+		if err != nil {
+			return domain.AuditEntry{}, err
+		}
+		entry.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return domain.AuditEntry{}, err
+		}
+		entry.CreatedAt = entry.CreatedAt.UTC()
+		return entry, fromJSON(changes, &entry.Changes)
+	},
+	values: func(entry domain.AuditEntry) []any {
+		return []any{entry.ID.Hex(), entry.TenantID, entry.UserID, entry.ActorID, entry.Action, jsonOf(entry.Changes), dbTime(entry.CreatedAt)}
+	},
+}
+
+type auditRepository struct {
+	s *store
+}
+
+// Create implements domain.AuditRepository.
+func (ar *auditRepository) Create(ctx context.Context, entry domain.AuditEntry) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	entry.TenantID = tenantID
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	return auditEntries.insert(ctx, ar.s, ar.s.conn(ctx), entry)
+}
+
+// ListByUser implements domain.AuditRepository.
+func (ar *auditRepository) ListByUser(ctx context.Context, userID string) ([]domain.AuditEntry, error) {
+	return auditEntries.find(ctx, ar.s, ar.s.conn(ctx), scoped(ctx, cond("user_id = ?", userID)), "ORDER BY created_at DESC")
+}
+
+// RedactChanges implements domain.AuditRepository.
+func (ar *auditRepository) RedactChanges(ctx context.Context, userID string, actions []string) error {
+	if len(actions) == 0 {
+		return nil
+	}
+	args := []any{userID}
+	for _, action := range actions {
+		args = append(args, action)
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(actions)), ", ")
+	c := scoped(ctx, cond("user_id = ? AND action IN ("+marks+")", args...))
+
+	return ar.s.inTx(ctx, func(tx *sql.Tx) error {
+		entries, err := auditEntries.find(ctx, ar.s, tx, c, ar.s.dialect.LockRow)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if len(entry.Changes) == 0 {
+				continue
+			}
+			for i := range entry.Changes {
+				entry.Changes[i].Old = domain.RedactedValue
+				entry.Changes[i].New = domain.RedactedValue
+			}
+			if err := auditEntries.update(ctx, ar.s, tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
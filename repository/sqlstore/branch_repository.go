@@ -0,0 +1,92 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var branches = table[domain.Branch]{
+	name:    "branches",
+	columns: []string{"id", "tenant_id", "name", "code", "active", "created_at", "updated_at"},
+	scan: func(row scanner) (domain.Branch, error) {
+		var (
+			branch domain.Branch
+			id     string
+		)
+		err := row.Scan(&id, &branch.TenantID, &branch.Name, &branch.Code, &branch.Active, &branch.CreatedAt, &branch.UpdatedAt)
+		if err != nil {
+			return domain.Branch{}, err
+		}
+		branch.CreatedAt = branch.CreatedAt.UTC()
+		branch.UpdatedAt = branch.UpdatedAt.UTC()
+		branch.ID, err = primitive.ObjectIDFromHex(id)
+		return branch, err
+	},
+	values: func(branch domain.Branch) []any {
+		return []any{branch.ID.Hex(), branch.TenantID, branch.Name, branch.Code, branch.Active, dbTime(branch.CreatedAt), dbTime(branch.UpdatedAt)}
+	},
+}
+
+type branchRepository struct {
+	s *store
+}
+
+// Create implements domain.BranchRepository.
+func (br *branchRepository) Create(ctx context.Context, branch domain.Branch) (domain.Branch, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	branch.TenantID = tenantID
+	if branch.ID.IsZero() {
+		branch.ID = primitive.NewObjectID()
+	}
+
+	err = branches.insert(ctx, br.s, br.s.conn(ctx), branch)
+	if err != nil && br.s.dialect.IsUniqueViolation(err) {
+		return domain.Branch{}, repository.ErrBranchCodeTaken
+	}
+	if err != nil {
+		return domain.Branch{}, err
+	}
+	return branch, nil
+}
+
+// GetByID implements domain.BranchRepository.
+func (br *branchRepository) GetByID(ctx context.Context, branchID string) (domain.Branch, error) {
+	if _, err := primitive.ObjectIDFromHex(branchID); err != nil {
+		return domain.Branch{}, repository.ErrBranchNotFound
+	}
+	branch, err := branches.findOne(ctx, br.s, br.s.conn(ctx), scoped(ctx, cond("id = ?", branchID)), "")
+	return branch, notFound(err, repository.ErrBranchNotFound)
+}
+
+// List implements domain.BranchRepository.
+func (br *branchRepository) List(ctx context.Context) ([]domain.Branch, error) {
+	return branches.find(ctx, br.s, br.s.conn(ctx), scoped(ctx, cond("1 = 1")), "ORDER BY name")
+}
+
+// Update implements domain.BranchRepository.
+func (br *branchRepository) Update(ctx context.Context, branchID string, request domain.UpdateBranchRequest) (domain.Branch, error) {
+	if _, err := primitive.ObjectIDFromHex(branchID); err != nil {
+		return domain.Branch{}, repository.ErrBranchNotFound
+	}
+	branch, err := branches.modify(ctx, br.s, scoped(ctx, cond("id = ?", branchID)), func(branch *domain.Branch) error {
+		branch.UpdatedAt = time.Now()
+		if request.Name != nil {
+			branch.Name = *request.Name
+		}
+		if request.Active != nil {
+			branch.Active = *request.Active
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Branch{}, notFound(err, repository.ErrBranchNotFound)
+	}
+	return branch, nil
+}
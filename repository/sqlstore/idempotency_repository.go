@@ -0,0 +1,84 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+var idempotencyKeys = table[domain.IdempotencyRecord]{
+	name:    "idempotency_keys",
+	columns: []string{"id", "fingerprint", "status", "content_type", "header", "body", "created_at", "expires_at"},
+	scan: func(row scanner) (domain.IdempotencyRecord, error) {
+		var (
+			record domain.IdempotencyRecord
+			header string
+		)
+		err := row.Scan(&record.Key, &record.Fingerprint, &record.Status, &record.ContentType, &header, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+		if err != nil {
+			return domain.IdempotencyRecord{}, err
+		}
+		record.CreatedAt = record.CreatedAt.UTC()
+		record.ExpiresAt = record.ExpiresAt.UTC()
+		return record, fromJSON(header, &record.Header)
+	},
+	values: func(record domain.IdempotencyRecord) []any {
+		return []any{record.Key, record.Fingerprint, record.Status, record.ContentType, jsonOf(record.Header), record.Body, dbTime(record.CreatedAt), dbTime(record.ExpiresAt)}
+	},
+}
+
+type idempotencyRepository struct {
+	s *store
+}
+
+// Reserve implements domain.IdempotencyRepository.
+// Every call also drops the records that have expired.
+func (ir *idempotencyRepository) Reserve(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	key := record.Key
+	record.Key = tenantKey(ctx, key)
+
+	var stored domain.IdempotencyRecord
+	reserved := false
+	err := ir.s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := ir.s.exec(ctx, tx, "DELETE FROM idempotency_keys WHERE expires_at <= ?", dbTime(time.Now())); err != nil {
+			return err
+		}
+
+		query := "INSERT INTO idempotency_keys (id, fingerprint, status, content_type, header, body, created_at, expires_at) " +
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING"
+		res, err := ir.s.exec(ctx, tx, query, idempotencyKeys.values(record)...)
+		if err != nil {
+			return err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if inserted == 1 {
+			stored, reserved = record, true
+			return nil
+		}
+
+		stored, err = idempotencyKeys.findOne(ctx, ir.s, tx, cond("id = ?", record.Key), "")
+		return err
+	})
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, err
+	}
+	stored.Key = key
+	return stored, reserved, nil
+}
+
+// Complete implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Complete(ctx context.Context, key string, status int, contentType string, header map[string]string, body []byte) error {
+	query := "UPDATE idempotency_keys SET status = ?, content_type = ?, header = ?, body = ? WHERE id = ? AND status = 0"
+	_, err := ir.s.exec(ctx, ir.s.conn(ctx), query, status, contentType, jsonOf(header), body, tenantKey(ctx, key))
+	return err
+}
+
+// Release implements domain.IdempotencyRepository.
+func (ir *idempotencyRepository) Release(ctx context.Context, key string) error {
+	return idempotencyKeys.delete(ctx, ir.s, ir.s.conn(ctx), cond("id = ?", tenantKey(ctx, key)))
+}
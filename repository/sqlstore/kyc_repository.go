@@ -0,0 +1,164 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var kycRecords = table[domain.KYC]{
+	name: "kyc",
+	columns: []string{
+		"id", "tenant_id", "user_id", "status", "documents", "rejection_reason", "reviewed_by",
+		"submitted_at", "reviewed_at", "expires_at", "created_at", "updated_at",
+	},
+	scan: func(row scanner) (domain.KYC, error) {
+		var (
+			record                             domain.KYC
+			id, documents                      string
+			submittedAt, reviewedAt, expiresAt sql.NullTime
+		)
+		err := row.Scan(
+			&id, &record.TenantID, &record.UserID, &record.Status, &documents, &record.RejectionReason, &record.ReviewedBy,
+			&submittedAt, &reviewedAt, &expiresAt, &record.CreatedAt, &record.UpdatedAt,
+		)
+		if err != nil {
+			return domain.KYC{}, err
+		}
+		record.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return domain.KYC{}, err
+		}
+		record.SubmittedAt = timeOf(submittedAt)
+		record.ReviewedAt = timeOf(reviewedAt)
+		record.ExpiresAt = timeOf(expiresAt)
+		record.CreatedAt = record.CreatedAt.UTC()
+		record.UpdatedAt = record.UpdatedAt.UTC()
+		return record, fromJSON(documents, &record.Documents)
+	},
+	values: func(record domain.KYC) []any {
+		documents := record.Documents
+		if documents == nil {
+			documents = []domain.KYCDocument{}
+		}
+		return []any{
+			record.ID.Hex(), record.TenantID, record.UserID, record.Status, jsonOf(documents), record.RejectionReason, record.ReviewedBy,
+			nullTime(record.SubmittedAt), nullTime(record.ReviewedAt), nullTime(record.ExpiresAt), dbTime(record.CreatedAt), dbTime(record.UpdatedAt),
+		}
+	},
+}
+
+type kycRepository struct {
+	s *store
+}
+
+func ofUser(ctx context.Context, userID string) where {
+	return scoped(ctx, cond("user_id = ?", userID))
+}
+
+// GetOrCreate implements domain.KYCRepository.
+func (kr *kycRepository) GetOrCreate(ctx context.Context, userID string) (domain.KYC, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	now := time.Now()
+	record := domain.KYC{
+		ID:        primitive.NewObjectID(),
+		TenantID:  tenantID,
+		UserID:    userID,
+		Status:    domain.KYCStatusNotStarted,
+		Documents: []domain.KYCDocument{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	query := "INSERT INTO kyc (id, tenant_id, user_id, status, documents, rejection_reason, reviewed_by, created_at, updated_at) " +
+		"VALUES (?, ?, ?, ?, ?, '', '', ?, ?) ON CONFLICT (tenant_id, user_id) DO NOTHING"
+	_, err = kr.s.exec(ctx, kr.s.conn(ctx), query,
+		record.ID.Hex(), tenantID, userID, record.Status, jsonOf(record.Documents), dbTime(now), dbTime(now))
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return kycRecords.findOne(ctx, kr.s, kr.s.conn(ctx), ofUser(ctx, userID), "")
+}
+
+// GetByUserID implements domain.KYCRepository.
+func (kr *kycRepository) GetByUserID(ctx context.Context, userID string) (domain.KYC, error) {
+	record, err := kycRecords.findOne(ctx, kr.s, kr.s.conn(ctx), ofUser(ctx, userID), "")
+	return record, notFound(err, repository.ErrKYCNotFound)
+}
+
+// AddDocument implements domain.KYCRepository.
+func (kr *kycRepository) AddDocument(ctx context.Context, userID string, document domain.KYCDocument) error {
+	_, err := kycRecords.modify(ctx, kr.s, ofUser(ctx, userID), func(record *domain.KYC) error {
+		record.Documents = append(record.Documents, document)
+		record.UpdatedAt = time.Now()
+		return nil
+	})
+	return notFound(err, repository.ErrKYCNotFound)
+}
+
+// Delete implements domain.KYCRepository.
+func (kr *kycRepository) Delete(ctx context.Context, userID string) (domain.KYC, error) {
+	var record domain.KYC
+	err := kr.s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		record, err = kycRecords.findOne(ctx, kr.s, tx, ofUser(ctx, userID), "")
+		if err != nil {
+			return err
+		}
+		return kycRecords.delete(ctx, kr.s, tx, cond("id = ?", record.ID.Hex()))
+	})
+	if err != nil {
+		return domain.KYC{}, notFound(err, repository.ErrKYCNotFound)
+	}
+	return record, nil
+}
+
+// Transition implements domain.KYCRepository.
+func (kr *kycRepository) Transition(ctx context.Context, userID string, transition domain.KYCTransition) (domain.KYC, error) {
+	c := and(ofUser(ctx, userID), cond("status = ?", transition.From))
+	record, err := kycRecords.modify(ctx, kr.s, c, func(record *domain.KYC) error {
+		record.Status = transition.To
+		record.UpdatedAt = transition.At
+		switch transition.To {
+		case domain.KYCStatusSubmitted:
+			record.SubmittedAt = transition.At
+			record.RejectionReason = ""
+			record.ReviewedBy = ""
+			record.ReviewedAt = time.Time{}
+		case domain.KYCStatusApproved:
+			record.ReviewedBy = transition.ReviewerID
+			record.ReviewedAt = transition.At
+			record.ExpiresAt = transition.ExpiresAt
+			record.RejectionReason = ""
+		case domain.KYCStatusRejected:
+			record.ReviewedBy = transition.ReviewerID
+			record.ReviewedAt = transition.At
+			record.RejectionReason = transition.Reason
+			record.ExpiresAt = time.Time{}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.KYC{}, notFound(err, repository.ErrKYCNotFound)
+	}
+	return record, nil
+}
+
+// ListByStatus implements domain.KYCRepository.
+func (kr *kycRepository) ListByStatus(ctx context.Context, status domain.KYCStatus) ([]domain.KYC, error) {
+	c := inPortfolio(ctx, cond("status = ?", status), "user_id")
+	return kycRecords.find(ctx, kr.s, kr.s.conn(ctx), scoped(ctx, c), "ORDER BY submitted_at")
+}
+
+// ListExpired implements domain.KYCRepository.
+func (kr *kycRepository) ListExpired(ctx context.Context, now time.Time) ([]domain.KYC, error) {
+	c := cond("status = ? AND expires_at <= ?", domain.KYCStatusApproved, dbTime(now))
+	return kycRecords.find(ctx, kr.s, kr.s.conn(ctx), scoped(ctx, c), "")
+}
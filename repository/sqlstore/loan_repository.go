@@ -0,0 +1,124 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var loans = table[domain.Loan]{
+	name: "loans",
+	columns: []string{
+		"id", "tenant_id", "borrower_id", "amount", "term_months", "status", "created_by",
+		"approved_by", "approved_at", "disbursed_by", "disbursed_at", "created_at", "updated_at", "version",
+	},
+	scan: func(row scanner) (domain.Loan, error) {
+		var (
+			loan                    domain.Loan
+			id                      string
+			approvedAt, disbursedAt sql.NullTime
+		)
+		err := row.Scan(
+			&id, &loan.TenantID, &loan.BorrowerID, &loan.Amount, &loan.TermMonths, &loan.Status, &loan.CreatedBy,
+			&loan.ApprovedBy, &approvedAt, &loan.DisbursedBy, &disbursedAt, &loan.CreatedAt, &loan.UpdatedAt, &loan.Version,
+		)
+		if err != nil {
+			return domain.Loan{}, err
+		}
+		loan.ApprovedAt = timeOf(approvedAt)
+		loan.DisbursedAt = timeOf(disbursedAt)
+		loan.CreatedAt = loan.CreatedAt.UTC()
+		loan.UpdatedAt = loan.UpdatedAt.UTC()
+		loan.ID, err = primitive.ObjectIDFromHex(id)
+		return loan, err
+	},
+	values: func(loan domain.Loan) []any {
+		return []any{
+			loan.ID.Hex(), loan.TenantID, loan.BorrowerID, loan.Amount, loan.TermMonths, loan.Status, loan.CreatedBy,
+			loan.ApprovedBy, nullTime(loan.ApprovedAt), loan.DisbursedBy, nullTime(loan.DisbursedAt), dbTime(loan.CreatedAt), dbTime(loan.UpdatedAt), loan.Version,
+		}
+	},
+}
+
+type loanRepository struct {
+	s *store
+}
+
+// Create implements domain.LoanRepository.
+func (lr *loanRepository) Create(ctx context.Context, loan domain.Loan) (domain.Loan, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	loan.TenantID = tenantID
+	if loan.ID.IsZero() {
+		loan.ID = primitive.NewObjectID()
+	}
+	loan.Version = 1
+
+	err = loans.insert(ctx, lr.s, lr.s.conn(ctx), loan)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return loan, nil
+}
+
+// GetByID implements domain.LoanRepository.
+func (lr *loanRepository) GetByID(ctx context.Context, loanID string) (domain.Loan, error) {
+	if _, err := primitive.ObjectIDFromHex(loanID); err != nil {
+		return domain.Loan{}, repository.ErrLoanNotFound
+	}
+	loan, err := loans.findOne(ctx, lr.s, lr.s.conn(ctx), scoped(ctx, cond("id = ?", loanID)), "")
+	return loan, notFound(err, repository.ErrLoanNotFound)
+}
+
+// ListByBorrower implements domain.LoanRepository.
+func (lr *loanRepository) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.Loan, error) {
+	c := scoped(ctx, cond("borrower_id = ?", borrowerID))
+	return loans.find(ctx, lr.s, lr.s.conn(ctx), c, "ORDER BY created_at DESC, id DESC")
+}
+
+// List implements domain.LoanRepository.
+func (lr *loanRepository) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.Loan], error) {
+	return findPage(ctx, lr.s, loans, scoped(ctx, inPortfolio(ctx, cond("1 = 1"), "borrower_id")), spec, loanSortValue)
+}
+
+// loanSortValue returns the value of the loan listings' sort field.
+func loanSortValue(loan domain.Loan, field string) any {
+	return loan.CreatedAt
+}
+
+// Transition implements domain.LoanRepository.
+func (lr *loanRepository) Transition(ctx context.Context, loanID string, transition domain.LoanTransition) (domain.Loan, error) {
+	if _, err := primitive.ObjectIDFromHex(loanID); err != nil {
+		return domain.Loan{}, repository.ErrLoanNotFound
+	}
+	c := scoped(ctx, cond("id = ?", loanID))
+	loan, err := loans.modify(ctx, lr.s, c, func(loan *domain.Loan) error {
+		if transition.Version != 0 && loan.Version != transition.Version {
+			return &repository.ConflictError{Expected: transition.Version, Current: loan.Version}
+		}
+		if loan.Status != transition.From {
+			return sql.ErrNoRows
+		}
+		loan.Status = transition.To
+		loan.Version++
+		loan.UpdatedAt = transition.At
+		switch transition.To {
+		case domain.LoanStatusApproved:
+			loan.ApprovedBy = transition.ActorID
+			loan.ApprovedAt = transition.At
+		case domain.LoanStatusDisbursed:
+			loan.DisbursedBy = transition.ActorID
+			loan.DisbursedAt = transition.At
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Loan{}, notFound(err, repository.ErrLoanNotFound)
+	}
+	return loan, nil
+}
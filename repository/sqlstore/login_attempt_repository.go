@@ -0,0 +1,109 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+var loginAttempts = table[domain.LoginAttempt]{
+	name:    "login_attempts",
+	columns: []string{"id", "failures", "last_failure", "locked_until", "unlock_token"},
+	scan: func(row scanner) (domain.LoginAttempt, error) {
+		var attempt domain.LoginAttempt
+		err := row.Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailure, &attempt.LockedUntil, &attempt.UnlockToken)
+		attempt.LastFailure = attempt.LastFailure.UTC()
+		attempt.LockedUntil = attempt.LockedUntil.UTC()
+		return attempt, err
+	},
+	values: func(attempt domain.LoginAttempt) []any {
+		return []any{attempt.Key, attempt.Failures, dbTime(attempt.LastFailure), dbTime(attempt.LockedUntil), attempt.UnlockToken}
+	},
+}
+
+type loginAttemptRepository struct {
+	s *store
+}
+
+// tenantKey prefixes key with the tenant of ctx like the Mongo repository.
+func tenantKey(ctx context.Context, key string) string {
+	tenantID, _ := domain.TenantFromContext(ctx)
+	return tenantID + ":" + key
+}
+
+// Get implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Get(ctx context.Context, key string) (domain.LoginAttempt, error) {
+	attempt, err := loginAttempts.findOne(ctx, lr.s, lr.s.conn(ctx), cond("id = ?", tenantKey(ctx, key)), "")
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.LoginAttempt{Key: key}, nil
+	}
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	attempt.Key = key
+	return attempt, nil
+}
+
+// RecordFailure implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, resetBefore time.Time) (domain.LoginAttempt, error) {
+	id := tenantKey(ctx, key)
+	var attempt domain.LoginAttempt
+	err := lr.s.inTx(ctx, func(tx *sql.Tx) error {
+		query := "INSERT INTO login_attempts (id, failures, last_failure, locked_until, unlock_token) VALUES (?, 0, ?, ?, '') ON CONFLICT (id) DO NOTHING"
+		zero := dbTime(time.Time{})
+		if _, err := lr.s.exec(ctx, tx, query, id, zero, zero); err != nil {
+			return err
+		}
+
+		var err error
+		attempt, err = loginAttempts.first(ctx, lr.s, tx, cond("id = ?", id), "LIMIT 1"+lr.s.dialect.LockRow)
+		if err != nil {
+			return err
+		}
+		if attempt.LastFailure.Before(resetBefore) {
+			attempt.Failures = 1
+		} else {
+			attempt.Failures++
+		}
+		attempt.LastFailure = dbTime(at)
+		return loginAttempts.update(ctx, lr.s, tx, attempt)
+	})
+	if err != nil {
+		return domain.LoginAttempt{}, err
+	}
+	attempt.Key = key
+	return attempt, nil
+}
+
+// Lock implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := lr.s.exec(ctx, lr.s.conn(ctx), "UPDATE login_attempts SET locked_until = ? WHERE id = ?", dbTime(until), tenantKey(ctx, key))
+	return err
+}
+
+// Reset implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	return loginAttempts.delete(ctx, lr.s, lr.s.conn(ctx), cond("id = ?", tenantKey(ctx, key)))
+}
+
+// SetUnlockToken implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) SetUnlockToken(ctx context.Context, key string, tokenHash string) error {
+	_, err := lr.s.exec(ctx, lr.s.conn(ctx), "UPDATE login_attempts SET unlock_token = ? WHERE id = ?", tokenHash, tenantKey(ctx, key))
+	return err
+}
+
+// ConsumeUnlockToken implements domain.LoginAttemptRepository.
+func (lr *loginAttemptRepository) ConsumeUnlockToken(ctx context.Context, key string, tokenHash string) (bool, error) {
+	if tokenHash == "" {
+		return false, nil
+	}
+	result, err := lr.s.exec(ctx, lr.s.conn(ctx), "DELETE FROM login_attempts WHERE id = ? AND unlock_token = ?", tenantKey(ctx, key), tokenHash)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted == 1, err
+}
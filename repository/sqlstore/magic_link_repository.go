@@ -0,0 +1,74 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var magicLinks = table[domain.MagicLink]{
+	name:    "magic_links",
+	columns: []string{"id", "tenant_id", "user_id", "token_hash", "nonce_hash", "used", "expires_at", "created_at"},
+	scan: func(row scanner) (domain.MagicLink, error) {
+		var (
+			link domain.MagicLink
+			id   string
+		)
+		err := row.Scan(&id, &link.TenantID, &link.UserID, &link.TokenHash, &link.NonceHash, &link.Used, &link.ExpiresAt, &link.CreatedAt)
+		if err != nil {
+			return domain.MagicLink{}, err
+		}
+		link.ExpiresAt = link.ExpiresAt.UTC()
+		link.CreatedAt = link.CreatedAt.UTC()
+		link.ID, err = primitive.ObjectIDFromHex(id)
+		return link, err
+	},
+	values: func(link domain.MagicLink) []any {
+		return []any{link.ID.Hex(), link.TenantID, link.UserID, link.TokenHash, link.NonceHash, link.Used, dbTime(link.ExpiresAt), dbTime(link.CreatedAt)}
+	},
+}
+
+type magicLinkRepository struct {
+	s *store
+}
+
+// Create implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) Create(ctx context.Context, link domain.MagicLink) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	link.TenantID = tenantID
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+
+	return mr.s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := magicLinks.delete(ctx, mr.s, tx, scoped(ctx, cond("user_id = ?", link.UserID))); err != nil {
+			return err
+		}
+		return magicLinks.insert(ctx, mr.s, tx, link)
+	})
+}
+
+// DeleteByUser implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return magicLinks.delete(ctx, mr.s, mr.s.conn(ctx), scoped(ctx, cond("user_id = ?", userID)))
+}
+
+// Consume implements domain.MagicLinkRepository.
+func (mr *magicLinkRepository) Consume(ctx context.Context, tokenHash string, nonceHash string, now time.Time) (domain.MagicLink, error) {
+	c := cond("token_hash = ? AND nonce_hash = ? AND used = ? AND expires_at > ?", tokenHash, nonceHash, false, dbTime(now))
+	link, err := magicLinks.modify(ctx, mr.s, scoped(ctx, c), func(link *domain.MagicLink) error {
+		link.Used = true
+		return nil
+	})
+	if err != nil {
+		return domain.MagicLink{}, notFound(err, repository.ErrMagicLinkNotFound)
+	}
+	return link, nil
+}
@@ -0,0 +1,79 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrate applies the migrations of fsys that db has not seen yet, oldest
+// first. A migration is a file named <version>_<name>.sql at the root of
+// fsys. Each one runs in its own transaction, which also records its
+// version in schema_migrations.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect, fsys fs.FS) error {
+	s := &store{db: db, dialect: dialect}
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return err
+	}
+	type migration struct {
+		version int64
+		name    string
+	}
+	migrations := make([]migration, 0, len(files))
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil {
+			return fmt.Errorf("migration %s is not named <version>_<name>.sql", file)
+		}
+		migrations = append(migrations, migration{version: version, name: file})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	for _, m := range migrations {
+		script, err := fs.ReadFile(fsys, m.name)
+		if err != nil {
+			return err
+		}
+		err = s.inTx(ctx, func(tx *sql.Tx) error {
+			if dialect.MigrationLock != "" {
+				if _, err := tx.ExecContext(ctx, dialect.MigrationLock); err != nil {
+					return err
+				}
+			}
+			var applied int
+			row := tx.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), m.version)
+			if err := row.Scan(&applied); err != nil || applied > 0 {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := s.exec(ctx, tx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				m.version, strings.TrimSuffix(path.Base(m.name), ".sql"), time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var officerAssignments = table[domain.OfficerAssignment]{
+	name: "officer_assignments",
+	columns: []string{
+		"id", "tenant_id", "borrower_id", "officer_id", "previous_officer_id", "branch_id", "actor_id", "reason", "assigned_at",
+	},
+	scan: func(row scanner) (domain.OfficerAssignment, error) {
+		var (
+			a  domain.OfficerAssignment
+			id string
+		)
+		err := row.Scan(&id, &a.TenantID, &a.BorrowerID, &a.OfficerID, &a.PreviousOfficerID, &a.BranchID, &a.ActorID, &a.Reason, &a.AssignedAt)
+		if err != nil {
+			return domain.OfficerAssignment{}, err
+		}
+		a.AssignedAt = a.AssignedAt.UTC()
+		a.ID, err = primitive.ObjectIDFromHex(id)
+		return a, err
+	},
+	values: func(a domain.OfficerAssignment) []any {
+		return []any{a.ID.Hex(), a.TenantID, a.BorrowerID, a.OfficerID, a.PreviousOfficerID, a.BranchID, a.ActorID, a.Reason, dbTime(a.AssignedAt)}
+	},
+}
+
+type officerAssignmentRepository struct {
+	s *store
+}
+
+// Create implements domain.OfficerAssignmentRepository.
+func (ar *officerAssignmentRepository) Create(ctx context.Context, assignment domain.OfficerAssignment) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	assignment.TenantID = tenantID
+	if assignment.ID.IsZero() {
+		assignment.ID = primitive.NewObjectID()
+	}
+	return officerAssignments.insert(ctx, ar.s, ar.s.conn(ctx), assignment)
+}
+
+// ListByBorrower implements domain.OfficerAssignmentRepository.
+func (ar *officerAssignmentRepository) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.OfficerAssignment, error) {
+	c := scoped(ctx, cond("borrower_id = ?", borrowerID))
+	return officerAssignments.find(ctx, ar.s, ar.s.conn(ctx), c, "ORDER BY assigned_at DESC")
+}
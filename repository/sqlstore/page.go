@@ -0,0 +1,108 @@
+package sqlstore
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/query"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// findPage returns the page of t described by spec among the rows matching
+// base. Field names in spec are column names; the query package only lets
+// through the fields of a schema. sortValue returns the value of the sort
+// field of a record, and the first column of t is the ID the pages are
+// keyed on together with it.
+func findPage[T any](ctx context.Context, s *store, t table[T], base where, spec domain.QuerySpec, sortValue func(T, string) any) (domain.Page[T], error) {
+	filter := []where{base}
+	for _, condition := range spec.Conditions {
+		filter = append(filter, conditionWhere(condition))
+	}
+
+	total, err := t.count(ctx, s, and(filter...))
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+
+	// keyset pagination: continue after the last (sort value, id) pair
+	after, direction := ">", "ASC"
+	if spec.SortDesc {
+		after, direction = "<", "DESC"
+	}
+	if spec.Cursor != "" {
+		value, id, err := query.DecodeCursor(spec)
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+		v := sqlValue(value)
+		filter = append(filter, cond(
+			spec.SortBy+" "+after+" ? OR ("+spec.SortBy+" = ? AND "+t.columns[0]+" "+after+" ?)",
+			v, v, id,
+		))
+	}
+
+	rest := "ORDER BY " + spec.SortBy + " " + direction + ", " + t.columns[0] + " " + direction +
+		" LIMIT " + strconv.Itoa(spec.Limit+1)
+	items, err := t.find(ctx, s, s.conn(ctx), and(filter...), rest)
+	if err != nil {
+		return domain.Page[T]{}, err
+	}
+
+	page := domain.Page[T]{Items: items, Total: total}
+	if len(items) > spec.Limit {
+		page.Items = items[:spec.Limit]
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor, err = query.EncodeCursor(spec, sortValue(last, spec.SortBy), t.values(last)[0])
+		if err != nil {
+			return domain.Page[T]{}, err
+		}
+	}
+	return page, nil
+}
+
+func conditionWhere(condition domain.Condition) where {
+	column := condition.Field
+	switch condition.Op {
+	case domain.OpIn:
+		values := reflect.ValueOf(condition.Value)
+		if values.Kind() != reflect.Slice || values.Len() == 0 {
+			return cond("1 = 0")
+		}
+		args := make([]any, values.Len())
+		for i := range args {
+			args[i] = sqlValue(values.Index(i).Interface())
+		}
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+		return cond(column+" IN ("+marks+")", args...)
+	case domain.OpGte:
+		return cond(column+" >= ?", sqlValue(condition.Value))
+	case domain.OpLte:
+		return cond(column+" <= ?", sqlValue(condition.Value))
+	case domain.OpPrefix:
+		prefix, _ := condition.Value.(string)
+		return cond("LOWER("+column+") LIKE ? ESCAPE '\\'", likePrefix(prefix))
+	}
+	return cond(column+" = ?", sqlValue(condition.Value))
+}
+
+// sqlValue converts a filter or cursor value to the form it is stored in.
+func sqlValue(v any) any {
+	switch v := v.(type) {
+	case time.Time:
+		return dbTime(v)
+	case primitive.DateTime:
+		return dbTime(v.Time())
+	}
+	return v
+}
+
+// likePrefix returns the LIKE pattern matching text starting with prefix,
+// in any case.
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(prefix))
+	return escaped + "%"
+}
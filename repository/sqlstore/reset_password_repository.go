@@ -0,0 +1,107 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// otpRecord is a one time password with the key of its row.
+type otpRecord struct {
+	id  string
+	otp domain.OtpSave
+}
+
+var otps = table[otpRecord]{
+	name:    "password_resets",
+	columns: []string{"id", "tenant_id", "email", "code", "expires_at", "attempts"},
+	scan: func(row scanner) (otpRecord, error) {
+		var r otpRecord
+		err := row.Scan(&r.id, &r.otp.TenantID, &r.otp.Email, &r.otp.Code, &r.otp.ExpiresAt, &r.otp.Attempts)
+		r.otp.ExpiresAt = r.otp.ExpiresAt.UTC()
+		return r, err
+	},
+	values: func(r otpRecord) []any {
+		return []any{r.id, r.otp.TenantID, r.otp.Email, r.otp.Code, dbTime(r.otp.ExpiresAt), r.otp.Attempts}
+	},
+}
+
+type resetPasswordRepository struct {
+	s *store
+}
+
+// GetUserByEmail implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	user, err := (&userRepository{rp.s}).GetByEmail(ctx, email)
+	if err != nil {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// ResetPassword implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) ResetPassword(ctx context.Context, userID string, resetPassword *domain.ResetPasswordRequest) error {
+	_, err := (&userRepository{rp.s}).update(ctx, userID, set(setPassword(resetPassword.NewPassword)))
+	return err
+}
+
+// SaveOtp implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) SaveOtp(ctx context.Context, otp *domain.OtpSave) error {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	otp.TenantID = tenantID
+	return otps.insert(ctx, rp.s, rp.s.conn(ctx), otpRecord{id: primitive.NewObjectID().Hex(), otp: *otp})
+}
+
+// otpOf is the condition on the OTPs of email.
+func otpOf(ctx context.Context, email string) where {
+	return scoped(ctx, cond("email = ?", email))
+}
+
+// GetOTPByEmail implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) GetOTPByEmail(ctx context.Context, email string) (*domain.OtpSave, error) {
+	r, err := otps.findOne(ctx, rp.s, rp.s.conn(ctx), otpOf(ctx, email), "ORDER BY id")
+	if err != nil {
+		return nil, notFound(err, repository.ErrUserNotFound)
+	}
+	return &r.otp, nil
+}
+
+// DeleteOtp implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) DeleteOtp(ctx context.Context, email string) error {
+	r, err := otps.findOne(ctx, rp.s, rp.s.conn(ctx), otpOf(ctx, email), "ORDER BY id")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return otps.delete(ctx, rp.s, rp.s.conn(ctx), cond("id = ?", r.id))
+}
+
+// DeleteOtps implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) DeleteOtps(ctx context.Context, email string) error {
+	return otps.delete(ctx, rp.s, rp.s.conn(ctx), otpOf(ctx, email))
+}
+
+// IncrementOtpAttempts implements domain.ResetPasswordRepository.
+func (rp *resetPasswordRepository) IncrementOtpAttempts(ctx context.Context, email string) (int, error) {
+	r, err := otps.findOne(ctx, rp.s, rp.s.conn(ctx), otpOf(ctx, email), "ORDER BY id")
+	if err != nil {
+		return 0, notFound(err, repository.ErrUserNotFound)
+	}
+	r, err = otps.modify(ctx, rp.s, cond("id = ?", r.id), func(r *otpRecord) error {
+		r.otp.Attempts++
+		return nil
+	})
+	if err != nil {
+		return 0, notFound(err, repository.ErrUserNotFound)
+	}
+	return r.otp.Attempts, nil
+}
@@ -0,0 +1,103 @@
+package sqlstore
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/search"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// number of records fetched before they are ranked
+const searchCandidates = 200
+
+type searchIndex struct {
+	s *store
+}
+
+// Search implements domain.SearchIndex.
+// The candidates are the borrowers containing the first term anywhere in
+// their searchable fields, their loans and the loans whose ID is in the
+// query; they are then ranked like the other indexes do.
+func (si *searchIndex) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	terms := search.Terms(query)
+	if len(terms) == 0 {
+		return []domain.SearchResult{}, nil
+	}
+
+	pattern := "%" + strings.TrimSuffix(likePrefix(terms[0]), "%") + "%"
+	var fields []string
+	var args []any
+	for _, field := range []string{"first_name", "last_name", "email", "CAST(profile AS TEXT)"} {
+		fields = append(fields, "LOWER("+field+") LIKE ? ESCAPE '\\'")
+		args = append(args, pattern)
+	}
+	c := userScope(ctx, and(
+		cond("role IN (?, ?)", domain.RoleBorrower, domain.RoleUser),
+		cond(strings.Join(fields, " OR "), args...),
+	))
+
+	candidates, err := users.find(ctx, si.s, si.s.conn(ctx), c, "LIMIT "+strconv.Itoa(searchCandidates))
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]domain.User)
+	docs := make([]search.Document, 0, len(candidates))
+	for _, user := range candidates {
+		found[user.ID.Hex()] = user
+		docs = append(docs, search.BorrowerDocument(user))
+	}
+
+	more, err := si.loanDocuments(ctx, found, search.IDs(terms))
+	if err != nil {
+		return nil, err
+	}
+	return search.Rank(append(docs, more...), terms, limit), nil
+}
+
+// loanDocuments returns the loans of the borrowers found and the loans with
+// one of ids, leaving out those whose borrower the caller cannot see.
+func (si *searchIndex) loanDocuments(ctx context.Context, found map[string]domain.User, ids []primitive.ObjectID) ([]search.Document, error) {
+	borrowerIDs := make([]string, 0, len(found))
+	for id := range found {
+		borrowerIDs = append(borrowerIDs, id)
+	}
+	loanIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		loanIDs = append(loanIDs, id.Hex())
+	}
+	byBorrower, byID := in("borrower_id", borrowerIDs), in("id", loanIDs)
+	c := scoped(ctx, cond(byBorrower.sql+" OR "+byID.sql, append(byBorrower.args, byID.args...)...))
+
+	candidates, err := loans.find(ctx, si.s, si.s.conn(ctx), c, "LIMIT "+strconv.Itoa(searchCandidates))
+	if err != nil {
+		return nil, err
+	}
+
+	// the borrowers of the loans found by ID
+	var missing []string
+	for _, loan := range candidates {
+		if _, ok := found[loan.BorrowerID]; !ok {
+			missing = append(missing, loan.BorrowerID)
+		}
+	}
+	if len(missing) > 0 {
+		borrowers, err := users.find(ctx, si.s, si.s.conn(ctx), userScope(ctx, in("id", missing)), "")
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range borrowers {
+			found[user.ID.Hex()] = user
+		}
+	}
+
+	docs := make([]search.Document, 0, len(candidates))
+	for _, loan := range candidates {
+		if borrower, ok := found[loan.BorrowerID]; ok {
+			docs = append(docs, search.LoanDocument(loan, borrower))
+		}
+	}
+	return docs, nil
+}
@@ -0,0 +1,335 @@
+// Package sqlstore implements every repository on a SQL database through
+// database/sql. The statements are portable; what differs between the
+// databases is described by a Dialect.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+// Dialect describes a SQL database the store runs on.
+type Dialect struct {
+	// Numbered placeholders are written $1, $2, ... instead of ?.
+	NumberedPlaceholders bool
+	// LockRow is appended to the query of a row that is about to be
+	// updated, e.g. " FOR UPDATE".
+	LockRow string
+	// MigrationLock, when set, is run at the start of every migration so
+	// that instances starting together apply each migration once.
+	MigrationLock string
+	// IsUniqueViolation reports whether err was caused by a unique
+	// constraint.
+	IsUniqueViolation func(err error) bool
+	// IsRetryable reports whether a transaction that failed with err may
+	// succeed when run again, e.g. after a deadlock.
+	IsRetryable func(err error) bool
+}
+
+type store struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewSet returns the repositories stored in db, whose schema has been
+// migrated by Migrate.
+func NewSet(db *sql.DB, dialect Dialect) repository.Set {
+	s := &store{db: db, dialect: dialect}
+	return repository.Set{
+		Users:              &userRepository{s},
+		ResetPassword:      &resetPasswordRepository{s},
+		Audit:              &auditRepository{s},
+		KYC:                &kycRepository{s},
+		MagicLinks:         &magicLinkRepository{s},
+		LoginAttempts:      &loginAttemptRepository{s},
+		Tenants:            &tenantRepository{s},
+		Branches:           &branchRepository{s},
+		Loans:              &loanRepository{s},
+		OfficerAssignments: &officerAssignmentRepository{s},
+		Search:             &searchIndex{s},
+		Idempotency:        &idempotencyRepository{s},
+		Tx:                 &txManager{s},
+	}
+}
+
+// querier is a database or a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rebind rewrites the ? placeholders of query for the dialect.
+func (s *store) rebind(query string) string {
+	if !s.dialect.NumberedPlaceholders {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *store) exec(ctx context.Context, q querier, query string, args ...any) (sql.Result, error) {
+	return q.ExecContext(ctx, s.rebind(query), args...)
+}
+
+// inTx runs fn in a transaction, committing it when fn succeeds. Within
+// the transaction of a TxManager fn runs in that one.
+func (s *store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{s}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// where is a SQL condition with its arguments.
+type where struct {
+	sql  string
+	args []any
+}
+
+func cond(sql string, args ...any) where {
+	return where{sql: sql, args: args}
+}
+
+// and joins conditions.
+func and(conds ...where) where {
+	parts := make([]string, 0, len(conds))
+	var args []any
+	for _, c := range conds {
+		parts = append(parts, "("+c.sql+")")
+		args = append(args, c.args...)
+	}
+	return where{sql: strings.Join(parts, " AND "), args: args}
+}
+
+// in matches the rows whose column holds one of values, and none when
+// there are no values.
+func in(column string, values []string) where {
+	if len(values) == 0 {
+		return cond("1 = 0")
+	}
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return cond(column+" IN ("+marks+")", args...)
+}
+
+// scoped restricts c to the tenant of ctx. Without a tenant it matches
+// nothing, like the scope of the Mongo repositories.
+func scoped(ctx context.Context, c where) where {
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		return and(c, cond("tenant_id = ?", tenantID))
+	}
+	if domain.IsAllTenants(ctx) {
+		return c
+	}
+	return and(c, cond("1 = 0"))
+}
+
+// userScope restricts c to the users of the tenant of ctx that have not
+// been deleted and, within a portfolio, to its borrowers.
+func userScope(ctx context.Context, c where) where {
+	c = and(c, cond("deleted_at IS NULL"))
+	if officerID, ok := domain.PortfolioFromContext(ctx); ok {
+		c = and(c, cond("officer_id = ?", officerID))
+	}
+	return scoped(ctx, c)
+}
+
+// inPortfolio restricts c, when ctx is restricted to a portfolio, to the
+// rows whose column holds the ID of one of its borrowers.
+func inPortfolio(ctx context.Context, c where, column string) where {
+	if _, ok := domain.PortfolioFromContext(ctx); !ok {
+		return c
+	}
+	portfolio := userScope(ctx, cond("1 = 1"))
+	return and(c, cond(column+" IN (SELECT id FROM users WHERE "+portfolio.sql+")", portfolio.args...))
+}
+
+func tenantOf(ctx context.Context) (string, error) {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		return "", repository.ErrNoTenant
+	}
+	return tenantID, nil
+}
+
+// scanner is a row being read.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// table maps the records of type T to the rows of a table whose first
+// column is the primary key.
+type table[T any] struct {
+	name    string
+	columns []string
+	scan    func(row scanner) (T, error)
+	values  func(record T) []any
+}
+
+func (t table[T]) insert(ctx context.Context, s *store, q querier, record T) error {
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(t.columns)), ", ")
+	query := "INSERT INTO " + t.name + " (" + strings.Join(t.columns, ", ") + ") VALUES (" + marks + ")"
+	_, err := s.exec(ctx, q, query, t.values(record)...)
+	return err
+}
+
+// find returns the records matching c; rest is appended to the query, e.g.
+// an ORDER BY clause.
+func (t table[T]) find(ctx context.Context, s *store, q querier, c where, rest string) ([]T, error) {
+	query := "SELECT " + strings.Join(t.columns, ", ") + " FROM " + t.name + " WHERE " + c.sql + " " + rest
+	rows, err := q.QueryContext(ctx, s.rebind(query), c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]T, 0)
+	for rows.Next() {
+		record, err := t.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// findOne returns the first record matching c, or sql.ErrNoRows. order is
+// the ORDER BY clause picking the first record, if any.
+func (t table[T]) findOne(ctx context.Context, s *store, q querier, c where, order string) (T, error) {
+	return t.first(ctx, s, q, c, order+" LIMIT 1")
+}
+
+func (t table[T]) first(ctx context.Context, s *store, q querier, c where, rest string) (T, error) {
+	records, err := t.find(ctx, s, q, c, rest)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if len(records) == 0 {
+		var zero T
+		return zero, sql.ErrNoRows
+	}
+	return records[0], nil
+}
+
+func (t table[T]) count(ctx context.Context, s *store, c where) (int64, error) {
+	var count int64
+	query := "SELECT COUNT(*) FROM " + t.name + " WHERE " + c.sql
+	err := s.conn(ctx).QueryRowContext(ctx, s.rebind(query), c.args...).Scan(&count)
+	return count, err
+}
+
+// update writes every column of record to its row.
+func (t table[T]) update(ctx context.Context, s *store, q querier, record T) error {
+	values := t.values(record)
+	sets := make([]string, 0, len(t.columns)-1)
+	for _, column := range t.columns[1:] {
+		sets = append(sets, column+" = ?")
+	}
+	query := "UPDATE " + t.name + " SET " + strings.Join(sets, ", ") + " WHERE " + t.columns[0] + " = ?"
+	_, err := s.exec(ctx, q, query, append(values[1:], values[0])...)
+	return err
+}
+
+// modify applies change to the first record matching c in one
+// transaction and returns the changed record. It returns sql.ErrNoRows
+// when nothing matches, and the error of change, if any, without writing.
+func (t table[T]) modify(ctx context.Context, s *store, c where, change func(*T) error) (T, error) {
+	var record T
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		record, err = t.first(ctx, s, tx, c, "LIMIT 1"+s.dialect.LockRow)
+		if err != nil {
+			return err
+		}
+		if err := change(&record); err != nil {
+			return err
+		}
+		return t.update(ctx, s, tx, record)
+	})
+	return record, err
+}
+
+func (t table[T]) delete(ctx context.Context, s *store, q querier, c where) error {
+	_, err := s.exec(ctx, q, "DELETE FROM "+t.name+" WHERE "+c.sql, c.args...)
+	return err
+}
+
+// notFound turns sql.ErrNoRows into err.
+func notFound(err error, notFoundErr error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFoundErr
+	}
+	return err
+}
+
+// dbTime is the form times are stored in. Like Mongo the store keeps
+// milliseconds, always in UTC.
+func dbTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Millisecond)
+}
+
+// nullTime stores the zero time as NULL, for the fields Mongo leaves out
+// when empty.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: dbTime(t), Valid: true}
+}
+
+// timeOf returns the time read into n, or the zero time for NULL.
+func timeOf(n sql.NullTime) time.Time {
+	if !n.Valid {
+		return time.Time{}
+	}
+	return n.Time.UTC()
+}
+
+// jsonOf encodes a field stored as JSON.
+func jsonOf(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// fromJSON decodes a field stored as JSON into v.
+func fromJSON(data string, v any) error {
+	if data == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(data), v)
+}
@@ -0,0 +1,116 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tenants = table[domain.Tenant]{
+	name:    "tenants",
+	columns: []string{"id", "name", "slug", "owner_email", "is_primary", "active", "created_at", "updated_at"},
+	scan: func(row scanner) (domain.Tenant, error) {
+		var (
+			tenant domain.Tenant
+			id     string
+		)
+		err := row.Scan(&id, &tenant.Name, &tenant.Slug, &tenant.OwnerEmail, &tenant.Primary, &tenant.Active, &tenant.CreatedAt, &tenant.UpdatedAt)
+		if err != nil {
+			return domain.Tenant{}, err
+		}
+		tenant.CreatedAt = tenant.CreatedAt.UTC()
+		tenant.UpdatedAt = tenant.UpdatedAt.UTC()
+		tenant.ID, err = primitive.ObjectIDFromHex(id)
+		return tenant, err
+	},
+	values: func(tenant domain.Tenant) []any {
+		return []any{tenant.ID.Hex(), tenant.Name, tenant.Slug, tenant.OwnerEmail, tenant.Primary, tenant.Active, dbTime(tenant.CreatedAt), dbTime(tenant.UpdatedAt)}
+	},
+}
+
+type tenantRepository struct {
+	s *store
+}
+
+// EnsurePrimary implements domain.TenantRepository.
+func (tr *tenantRepository) EnsurePrimary(ctx context.Context, slug string) (domain.Tenant, error) {
+	now := dbTime(time.Now())
+	query := "INSERT INTO tenants (id, name, slug, owner_email, is_primary, active, created_at, updated_at) " +
+		"VALUES (?, ?, ?, '', ?, ?, ?, ?) ON CONFLICT (slug) DO NOTHING"
+	_, err := tr.s.exec(ctx, tr.s.conn(ctx), query, primitive.NewObjectID().Hex(), slug, slug, true, true, now, now)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	return tr.GetBySlug(ctx, slug)
+}
+
+// Create implements domain.TenantRepository.
+func (tr *tenantRepository) Create(ctx context.Context, tenant domain.Tenant) (domain.Tenant, error) {
+	if tenant.ID.IsZero() {
+		tenant.ID = primitive.NewObjectID()
+	}
+	err := tenants.insert(ctx, tr.s, tr.s.conn(ctx), tenant)
+	if err != nil && tr.s.dialect.IsUniqueViolation(err) {
+		return domain.Tenant{}, repository.ErrTenantSlugTaken
+	}
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	return tenant, nil
+}
+
+// GetByID implements domain.TenantRepository.
+func (tr *tenantRepository) GetByID(ctx context.Context, tenantID string) (domain.Tenant, error) {
+	if _, err := primitive.ObjectIDFromHex(tenantID); err != nil {
+		return domain.Tenant{}, repository.ErrTenantNotFound
+	}
+	tenant, err := tenants.findOne(ctx, tr.s, tr.s.conn(ctx), cond("id = ?", tenantID), "")
+	return tenant, notFound(err, repository.ErrTenantNotFound)
+}
+
+// GetBySlug implements domain.TenantRepository.
+func (tr *tenantRepository) GetBySlug(ctx context.Context, slug string) (domain.Tenant, error) {
+	tenant, err := tenants.findOne(ctx, tr.s, tr.s.conn(ctx), cond("slug = ?", slug), "")
+	return tenant, notFound(err, repository.ErrTenantNotFound)
+}
+
+// List implements domain.TenantRepository.
+func (tr *tenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
+	return tenants.find(ctx, tr.s, tr.s.conn(ctx), cond("1 = 1"), "ORDER BY created_at, id")
+}
+
+// Update implements domain.TenantRepository.
+func (tr *tenantRepository) Update(ctx context.Context, tenantID string, request domain.UpdateTenantRequest) (domain.Tenant, error) {
+	if _, err := primitive.ObjectIDFromHex(tenantID); err != nil {
+		return domain.Tenant{}, repository.ErrTenantNotFound
+	}
+	tenant, err := tenants.modify(ctx, tr.s, cond("id = ?", tenantID), func(tenant *domain.Tenant) error {
+		tenant.UpdatedAt = time.Now()
+		if request.Name != nil {
+			tenant.Name = *request.Name
+		}
+		if request.Active != nil {
+			tenant.Active = *request.Active
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Tenant{}, notFound(err, repository.ErrTenantNotFound)
+	}
+	return tenant, nil
+}
+
+// ClaimOwner implements domain.TenantRepository.
+func (tr *tenantRepository) ClaimOwner(ctx context.Context, tenantID string, email string) (domain.Tenant, error) {
+	if _, err := primitive.ObjectIDFromHex(tenantID); err != nil {
+		return domain.Tenant{}, repository.ErrTenantNotFound
+	}
+	query := "UPDATE tenants SET owner_email = ?, updated_at = ? WHERE id = ? AND owner_email = ''"
+	if _, err := tr.s.exec(ctx, tr.s.conn(ctx), query, email, dbTime(time.Now()), tenantID); err != nil {
+		return domain.Tenant{}, err
+	}
+	return tr.GetByID(ctx, tenantID)
+}
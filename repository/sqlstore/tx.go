@@ -0,0 +1,57 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// txKey is the context key of the transaction a store is running in.
+type txKey struct {
+	s *store
+}
+
+// conn returns the transaction of ctx, or the database outside of one.
+func (s *store) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{s}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+// a transaction is run at most maxTxAttempts times, waiting longer after
+// every transient failure
+const (
+	maxTxAttempts = 5
+	txRetryDelay  = 10 * time.Millisecond
+)
+
+type txManager struct {
+	s *store
+}
+
+// WithinTx implements domain.TxManager.
+func (tm *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{tm.s}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := tm.s.inTx(ctx, func(tx *sql.Tx) error {
+			return fn(context.WithValue(ctx, txKey{tm.s}, tx))
+		})
+		if err == nil || attempt == maxTxAttempts || !tm.retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func (tm *txManager) retryable(err error) bool {
+	return tm.s.dialect.IsRetryable != nil && tm.s.dialect.IsRetryable(err)
+}
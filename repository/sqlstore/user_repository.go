@@ -0,0 +1,458 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var users = table[domain.User]{
+	name: "users",
+	columns: []string{
+		"id", "tenant_id", "first_name", "last_name", "email", "active",
+		"suspended", "suspended_at", "suspend_reason", "password", "password_history",
+		"verify_token", "verify_sent_at", "pending_email", "email_change_token", "email_change_expires_at",
+		"is_owner", "refresh_tokens", "role", "branch_id", "officer_id", "profile",
+		"created_at", "updated_at", "last_login", "deleted_at", "erased_at", "version", "token_version",
+	},
+	scan: func(row scanner) (domain.User, error) {
+		var (
+			user                                                 domain.User
+			id, history, tokens, profile                         string
+			suspendedAt, emailChangeExpiresAt, deletedAt, erased sql.NullTime
+		)
+		err := row.Scan(
+			&id, &user.TenantID, &user.FirstName, &user.LastName, &user.Email, &user.Active,
+			&user.Suspended, &suspendedAt, &user.SuspendReason, &user.Password, &history,
+			&user.VerifyToken, &user.VerifySentAt, &user.PendingEmail, &user.EmailChangeToken, &emailChangeExpiresAt,
+			&user.IsOwner, &tokens, &user.Role, &user.BranchID, &user.OfficerID, &profile,
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLogin, &deletedAt, &erased, &user.Version, &user.TokenVersion,
+		)
+		if err != nil {
+			return domain.User{}, err
+		}
+		user.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return domain.User{}, err
+		}
+		user.SuspendedAt = timeOf(suspendedAt)
+		user.EmailChangeExpiresAt = timeOf(emailChangeExpiresAt)
+		user.DeletedAt = timeOf(deletedAt)
+		user.ErasedAt = timeOf(erased)
+		user.VerifySentAt = user.VerifySentAt.UTC()
+		user.CreatedAt = user.CreatedAt.UTC()
+		user.UpdatedAt = user.UpdatedAt.UTC()
+		user.LastLogin = user.LastLogin.UTC()
+		for _, field := range []struct {
+			data string
+			v    any
+		}{{history, &user.PasswordHistory}, {tokens, &user.Tokens}, {profile, &user.Profile}} {
+			if err := fromJSON(field.data, field.v); err != nil {
+				return domain.User{}, err
+			}
+		}
+		return user, nil
+	},
+	values: func(user domain.User) []any {
+		history, tokens := user.PasswordHistory, user.Tokens
+		if history == nil {
+			history = []string{}
+		}
+		if tokens == nil {
+			tokens = []string{}
+		}
+		return []any{
+			user.ID.Hex(), user.TenantID, user.FirstName, user.LastName, user.Email, user.Active,
+			user.Suspended, nullTime(user.SuspendedAt), user.SuspendReason, user.Password, jsonOf(history),
+			user.VerifyToken, dbTime(user.VerifySentAt), user.PendingEmail, user.EmailChangeToken, nullTime(user.EmailChangeExpiresAt),
+			user.IsOwner, jsonOf(tokens), user.Role, user.BranchID, user.OfficerID, jsonOf(user.Profile),
+			dbTime(user.CreatedAt), dbTime(user.UpdatedAt), dbTime(user.LastLogin), nullTime(user.DeletedAt), nullTime(user.ErasedAt), user.Version, user.TokenVersion,
+		}
+	},
+}
+
+type userRepository struct {
+	s *store
+}
+
+// byID is the condition on the user userID visible with ctx.
+func byID(ctx context.Context, userID string) (where, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return where{}, repository.ErrInvalidID
+	}
+	return userScope(ctx, cond("id = ?", userID)), nil
+}
+
+// update applies change to the user userID and returns the updated user.
+func (ur *userRepository) update(ctx context.Context, userID string, change func(*domain.User) error) (domain.User, error) {
+	c, err := byID(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user, err := users.modify(ctx, ur.s, c, change)
+	return user, notFound(err, repository.ErrUserNotFound)
+}
+
+// set is a change that cannot fail.
+func set(change func(*domain.User)) func(*domain.User) error {
+	return func(user *domain.User) error {
+		change(user)
+		return nil
+	}
+}
+
+// edit is a change to the data of the user, which moves its version on.
+func edit(change func(*domain.User)) func(*domain.User) error {
+	return func(user *domain.User) error {
+		change(user)
+		user.Version++
+		return nil
+	}
+}
+
+// at makes change apply only to the user at version, or at any version
+// when version is 0.
+func at(version int64, change func(*domain.User) error) func(*domain.User) error {
+	return func(user *domain.User) error {
+		if version != 0 && user.Version != version {
+			return &repository.ConflictError{Expected: version, Current: user.Version}
+		}
+		return change(user)
+	}
+}
+
+func (ur *userRepository) findOne(ctx context.Context, c where) (domain.User, error) {
+	user, err := users.findOne(ctx, ur.s, ur.s.conn(ctx), userScope(ctx, c), "")
+	return user, notFound(err, repository.ErrUserNotFound)
+}
+
+// ActivateUser implements domain.UserRepository.
+func (ur *userRepository) ActivateUser(ctx context.Context, userID string) error {
+	_, err := ur.update(ctx, userID, edit(func(user *domain.User) {
+		user.Active = true
+		user.VerifyToken = ""
+		user.UpdatedAt = time.Now()
+	}))
+	return err
+}
+
+// UpdateVerifyToken implements domain.UserRepository.
+func (ur *userRepository) UpdateVerifyToken(ctx context.Context, userID string, tokenHash string, sentAt time.Time) error {
+	_, err := ur.update(ctx, userID, set(func(user *domain.User) {
+		user.VerifyToken = tokenHash
+		user.VerifySentAt = sentAt
+	}))
+	return err
+}
+
+// Create implements domain.UserRepository.
+func (ur *userRepository) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.TenantID = tenantID
+	user.Version = 1
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	err = users.insert(ctx, ur.s, ur.s.conn(ctx), user)
+	if err != nil && ur.s.dialect.IsUniqueViolation(err) {
+		return domain.User{}, repository.ErrEmailTaken
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// Delete implements domain.UserRepository.
+func (ur *userRepository) Delete(ctx context.Context, userID string) error {
+	_, err := ur.update(ctx, userID, edit(func(user *domain.User) {
+		now := time.Now()
+		user.DeletedAt = now
+		user.Tokens = []string{}
+		user.UpdatedAt = now
+	}))
+	return err
+}
+
+// Erase implements domain.UserRepository.
+func (ur *userRepository) Erase(ctx context.Context, userID string) (domain.User, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return domain.User{}, repository.ErrInvalidID
+	}
+
+	var before domain.User
+	c := scoped(ctx, cond("id = ? AND erased_at IS NULL", userID))
+	_, err := users.modify(ctx, ur.s, c, set(func(user *domain.User) {
+		before = *user
+
+		now := time.Now()
+		user.FirstName = domain.ErasedName
+		user.LastName = ""
+		user.Email = domain.ErasedEmail(userID)
+		user.Password = ""
+		user.PasswordHistory = []string{}
+		user.Tokens = []string{}
+		user.Active = false
+		user.ErasedAt = now
+		user.UpdatedAt = now
+		if user.DeletedAt.IsZero() {
+			user.DeletedAt = now
+		}
+		user.Profile = domain.Profile{}
+		user.VerifyToken = ""
+		user.PendingEmail = ""
+		user.EmailChangeToken = ""
+		user.EmailChangeExpiresAt = time.Time{}
+		user.SuspendReason = ""
+		user.Version++
+	}))
+	if err != nil {
+		return domain.User{}, notFound(err, repository.ErrUserNotFound)
+	}
+	return before, nil
+}
+
+// List implements domain.UserRepository.
+func (ur *userRepository) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.User], error) {
+	return findPage(ctx, ur.s, users, userScope(ctx, cond("1 = 1")), spec, userSortValue)
+}
+
+// userSortValue returns the value of the sortable field of user.
+func userSortValue(user domain.User, field string) any {
+	switch field {
+	case "email":
+		return user.Email
+	case "last_login":
+		return user.LastLogin
+	}
+	return user.CreatedAt
+}
+
+// GetByEmail implements domain.UserRepository.
+func (ur *userRepository) GetByEmail(ctx context.Context, email string) (domain.User, error) {
+	return ur.findOne(ctx, cond("email = ?", email))
+}
+
+// GetByID implements domain.UserRepository.
+func (ur *userRepository) GetByID(ctx context.Context, userID string) (domain.User, error) {
+	if _, err := primitive.ObjectIDFromHex(userID); err != nil {
+		return domain.User{}, repository.ErrInvalidID
+	}
+	return ur.findOne(ctx, cond("id = ?", userID))
+}
+
+// IsUserActive implements domain.UserRepository.
+func (ur *userRepository) IsUserActive(ctx context.Context, userID string) (bool, error) {
+	user, err := ur.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.Active, nil
+}
+
+// IsOwner implements domain.UserRepository.
+func (ur *userRepository) IsOwner(ctx context.Context, userID string) (bool, error) {
+	user, err := ur.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.IsOwner, nil
+}
+
+// UpdateRole implements domain.UserRepository.
+func (ur *userRepository) UpdateRole(ctx context.Context, userID string, role string) (domain.User, error) {
+	return ur.update(ctx, userID, edit(func(user *domain.User) {
+		user.Role = role
+		user.UpdatedAt = time.Now()
+		user.TokenVersion++
+	}))
+}
+
+// SetOfficer implements domain.UserRepository.
+func (ur *userRepository) SetOfficer(ctx context.Context, userID string, officerID string) (domain.User, error) {
+	return ur.update(ctx, userID, edit(func(user *domain.User) {
+		user.OfficerID = officerID
+		user.UpdatedAt = time.Now()
+	}))
+}
+
+// SetBranch implements domain.UserRepository.
+func (ur *userRepository) SetBranch(ctx context.Context, userID string, branchID string) (domain.User, error) {
+	return ur.update(ctx, userID, edit(func(user *domain.User) {
+		user.BranchID = branchID
+		user.UpdatedAt = time.Now()
+	}))
+}
+
+// SetPendingEmail implements domain.UserRepository.
+func (ur *userRepository) SetPendingEmail(ctx context.Context, userID string, email string, tokenHash string, expiresAt time.Time) error {
+	_, err := ur.update(ctx, userID, edit(func(user *domain.User) {
+		user.PendingEmail = email
+		user.EmailChangeToken = tokenHash
+		user.EmailChangeExpiresAt = expiresAt
+	}))
+	return err
+}
+
+// GetByEmailChangeToken implements domain.UserRepository.
+func (ur *userRepository) GetByEmailChangeToken(ctx context.Context, tokenHash string) (domain.User, error) {
+	return ur.findOne(ctx, cond("email_change_token = ?", tokenHash))
+}
+
+// ConfirmEmail implements domain.UserRepository.
+func (ur *userRepository) ConfirmEmail(ctx context.Context, userID string, tokenHash string) error {
+	c, err := byID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	c = and(c, cond("email_change_token = ?", tokenHash))
+	_, err = users.modify(ctx, ur.s, c, edit(func(user *domain.User) {
+		user.Email = user.PendingEmail
+		user.Tokens = []string{}
+		user.TokenVersion++
+		user.UpdatedAt = time.Now()
+		user.PendingEmail = ""
+		user.EmailChangeToken = ""
+		user.EmailChangeExpiresAt = time.Time{}
+	}))
+	if err != nil && ur.s.dialect.IsUniqueViolation(err) {
+		return repository.ErrEmailTaken
+	}
+	return notFound(err, repository.ErrUserNotFound)
+}
+
+// RevokeAllRefreshTokens implements domain.UserRepository.
+func (ur *userRepository) RevokeAllRefreshTokens(ctx context.Context, userID string) error {
+	_, err := ur.update(ctx, userID, set(func(user *domain.User) {
+		user.Tokens = []string{}
+		user.TokenVersion++
+	}))
+	return err
+}
+
+// Count implements domain.UserRepository. Like the Mongo count it includes
+// deleted users.
+func (ur *userRepository) Count(ctx context.Context) (int64, error) {
+	return users.count(ctx, ur.s, userScope(ctx, cond("1 = 1")))
+}
+
+// SetSuspended implements domain.UserRepository.
+func (ur *userRepository) SetSuspended(ctx context.Context, userID string, suspended bool, reason string) (domain.User, error) {
+	return ur.update(ctx, userID, edit(func(user *domain.User) {
+		now := time.Now()
+		user.Suspended = suspended
+		user.UpdatedAt = now
+		if suspended {
+			user.SuspendedAt = now
+			user.SuspendReason = reason
+			user.Tokens = []string{}
+			return
+		}
+		user.SuspendedAt = time.Time{}
+		user.SuspendReason = ""
+	}))
+}
+
+// UpdateProfile implements domain.UserRepository.
+func (ur *userRepository) UpdateProfile(ctx context.Context, userID string, version int64, profile domain.EditableProfile) (domain.User, error) {
+	return ur.update(ctx, userID, at(version, edit(func(user *domain.User) {
+		user.FirstName = profile.FirstName
+		user.LastName = profile.LastName
+		user.Profile = profile.Profile
+		user.UpdatedAt = time.Now()
+	})))
+}
+
+// RefreshTokenExist implements domain.UserRepository.
+func (ur *userRepository) RefreshTokenExist(ctx context.Context, userID string, refreshToken string) (bool, error) {
+	user, err := ur.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, token := range user.Tokens {
+		if token == refreshToken {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ResetUserPassword implements domain.UserRepository.
+func (ur *userRepository) ResetUserPassword(ctx context.Context, userID string, resetPassword domain.ResetPasswordRequest) error {
+	_, err := ur.update(ctx, userID, set(setPassword(resetPassword.NewPassword)))
+	return err
+}
+
+// errTokenNotFound aborts the revocation of a refresh token the user does
+// not have.
+var errTokenNotFound = errors.New("refresh token not found")
+
+// RevokeRefreshToken implements domain.UserRepository.
+func (ur *userRepository) RevokeRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+	_, err := ur.update(ctx, userID, func(user *domain.User) error {
+		tokens := make([]string, 0, len(user.Tokens))
+		for _, token := range user.Tokens {
+			if token != refreshToken {
+				tokens = append(tokens, token)
+			}
+		}
+		if len(tokens) == len(user.Tokens) {
+			return errTokenNotFound
+		}
+		user.Tokens = tokens
+		return nil
+	})
+	if errors.Is(err, errTokenNotFound) {
+		return repository.ErrUserNotFound
+	}
+	return err
+}
+
+// Update implements domain.UserRepository.
+func (ur *userRepository) Update(ctx context.Context, userID string, version int64, update domain.UserUpdate) (domain.User, error) {
+	return ur.update(ctx, userID, at(version, edit(func(user *domain.User) {
+		user.FirstName = update.FirstName
+		user.LastName = update.LastName
+		user.UpdatedAt = update.UpdatedAt
+	})))
+}
+
+// UpdateRefreshToken implements domain.UserRepository.
+func (ur *userRepository) UpdateRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+	_, err := ur.update(ctx, userID, set(func(user *domain.User) {
+		user.Tokens = append(user.Tokens, refreshToken)
+	}))
+	return err
+}
+
+// UpdateUserPassword implements domain.UserRepository.
+func (ur *userRepository) UpdateUserPassword(ctx context.Context, userID string, updatePassword domain.UpdatePassword) error {
+	_, err := ur.update(ctx, userID, set(setPassword(updatePassword.NewPassword)))
+	return err
+}
+
+// setPassword sets a new password hash and moves the current one to the
+// front of the password history, like the Mongo passwordUpdate.
+func setPassword(hashedPassword string) func(*domain.User) {
+	return func(user *domain.User) {
+		history := append([]string{user.Password}, user.PasswordHistory...)
+		if len(history) > domain.MaxPasswordHistory {
+			history = history[:domain.MaxPasswordHistory]
+		}
+		user.Password = hashedPassword
+		user.PasswordHistory = history
+		user.UpdatedAt = time.Now()
+	}
+}
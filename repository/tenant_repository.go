@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrTenantNotFound  = domain.NotFound("tenant_not_found", "tenant not found")
+	ErrTenantSlugTaken = domain.Conflict("tenant_slug_taken", "tenant slug already taken")
+)
+
+type tenantRepository struct {
+	tenants *mongo.Collection
+}
+
+func NewTenantRepository(db *mongo.Database) domain.TenantRepository {
+	return &tenantRepository{
+		tenants: db.Collection(domain.CollectionTenants),
+	}
+}
+
+// tenantCollections are the collections whose records belong to a tenant.
+var tenantCollections = []string{
+	"users",
+	domain.CollectionKYC,
+	domain.CollectionAuditLog,
+	domain.CollectionMagicLinks,
+	"password-reset",
+}
+
+// EnsurePrimary implements domain.TenantRepository.
+// On first start it also moves every record stored before tenants existed
+// into the primary tenant.
+func (tr *tenantRepository) EnsurePrimary(ctx context.Context, slug string) (domain.Tenant, error) {
+	now := time.Now()
+	update := bson.M{"$setOnInsert": bson.M{
+		"name":       slug,
+		"slug":       slug,
+		"primary":    true,
+		"active":     true,
+		"created_at": now,
+		"updated_at": now,
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var tenant domain.Tenant
+	err := tr.tenants.FindOneAndUpdate(ctx, bson.M{"slug": slug}, update, opts).Decode(&tenant)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+
+	missing := bson.M{"tenant_id": bson.M{"$exists": false}}
+	backfill := bson.M{"$set": bson.M{"tenant_id": tenant.ID.Hex()}}
+	for _, name := range tenantCollections {
+		if _, err := tr.tenants.Database().Collection(name).UpdateMany(ctx, missing, backfill); err != nil {
+			return domain.Tenant{}, err
+		}
+	}
+	return tenant, nil
+}
+
+// Create implements domain.TenantRepository.
+func (tr *tenantRepository) Create(ctx context.Context, tenant domain.Tenant) (domain.Tenant, error) {
+	res, err := tr.tenants.InsertOne(ctx, tenant)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Tenant{}, ErrTenantSlugTaken
+	}
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	tenant.ID = res.InsertedID.(primitive.ObjectID)
+	return tenant, nil
+}
+
+// GetByID implements domain.TenantRepository.
+func (tr *tenantRepository) GetByID(ctx context.Context, tenantID string) (domain.Tenant, error) {
+	objID, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return domain.Tenant{}, ErrTenantNotFound
+	}
+	return tr.findOne(ctx, bson.M{"_id": objID})
+}
+
+// GetBySlug implements domain.TenantRepository.
+func (tr *tenantRepository) GetBySlug(ctx context.Context, slug string) (domain.Tenant, error) {
+	return tr.findOne(ctx, bson.M{"slug": slug})
+}
+
+// List implements domain.TenantRepository.
+func (tr *tenantRepository) List(ctx context.Context) ([]domain.Tenant, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := tr.tenants.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tenants := make([]domain.Tenant, 0)
+	err = cursor.All(ctx, &tenants)
+	if err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// Update implements domain.TenantRepository.
+func (tr *tenantRepository) Update(ctx context.Context, tenantID string, request domain.UpdateTenantRequest) (domain.Tenant, error) {
+	objID, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return domain.Tenant{}, ErrTenantNotFound
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if request.Name != nil {
+		set["name"] = *request.Name
+	}
+	if request.Active != nil {
+		set["active"] = *request.Active
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tenant domain.Tenant
+	err = tr.tenants.FindOneAndUpdate(ctx, bson.M{"_id": objID}, bson.M{"$set": set}, opts).Decode(&tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Tenant{}, ErrTenantNotFound
+	}
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	return tenant, nil
+}
+
+// ClaimOwner implements domain.TenantRepository.
+func (tr *tenantRepository) ClaimOwner(ctx context.Context, tenantID string, email string) (domain.Tenant, error) {
+	objID, err := primitive.ObjectIDFromHex(tenantID)
+	if err != nil {
+		return domain.Tenant{}, ErrTenantNotFound
+	}
+
+	unclaimed := bson.M{"_id": objID, "owner_email": bson.M{"$in": bson.A{nil, ""}}}
+	update := bson.M{"$set": bson.M{"owner_email": email, "updated_at": time.Now()}}
+	if _, err := tr.tenants.UpdateOne(ctx, unclaimed, update); err != nil {
+		return domain.Tenant{}, err
+	}
+	return tr.findOne(ctx, bson.M{"_id": objID})
+}
+
+func (tr *tenantRepository) findOne(ctx context.Context, filter bson.M) (domain.Tenant, error) {
+	var tenant domain.Tenant
+	err := tr.tenants.FindOne(ctx, filter).Decode(&tenant)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.Tenant{}, ErrTenantNotFound
+	}
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	return tenant, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrNoTenant = errors.New("no tenant to store the record under")
+
+// scoped returns filter restricted to the tenant of ctx, leaving filter
+// itself alone. Without a tenant the filter matches nothing, so a missing
+// scope can never expose another tenant's records.
+func scoped(ctx context.Context, filter bson.M) bson.M {
+	filter = maps.Clone(filter)
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		filter["tenant_id"] = tenantID
+	} else if !domain.IsAllTenants(ctx) {
+		filter["tenant_id"] = bson.M{"$in": bson.A{}}
+	}
+	return filter
+}
+
+// tenantOf returns the tenant the records created with ctx belong to.
+func tenantOf(ctx context.Context) (string, error) {
+	tenantID, ok := domain.TenantFromContext(ctx)
+	if !ok {
+		return "", ErrNoTenant
+	}
+	return tenantID, nil
+}
+
+// tenantKey prefixes key with the tenant of ctx, for records whose ID is a
+// natural key.
+func tenantKey(ctx context.Context, key string) string {
+	tenantID, _ := domain.TenantFromContext(ctx)
+	return tenantID + ":" + key
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNoTransactions is returned by WithinTx on a standalone Mongo server,
+// which would otherwise leave half of a failed unit of work applied.
+var ErrNoTransactions = errors.New("MongoDB is a standalone server without transactions; run a replica set or set MONGO_ALLOW_NON_TX")
+
+type txManager struct {
+	client     *mongo.Client
+	allowNonTx bool
+
+	mu        sync.Mutex
+	checked   bool
+	supported bool
+}
+
+// NewTxManager returns a domain.TxManager running Mongo multi-document
+// transactions. The driver retries a transaction that fails with a
+// transient error, and its commit when the outcome is unknown. On a
+// standalone server transactions fail with ErrNoTransactions, unless
+// allowNonTx lets them run their writes one by one.
+func NewTxManager(client *mongo.Client, allowNonTx bool) domain.TxManager {
+	return &txManager{client: client, allowNonTx: allowNonTx}
+}
+
+// WithinTx implements domain.TxManager.
+func (tm *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	if !tm.transactional(ctx) {
+		if !tm.allowNonTx {
+			return ErrNoTransactions
+		}
+		return fn(ctx)
+	}
+
+	session, err := tm.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// transactional reports whether the server is part of a replica set or a
+// sharded cluster, the deployments that run transactions.
+func (tm *txManager) transactional(ctx context.Context) bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.checked {
+		return tm.supported
+	}
+
+	var hello bson.M
+	err := tm.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		// the transaction reports what is wrong with the server
+		return true
+	}
+	_, replicaSet := hello["setName"]
+	tm.supported = replicaSet || hello["msg"] == "isdbgrid"
+	tm.checked = true
+	if !tm.supported && tm.allowNonTx {
+		log.Println("[repo] MongoDB is a standalone server, writes of a transaction are not rolled back")
+	}
+	return tm.supported
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"log"
+	"time"
 
 	"errors"
 
@@ -14,8 +15,9 @@ import (
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrInvalidID    = errors.New("user not id invalid")
+	ErrUserNotFound = domain.NotFound("user_not_found", "user not found")
+	ErrInvalidID    = domain.Validation("invalid_id", "invalid user id")
+	ErrEmailTaken   = domain.Conflict("email_taken", "email already taken")
 )
 
 type userRepository struct {
@@ -30,6 +32,52 @@ func NewUserRepository(db *mongo.Database) domain.UserRepository {
 	}
 }
 
+// userScope returns filter restricted to the users of the tenant of ctx
+// that have not been deleted and, when ctx is restricted to a portfolio, to
+// the borrowers in it. Like scoped, it leaves filter itself alone.
+func userScope(ctx context.Context, filter bson.M) bson.M {
+	filter = scoped(ctx, filter)
+	filter["deleted_at"] = bson.M{"$exists": false}
+	if officerID, ok := domain.PortfolioFromContext(ctx); ok {
+		filter["officer_id"] = officerID
+	}
+	return filter
+}
+
+// portfolio returns the IDs of the borrowers in the portfolio of ctx,
+// looked up in users.
+func portfolio(ctx context.Context, users *mongo.Collection) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := users.Find(ctx, userScope(ctx, bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = cursor.All(ctx, &ids)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, 0, len(ids))
+	for _, user := range ids {
+		userIDs = append(userIDs, user.ID.Hex())
+	}
+	return userIDs, nil
+}
+
+// versionUp is the part of an update that moves the version of a user on,
+// and nextVersion the same in an update pipeline.
+var (
+	versionUp   = bson.M{"version": int64(1)}
+	nextVersion = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", int64(0)}}, int64(1)}}
+	// nextTokenVersion revokes the access tokens of a user in an update
+	// pipeline.
+	nextTokenVersion = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$token_version", int64(0)}}, int64(1)}}
+)
+
 // ActivateUser implements domain.UserRepository.
 func (ur *userRepository) ActivateUser(ctx context.Context, userID string) error {
 	ObjID, err := primitive.ObjectIDFromHex(userID)
@@ -37,8 +85,14 @@ func (ur *userRepository) ActivateUser(ctx context.Context, userID string) error
 		return ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID}
-	res, err := ur.users.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"active": true}})
+	// the verification token is single use
+	filter := userScope(ctx, bson.M{"_id": ObjID})
+	update := bson.M{
+		"$set":   bson.M{"active": true, "updated_at": time.Now()},
+		"$unset": bson.M{"verify_token": ""},
+		"$inc":   versionUp,
+	}
+	res, err := ur.users.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
@@ -48,9 +102,37 @@ func (ur *userRepository) ActivateUser(ctx context.Context, userID string) error
 	return nil
 }
 
+// UpdateVerifyToken implements domain.UserRepository.
+func (ur *userRepository) UpdateVerifyToken(ctx context.Context, userID string, tokenHash string, sentAt time.Time) error {
+	ObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	update := bson.M{"$set": bson.M{"verify_token": tokenHash, "verify_sent_at": sentAt}}
+	res, err := ur.users.UpdateOne(ctx, userScope(ctx, bson.M{"_id": ObjID}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 // Create implements domain.UserRepository.
 func (ur *userRepository) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	tenantID, err := tenantOf(ctx)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.TenantID = tenantID
+	user.Version = 1
+
 	res, err := ur.users.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.User{}, ErrEmailTaken
+	}
 	if err != nil {
 		return domain.User{}, err
 	}
@@ -60,38 +142,79 @@ func (ur *userRepository) Create(ctx context.Context, user domain.User) (domain.
 
 // Delete implements domain.UserRepository.
 func (ur *userRepository) Delete(ctx context.Context, userID string) error {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidID
+	}
 
-	res, err := ur.users.DeleteOne(ctx, userID)
+	// financial records keep referring to the user, so it is only marked
+	// as deleted
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"deleted_at":     now,
+			"refresh_tokens": bson.A{},
+			"updated_at":     now,
+		},
+		"$inc": versionUp,
+	}
+	res, err := ur.users.UpdateOne(ctx, userScope(ctx, bson.M{"_id": objID}), update)
 	if err != nil {
 		return err
 	}
-	if res.DeletedCount == 0 {
+	if res.MatchedCount == 0 {
 		return ErrUserNotFound
 	}
 	return nil
 }
 
-// GetAll implements domain.UserRepository.
-func (ur *userRepository) GetAll(ctx context.Context) ([]domain.User, error) {
-	//get all users
-	cursor, err := ur.users.Find(ctx, bson.M{})
-	//check if there is an error
+// Erase implements domain.UserRepository.
+func (ur *userRepository) Erase(ctx context.Context, userID string) (domain.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
+		return domain.User{}, ErrInvalidID
 	}
-	defer cursor.Close(ctx)
-	users := make([]domain.User, 0)
-	err = cursor.All(ctx, &users)
+
+	now := time.Now()
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"first_name":       domain.ErasedName,
+			"last_name":        "",
+			"email":            domain.ErasedEmail(userID),
+			"password":         "",
+			"password_history": bson.A{},
+			"refresh_tokens":   bson.A{},
+			"active":           false,
+			"erased_at":        now,
+			"updated_at":       now,
+			"version":          nextVersion,
+			// keep the original deletion time of a user deleted before erasure
+			"deleted_at": bson.M{"$ifNull": bson.A{"$deleted_at", now}},
+		}}},
+		{{Key: "$unset", Value: bson.A{"profile", "verify_token", "pending_email", "email_change_token", "email_change_expires_at", "suspend_reason"}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var user domain.User
+	err = ur.users.FindOneAndUpdate(ctx, scoped(ctx, bson.M{"_id": objID, "erased_at": bson.M{"$exists": false}}), pipeline, opts).Decode(&user)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
 	}
-	return users, nil
+	return user, nil
+}
+
+// List implements domain.UserRepository.
+func (ur *userRepository) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.User], error) {
+	return findPage[domain.User](ctx, ur.users, userScope(ctx, bson.M{}), spec)
 }
 
 // GetByEmail implements domain.UserRepository.
 func (ur *userRepository) GetByEmail(ctx context.Context, email string) (domain.User, error) {
 	//get user by email
-	filter := bson.M{"email": email}
+	filter := userScope(ctx, bson.M{"email": email})
 	user := domain.User{}
 	err := ur.users.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
@@ -112,7 +235,7 @@ func (ur *userRepository) GetByID(ctx context.Context, userID string) (domain.Us
 		return domain.User{}, ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID}
+	filter := userScope(ctx, bson.M{"_id": ObjID})
 	user := domain.User{}
 	err = ur.users.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
@@ -133,7 +256,7 @@ func (ur *userRepository) IsUserActive(ctx context.Context, userID string) (bool
 		return false, ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID}
+	filter := userScope(ctx, bson.M{"_id": ObjID})
 	user := domain.User{}
 	//check if user exists
 	err = ur.users.FindOne(ctx, filter).Decode(&user)
@@ -153,7 +276,7 @@ func (ur *userRepository) IsOwner(ctx context.Context, userID string) (bool, err
 		return false, ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID}
+	filter := userScope(ctx, bson.M{"_id": ObjID})
 	user := domain.User{}
 	//check if user exists
 	err = ur.users.FindOne(ctx, filter).Decode(&user)
@@ -168,90 +291,313 @@ func (ur *userRepository) IsOwner(ctx context.Context, userID string) (bool, err
 
 }
 
-// RefreshTokenExist implements domain.UserRepository.
-func (ur *userRepository) RefreshTokenExist(ctx context.Context, userID string, refreshToken string) (bool, error) {
-	ObjID, err := primitive.ObjectIDFromHex(userID)
+// UpdateRole implements domain.UserRepository.
+func (ur *userRepository) UpdateRole(ctx context.Context, userID string, role string) (domain.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return false, ErrInvalidID
+		return domain.User{}, ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID, "refresh_tokens": refreshToken}
-	res, err := ur.users.CountDocuments(ctx, filter)
+	// the access tokens of the user carry the permissions of the old role
+	update := bson.M{
+		"$set": bson.M{"role": role, "updated_at": time.Now()},
+		"$inc": bson.M{"version": int64(1), "token_version": int64(1)},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedUser domain.User
+	err = ur.users.FindOneAndUpdate(ctx, userScope(ctx, bson.M{"_id": objID}), update, opts).Decode(&updatedUser)
 	if err != nil {
-		return false, err
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
 	}
-	return res > 0, nil
+	return updatedUser, nil
+}
 
+// SetOfficer implements domain.UserRepository.
+func (ur *userRepository) SetOfficer(ctx context.Context, userID string, officerID string) (domain.User, error) {
+	return ur.setField(ctx, userID, "officer_id", officerID)
 }
 
-// ResetUserPassword implements domain.UserRepository.
-func (ur *userRepository) ResetUserPassword(ctx context.Context, userID string, resetPassword domain.ResetPasswordRequest) error {
+// SetBranch implements domain.UserRepository.
+func (ur *userRepository) SetBranch(ctx context.Context, userID string, branchID string) (domain.User, error) {
+	return ur.setField(ctx, userID, "branch_id", branchID)
+}
+
+func (ur *userRepository) setField(ctx context.Context, userID string, field string, value interface{}) (domain.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.User{}, ErrInvalidID
+	}
+
+	update := bson.M{"$set": bson.M{field: value, "updated_at": time.Now()}, "$inc": versionUp}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedUser domain.User
+	err = ur.users.FindOneAndUpdate(ctx, userScope(ctx, bson.M{"_id": objID}), update, opts).Decode(&updatedUser)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
+	}
+	return updatedUser, nil
+}
+
+// SetPendingEmail implements domain.UserRepository.
+func (ur *userRepository) SetPendingEmail(ctx context.Context, userID string, email string, tokenHash string, expiresAt time.Time) error {
 	ObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID}
-	update := bson.M{"$set": bson.M{"password": resetPassword.NewPassword}}
-	res, err := ur.users.UpdateOne(ctx, filter, update)
+	update := bson.M{
+		"$set": bson.M{
+			"pending_email":           email,
+			"email_change_token":      tokenHash,
+			"email_change_expires_at": expiresAt,
+		},
+		"$inc": versionUp,
+	}
+	res, err := ur.users.UpdateOne(ctx, userScope(ctx, bson.M{"_id": ObjID}), update)
 	if err != nil {
 		return err
 	}
-	if res.ModifiedCount == 0 {
+	if res.MatchedCount == 0 {
 		return ErrUserNotFound
 	}
 	return nil
+}
 
+// GetByEmailChangeToken implements domain.UserRepository.
+func (ur *userRepository) GetByEmailChangeToken(ctx context.Context, tokenHash string) (domain.User, error) {
+	user := domain.User{}
+	err := ur.users.FindOne(ctx, userScope(ctx, bson.M{"email_change_token": tokenHash})).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
+	}
+	return user, nil
 }
 
-// RevokeRefreshToken implements domain.UserRepository.
-func (ur *userRepository) RevokeRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+// ConfirmEmail implements domain.UserRepository.
+func (ur *userRepository) ConfirmEmail(ctx context.Context, userID string, tokenHash string) error {
 	ObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return ErrInvalidID
 	}
 
-	filter := bson.M{"_id": ObjID}
-	update := bson.M{"$pull": bson.M{"refresh_tokens": refreshToken}}
+	// the pipeline copies pending_email over email in the same write that
+	// consumes the token
+	filter := userScope(ctx, bson.M{"_id": ObjID, "email_change_token": tokenHash})
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"email":          "$pending_email",
+			"refresh_tokens": bson.A{},
+			"updated_at":     time.Now(),
+			"version":        nextVersion,
+			"token_version":  nextTokenVersion,
+		}}},
+		{{Key: "$unset", Value: bson.A{"pending_email", "email_change_token", "email_change_expires_at"}}},
+	}
 	res, err := ur.users.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
 	if err != nil {
 		return err
 	}
-	if res.ModifiedCount == 0 {
+	if res.MatchedCount == 0 {
 		return ErrUserNotFound
 	}
 	return nil
 }
 
-// Update updates the user information by user ID.
-func (ur *userRepository) Update(ctx context.Context, userID string, user domain.UserUpdate) (domain.User, error) {
-	// Convert string ID to ObjectID
+// RevokeAllRefreshTokens implements domain.UserRepository.
+func (ur *userRepository) RevokeAllRefreshTokens(ctx context.Context, userID string) error {
+	ObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	update := bson.M{
+		"$set": bson.M{"refresh_tokens": bson.A{}},
+		"$inc": bson.M{"token_version": int64(1)},
+	}
+	res, err := ur.users.UpdateOne(ctx, userScope(ctx, bson.M{"_id": ObjID}), update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Count implements domain.UserRepository.
+func (ur *userRepository) Count(ctx context.Context) (int64, error) {
+	return ur.users.CountDocuments(ctx, userScope(ctx, bson.M{}))
+}
+
+// SetSuspended implements domain.UserRepository.
+func (ur *userRepository) SetSuspended(ctx context.Context, userID string, suspended bool, reason string) (domain.User, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return domain.User{}, ErrInvalidID
 	}
 
-	// Define update fields using bson.D
-	updateFields := bson.M{"$set": user}
-	// Create update document
-	update := bson.D{
-		{Key: "$set", Value: updateFields},
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"suspended":      true,
+			"suspended_at":   now,
+			"suspend_reason": reason,
+			"refresh_tokens": []string{},
+			"updated_at":     now,
+		},
+		"$inc": versionUp,
+	}
+	if !suspended {
+		update = bson.M{
+			"$set":   bson.M{"suspended": false, "updated_at": now},
+			"$unset": bson.M{"suspended_at": "", "suspend_reason": ""},
+			"$inc":   versionUp,
+		}
 	}
 
-	// Perform the update operation
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var updatedUser domain.User
-	err = ur.users.FindOneAndUpdate(ctx, bson.M{"_id": objID}, update, opts).Decode(&updatedUser)
+	err = ur.users.FindOneAndUpdate(ctx, userScope(ctx, bson.M{"_id": objID}), update, opts).Decode(&updatedUser)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return domain.User{}, ErrUserNotFound
 		}
 		return domain.User{}, err
 	}
+	return updatedUser, nil
+}
 
+// UpdateProfile implements domain.UserRepository.
+func (ur *userRepository) UpdateProfile(ctx context.Context, userID string, version int64, profile domain.EditableProfile) (domain.User, error) {
+	return ur.updateVersion(ctx, userID, version, bson.M{
+		"first_name": profile.FirstName,
+		"last_name":  profile.LastName,
+		"profile":    profile.Profile,
+		"updated_at": time.Now(),
+	})
+}
+
+// updateVersion sets the fields of set on the user userID when it is at
+// version, or at any version when version is 0, and moves the version on.
+func (ur *userRepository) updateVersion(ctx context.Context, userID string, version int64, set bson.M) (domain.User, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.User{}, ErrInvalidID
+	}
+
+	filter := bson.M{"_id": objID}
+	if version != 0 {
+		filter["version"] = version
+	}
+	update := bson.M{"$set": set, "$inc": versionUp}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedUser domain.User
+	err = ur.users.FindOneAndUpdate(ctx, userScope(ctx, filter), update, opts).Decode(&updatedUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.User{}, ur.conflictOrNotFound(ctx, objID, version)
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
 	return updatedUser, nil
 }
 
+// conflictOrNotFound tells why a versioned update of the user objID
+// matched nothing: the user is gone, or is at another version.
+func (ur *userRepository) conflictOrNotFound(ctx context.Context, objID primitive.ObjectID, version int64) error {
+	if version == 0 {
+		return ErrUserNotFound
+	}
+
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1})
+	err := ur.users.FindOne(ctx, userScope(ctx, bson.M{"_id": objID}), opts).Decode(&current)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return &ConflictError{Expected: version, Current: current.Version}
+}
+
+// RefreshTokenExist implements domain.UserRepository.
+func (ur *userRepository) RefreshTokenExist(ctx context.Context, userID string, refreshToken string) (bool, error) {
+	ObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, ErrInvalidID
+	}
+
+	filter := userScope(ctx, bson.M{"_id": ObjID, "refresh_tokens": refreshToken})
+	res, err := ur.users.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return res > 0, nil
+
+}
+
+// ResetUserPassword implements domain.UserRepository.
+func (ur *userRepository) ResetUserPassword(ctx context.Context, userID string, resetPassword domain.ResetPasswordRequest) error {
+	ObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	filter := userScope(ctx, bson.M{"_id": ObjID})
+	res, err := ur.users.UpdateOne(ctx, filter, passwordUpdate(resetPassword.NewPassword))
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+
+}
+
+// RevokeRefreshToken implements domain.UserRepository.
+func (ur *userRepository) RevokeRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+	ObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrInvalidID
+	}
+
+	filter := userScope(ctx, bson.M{"_id": ObjID})
+	update := bson.M{"$pull": bson.M{"refresh_tokens": refreshToken}}
+	res, err := ur.users.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Update updates the user information by user ID.
+func (ur *userRepository) Update(ctx context.Context, userID string, version int64, user domain.UserUpdate) (domain.User, error) {
+	return ur.updateVersion(ctx, userID, version, bson.M{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"updated_at": user.UpdatedAt,
+	})
+}
+
 // UpdateRefreshToken updates the refresh token of the user.
 func (ur *userRepository) UpdateRefreshToken(ctx context.Context, userID string, refreshToken string) error {
 	// Convert string ID to ObjectID
@@ -266,7 +612,7 @@ func (ur *userRepository) UpdateRefreshToken(ctx context.Context, userID string,
 	}
 
 	// Perform the update operation
-	result, err := ur.users.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	result, err := ur.users.UpdateOne(ctx, userScope(ctx, bson.M{"_id": objID}), update)
 	if err != nil {
 		return err
 	}
@@ -286,13 +632,8 @@ func (ur *userRepository) UpdateUserPassword(ctx context.Context, userID string,
 		return ErrInvalidID
 	}
 
-	// Define update fields
-	update := bson.D{
-		{Key: "$set", Value: bson.D{{Key: "password", Value: updatePassword.NewPassword}}},
-	}
-
 	// Perform the update operation
-	result, err := ur.users.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	result, err := ur.users.UpdateOne(ctx, userScope(ctx, bson.M{"_id": objID}), passwordUpdate(updatePassword.NewPassword))
 	if err != nil {
 		return err
 	}
@@ -303,3 +644,21 @@ func (ur *userRepository) UpdateUserPassword(ctx context.Context, userID string,
 
 	return nil
 }
+
+// passwordUpdate sets a new password hash and moves the current one to the
+// front of the password history, keeping at most domain.MaxPasswordHistory
+// entries.
+func passwordUpdate(hashedPassword string) mongo.Pipeline {
+	history := bson.M{"$concatArrays": bson.A{
+		bson.A{"$password"},
+		bson.M{"$ifNull": bson.A{"$password_history", bson.A{}}},
+	}}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"password":         hashedPassword,
+			"password_history": bson.M{"$slice": bson.A{history, domain.MaxPasswordHistory}},
+			"updated_at":       time.Now(),
+		}}},
+	}
+}
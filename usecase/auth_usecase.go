@@ -2,9 +2,16 @@ package usecase
 
 import (
 	"context"
+	"errors"
 
 	"github.com/dagota12/Loan-Tracker/domain"
 	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+var (
+	ErrSessionRevoked   = domain.Unauthorized("session_revoked", "the session has been revoked")
+	ErrAccountSuspended = domain.Forbidden("account_suspended", "user is suspended")
 )
 
 type authUsecase struct {
@@ -36,6 +43,37 @@ func (au *authUsecase) UpdateRefreshToken(ctx context.Context, userID string, re
 	return au.userRepo.UpdateRefreshToken(ctx, userID, refreshToken)
 }
 
+// RefreshTokenExist implements domain.AuthUsecase.
+func (au *authUsecase) RefreshTokenExist(ctx context.Context, userID string, refreshToken string) (bool, error) {
+	return au.userRepo.RefreshTokenExist(ctx, userID, refreshToken)
+}
+
+// RevokeRefreshToken implements domain.AuthUsecase.
+func (au *authUsecase) RevokeRefreshToken(ctx context.Context, userID string, refreshToken string) error {
+	return au.userRepo.RevokeRefreshToken(ctx, userID, refreshToken)
+}
+
+// CheckSession implements domain.AuthUsecase.
+func (au *authUsecase) CheckSession(ctx context.Context, userID string, tokenVersion int64) error {
+	user, err := au.userRepo.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) || errors.Is(err, repository.ErrInvalidID) {
+		// deleted users are signed out too
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if user.TokenVersion != tokenVersion {
+		return ErrSessionRevoked
+	}
+	// suspending a user does not revoke their tokens, so that lifting the
+	// suspension restores them
+	if user.Suspended {
+		return ErrAccountSuspended
+	}
+	return nil
+}
+
 func NewAuthUsease(userRepo domain.UserRepository) domain.AuthUsecase {
 	return &authUsecase{
 		userRepo: userRepo,
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+type branchUsecase struct {
+	branchRepo     domain.BranchRepository
+	contextTimeout time.Duration
+}
+
+func NewBranchUsecase(branchRepo domain.BranchRepository, timeout time.Duration) domain.BranchUsecase {
+	return &branchUsecase{
+		branchRepo:     branchRepo,
+		contextTimeout: timeout,
+	}
+}
+
+// Create implements domain.BranchUsecase.
+func (bu *branchUsecase) Create(ctx context.Context, request domain.CreateBranchRequest) (domain.Branch, error) {
+	ctx, cancel := context.WithTimeout(ctx, bu.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	return bu.branchRepo.Create(ctx, domain.Branch{
+		Name:      request.Name,
+		Code:      strings.ToUpper(request.Code),
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+// List implements domain.BranchUsecase.
+func (bu *branchUsecase) List(ctx context.Context) ([]domain.Branch, error) {
+	ctx, cancel := context.WithTimeout(ctx, bu.contextTimeout)
+	defer cancel()
+	return bu.branchRepo.List(ctx)
+}
+
+// Update implements domain.BranchUsecase.
+func (bu *branchUsecase) Update(ctx context.Context, branchID string, request domain.UpdateBranchRequest) (domain.Branch, error) {
+	ctx, cancel := context.WithTimeout(ctx, bu.contextTimeout)
+	defer cancel()
+	return bu.branchRepo.Update(ctx, branchID, request)
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+var (
+	ErrIdempotencyKeyReused    = domain.Unprocessable("idempotency_key_reused", "the idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProcess = domain.Conflict("idempotency_key_in_process", "a request with this idempotency key is still being processed")
+)
+
+type idempotencyUsecase struct {
+	repo           domain.IdempotencyRepository
+	ttl            time.Duration
+	contextTimeout time.Duration
+}
+
+func NewIdempotencyUsecase(repo domain.IdempotencyRepository, env *bootstrap.Env) domain.IdempotencyUsecase {
+	return &idempotencyUsecase{
+		repo:           repo,
+		ttl:            time.Duration(env.IdempotencyKeyTTLMin) * time.Minute,
+		contextTimeout: time.Duration(env.ContextTimeout) * time.Second,
+	}
+}
+
+// Begin implements domain.IdempotencyUsecase.
+func (iu *idempotencyUsecase) Begin(ctx context.Context, key string, fingerprint string) (domain.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	record, reserved, err := iu.repo.Reserve(ctx, domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(iu.ttl),
+	})
+	if err != nil {
+		return domain.IdempotencyRecord{}, err
+	}
+	if reserved {
+		return record, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return domain.IdempotencyRecord{}, ErrIdempotencyKeyReused
+	}
+	if record.Status == 0 {
+		return domain.IdempotencyRecord{}, ErrIdempotencyKeyInProcess
+	}
+	return record, nil
+}
+
+// Finish implements domain.IdempotencyUsecase.
+func (iu *idempotencyUsecase) Finish(ctx context.Context, key string, status int, contentType string, header map[string]string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	return iu.repo.Complete(ctx, key, status, contentType, header, body)
+}
+
+// Abandon implements domain.IdempotencyUsecase.
+func (iu *idempotencyUsecase) Abandon(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, iu.contextTimeout)
+	defer cancel()
+
+	return iu.repo.Release(ctx, key)
+}
@@ -0,0 +1,442 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrKYCNotVerified          = domain.Forbidden("kyc_not_verified", "identity verification is not approved")
+	ErrKYCLocked               = domain.Conflict("kyc_locked", "documents cannot be changed while the verification is under review or approved")
+	ErrKYCNotSubmittable       = domain.Conflict("kyc_not_submittable", "verification was already submitted or approved")
+	ErrKYCNotUnderReview       = domain.Conflict("kyc_not_under_review", "verification is not waiting for review")
+	ErrKYCNoValidDocument      = domain.Validation("kyc_no_valid_document", "a valid identity document is required")
+	ErrKYCDocumentExpiry       = domain.Validation("kyc_document_expiry", "identity documents need an expiry date in the future")
+	ErrKYCDocumentType         = &domain.Error{Kind: domain.KindUnsupportedMediaType, Code: "kyc_document_type", Message: "documents must be PDF, JPEG or PNG files"}
+	ErrKYCDocumentTooLarge     = &domain.Error{Kind: domain.KindTooLarge, Code: "kyc_document_too_large", Message: "document is too large"}
+	ErrKYCDocumentNotFound     = domain.NotFound("kyc_document_not_found", "document not found")
+	ErrKYCRejectionNeedsReason = domain.Validation("kyc_rejection_reason_required", "a reason is required to reject a verification")
+)
+
+// accepted document formats and the extension they are stored with
+var kycContentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+type kycUsecase struct {
+	kycRepo        domain.KYCRepository
+	userRepo       domain.UserRepository
+	auditRepo      domain.AuditRepository
+	storage        domain.DocumentStorage
+	contextTimeout time.Duration
+	Env            *bootstrap.Env
+	mailer         emailutil.Mailer
+}
+
+func NewKYCUsecase(kycRepo domain.KYCRepository, userRepo domain.UserRepository, auditRepo domain.AuditRepository, storage domain.DocumentStorage, env *bootstrap.Env, mailer emailutil.Mailer) domain.KYCUsecase {
+	return &kycUsecase{
+		kycRepo:        kycRepo,
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		storage:        storage,
+		contextTimeout: time.Duration(env.ContextTimeout) * time.Second,
+		Env:            env,
+		mailer:         mailer,
+	}
+}
+
+// GetStatus implements domain.KYCUsecase.
+func (ku *kycUsecase) GetStatus(ctx context.Context, userID string) (domain.KYC, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	record, err := ku.kycRepo.GetOrCreate(ctx, userID)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return ku.expireIfDue(ctx, record, time.Now())
+}
+
+// Get implements domain.KYCUsecase.
+func (ku *kycUsecase) Get(ctx context.Context, userID string) (domain.KYC, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	if err := ku.visible(ctx, userID); err != nil {
+		return domain.KYC{}, err
+	}
+	record, err := ku.kycRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return ku.expireIfDue(ctx, record, time.Now())
+}
+
+// UploadDocument implements domain.KYCUsecase.
+// UploadDocument stores the file and attaches it to the record of the user.
+// Only PDF and image files up to the configured size are accepted.
+func (ku *kycUsecase) UploadDocument(ctx context.Context, userID string, upload domain.KYCDocumentUpload, fileName string, content io.Reader) (domain.KYCDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	record, err := ku.kycRepo.GetOrCreate(ctx, userID)
+	if err != nil {
+		return domain.KYCDocument{}, err
+	}
+	record, err = ku.expireIfDue(ctx, record, time.Now())
+	if err != nil {
+		return domain.KYCDocument{}, err
+	}
+	if record.Status == domain.KYCStatusSubmitted || record.Status == domain.KYCStatusApproved {
+		return domain.KYCDocument{}, ErrKYCLocked
+	}
+
+	var expiresAt time.Time
+	if upload.ExpiresAt != "" {
+		expiresAt, err = time.Parse("2006-01-02", upload.ExpiresAt)
+		if err != nil {
+			return domain.KYCDocument{}, ErrKYCDocumentExpiry
+		}
+	}
+	if domain.IsIdentityDocument(upload.Type) && !expiresAt.After(time.Now()) {
+		return domain.KYCDocument{}, ErrKYCDocumentExpiry
+	}
+
+	// the declared content type of an upload cannot be trusted
+	reader := bufio.NewReaderSize(content, 512)
+	head, err := reader.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return domain.KYCDocument{}, err
+	}
+	contentType := http.DetectContentType(head)
+	ext, ok := kycContentTypes[contentType]
+	if !ok {
+		return domain.KYCDocument{}, ErrKYCDocumentType
+	}
+
+	document := domain.KYCDocument{
+		ID:          primitive.NewObjectID().Hex(),
+		Type:        upload.Type,
+		FileName:    path.Base(fileName),
+		ContentType: contentType,
+		ExpiresAt:   expiresAt,
+		UploadedAt:  time.Now(),
+	}
+	document.StorageKey = userID + "/" + document.ID + ext
+
+	limited := &limitedReader{r: reader, limit: int64(ku.Env.KYCMaxUploadMB) << 20}
+	err = ku.storage.Save(ctx, document.StorageKey, limited)
+	if err != nil {
+		return domain.KYCDocument{}, err
+	}
+	document.Size = limited.read
+
+	err = ku.kycRepo.AddDocument(ctx, userID, document)
+	if err != nil {
+		return domain.KYCDocument{}, err
+	}
+	return document, nil
+}
+
+// Submit implements domain.KYCUsecase.
+// Submit sends the uploaded documents for review.
+func (ku *kycUsecase) Submit(ctx context.Context, userID string, actorID string) (domain.KYC, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	record, err := ku.kycRepo.GetOrCreate(ctx, userID)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	record, err = ku.expireIfDue(ctx, record, now)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	if record.Status == domain.KYCStatusSubmitted || record.Status == domain.KYCStatusApproved {
+		return domain.KYC{}, ErrKYCNotSubmittable
+	}
+	if validIdentityExpiry(record, now).IsZero() {
+		return domain.KYC{}, ErrKYCNoValidDocument
+	}
+
+	updated, err := ku.kycRepo.Transition(ctx, userID, domain.KYCTransition{
+		From: record.Status,
+		To:   domain.KYCStatusSubmitted,
+		At:   now,
+	})
+	if errors.Is(err, repository.ErrKYCNotFound) {
+		return domain.KYC{}, ErrKYCNotSubmittable
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	err = ku.audit(ctx, userID, actorID, domain.AuditActionKYCSubmit, record.Status, updated.Status, now)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return updated, nil
+}
+
+// ListByStatus implements domain.KYCUsecase.
+func (ku *kycUsecase) ListByStatus(ctx context.Context, status domain.KYCStatus) ([]domain.KYC, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	return ku.kycRepo.ListByStatus(ctx, status)
+}
+
+// OpenDocument implements domain.KYCUsecase.
+func (ku *kycUsecase) OpenDocument(ctx context.Context, userID string, documentID string) (domain.KYCDocument, io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	if err := ku.visible(ctx, userID); err != nil {
+		return domain.KYCDocument{}, nil, err
+	}
+	record, err := ku.kycRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrKYCNotFound) {
+		return domain.KYCDocument{}, nil, ErrKYCDocumentNotFound
+	}
+	if err != nil {
+		return domain.KYCDocument{}, nil, err
+	}
+
+	for _, document := range record.Documents {
+		if document.ID != documentID {
+			continue
+		}
+		// the file outlives this call, so it is not opened with ctx
+		content, err := ku.storage.Open(context.Background(), document.StorageKey)
+		if err != nil {
+			return domain.KYCDocument{}, nil, err
+		}
+		return document, content, nil
+	}
+	return domain.KYCDocument{}, nil, ErrKYCDocumentNotFound
+}
+
+// Review implements domain.KYCUsecase.
+// Review approves or rejects a submitted verification. An approval lasts
+// until the longest valid identity document expires.
+func (ku *kycUsecase) Review(ctx context.Context, userID string, reviewerID string, request domain.KYCReviewRequest) (domain.KYC, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	if err := ku.visible(ctx, userID); err != nil {
+		return domain.KYC{}, err
+	}
+	now := time.Now()
+	record, err := ku.kycRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrKYCNotFound) {
+		return domain.KYC{}, ErrKYCNotUnderReview
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	if record.Status != domain.KYCStatusSubmitted {
+		return domain.KYC{}, ErrKYCNotUnderReview
+	}
+
+	transition := domain.KYCTransition{
+		From:       domain.KYCStatusSubmitted,
+		ReviewerID: reviewerID,
+		At:         now,
+	}
+	if request.Decision == "approve" {
+		transition.To = domain.KYCStatusApproved
+		transition.ExpiresAt = validIdentityExpiry(record, now)
+		if transition.ExpiresAt.IsZero() {
+			return domain.KYC{}, ErrKYCNoValidDocument
+		}
+	} else {
+		if request.Reason == "" {
+			return domain.KYC{}, ErrKYCRejectionNeedsReason
+		}
+		transition.To = domain.KYCStatusRejected
+		transition.Reason = request.Reason
+	}
+
+	updated, err := ku.kycRepo.Transition(ctx, userID, transition)
+	if errors.Is(err, repository.ErrKYCNotFound) {
+		return domain.KYC{}, ErrKYCNotUnderReview
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	err = ku.audit(ctx, userID, reviewerID, domain.AuditActionKYCReview, record.Status, updated.Status, now)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+	return updated, nil
+}
+
+// EnsureVerified implements domain.KYCUsecase.
+func (ku *kycUsecase) EnsureVerified(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	record, err := ku.kycRepo.GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrKYCNotFound) {
+		return ErrKYCNotVerified
+	}
+	if err != nil {
+		return err
+	}
+	record, err = ku.expireIfDue(ctx, record, time.Now())
+	if err != nil {
+		return err
+	}
+	if record.Status != domain.KYCStatusApproved {
+		return ErrKYCNotVerified
+	}
+	return nil
+}
+
+// ExpireDue implements domain.KYCUsecase.
+func (ku *kycUsecase) ExpireDue(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ku.contextTimeout)
+	defer cancel()
+
+	// the sweep covers every tenant; each record is then expired within
+	// its own tenant
+	now := time.Now()
+	records, err := ku.kycRepo.ListExpired(domain.WithAllTenants(ctx), now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, record := range records {
+		record, err = ku.expireIfDue(domain.WithTenant(ctx, record.TenantID), record, now)
+		if err != nil {
+			return expired, err
+		}
+		if record.Status == domain.KYCStatusExpired {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// ExpireKYCEvery expires the verifications whose documents lapsed every
+// interval, so their users are asked to verify again without having to
+// sign in. It never returns.
+func ExpireKYCEvery(kycUsecase domain.KYCUsecase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := kycUsecase.ExpireDue(context.Background())
+		if err != nil {
+			log.Println("kyc: expiring verifications failed: ", err)
+			continue
+		}
+		if expired > 0 {
+			log.Printf("kyc: %d verifications expired", expired)
+		}
+	}
+}
+
+// visible checks that the user is in the portfolio the caller is
+// restricted to, if any.
+func (ku *kycUsecase) visible(ctx context.Context, userID string) error {
+	if _, ok := domain.PortfolioFromContext(ctx); !ok {
+		return nil
+	}
+	_, err := ku.userRepo.GetByID(ctx, userID)
+	return err
+}
+
+// expireIfDue moves an approved record whose documents have expired to the
+// expired status and asks the user to verify again.
+func (ku *kycUsecase) expireIfDue(ctx context.Context, record domain.KYC, now time.Time) (domain.KYC, error) {
+	if record.Status != domain.KYCStatusApproved || record.ExpiresAt.After(now) {
+		return record, nil
+	}
+
+	updated, err := ku.kycRepo.Transition(ctx, record.UserID, domain.KYCTransition{
+		From: domain.KYCStatusApproved,
+		To:   domain.KYCStatusExpired,
+		At:   now,
+	})
+	if errors.Is(err, repository.ErrKYCNotFound) {
+		// expired concurrently
+		return ku.kycRepo.GetByUserID(ctx, record.UserID)
+	}
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	err = ku.audit(ctx, record.UserID, "system", domain.AuditActionKYCExpire, record.Status, updated.Status, now)
+	if err != nil {
+		return domain.KYC{}, err
+	}
+
+	user, err := ku.userRepo.GetByID(ctx, record.UserID)
+	if err == nil {
+		err = emailutil.SendKYCExpiredEmail(ku.mailer, user.Email, ku.Env)
+	}
+	if err != nil {
+		log.Printf("kyc: could not notify user %s of expired verification: %v", record.UserID, err)
+	}
+	return updated, nil
+}
+
+func (ku *kycUsecase) audit(ctx context.Context, userID, actorID, action string, from, to domain.KYCStatus, at time.Time) error {
+	return ku.auditRepo.Create(ctx, domain.AuditEntry{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		Changes:   []domain.FieldChange{{Field: "kyc.status", Old: from, New: to}},
+		CreatedAt: at,
+	})
+}
+
+// validIdentityExpiry returns the latest expiry among the identity documents
+// of record that are still valid at now, or the zero time if there is none.
+func validIdentityExpiry(record domain.KYC, now time.Time) time.Time {
+	var expiresAt time.Time
+	for _, document := range record.Documents {
+		if !domain.IsIdentityDocument(document.Type) || !document.ExpiresAt.After(now) {
+			continue
+		}
+		if document.ExpiresAt.After(expiresAt) {
+			expiresAt = document.ExpiresAt
+		}
+	}
+	return expiresAt
+}
+
+// limitedReader fails once more than limit bytes are read, so that an
+// oversized upload is never stored.
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, ErrKYCDocumentTooLarge
+	}
+	return n, err
+}
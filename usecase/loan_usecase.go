@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+var (
+	ErrLoanNotPending  = domain.Conflict("loan_not_pending", "loan is not waiting for approval")
+	ErrLoanNotApproved = domain.Conflict("loan_not_approved", "loan is not approved")
+)
+
+type loanUsecase struct {
+	loanRepo       domain.LoanRepository
+	userRepo       domain.UserRepository
+	auditRepo      domain.AuditRepository
+	txManager      domain.TxManager
+	kycUsecase     domain.KYCUsecase
+	contextTimeout time.Duration
+}
+
+func NewLoanUsecase(loanRepo domain.LoanRepository, userRepo domain.UserRepository, auditRepo domain.AuditRepository, txManager domain.TxManager, kycUsecase domain.KYCUsecase, timeout time.Duration) domain.LoanUsecase {
+	return &loanUsecase{
+		loanRepo:       loanRepo,
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		txManager:      txManager,
+		kycUsecase:     kycUsecase,
+		contextTimeout: timeout,
+	}
+}
+
+// Create implements domain.LoanUsecase.
+func (lu *loanUsecase) Create(ctx context.Context, actorID string, request domain.CreateLoanRequest) (domain.Loan, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	borrower, err := lu.userRepo.GetByID(ctx, request.BorrowerID)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	if borrower.Role != domain.RoleBorrower && borrower.Role != domain.RoleUser {
+		return domain.Loan{}, ErrNotBorrower
+	}
+
+	now := time.Now()
+	var loan domain.Loan
+	err = lu.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		loan, err = lu.loanRepo.Create(ctx, domain.Loan{
+			BorrowerID: request.BorrowerID,
+			Amount:     request.Amount,
+			TermMonths: request.TermMonths,
+			Status:     domain.LoanStatusPending,
+			CreatedBy:  actorID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+		return lu.audit(ctx, loan, actorID, domain.AuditActionLoanCreate, "", loan.Status, now)
+	})
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return loan, nil
+}
+
+// Get implements domain.LoanUsecase.
+func (lu *loanUsecase) Get(ctx context.Context, loanID string) (domain.Loan, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	return lu.visible(ctx, loanID)
+}
+
+// ListByBorrower implements domain.LoanUsecase.
+func (lu *loanUsecase) ListByBorrower(ctx context.Context, borrowerID string) ([]domain.Loan, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	if _, err := lu.userRepo.GetByID(ctx, borrowerID); err != nil {
+		return nil, err
+	}
+	return lu.loanRepo.ListByBorrower(ctx, borrowerID)
+}
+
+// List implements domain.LoanUsecase.
+func (lu *loanUsecase) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.Loan], error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	return lu.loanRepo.List(ctx, spec)
+}
+
+// Approve implements domain.LoanUsecase.
+func (lu *loanUsecase) Approve(ctx context.Context, loanID string, actorID string, version int64) (domain.Loan, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	loan, err := lu.visibleAt(ctx, loanID, version)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	if loan.Status != domain.LoanStatusPending {
+		return domain.Loan{}, ErrLoanNotPending
+	}
+	return lu.transition(ctx, loan, actorID, version, domain.LoanStatusApproved, domain.AuditActionLoanApprove, ErrLoanNotPending)
+}
+
+// Disburse implements domain.LoanUsecase.
+func (lu *loanUsecase) Disburse(ctx context.Context, loanID string, actorID string, version int64) (domain.Loan, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	loan, err := lu.visibleAt(ctx, loanID, version)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	if loan.Status != domain.LoanStatusApproved {
+		return domain.Loan{}, ErrLoanNotApproved
+	}
+	// the verification may have lapsed since the loan was approved
+	if err := lu.kycUsecase.EnsureVerified(ctx, loan.BorrowerID); err != nil {
+		return domain.Loan{}, err
+	}
+	return lu.transition(ctx, loan, actorID, version, domain.LoanStatusDisbursed, domain.AuditActionLoanDisburse, ErrLoanNotApproved)
+}
+
+// visible returns the loan when its borrower is visible to the caller,
+// which it may not be when the caller is restricted to their portfolio.
+func (lu *loanUsecase) visible(ctx context.Context, loanID string) (domain.Loan, error) {
+	loan, err := lu.loanRepo.GetByID(ctx, loanID)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	_, err = lu.userRepo.GetByID(ctx, loan.BorrowerID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return domain.Loan{}, repository.ErrLoanNotFound
+	}
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return loan, nil
+}
+
+// visibleAt is visible for a change based on version: a loan at another
+// version fails with a conflict, unless version is 0.
+func (lu *loanUsecase) visibleAt(ctx context.Context, loanID string, version int64) (domain.Loan, error) {
+	loan, err := lu.visible(ctx, loanID)
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	if version != 0 && loan.Version != version {
+		return domain.Loan{}, &repository.ConflictError{Expected: version, Current: loan.Version}
+	}
+	return loan, nil
+}
+
+// transition moves loan to status, failing with conflict when it was
+// moved concurrently.
+func (lu *loanUsecase) transition(ctx context.Context, loan domain.Loan, actorID string, version int64, status domain.LoanStatus, action string, conflict error) (domain.Loan, error) {
+	now := time.Now()
+	var updated domain.Loan
+	err := lu.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = lu.loanRepo.Transition(ctx, loan.ID.Hex(), domain.LoanTransition{
+			From:    loan.Status,
+			To:      status,
+			Version: version,
+			ActorID: actorID,
+			At:      now,
+		})
+		if err != nil {
+			return err
+		}
+		return lu.audit(ctx, loan, actorID, action, loan.Status, updated.Status, now)
+	})
+	if errors.Is(err, repository.ErrLoanNotFound) {
+		return domain.Loan{}, conflict
+	}
+	if err != nil {
+		return domain.Loan{}, err
+	}
+	return updated, nil
+}
+
+// audit records the change in the trail of the borrower.
+func (lu *loanUsecase) audit(ctx context.Context, loan domain.Loan, actorID, action string, from, to domain.LoanStatus, at time.Time) error {
+	return lu.auditRepo.Create(ctx, domain.AuditEntry{
+		UserID:    loan.BorrowerID,
+		ActorID:   actorID,
+		Action:    action,
+		Changes:   []domain.FieldChange{{Field: "loans." + loan.ID.Hex() + ".status", Old: from, New: to}},
+		CreatedAt: at,
+	})
+}
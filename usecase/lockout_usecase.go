@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/security"
+	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+)
+
+var ErrInvalidUnlockToken = domain.Unauthorized("invalid_unlock_token", "invalid or expired unlock token")
+
+type lockoutUsecase struct {
+	attemptRepo    domain.LoginAttemptRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+	Env            *bootstrap.Env
+	mailer         emailutil.Mailer
+}
+
+func NewLockoutUsecase(attemptRepo domain.LoginAttemptRepository, userRepo domain.UserRepository, env *bootstrap.Env, mailer emailutil.Mailer) domain.LockoutUsecase {
+	return &lockoutUsecase{
+		attemptRepo:    attemptRepo,
+		userRepo:       userRepo,
+		contextTimeout: time.Duration(env.ContextTimeout) * time.Second,
+		Env:            env,
+		mailer:         mailer,
+	}
+}
+
+func accountKey(scope domain.LockoutScope, email string) string {
+	return string(scope) + ":account:" + strings.ToLower(email)
+}
+
+func ipKey(scope domain.LockoutScope, ip string) string {
+	return string(scope) + ":ip:" + ip
+}
+
+// Check implements domain.LockoutUsecase.
+func (lu *lockoutUsecase) Check(ctx context.Context, scope domain.LockoutScope, email string, ip string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	var wait time.Duration
+	for _, key := range []string{accountKey(scope, email), ipKey(scope, ip)} {
+		attempt, err := lu.attemptRepo.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if remaining := time.Until(attempt.LockedUntil); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// RegisterFailure counts a failed attempt in scope against both the account
+// and the client address and locks whichever crossed its threshold. The
+// account owner is emailed an unlock link the first time sign in gets
+// locked.
+func (lu *lockoutUsecase) RegisterFailure(ctx context.Context, scope domain.LockoutScope, email string, ip string) error {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	resetBefore := now.Add(-time.Duration(lu.Env.LoginAttemptWindowMin) * time.Minute)
+	base := time.Duration(lu.Env.LoginLockoutBaseSec) * time.Second
+	max := time.Duration(lu.Env.LoginLockoutMaxMin) * time.Minute
+
+	account, err := lu.attemptRepo.RecordFailure(ctx, accountKey(scope, email), now, resetBefore)
+	if err != nil {
+		return err
+	}
+	if delay := security.LockoutDelay(account.Failures, lu.Env.LoginMaxAttempts, base, max); delay > 0 {
+		if err := lu.attemptRepo.Lock(ctx, account.Key, now.Add(delay)); err != nil {
+			return err
+		}
+		if scope == domain.LockoutLogin && account.Failures == lu.Env.LoginMaxAttempts {
+			lu.sendUnlockEmail(ctx, email)
+		}
+	}
+
+	client, err := lu.attemptRepo.RecordFailure(ctx, ipKey(scope, ip), now, resetBefore)
+	if err != nil {
+		return err
+	}
+	if delay := security.LockoutDelay(client.Failures, lu.Env.LoginIPMaxAttempts, base, max); delay > 0 {
+		return lu.attemptRepo.Lock(ctx, client.Key, now.Add(delay))
+	}
+	return nil
+}
+
+func (lu *lockoutUsecase) sendUnlockEmail(ctx context.Context, email string) {
+	user, err := lu.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		// nobody to notify when the email does not belong to an account
+		return
+	}
+
+	token, err := tokenutil.CreateUnlockToken(&user, lu.Env.VerificationTokenSecret, lu.Env.UnlockTokenExpiryMin)
+	if err == nil {
+		err = lu.attemptRepo.SetUnlockToken(ctx, accountKey(domain.LockoutLogin, email), security.HashToken(token))
+	}
+	if err == nil {
+		err = emailutil.SendUnlockEmail(lu.mailer, user.Email, token, lu.Env)
+	}
+	if err != nil {
+		log.Println("[usecase] lockout unlock email", err)
+	}
+}
+
+// RegisterSuccess implements domain.LockoutUsecase.
+func (lu *lockoutUsecase) RegisterSuccess(ctx context.Context, scope domain.LockoutScope, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	return lu.attemptRepo.Reset(ctx, accountKey(scope, email))
+}
+
+// Unlock implements domain.LockoutUsecase. It lifts the locks of every
+// scope.
+func (lu *lockoutUsecase) Unlock(ctx context.Context, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, lu.contextTimeout)
+	defer cancel()
+
+	user, err := lu.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, scope := range []domain.LockoutScope{domain.LockoutLogin, domain.LockoutOTP} {
+		if err := lu.attemptRepo.Reset(ctx, accountKey(scope, user.Email)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnlockWithToken implements domain.LockoutUsecase. The token is consumed,
+// so a link unlocks the account once.
+func (lu *lockoutUsecase) UnlockWithToken(ctx context.Context, token string) error {
+	claims, err := tokenutil.ExtractUserClaimsFromToken(token, lu.Env.VerificationTokenSecret)
+	if err != nil || claims["purpose"] != domain.TokenPurposeUnlock {
+		return ErrInvalidUnlockToken
+	}
+
+	ctx, cancel := context.WithTimeout(tokenutil.WithTokenTenant(ctx, claims), lu.contextTimeout)
+	defer cancel()
+
+	userID, _ := claims["id"].(string)
+	user, err := lu.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+	unlocked, err := lu.attemptRepo.ConsumeUnlockToken(ctx, accountKey(domain.LockoutLogin, user.Email), security.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if !unlocked {
+		return ErrInvalidUnlockToken
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/security"
+	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+var ErrInvalidMagicLink = domain.Unauthorized("invalid_magic_link", "sign in link is invalid, expired or was opened in another browser")
+
+type magicLinkUsecase struct {
+	magicLinkRepo  domain.MagicLinkRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+	Env            *bootstrap.Env
+	mailer         emailutil.Mailer
+}
+
+func NewMagicLinkUsecase(magicLinkRepo domain.MagicLinkRepository, userRepo domain.UserRepository, env *bootstrap.Env, mailer emailutil.Mailer) domain.MagicLinkUsecase {
+	return &magicLinkUsecase{
+		magicLinkRepo:  magicLinkRepo,
+		userRepo:       userRepo,
+		contextTimeout: time.Duration(env.ContextTimeout) * time.Second,
+		Env:            env,
+		mailer:         mailer,
+	}
+}
+
+// RequestLink implements domain.MagicLinkUsecase.
+func (mu *magicLinkUsecase) RequestLink(ctx context.Context, email string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, mu.contextTimeout)
+	defer cancel()
+
+	nonce, err := security.NewRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	user, err := mu.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) || (err == nil && (!user.Active || user.Suspended)) {
+		return nonce, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token, err := tokenutil.CreateMagicLinkToken(&user, mu.Env.VerificationTokenSecret, mu.Env.MagicLinkExpiryMin)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = mu.magicLinkRepo.Create(ctx, domain.MagicLink{
+		UserID:    user.ID.Hex(),
+		TokenHash: security.HashToken(token),
+		NonceHash: security.HashToken(nonce),
+		ExpiresAt: now.Add(time.Duration(mu.Env.MagicLinkExpiryMin) * time.Minute),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = emailutil.SendMagicLinkEmail(mu.mailer, user.Email, token, mu.Env)
+	if err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// Redeem implements domain.MagicLinkUsecase.
+func (mu *magicLinkUsecase) Redeem(ctx context.Context, token string, nonce string) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, mu.contextTimeout)
+	defer cancel()
+
+	claims, err := tokenutil.ExtractUserClaimsFromToken(token, mu.Env.VerificationTokenSecret)
+	if err != nil || claims["purpose"] != domain.TokenPurposeMagicLink || nonce == "" {
+		return domain.User{}, ErrInvalidMagicLink
+	}
+	ctx = tokenutil.WithTokenTenant(ctx, claims)
+
+	link, err := mu.magicLinkRepo.Consume(ctx, security.HashToken(token), security.HashToken(nonce), time.Now())
+	if errors.Is(err, repository.ErrMagicLinkNotFound) {
+		return domain.User{}, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user, err := mu.userRepo.GetByID(ctx, link.UserID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !user.Active || user.Suspended {
+		return domain.User{}, ErrInvalidMagicLink
+	}
+	return user, nil
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+var (
+	ErrNotLoanOfficer  = domain.Unprocessable("not_loan_officer", "user is not a loan officer")
+	ErrNotBorrower     = domain.Unprocessable("not_borrower", "user is not a borrower")
+	ErrBranchInactive  = domain.Conflict("branch_inactive", "branch is not active")
+	ErrAlreadyAssigned = domain.Conflict("already_assigned", "borrower is already assigned to this officer")
+)
+
+type portfolioUsecase struct {
+	userRepo       domain.UserRepository
+	branchRepo     domain.BranchRepository
+	assignmentRepo domain.OfficerAssignmentRepository
+	loanRepo       domain.LoanRepository
+	auditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+}
+
+func NewPortfolioUsecase(userRepo domain.UserRepository, branchRepo domain.BranchRepository, assignmentRepo domain.OfficerAssignmentRepository, loanRepo domain.LoanRepository, auditRepo domain.AuditRepository, timeout time.Duration) domain.PortfolioUsecase {
+	return &portfolioUsecase{
+		userRepo:       userRepo,
+		branchRepo:     branchRepo,
+		assignmentRepo: assignmentRepo,
+		loanRepo:       loanRepo,
+		auditRepo:      auditRepo,
+		contextTimeout: timeout,
+	}
+}
+
+// AssignOfficer implements domain.PortfolioUsecase.
+// Every assignment is kept in the borrower's assignment history.
+func (pu *portfolioUsecase) AssignOfficer(ctx context.Context, actorID string, borrowerID string, request domain.AssignOfficerRequest) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, pu.contextTimeout)
+	defer cancel()
+
+	borrower, err := pu.userRepo.GetByID(ctx, borrowerID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if borrower.Role != domain.RoleBorrower && borrower.Role != domain.RoleUser {
+		return domain.User{}, ErrNotBorrower
+	}
+	if borrower.OfficerID == request.OfficerID {
+		return domain.User{}, ErrAlreadyAssigned
+	}
+
+	officer, err := pu.activeOfficer(ctx, request.OfficerID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user, err := pu.userRepo.SetOfficer(ctx, borrowerID, request.OfficerID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	now := time.Now()
+	err = pu.assignmentRepo.Create(ctx, domain.OfficerAssignment{
+		BorrowerID:        borrowerID,
+		OfficerID:         request.OfficerID,
+		PreviousOfficerID: borrower.OfficerID,
+		BranchID:          officer.BranchID,
+		ActorID:           actorID,
+		Reason:            request.Reason,
+		AssignedAt:        now,
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	err = pu.audit(ctx, borrowerID, actorID, domain.AuditActionOfficerAssign, domain.FieldChange{Field: "officer_id", Old: borrower.OfficerID, New: request.OfficerID}, now)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// AssignBranch implements domain.PortfolioUsecase.
+func (pu *portfolioUsecase) AssignBranch(ctx context.Context, actorID string, officerID string, branchID string) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, pu.contextTimeout)
+	defer cancel()
+
+	officer, err := pu.activeOfficer(ctx, officerID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	branch, err := pu.branchRepo.GetByID(ctx, branchID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !branch.Active {
+		return domain.User{}, ErrBranchInactive
+	}
+
+	user, err := pu.userRepo.SetBranch(ctx, officerID, branchID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	err = pu.audit(ctx, officerID, actorID, domain.AuditActionBranchAssign, domain.FieldChange{Field: "branch_id", Old: officer.BranchID, New: branchID}, time.Now())
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// History implements domain.PortfolioUsecase.
+func (pu *portfolioUsecase) History(ctx context.Context, borrowerID string) ([]domain.OfficerAssignment, error) {
+	ctx, cancel := context.WithTimeout(ctx, pu.contextTimeout)
+	defer cancel()
+
+	// the borrower has to be visible to the caller, which it may not be
+	// when the caller is restricted to their portfolio
+	if _, err := pu.userRepo.GetByID(ctx, borrowerID); err != nil {
+		return nil, err
+	}
+	return pu.assignmentRepo.ListByBorrower(ctx, borrowerID)
+}
+
+// Portfolio implements domain.PortfolioUsecase.
+func (pu *portfolioUsecase) Portfolio(ctx context.Context, officerID string, spec domain.QuerySpec) (domain.Page[domain.PortfolioLoan], error) {
+	ctx, cancel := context.WithTimeout(ctx, pu.contextTimeout)
+	defer cancel()
+
+	page, err := pu.loanRepo.List(domain.WithPortfolio(ctx, officerID), spec)
+	if err != nil {
+		return domain.Page[domain.PortfolioLoan]{}, err
+	}
+
+	now := time.Now()
+	portfolio := domain.Page[domain.PortfolioLoan]{
+		Items:      make([]domain.PortfolioLoan, 0, len(page.Items)),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	for _, loan := range page.Items {
+		portfolio.Items = append(portfolio.Items, balance(loan, now))
+	}
+	return portfolio, nil
+}
+
+// balance returns what the borrower owes on loan at now. A disbursed loan
+// is repaid in monthly instalments, the first a month after the payout,
+// which split the amount evenly and leave the remainder to the last one. No
+// repayments are recorded, so every instalment whose date has passed is
+// overdue.
+func balance(loan domain.Loan, now time.Time) domain.PortfolioLoan {
+	owed := domain.PortfolioLoan{Loan: loan}
+	if loan.Status != domain.LoanStatusDisbursed || loan.TermMonths < 1 {
+		return owed
+	}
+
+	owed.Outstanding = loan.Amount
+	instalment := loan.Amount / int64(loan.TermMonths)
+	for month := 1; month <= loan.TermMonths; month++ {
+		amount := instalment
+		if month == loan.TermMonths {
+			amount = loan.Amount - instalment*int64(loan.TermMonths-1)
+		}
+		dueAt := loan.DisbursedAt.AddDate(0, month, 0)
+		if dueAt.After(now) {
+			owed.Due = amount
+			owed.NextDueAt = &dueAt
+			break
+		}
+		owed.Overdue += amount
+	}
+	return owed
+}
+
+// activeOfficer returns the loan officer officerID, who must be able to
+// take on borrowers.
+func (pu *portfolioUsecase) activeOfficer(ctx context.Context, officerID string) (domain.User, error) {
+	officer, err := pu.userRepo.GetByID(ctx, officerID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if officer.Role != domain.RoleLoanOfficer {
+		return domain.User{}, ErrNotLoanOfficer
+	}
+	if officer.Suspended {
+		return domain.User{}, ErrUserSuspended
+	}
+	return officer, nil
+}
+
+func (pu *portfolioUsecase) audit(ctx context.Context, userID string, actorID string, action string, change domain.FieldChange, at time.Time) error {
+	return pu.auditRepo.Create(ctx, domain.AuditEntry{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		Changes:   []domain.FieldChange{change},
+		CreatedAt: at,
+	})
+}
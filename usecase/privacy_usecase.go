@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/bootstrap"
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+// audit actions whose recorded values hold personal data
+var personalAuditActions = []string{
+	domain.AuditActionProfileUpdate,
+	domain.AuditActionUserUpdate,
+	domain.AuditActionUserDelete,
+	domain.AuditActionUserSuspend,
+}
+
+type privacyUsecase struct {
+	userRepo       domain.UserRepository
+	kycRepo        domain.KYCRepository
+	auditRepo      domain.AuditRepository
+	attemptRepo    domain.LoginAttemptRepository
+	magicLinkRepo  domain.MagicLinkRepository
+	resetRepo      domain.ResetPasswordRepository
+	txManager      domain.TxManager
+	storage        domain.DocumentStorage
+	contextTimeout time.Duration
+}
+
+func NewPrivacyUsecase(userRepo domain.UserRepository, kycRepo domain.KYCRepository, auditRepo domain.AuditRepository, attemptRepo domain.LoginAttemptRepository, magicLinkRepo domain.MagicLinkRepository, resetRepo domain.ResetPasswordRepository, txManager domain.TxManager, storage domain.DocumentStorage, env *bootstrap.Env) domain.PrivacyUsecase {
+	return &privacyUsecase{
+		userRepo:       userRepo,
+		kycRepo:        kycRepo,
+		auditRepo:      auditRepo,
+		attemptRepo:    attemptRepo,
+		magicLinkRepo:  magicLinkRepo,
+		resetRepo:      resetRepo,
+		txManager:      txManager,
+		storage:        storage,
+		contextTimeout: time.Duration(env.ContextTimeout) * time.Second,
+	}
+}
+
+// Export implements domain.PrivacyUsecase.
+func (pu *privacyUsecase) Export(ctx context.Context, userID string) (domain.UserExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, pu.contextTimeout)
+	defer cancel()
+
+	user, err := pu.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+
+	export := domain.UserExport{
+		ExportedAt: time.Now(),
+		User:       user,
+	}
+
+	record, err := pu.kycRepo.GetByUserID(ctx, userID)
+	if err == nil {
+		export.KYC = &record
+	} else if !errors.Is(err, repository.ErrKYCNotFound) {
+		return domain.UserExport{}, err
+	}
+
+	export.AuditTrail, err = pu.auditRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return domain.UserExport{}, err
+	}
+	return export, nil
+}
+
+// WriteArchive implements domain.PrivacyUsecase.
+func (pu *privacyUsecase) WriteArchive(ctx context.Context, userID string, w io.Writer) error {
+	export, err := pu.Export(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	data, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(data)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(export)
+	if err != nil {
+		return err
+	}
+
+	if export.KYC != nil {
+		for _, document := range export.KYC.Documents {
+			err = pu.archiveDocument(ctx, archive, document)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return archive.Close()
+}
+
+func (pu *privacyUsecase) archiveDocument(ctx context.Context, archive *zip.Writer, document domain.KYCDocument) error {
+	content, err := pu.storage.Open(ctx, document.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	file, err := archive.Create(path.Join("documents", document.ID+"-"+path.Base(document.FileName)))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	return err
+}
+
+// Erase implements domain.PrivacyUsecase.
+// Erase pseudonymizes the user and removes the rest of their personal
+// data: the KYC record with its documents, the sign-in links and reset
+// codes, the lockout counters and the values recorded in the audit trail.
+func (pu *privacyUsecase) Erase(ctx context.Context, actorID string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, pu.contextTimeout)
+	defer cancel()
+
+	// a user that is already deleted is not found here; deleting them was
+	// authorized the same way
+	_, err := authorizeAdminAction(ctx, pu.userRepo, actorID, userID)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return err
+	}
+
+	var record domain.KYC
+	err = pu.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := pu.userRepo.Erase(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		record, err = pu.kycRepo.Delete(ctx, userID)
+		if err != nil && !errors.Is(err, repository.ErrKYCNotFound) {
+			return err
+		}
+		err = pu.magicLinkRepo.DeleteByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		err = pu.resetRepo.DeleteOtps(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+
+		err = pu.auditRepo.RedactChanges(ctx, userID, personalAuditActions)
+		if err != nil {
+			return err
+		}
+		for _, scope := range []domain.LockoutScope{domain.LockoutLogin, domain.LockoutOTP} {
+			if err := pu.attemptRepo.Reset(ctx, accountKey(scope, user.Email)); err != nil {
+				return err
+			}
+		}
+
+		return pu.auditRepo.Create(ctx, domain.AuditEntry{
+			UserID:    userID,
+			ActorID:   actorID,
+			Action:    domain.AuditActionUserErase,
+			CreatedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// the files go last, once the records referring to them are gone for
+	// good
+	for _, document := range record.Documents {
+		if err := pu.storage.Delete(ctx, document.StorageKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,18 +5,25 @@ import (
 	"time"
 
 	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/security"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type resetPasswordUsecase struct {
 	resetPasswordRepository domain.ResetPasswordRepository
+	txManager               domain.TxManager
 	contextTimeout          time.Duration
+	maxOtpAttempts          int
+	passwordPolicy          *security.PasswordPolicy
 }
 
-func NewResetPasswordUsecase(resetPasswordRepository domain.ResetPasswordRepository, timeout time.Duration) domain.ResetPasswordUsecase {
+func NewResetPasswordUsecase(resetPasswordRepository domain.ResetPasswordRepository, txManager domain.TxManager, timeout time.Duration, maxOtpAttempts int, passwordPolicy *security.PasswordPolicy) domain.ResetPasswordUsecase {
 	return &resetPasswordUsecase{
 		resetPasswordRepository: resetPasswordRepository,
+		txManager:               txManager,
 		contextTimeout:          timeout,
+		maxOtpAttempts:          maxOtpAttempts,
+		passwordPolicy:          passwordPolicy,
 	}
 }
 func (r *resetPasswordUsecase) SaveOtp(c context.Context, otp *domain.OtpSave) error {
@@ -38,16 +45,35 @@ func (r *resetPasswordUsecase) GetUserByEmail(c context.Context, email string) (
 func (r *resetPasswordUsecase) ResetPassword(c context.Context, userID string, resetPassword *domain.ResetPasswordRequest) error {
 	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
 	defer cancel()
-	//enctypt the user password
 
+	user, err := r.resetPasswordRepository.GetUserByEmail(ctx, resetPassword.Email)
+	if err != nil {
+		return err
+	}
+	err = r.passwordPolicy.Validate(resetPassword.NewPassword, user.Email, user.FirstName, user.LastName)
+	if err != nil {
+		return err
+	}
+	err = r.passwordPolicy.CheckReuse(resetPassword.NewPassword, append([]string{user.Password}, user.PasswordHistory...))
+	if err != nil {
+		return err
+	}
+
+	//enctypt the user password
 	bcryptPassword, err := bcrypt.GenerateFromPassword([]byte(resetPassword.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	resetPassword.NewPassword = string(bcryptPassword)
 
-	err = r.resetPasswordRepository.ResetPassword(ctx, userID, resetPassword)
-	return err
+	// the code is used up by the same write that changes the password
+	return r.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		err := r.resetPasswordRepository.ResetPassword(ctx, userID, resetPassword)
+		if err != nil {
+			return err
+		}
+		return r.resetPasswordRepository.DeleteOtp(ctx, resetPassword.Email)
+	})
 }
 
 func (r *resetPasswordUsecase) GetOTPByEmail(c context.Context, email string) (*domain.OtpSave, error) {
@@ -65,3 +91,18 @@ func (r *resetPasswordUsecase) DeleteOtp(c context.Context, email string) error
 	err := r.resetPasswordRepository.DeleteOtp(ctx, email)
 	return err
 }
+
+// RegisterInvalidOtp counts a wrong code against the pending OTP and deletes
+// the OTP once the allowed number of attempts is used up.
+func (r *resetPasswordUsecase) RegisterInvalidOtp(c context.Context, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+	attempts, err := r.resetPasswordRepository.IncrementOtpAttempts(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	if attempts < r.maxOtpAttempts {
+		return false, nil
+	}
+	return true, r.resetPasswordRepository.DeleteOtp(ctx, email)
+}
@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+var ErrInvalidSearchQuery = domain.Validation("invalid_search_query", "search query must be between 2 and 100 characters")
+
+type searchUsecase struct {
+	index          domain.SearchIndex
+	contextTimeout time.Duration
+}
+
+func NewSearchUsecase(index domain.SearchIndex, timeout time.Duration) domain.SearchUsecase {
+	return &searchUsecase{
+		index:          index,
+		contextTimeout: timeout,
+	}
+}
+
+// Search implements domain.SearchUsecase.
+func (su *searchUsecase) Search(ctx context.Context, query string, limit int) ([]domain.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, su.contextTimeout)
+	defer cancel()
+
+	query = strings.TrimSpace(query)
+	if n := len([]rune(query)); n < domain.MinSearchQueryLength || n > domain.MaxSearchQueryLength {
+		return nil, ErrInvalidSearchQuery
+	}
+	if limit < 1 || limit > domain.MaxPageLimit {
+		limit = domain.DefaultPageLimit
+	}
+
+	return su.index.Search(ctx, query, limit)
+}
@@ -2,23 +2,33 @@ package usecase
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/dagota12/Loan-Tracker/bootstrap"
 	"github.com/dagota12/Loan-Tracker/domain"
 	"github.com/dagota12/Loan-Tracker/internal/emailutil"
+	"github.com/dagota12/Loan-Tracker/internal/security"
 	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
 )
 
 type signupUsecase struct {
-	userRepository domain.UserRepository
-	contextTimeout time.Duration
+	userRepository   domain.UserRepository
+	tenantRepository domain.TenantRepository
+	contextTimeout   time.Duration
+	passwordPolicy   *security.PasswordPolicy
+	env              *bootstrap.Env
+	mailer           emailutil.Mailer
 }
 
-func NewSignupUsecase(userRepository domain.UserRepository, timeout time.Duration) domain.SignupUsecase {
+func NewSignupUsecase(userRepository domain.UserRepository, tenantRepository domain.TenantRepository, timeout time.Duration, passwordPolicy *security.PasswordPolicy, env *bootstrap.Env, mailer emailutil.Mailer) domain.SignupUsecase {
 	return &signupUsecase{
-		userRepository: userRepository,
-		contextTimeout: timeout,
+		userRepository:   userRepository,
+		tenantRepository: tenantRepository,
+		contextTimeout:   timeout,
+		passwordPolicy:   passwordPolicy,
+		env:              env,
+		mailer:           mailer,
 	}
 }
 
@@ -38,9 +48,50 @@ func (su *signupUsecase) Create(ctx context.Context, user *domain.User) (domain.
 	return su.userRepository.Create(ctx, *user)
 }
 
-func (su *signupUsecase) IsOwner(ctx context.Context, userID string) (bool, error) {
-	result, err := su.userRepository.IsOwner(ctx, userID)
-	return result, err
+func (su *signupUsecase) UpdateVerifyToken(ctx context.Context, userID string, tokenHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, su.contextTimeout)
+	defer cancel()
+	return su.userRepository.UpdateVerifyToken(ctx, userID, tokenHash, time.Now())
+}
+
+// InitialRole implements domain.SignupUsecase.
+// The owner of a tenant is the account registered with the owner email the
+// tenant was created with or, without one, the first account of the tenant,
+// which claims the owner email so that concurrent sign ups cannot both
+// become the owner. Owners administer their tenant; the owner of the
+// primary tenant is the super admin.
+func (su *signupUsecase) InitialRole(ctx context.Context, email string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, su.contextTimeout)
+	defer cancel()
+
+	tenantID, _ := domain.TenantFromContext(ctx)
+	tenant, err := su.tenantRepository.GetByID(ctx, tenantID)
+	if err != nil {
+		return "", false, err
+	}
+
+	if tenant.OwnerEmail == "" {
+		count, err := su.userRepository.Count(ctx)
+		if err != nil {
+			return "", false, err
+		}
+		if count == 0 {
+			tenant, err = su.tenantRepository.ClaimOwner(ctx, tenantID, email)
+			if err != nil {
+				return "", false, err
+			}
+		}
+	}
+	isOwner := strings.EqualFold(tenant.OwnerEmail, email)
+
+	switch {
+	case isOwner && tenant.Primary:
+		return domain.RoleSuperAdmin, true, nil
+	case isOwner:
+		return domain.RoleAdmin, true, nil
+	default:
+		return domain.RoleBorrower, false, nil
+	}
 }
 func (su *signupUsecase) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, su.contextTimeout)
@@ -52,6 +103,10 @@ func (su *signupUsecase) GetUserByEmail(ctx context.Context, email string) (doma
 	return user, nil
 }
 
+func (su *signupUsecase) ValidatePassword(request *domain.SignupRequest) error {
+	return su.passwordPolicy.Validate(request.Password, request.Email, request.FirstName, request.LastName)
+}
+
 func (su *signupUsecase) CreateAccessToken(user *domain.User, secret string, expiry int) (accessToken string, err error) {
 	return tokenutil.CreateAccessToken(*user, secret, expiry)
 }
@@ -64,6 +119,6 @@ func (su *signupUsecase) CreateVerificationToken(user *domain.User, secret strin
 	return tokenutil.CreateVerificationToken(user, secret, expiry)
 }
 
-func (su *signupUsecase) SendVerificationEmail(recipientEmail string, encodedToken string, env *bootstrap.Env) (err error) {
-	return emailutil.SendVerificationEmail(recipientEmail, encodedToken, env)
+func (su *signupUsecase) SendVerificationEmail(recipientEmail string, encodedToken string) (err error) {
+	return emailutil.SendVerificationEmail(su.mailer, recipientEmail, encodedToken, su.env)
 }
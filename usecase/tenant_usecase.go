@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+)
+
+var (
+	ErrTenantInactive        = domain.Forbidden("tenant_inactive", "tenant is not active")
+	ErrPrimaryTenantRequired = domain.Conflict("primary_tenant_required", "the primary tenant cannot be deactivated")
+)
+
+type tenantUsecase struct {
+	tenantRepo     domain.TenantRepository
+	contextTimeout time.Duration
+}
+
+func NewTenantUsecase(tenantRepo domain.TenantRepository, timeout time.Duration) domain.TenantUsecase {
+	return &tenantUsecase{
+		tenantRepo:     tenantRepo,
+		contextTimeout: timeout,
+	}
+}
+
+// Create implements domain.TenantUsecase.
+// The account later registered with the owner email becomes the owner.
+func (tu *tenantUsecase) Create(ctx context.Context, request domain.CreateTenantRequest) (domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, tu.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	return tu.tenantRepo.Create(ctx, domain.Tenant{
+		Name:       request.Name,
+		Slug:       strings.ToLower(request.Slug),
+		OwnerEmail: request.OwnerEmail,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+}
+
+// List implements domain.TenantUsecase.
+func (tu *tenantUsecase) List(ctx context.Context) ([]domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, tu.contextTimeout)
+	defer cancel()
+	return tu.tenantRepo.List(ctx)
+}
+
+// Update implements domain.TenantUsecase.
+func (tu *tenantUsecase) Update(ctx context.Context, tenantID string, request domain.UpdateTenantRequest) (domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, tu.contextTimeout)
+	defer cancel()
+
+	// the super admin signs in through the primary tenant
+	if request.Active != nil && !*request.Active {
+		tenant, err := tu.tenantRepo.GetByID(ctx, tenantID)
+		if err != nil {
+			return domain.Tenant{}, err
+		}
+		if tenant.Primary {
+			return domain.Tenant{}, ErrPrimaryTenantRequired
+		}
+	}
+	return tu.tenantRepo.Update(ctx, tenantID, request)
+}
+
+// Resolve implements domain.TenantUsecase.
+func (tu *tenantUsecase) Resolve(ctx context.Context, slug string) (domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, tu.contextTimeout)
+	defer cancel()
+
+	tenant, err := tu.tenantRepo.GetBySlug(ctx, strings.ToLower(slug))
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	if !tenant.Active {
+		return domain.Tenant{}, ErrTenantInactive
+	}
+	return tenant, nil
+}
+
+// GetActive implements domain.TenantUsecase.
+func (tu *tenantUsecase) GetActive(ctx context.Context, tenantID string) (domain.Tenant, error) {
+	ctx, cancel := context.WithTimeout(ctx, tu.contextTimeout)
+	defer cancel()
+
+	tenant, err := tu.tenantRepo.GetByID(ctx, tenantID)
+	if err != nil {
+		return domain.Tenant{}, err
+	}
+	if !tenant.Active {
+		return domain.Tenant{}, ErrTenantInactive
+	}
+	return tenant, nil
+}
+
+// EnsurePrimaryTenant returns the primary tenant, creating it on first
+// start. The owner of an install that predates tenants is moved into it
+// with the admin role they had, so they are promoted to super admin and
+// recorded as its owner.
+func EnsurePrimaryTenant(ctx context.Context, tenantRepo domain.TenantRepository, userRepo domain.UserRepository, slug string) (domain.Tenant, error) {
+	tenant, err := tenantRepo.EnsurePrimary(ctx, slug)
+	if err != nil || tenant.OwnerEmail != "" {
+		return tenant, err
+	}
+
+	ctx = domain.WithTenant(ctx, tenant.ID.Hex())
+	owners, err := userRepo.List(ctx, domain.QuerySpec{
+		Conditions: []domain.Condition{{Field: "is_owner", Op: domain.OpEq, Value: true}},
+		SortBy:     "created_at",
+		Limit:      1,
+	})
+	if err != nil || len(owners.Items) == 0 {
+		return tenant, err
+	}
+	owner := owners.Items[0]
+
+	// promoted before the owner email is set, which is what marks the
+	// tenant as done
+	if owner.Role != domain.RoleSuperAdmin {
+		if _, err := userRepo.UpdateRole(ctx, owner.ID.Hex(), domain.RoleSuperAdmin); err != nil {
+			return domain.Tenant{}, err
+		}
+	}
+	return tenantRepo.ClaimOwner(ctx, tenant.ID.Hex(), owner.Email)
+}
+
+// cachedTenantUsecase remembers the active tenants found by GetActive, so
+// that checking the tenant of every authenticated request does not cost a
+// lookup. Updates made through it take effect at once, those made by
+// another instance within the ttl.
+type cachedTenantUsecase struct {
+	domain.TenantUsecase
+	ttl time.Duration
+
+	mu     sync.Mutex
+	active map[string]cachedTenant
+}
+
+type cachedTenant struct {
+	tenant    domain.Tenant
+	expiresAt time.Time
+}
+
+func NewCachedTenantUsecase(tenantUsecase domain.TenantUsecase, ttl time.Duration) domain.TenantUsecase {
+	return &cachedTenantUsecase{
+		TenantUsecase: tenantUsecase,
+		ttl:           ttl,
+		active:        make(map[string]cachedTenant),
+	}
+}
+
+// GetActive implements domain.TenantUsecase.
+func (cu *cachedTenantUsecase) GetActive(ctx context.Context, tenantID string) (domain.Tenant, error) {
+	cu.mu.Lock()
+	cached, ok := cu.active[tenantID]
+	cu.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.tenant, nil
+	}
+
+	tenant, err := cu.TenantUsecase.GetActive(ctx, tenantID)
+	if err != nil {
+		cu.forget(tenantID)
+		return domain.Tenant{}, err
+	}
+	cu.mu.Lock()
+	cu.active[tenantID] = cachedTenant{tenant: tenant, expiresAt: time.Now().Add(cu.ttl)}
+	cu.mu.Unlock()
+	return tenant, nil
+}
+
+// Update implements domain.TenantUsecase.
+func (cu *cachedTenantUsecase) Update(ctx context.Context, tenantID string, request domain.UpdateTenantRequest) (domain.Tenant, error) {
+	tenant, err := cu.TenantUsecase.Update(ctx, tenantID, request)
+	cu.forget(tenantID)
+	return tenant, err
+}
+
+func (cu *cachedTenantUsecase) forget(tenantID string) {
+	cu.mu.Lock()
+	defer cu.mu.Unlock()
+	delete(cu.active, tenantID)
+}
@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/tokenutil"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+var (
+	ErrInvalidRole    = domain.Validation("invalid_role", "invalid role")
+	ErrSelfAction     = domain.Forbidden("self_action", "admins cannot perform this action on their own account")
+	ErrOwnerProtected = domain.Forbidden("owner_protected", "the owner account cannot be changed")
+	ErrOwnerRequired  = domain.Forbidden("owner_required", "only the owner can manage admins")
+	ErrUserSuspended  = domain.Conflict("user_suspended", "user is suspended")
+	ErrUserNotActive  = domain.Conflict("user_not_active", "user is not active")
+)
+
+// Update implements domain.UserUsecase.
+// Update renames a user, changing only the names in the request, and
+// records the changed names in the audit trail. A non-zero version must
+// match the current version of the user.
+func (uc *userUsecase) Update(ctx context.Context, actorID string, userID string, version int64, request domain.UpdateUserRequest) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	target, err := authorizeAdminAction(ctx, uc.UserRepo, actorID, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if version != 0 && version != target.Version {
+		return domain.User{}, &repository.ConflictError{Expected: version, Current: target.Version}
+	}
+
+	update := domain.UserUpdate{FirstName: target.FirstName, LastName: target.LastName}
+	var changes []domain.FieldChange
+	if request.FirstName != nil && *request.FirstName != target.FirstName {
+		update.FirstName = *request.FirstName
+		changes = append(changes, domain.FieldChange{Field: "first_name", Old: target.FirstName, New: update.FirstName})
+	}
+	if request.LastName != nil && *request.LastName != target.LastName {
+		update.LastName = *request.LastName
+		changes = append(changes, domain.FieldChange{Field: "last_name", Old: target.LastName, New: update.LastName})
+	}
+	if len(changes) == 0 {
+		return target, nil
+	}
+
+	// the request was merged into the version just read, so only that
+	// version may be overwritten
+	update.UpdatedAt = time.Now()
+	var user domain.User
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err = uc.UserRepo.Update(ctx, userID, target.Version, update)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, userID, actorID, domain.AuditActionUserUpdate, changes...)
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// Delete implements domain.UserUsecase.
+// Delete deletes a user by their ID.
+func (uc *userUsecase) Delete(ctx context.Context, actorID string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	target, err := authorizeAdminAction(ctx, uc.UserRepo, actorID, userID)
+	if err != nil {
+		return err
+	}
+
+	return uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		err := uc.UserRepo.Delete(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, userID, actorID, domain.AuditActionUserDelete, domain.FieldChange{Field: "email", Old: target.Email})
+	})
+}
+
+// AssignRole implements domain.UserUsecase.
+// AssignRole replaces the role of a user after checking it is a known role.
+// Only the owner can grant or take away the admin role.
+func (uc *userUsecase) AssignRole(ctx context.Context, actorID string, userID string, role string) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	if !domain.IsValidRole(role) {
+		return domain.User{}, ErrInvalidRole
+	}
+
+	target, err := authorizeAdminAction(ctx, uc.UserRepo, actorID, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if role == domain.RoleAdmin {
+		actor, err := uc.UserRepo.GetByID(ctx, actorID)
+		if err != nil {
+			return domain.User{}, err
+		}
+		if !actor.IsOwner {
+			return domain.User{}, ErrOwnerRequired
+		}
+	}
+
+	var user domain.User
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err = uc.UserRepo.UpdateRole(ctx, userID, role)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, userID, actorID, domain.AuditActionUserRoleChange, domain.FieldChange{Field: "role", Old: target.Role, New: role})
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// Suspend implements domain.UserUsecase.
+// Suspend blocks the user from signing in and refreshing tokens.
+func (uc *userUsecase) Suspend(ctx context.Context, actorID string, userID string, reason string) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	target, err := authorizeAdminAction(ctx, uc.UserRepo, actorID, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	var user domain.User
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err = uc.UserRepo.SetSuspended(ctx, userID, true, reason)
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, userID, actorID, domain.AuditActionUserSuspend,
+			domain.FieldChange{Field: "suspended", Old: target.Suspended, New: true},
+			domain.FieldChange{Field: "suspend_reason", Old: target.SuspendReason, New: reason},
+		)
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// Reactivate implements domain.UserUsecase.
+func (uc *userUsecase) Reactivate(ctx context.Context, actorID string, userID string) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	target, err := authorizeAdminAction(ctx, uc.UserRepo, actorID, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	var user domain.User
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		user, err = uc.UserRepo.SetSuspended(ctx, userID, false, "")
+		if err != nil {
+			return err
+		}
+		return uc.audit(ctx, userID, actorID, domain.AuditActionUserReactivate, domain.FieldChange{Field: "suspended", Old: target.Suspended, New: false})
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// Impersonate implements domain.UserUsecase.
+// Impersonate issues a short lived access token for the user that names the
+// admin as its actor, so what is done with it is attributed to the admin.
+func (uc *userUsecase) Impersonate(ctx context.Context, actorID string, userID string) (domain.ImpersonationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	target, err := authorizeAdminAction(ctx, uc.UserRepo, actorID, userID)
+	if err != nil {
+		return domain.ImpersonationResponse{}, err
+	}
+	if target.Suspended {
+		return domain.ImpersonationResponse{}, ErrUserSuspended
+	}
+	if !target.Active {
+		return domain.ImpersonationResponse{}, ErrUserNotActive
+	}
+
+	token, expiresAt, err := tokenutil.CreateImpersonationToken(target, actorID, uc.Env.AccessTokenSecret, uc.Env.ImpersonationExpiryMin)
+	if err != nil {
+		return domain.ImpersonationResponse{}, err
+	}
+
+	err = uc.audit(ctx, userID, actorID, domain.AuditActionUserImpersonate, domain.FieldChange{Field: "expires_at", New: expiresAt})
+	if err != nil {
+		return domain.ImpersonationResponse{}, err
+	}
+	return domain.ImpersonationResponse{AccessToken: token, ExpiresAt: expiresAt}, nil
+}
+
+// authorizeAdminAction returns the target of an administrative action after
+// checking the actor may act on it: nobody acts on themselves or on the
+// owner, and only the owner acts on other admins.
+func authorizeAdminAction(ctx context.Context, userRepo domain.UserRepository, actorID string, userID string) (domain.User, error) {
+	if actorID == userID {
+		return domain.User{}, ErrSelfAction
+	}
+
+	target, err := userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if target.IsOwner {
+		return domain.User{}, ErrOwnerProtected
+	}
+	if target.Role == domain.RoleAdmin {
+		actor, err := userRepo.GetByID(ctx, actorID)
+		if err != nil {
+			return domain.User{}, err
+		}
+		if !actor.IsOwner {
+			return domain.User{}, ErrOwnerRequired
+		}
+	}
+	return target, nil
+}
+
+func (uc *userUsecase) audit(ctx context.Context, userID string, actorID string, action string, changes ...domain.FieldChange) error {
+	return uc.AuditRepo.Create(ctx, domain.AuditEntry{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	})
+}
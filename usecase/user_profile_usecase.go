@@ -0,0 +1,264 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/mergepatch"
+	"github.com/dagota12/Loan-Tracker/repository"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalidProfilePatch is returned when the patch is not a JSON object or
+// does not produce a well-formed profile.
+var ErrInvalidProfilePatch = domain.Validation("invalid_profile_patch", "invalid profile patch")
+
+// fields whose values never appear in the audit trail
+var redactedProfileFields = map[string]bool{
+	"national_id": true,
+}
+
+var profileValidator = newProfileValidator()
+
+func newProfileValidator() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// UpdateProfile implements domain.UserUsecase.
+// UpdateProfile applies a JSON Merge Patch to the editable profile of a user,
+// validates the result and records the changed fields in the audit trail.
+// A non-zero version must match the current version of the user.
+func (uc *userUsecase) UpdateProfile(ctx context.Context, userID string, actorID string, version int64, patch []byte) (domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if version != 0 && version != user.Version {
+		return domain.User{}, &repository.ConflictError{Expected: version, Current: user.Version}
+	}
+
+	current := domain.EditableProfile{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Profile:   user.Profile,
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return domain.User{}, err
+	}
+	patched, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return domain.User{}, ErrInvalidProfilePatch
+	}
+
+	var updated domain.EditableProfile
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&updated); err != nil {
+		return domain.User{}, fmt.Errorf("%w: %v", ErrInvalidProfilePatch, err)
+	}
+	if err := validateProfile(updated, time.Now()); err != nil {
+		return domain.User{}, err
+	}
+
+	changes, err := profileChanges(current, updated)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if len(changes) == 0 {
+		return user, nil
+	}
+
+	// the patch was applied to the version just read, so only that
+	// version may be overwritten
+	user, err = uc.UserRepo.UpdateProfile(ctx, userID, user.Version, updated)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	err = uc.audit(ctx, userID, actorID, domain.AuditActionProfileUpdate, changes...)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+// GetAuditTrail implements domain.UserUsecase.
+func (uc *userUsecase) GetAuditTrail(ctx context.Context, userID string) ([]domain.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	// the trail of a deleted user stays readable, except within a
+	// portfolio, which only holds the borrowers assigned to the caller
+	if _, ok := domain.PortfolioFromContext(ctx); ok {
+		if _, err := uc.UserRepo.GetByID(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return uc.AuditRepo.ListByUser(ctx, userID)
+}
+
+// validateProfile checks the field rules of the profile and that the date of
+// birth, when set, belongs to an adult.
+func validateProfile(profile domain.EditableProfile, now time.Time) error {
+	fields := make(map[string]string)
+
+	var validationErrs validator.ValidationErrors
+	if err := profileValidator.Struct(profile); err != nil {
+		if !errors.As(err, &validationErrs) {
+			return err
+		}
+		for _, fe := range validationErrs {
+			fields[profileFieldPath(fe.Namespace())] = DescribeFieldError(fe)
+		}
+	}
+
+	if profile.DateOfBirth != "" {
+		if _, invalid := fields["date_of_birth"]; !invalid {
+			dob, _ := time.Parse("2006-01-02", profile.DateOfBirth)
+			if dob.AddDate(domain.MinBorrowerAge, 0, 0).After(now) {
+				fields["date_of_birth"] = fmt.Sprintf("must be at least %d years ago", domain.MinBorrowerAge)
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return domain.InvalidFields("invalid_profile", "invalid profile fields: "+strings.Join(names, ", "), fields)
+}
+
+// profileFieldPath turns a validator namespace such as
+// "EditableProfile.Profile.address.city" into "address.city".
+func profileFieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	path := make([]string, 0, len(parts))
+	for _, part := range parts[1:] {
+		// the embedded Profile has no json name of its own
+		if part == "Profile" {
+			continue
+		}
+		path = append(path, part)
+	}
+	return strings.Join(path, ".")
+}
+
+// DescribeFieldError tells why a field failed a validation rule.
+func DescribeFieldError(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "min":
+		return "must be at least " + fe.Param() + " characters"
+	case "max":
+		return "must be at most " + fe.Param() + " characters"
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "e164":
+		return "must be a phone number in E.164 format"
+	case "datetime":
+		return "must be a date in YYYY-MM-DD format"
+	case "iso3166_1_alpha2":
+		return "must be an ISO 3166-1 alpha-2 country code"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "alphanum":
+		return "must contain only letters and digits"
+	default:
+		return "is invalid"
+	}
+}
+
+// profileChanges lists the leaf fields that differ between two profiles.
+func profileChanges(before, after domain.EditableProfile) ([]domain.FieldChange, error) {
+	old, err := flattenProfile(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := flattenProfile(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range updated {
+		keys[key] = true
+	}
+
+	changes := make([]domain.FieldChange, 0)
+	for key := range keys {
+		if reflect.DeepEqual(old[key], updated[key]) {
+			continue
+		}
+		change := domain.FieldChange{Field: key, Old: old[key], New: updated[key]}
+		if redactedProfileFields[key] {
+			if change.Old != nil {
+				change.Old = domain.RedactedValue
+			}
+			if change.New != nil {
+				change.New = domain.RedactedValue
+			}
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func flattenProfile(profile domain.EditableProfile) (map[string]interface{}, error) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	flat := make(map[string]interface{})
+	flattenInto(flat, "", doc)
+	return flat, nil
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, doc map[string]interface{}) {
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenInto(flat, key, nested)
+			continue
+		}
+		flat[key] = value
+	}
+}
@@ -3,24 +3,42 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/dagota12/Loan-Tracker/bootstrap"
 	"github.com/dagota12/Loan-Tracker/domain"
+	"github.com/dagota12/Loan-Tracker/internal/emailutil"
 	"github.com/dagota12/Loan-Tracker/internal/security"
+	"github.com/dagota12/Loan-Tracker/repository"
+)
+
+var (
+	ErrIncorrectPassword       = domain.Validation("incorrect_password", "current password is incorrect")
+	ErrEmailInUse              = domain.Conflict("email_in_use", "email already in use")
+	ErrSameEmail               = domain.Validation("same_email", "new email is the same as the current one")
+	ErrInvalidEmailChangeToken = domain.Unauthorized("invalid_email_change_token", "email change link is invalid or expired")
 )
 
 type userUsecase struct {
 	UserRepo       domain.UserRepository
+	AuditRepo      domain.AuditRepository
+	txManager      domain.TxManager
 	contextTimeout time.Duration
 	Env            *bootstrap.Env
+	passwordPolicy *security.PasswordPolicy
+	mailer         emailutil.Mailer
 }
 
-func NewUserUsecase(repo domain.UserRepository, env *bootstrap.Env) domain.UserUsecase {
+func NewUserUsecase(repo domain.UserRepository, auditRepo domain.AuditRepository, txManager domain.TxManager, env *bootstrap.Env, passwordPolicy *security.PasswordPolicy, mailer emailutil.Mailer) domain.UserUsecase {
 	return &userUsecase{
 		UserRepo:       repo,
+		AuditRepo:      auditRepo,
+		txManager:      txManager,
 		contextTimeout: time.Duration(env.ContextTimeout) * time.Second,
 		Env:            env,
+		passwordPolicy: passwordPolicy,
+		mailer:         mailer,
 	}
 }
 
@@ -33,7 +51,12 @@ func (uc *userUsecase) Create(ctx context.Context, user domain.User) (domain.Use
 	// Check if the email already exists
 	existingUser, _ := uc.UserRepo.GetByEmail(ctx, user.Email)
 	if existingUser.Email != "" {
-		return domain.User{}, errors.New("email already in use")
+		return domain.User{}, ErrEmailInUse
+	}
+
+	err := uc.passwordPolicy.Validate(user.Password, user.Email, user.FirstName, user.LastName)
+	if err != nil {
+		return domain.User{}, err
 	}
 
 	// Hash the password before creating the user
@@ -44,6 +67,9 @@ func (uc *userUsecase) Create(ctx context.Context, user domain.User) (domain.Use
 	user.Password = hashedPassword
 
 	createdUser, err := uc.UserRepo.Create(ctx, user)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return domain.User{}, ErrEmailInUse
+	}
 	if err != nil {
 		return domain.User{}, err
 	}
@@ -51,31 +77,12 @@ func (uc *userUsecase) Create(ctx context.Context, user domain.User) (domain.Use
 	return createdUser, nil
 }
 
-// Delete implements domain.UserUsecase.
-// Delete deletes a user by their ID.
-func (uc *userUsecase) Delete(ctx context.Context, userID string) error {
-	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
-	defer cancel()
-
-	err := uc.UserRepo.Delete(ctx, userID)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// GetAll retrieves all users from the repository.
-func (uc *userUsecase) GetAll(ctx context.Context) ([]domain.User, error) {
+// List returns one page of the users matching spec.
+func (uc *userUsecase) List(ctx context.Context, spec domain.QuerySpec) (domain.Page[domain.User], error) {
 	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
 	defer cancel()
 
-	users, err := uc.UserRepo.GetAll(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return users, nil
+	return uc.UserRepo.List(ctx, spec)
 }
 
 // GetByEmail implements domain.UserUsecase.
@@ -106,20 +113,6 @@ func (uc *userUsecase) GetByID(ctx context.Context, userID string) (domain.User,
 	return user, nil
 }
 
-// Update implements domain.UserUsecase.
-// Update updates user details by user ID.
-func (uc *userUsecase) Update(ctx context.Context, userID string, user domain.UserUpdate) (domain.User, error) {
-	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
-	defer cancel()
-
-	updatedUser, err := uc.UserRepo.Update(ctx, userID, user)
-	if err != nil {
-		return domain.User{}, err
-	}
-
-	return updatedUser, nil
-}
-
 // ResetUserPassword implements domain.UserUsecase.
 // ResetUserPassword resets the user's password using a reset token or temporary password.
 func (uc *userUsecase) ResetUserPassword(ctx context.Context, userID string, resetPassword domain.ResetPasswordRequest) error {
@@ -133,9 +126,24 @@ func (uc *userUsecase) ResetUserPassword(ctx context.Context, userID string, res
 		return err
 	}
 	if !active {
-		return errors.New("user is not active or does not exist")
+		return ErrUserNotActive
 	}
 
+	user, err := uc.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	err = uc.checkNewPassword(user, resetPassword.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := security.HashPassword(resetPassword.NewPassword)
+	if err != nil {
+		return err
+	}
+	resetPassword.NewPassword = hashedPassword
+
 	// Call the repository method to reset the password
 	err = uc.UserRepo.ResetUserPassword(ctx, userID, resetPassword)
 	if err != nil {
@@ -157,7 +165,7 @@ func (uc *userUsecase) UpdateUserPassword(ctx context.Context, userID string, up
 		return err
 	}
 	if !active {
-		return errors.New("user is not active or does not exist")
+		return ErrUserNotActive
 	}
 
 	// Validate the current password
@@ -166,8 +174,13 @@ func (uc *userUsecase) UpdateUserPassword(ctx context.Context, userID string, up
 		return err
 	}
 
-	if security.CheckPasswordHash(updatePassword.OldPassword, user.Password) {
-		return errors.New("current password is incorrect")
+	if !security.CheckPasswordHash(updatePassword.OldPassword, user.Password) {
+		return ErrIncorrectPassword
+	}
+
+	err = uc.checkNewPassword(user, updatePassword.NewPassword)
+	if err != nil {
+		return err
 	}
 
 	// Update the password using the repository method
@@ -184,3 +197,98 @@ func (uc *userUsecase) UpdateUserPassword(ctx context.Context, userID string, up
 
 	return nil
 }
+
+// checkNewPassword applies the password policy, including the reuse check
+// against the current and previous passwords of user.
+func (uc *userUsecase) checkNewPassword(user domain.User, password string) error {
+	err := uc.passwordPolicy.Validate(password, user.Email, user.FirstName, user.LastName)
+	if err != nil {
+		return err
+	}
+	return uc.passwordPolicy.CheckReuse(password, append([]string{user.Password}, user.PasswordHistory...))
+}
+
+// RequestEmailChange implements domain.UserUsecase.
+// RequestEmailChange re-authenticates the user, then sends a confirmation
+// link to the new address and a notice to the current one. The email only
+// changes once the link is followed.
+func (uc *userUsecase) RequestEmailChange(ctx context.Context, userID string, request domain.ChangeEmailRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !security.CheckPasswordHash(request.Password, user.Password) {
+		return ErrIncorrectPassword
+	}
+	if strings.EqualFold(user.Email, request.Email) {
+		return ErrSameEmail
+	}
+
+	_, err = uc.UserRepo.GetByEmail(ctx, request.Email)
+	if err == nil {
+		return ErrEmailInUse
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return err
+	}
+
+	token, err := security.NewRandomToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(time.Duration(uc.Env.EmailChangeExpiryMin) * time.Minute)
+	err = uc.UserRepo.SetPendingEmail(ctx, userID, request.Email, security.HashToken(token), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	err = emailutil.SendEmailChangeConfirmation(uc.mailer, request.Email, token, uc.Env)
+	if err != nil {
+		return err
+	}
+	return emailutil.SendEmailChangeNotice(uc.mailer, user.Email, request.Email, uc.Env)
+}
+
+// ConfirmEmailChange implements domain.UserUsecase.
+// ConfirmEmailChange switches the user to the pending email once its
+// uniqueness is checked again, and signs the user out everywhere.
+func (uc *userUsecase) ConfirmEmailChange(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, uc.contextTimeout)
+	defer cancel()
+
+	// the link carries nothing but the token, which is what the user is
+	// found by; everything else happens within the tenant of that user
+	tokenHash := security.HashToken(token)
+	user, err := uc.UserRepo.GetByEmailChangeToken(domain.WithAllTenants(ctx), tokenHash)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrInvalidEmailChangeToken
+	}
+	if err != nil {
+		return err
+	}
+	ctx = domain.WithTenant(ctx, user.TenantID)
+	if time.Now().After(user.EmailChangeExpiresAt) {
+		return ErrInvalidEmailChangeToken
+	}
+
+	// the address may have been taken since the change was requested
+	_, err = uc.UserRepo.GetByEmail(ctx, user.PendingEmail)
+	if err == nil {
+		return ErrEmailInUse
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return err
+	}
+
+	err = uc.UserRepo.ConfirmEmail(ctx, user.ID.Hex(), tokenHash)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrInvalidEmailChangeToken
+	}
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return ErrEmailInUse
+	}
+	return err
+}